@@ -6,7 +6,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
-	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
@@ -15,6 +15,7 @@ import (
 	"github.com/sangketkit01/media-library-api/internal/handlers"
 	"github.com/sangketkit01/media-library-api/internal/middleware"
 	"github.com/sangketkit01/media-library-api/internal/routes"
+	"github.com/sangketkit01/media-library-api/internal/storage"
 	"github.com/sangketkit01/media-library-api/internal/token"
 )
 
@@ -61,13 +62,13 @@ func main() {
 		log.Panic(err)
 	}
 
-	middleware := middleware.NewMiddleware(tokenMaker)
-
 	handler, err := handlers.NewHandler(config, tokenMaker)
 	if err != nil {
 		log.Panic(err)
 	}
 
+	middleware := middleware.NewMiddleware(tokenMaker, handler.Sessions, handler.Store, config.UnverifiedEmailPolicy)
+
 	router := routes.NewRoute(middleware, handler)
 
 	app := App{
@@ -77,14 +78,35 @@ func main() {
 		routes:     router,
 	}
 
-	uploadDir := "../../uploads"
-
 	// Create shutdown-aware context
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
 	// Start background task with graceful shutdown
-	go startBackgroundTask(ctx, uploadDir)
+	go startBackgroundTask(ctx, app.handler.Storage)
+
+	// Start media processing pipeline
+	pipelineDone := make(chan struct{})
+	go func() {
+		app.handler.Pipeline.Run(ctx)
+		close(pipelineDone)
+	}()
+
+	// Start trash purger
+	purgerDone := make(chan struct{})
+	go func() {
+		startTrashPurger(ctx, app.handler)
+		close(purgerDone)
+	}()
+
+	// Start session revocation listener
+	listenerDone := make(chan struct{})
+	go func() {
+		if app.config.SessionNotify {
+			app.handler.Sessions.Listen(ctx, app.handler.Pool)
+		}
+		close(listenerDone)
+	}()
 
 	// Start Fiber server
 	go func() {
@@ -104,56 +126,48 @@ func main() {
 		log.Printf("Fiber shutdown error: %v\n", err)
 	}
 
+	// Wait for in-flight media processing and purging before closing the pool
+	<-pipelineDone
+	<-purgerDone
+	<-listenerDone
+
 	// Close database pool
 	app.handler.Pool.Close()
 
 	log.Println("Graceful shutdown complete.")
 }
 
-func logTotalSizePerUser(uploadDir string) {
-	users, err := os.ReadDir(uploadDir)
+func logTotalSizePerUser(ctx context.Context, backend storage.Backend) {
+	objects, err := backend.List(ctx, "")
 	if err != nil {
-		log.Println("Failed to read upload directory:", err)
+		log.Println("Failed to list stored objects:", err)
 		return
 	}
 
-	for _, userDir := range users {
-		if !userDir.IsDir() {
+	totalSizes := make(map[string]int64)
+	for _, object := range objects {
+		userID, _, found := strings.Cut(object.Key, "/")
+		if !found {
 			continue
 		}
 
-		userPath := filepath.Join(uploadDir, userDir.Name())
-
-		var totalSize int64 = 0
-
-		err := filepath.Walk(userPath, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-			if !info.IsDir() {
-				totalSize += info.Size()
-			}
-			return nil
-		})
-
-		if err != nil {
-			log.Println("Error walking user folder", userDir.Name(), ":", err)
-			continue
-		}
+		totalSizes[userID] += object.Size
+	}
 
+	for userID, totalSize := range totalSizes {
 		totalMB := float64(totalSize) / (1024 * 1024)
-		log.Printf("User %s: Total size = %.2f MB\n", userDir.Name(), totalMB)
+		log.Printf("User %s: Total size = %.2f MB\n", userID, totalMB)
 	}
 }
 
-func startBackgroundTask(ctx context.Context, uploadDir string) {
+func startBackgroundTask(ctx context.Context, backend storage.Backend) {
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			logTotalSizePerUser(uploadDir)
+			logTotalSizePerUser(ctx, backend)
 
 		case <-ctx.Done():
 			log.Println("Background task stopping...")
@@ -161,3 +175,21 @@ func startBackgroundTask(ctx context.Context, uploadDir string) {
 		}
 	}
 }
+
+func startTrashPurger(ctx context.Context, handler *handlers.Handler) {
+	ticker := time.NewTicker(handler.Config.TrashPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := handler.PurgeTrash(ctx); err != nil && ctx.Err() == nil {
+				log.Println("Failed to purge trash:", err)
+			}
+
+		case <-ctx.Done():
+			log.Println("Trash purger stopping...")
+			return
+		}
+	}
+}
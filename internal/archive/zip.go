@@ -0,0 +1,422 @@
+// Package archive streams ZIP archives of stored media. Entries are stored
+// without compression, media formats are compressed already, which makes the
+// layout of an archive known before any content is read: its size is exact
+// and any byte range of it can be produced on its own, so interrupted
+// downloads can be resumed.
+package archive
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	localHeaderLen   = 30
+	centralHeaderLen = 46
+	endRecordLen     = 22
+	zip64EndLen      = 56
+	zip64LocatorLen  = 20
+
+	localHeaderSignature   = 0x04034b50
+	centralHeaderSignature = 0x02014b50
+	endSignature           = 0x06054b50
+	zip64EndSignature      = 0x06064b50
+	zip64LocatorSignature  = 0x07064b50
+	zip64ExtraID           = 0x0001
+
+	// flagUTF8 marks names as UTF-8.
+	flagUTF8 = 0x0800
+
+	versionDefault = 20
+	versionZip64   = 45
+
+	attrDirectory = 0x10
+
+	max16 = 0xffff
+	max32 = 0xffffffff
+)
+
+var errShortContent = errors.New("archive: entry content is shorter than its size")
+
+// Entry is a file or a directory of an archive.
+type Entry struct {
+	// Name is the slash separated path of the entry inside the archive.
+	Name     string
+	Size     int64
+	Modified time.Time
+	// CRC32 is the checksum of the content, Source.Checksum is asked for it
+	// when it is not known.
+	CRC32       uint32
+	HasCRC32    bool
+	IsDirectory bool
+}
+
+// Source provides the content of the entries of a manifest.
+type Source interface {
+	// Open reads length bytes of entry i starting at offset.
+	Open(i int, offset, length int64) (io.ReadCloser, error)
+	// Checksum returns the CRC-32 of the content of entry i.
+	Checksum(i int) (uint32, error)
+}
+
+// Manifest is the layout of an archive.
+type Manifest struct {
+	entries []Entry
+	// offsets holds the offset of the local header of each entry.
+	offsets         []int64
+	directoryOffset int64
+	directorySize   int64
+	size            int64
+}
+
+// NewManifest lays out an archive of entries in the given order. Directory
+// names get a trailing slash.
+func NewManifest(entries []Entry) *Manifest {
+	m := &Manifest{
+		entries: make([]Entry, len(entries)),
+		offsets: make([]int64, len(entries)),
+	}
+	copy(m.entries, entries)
+
+	var offset int64
+	for i := range m.entries {
+		e := &m.entries[i]
+		if e.IsDirectory {
+			if len(e.Name) == 0 || e.Name[len(e.Name)-1] != '/' {
+				e.Name += "/"
+			}
+			e.Size = 0
+			e.CRC32 = 0
+			e.HasCRC32 = true
+		}
+
+		m.offsets[i] = offset
+		offset += int64(localHeaderLen+len(e.Name)+len(localExtra(e))) + e.Size
+	}
+
+	m.directoryOffset = offset
+	for i := range m.entries {
+		m.directorySize += int64(centralHeaderLen + len(m.entries[i].Name) + len(m.centralExtra(i)))
+	}
+
+	m.size = m.directoryOffset + m.directorySize + endRecordLen
+	if m.needsZip64End() {
+		m.size += zip64EndLen + zip64LocatorLen
+	}
+
+	return m
+}
+
+// Size is the length of the archive in bytes.
+func (m *Manifest) Size() int64 {
+	return m.size
+}
+
+// Entries returns the entries of the archive.
+func (m *Manifest) Entries() []Entry {
+	return m.entries
+}
+
+// WriteRange writes length bytes of the archive starting at offset to w. Only
+// the content overlapping the range is read from src, checksums that are not
+// known are asked for when a header needing them is written.
+func (m *Manifest) WriteRange(w io.Writer, src Source, offset, length int64) error {
+	if offset < 0 || length < 0 || offset+length > m.size {
+		return fmt.Errorf("archive: range %d+%d outside of %d bytes", offset, length, m.size)
+	}
+
+	rw := &rangeWriter{w: w, start: offset, end: offset + length}
+
+	for i := range m.entries {
+		if rw.done() {
+			return nil
+		}
+
+		e := &m.entries[i]
+		headerLen := int64(localHeaderLen + len(e.Name) + len(localExtra(e)))
+		if rw.overlaps(headerLen) {
+			if err := m.resolve(i, src); err != nil {
+				return err
+			}
+			if err := rw.write(m.localHeader(i)); err != nil {
+				return err
+			}
+		} else {
+			rw.skip(headerLen)
+		}
+
+		from, n := rw.overlap(e.Size)
+		if n > 0 {
+			if err := copyContent(rw.w, src, i, from, n); err != nil {
+				return err
+			}
+		}
+		rw.skip(e.Size)
+	}
+
+	if rw.done() {
+		return nil
+	}
+
+	// The central directory repeats the checksum of every entry.
+	for i := range m.entries {
+		if err := m.resolve(i, src); err != nil {
+			return err
+		}
+	}
+
+	for i := range m.entries {
+		if err := rw.write(m.centralHeader(i)); err != nil {
+			return err
+		}
+	}
+
+	return rw.write(m.end())
+}
+
+func (m *Manifest) resolve(i int, src Source) error {
+	e := &m.entries[i]
+	if e.HasCRC32 {
+		return nil
+	}
+
+	sum, err := src.Checksum(i)
+	if err != nil {
+		return err
+	}
+
+	e.CRC32 = sum
+	e.HasCRC32 = true
+	return nil
+}
+
+func copyContent(w io.Writer, src Source, i int, offset, length int64) error {
+	r, err := src.Open(i, offset, length)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	n, err := io.CopyN(w, r, length)
+	if err == io.EOF && n < length {
+		return errShortContent
+	}
+	return err
+}
+
+func (m *Manifest) needsZip64End() bool {
+	return len(m.entries) >= max16 || m.directoryOffset >= max32 || m.directorySize >= max32
+}
+
+func needsZip64Size(e *Entry) bool {
+	return e.Size >= max32
+}
+
+// localExtra returns the ZIP64 extra field of a local header, which holds the
+// sizes of entries too large for the header itself.
+func localExtra(e *Entry) []byte {
+	if !needsZip64Size(e) {
+		return nil
+	}
+
+	b := make([]byte, 0, 20)
+	b = binary.LittleEndian.AppendUint16(b, zip64ExtraID)
+	b = binary.LittleEndian.AppendUint16(b, 16)
+	b = binary.LittleEndian.AppendUint64(b, uint64(e.Size))
+	b = binary.LittleEndian.AppendUint64(b, uint64(e.Size))
+	return b
+}
+
+// centralExtra returns the ZIP64 extra field of a central directory header,
+// holding the sizes and the offset that do not fit the header.
+func (m *Manifest) centralExtra(i int) []byte {
+	e := &m.entries[i]
+
+	var fields []uint64
+	if needsZip64Size(e) {
+		fields = append(fields, uint64(e.Size), uint64(e.Size))
+	}
+	if m.offsets[i] >= max32 {
+		fields = append(fields, uint64(m.offsets[i]))
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+
+	b := make([]byte, 0, 4+8*len(fields))
+	b = binary.LittleEndian.AppendUint16(b, zip64ExtraID)
+	b = binary.LittleEndian.AppendUint16(b, uint16(8*len(fields)))
+	for _, field := range fields {
+		b = binary.LittleEndian.AppendUint64(b, field)
+	}
+	return b
+}
+
+func (m *Manifest) version(i int) uint16 {
+	if needsZip64Size(&m.entries[i]) || m.offsets[i] >= max32 {
+		return versionZip64
+	}
+	return versionDefault
+}
+
+func (m *Manifest) localHeader(i int) []byte {
+	e := &m.entries[i]
+	extra := localExtra(e)
+	date, clock := msDosTime(e.Modified)
+
+	b := make([]byte, 0, localHeaderLen+len(e.Name)+len(extra))
+	b = binary.LittleEndian.AppendUint32(b, localHeaderSignature)
+	b = binary.LittleEndian.AppendUint16(b, m.version(i))
+	b = binary.LittleEndian.AppendUint16(b, flagUTF8)
+	b = binary.LittleEndian.AppendUint16(b, 0) // stored
+	b = binary.LittleEndian.AppendUint16(b, clock)
+	b = binary.LittleEndian.AppendUint16(b, date)
+	b = binary.LittleEndian.AppendUint32(b, e.CRC32)
+	b = binary.LittleEndian.AppendUint32(b, size32(e.Size))
+	b = binary.LittleEndian.AppendUint32(b, size32(e.Size))
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(e.Name)))
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(extra)))
+	b = append(b, e.Name...)
+	return append(b, extra...)
+}
+
+func (m *Manifest) centralHeader(i int) []byte {
+	e := &m.entries[i]
+	extra := m.centralExtra(i)
+	date, clock := msDosTime(e.Modified)
+
+	var attributes uint32
+	if e.IsDirectory {
+		attributes = attrDirectory
+	}
+
+	b := make([]byte, 0, centralHeaderLen+len(e.Name)+len(extra))
+	b = binary.LittleEndian.AppendUint32(b, centralHeaderSignature)
+	b = binary.LittleEndian.AppendUint16(b, versionZip64)
+	b = binary.LittleEndian.AppendUint16(b, m.version(i))
+	b = binary.LittleEndian.AppendUint16(b, flagUTF8)
+	b = binary.LittleEndian.AppendUint16(b, 0) // stored
+	b = binary.LittleEndian.AppendUint16(b, clock)
+	b = binary.LittleEndian.AppendUint16(b, date)
+	b = binary.LittleEndian.AppendUint32(b, e.CRC32)
+	b = binary.LittleEndian.AppendUint32(b, size32(e.Size))
+	b = binary.LittleEndian.AppendUint32(b, size32(e.Size))
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(e.Name)))
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(extra)))
+	b = binary.LittleEndian.AppendUint16(b, 0) // comment length
+	b = binary.LittleEndian.AppendUint16(b, 0) // disk number
+	b = binary.LittleEndian.AppendUint16(b, 0) // internal attributes
+	b = binary.LittleEndian.AppendUint32(b, attributes)
+	b = binary.LittleEndian.AppendUint32(b, size32(m.offsets[i]))
+	b = append(b, e.Name...)
+	return append(b, extra...)
+}
+
+// end returns the records closing the archive, preceded by their ZIP64
+// versions when the archive is too large for the classic one.
+func (m *Manifest) end() []byte {
+	var b []byte
+	count := uint64(len(m.entries))
+
+	if m.needsZip64End() {
+		zip64End := m.directoryOffset + m.directorySize
+
+		b = binary.LittleEndian.AppendUint32(b, zip64EndSignature)
+		b = binary.LittleEndian.AppendUint64(b, zip64EndLen-12)
+		b = binary.LittleEndian.AppendUint16(b, versionZip64)
+		b = binary.LittleEndian.AppendUint16(b, versionZip64)
+		b = binary.LittleEndian.AppendUint32(b, 0) // disk number
+		b = binary.LittleEndian.AppendUint32(b, 0) // disk with the directory
+		b = binary.LittleEndian.AppendUint64(b, count)
+		b = binary.LittleEndian.AppendUint64(b, count)
+		b = binary.LittleEndian.AppendUint64(b, uint64(m.directorySize))
+		b = binary.LittleEndian.AppendUint64(b, uint64(m.directoryOffset))
+
+		b = binary.LittleEndian.AppendUint32(b, zip64LocatorSignature)
+		b = binary.LittleEndian.AppendUint32(b, 0) // disk with the record
+		b = binary.LittleEndian.AppendUint64(b, uint64(zip64End))
+		b = binary.LittleEndian.AppendUint32(b, 1) // total disks
+	}
+
+	entries := uint16(max16)
+	if count < max16 {
+		entries = uint16(count)
+	}
+
+	b = binary.LittleEndian.AppendUint32(b, endSignature)
+	b = binary.LittleEndian.AppendUint16(b, 0) // disk number
+	b = binary.LittleEndian.AppendUint16(b, 0) // disk with the directory
+	b = binary.LittleEndian.AppendUint16(b, entries)
+	b = binary.LittleEndian.AppendUint16(b, entries)
+	b = binary.LittleEndian.AppendUint32(b, size32(m.directorySize))
+	b = binary.LittleEndian.AppendUint32(b, size32(m.directoryOffset))
+	return binary.LittleEndian.AppendUint16(b, 0) // comment length
+}
+
+// size32 returns a size or offset for a 32 bit field, which is saturated when
+// the value is stored in a ZIP64 field instead.
+func size32(n int64) uint32 {
+	if n >= max32 {
+		return max32
+	}
+	return uint32(n)
+}
+
+// msDosTime converts a time to the MS-DOS date and time of ZIP headers, which
+// cannot represent times before 1980.
+func msDosTime(t time.Time) (date, clock uint16) {
+	t = t.UTC()
+	if t.Year() < 1980 {
+		t = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	date = uint16(t.Day() + int(t.Month())<<5 + (t.Year()-1980)<<9)
+	clock = uint16(t.Second()/2 + t.Minute()<<5 + t.Hour()<<11)
+	return date, clock
+}
+
+// rangeWriter writes the part of a stream of sections that falls in the byte
+// range [start, end).
+type rangeWriter struct {
+	w          io.Writer
+	pos        int64
+	start, end int64
+}
+
+func (rw *rangeWriter) done() bool {
+	return rw.pos >= rw.end
+}
+
+// overlap returns the part of the next n bytes that falls in the range, as
+// an offset into them and a length.
+func (rw *rangeWriter) overlap(n int64) (int64, int64) {
+	from := max(rw.start, rw.pos)
+	to := min(rw.end, rw.pos+n)
+	if from >= to {
+		return 0, 0
+	}
+	return from - rw.pos, to - from
+}
+
+func (rw *rangeWriter) overlaps(n int64) bool {
+	_, length := rw.overlap(n)
+	return length > 0
+}
+
+func (rw *rangeWriter) skip(n int64) {
+	rw.pos += n
+}
+
+func (rw *rangeWriter) write(b []byte) error {
+	from, n := rw.overlap(int64(len(b)))
+	rw.pos += int64(len(b))
+	if n == 0 {
+		return nil
+	}
+
+	_, err := rw.w.Write(b[from : from+n])
+	return err
+}
@@ -0,0 +1,307 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testSource serves entries from memory. Entries without content are made
+// up of pattern bytes, so that large archives need no memory.
+type testSource struct {
+	t        *testing.T
+	contents [][]byte
+	sizes    []int64
+	// short makes Open return fewer bytes than asked for.
+	short     bool
+	checksums int
+}
+
+func (s *testSource) Open(i int, offset, length int64) (io.ReadCloser, error) {
+	if offset < 0 || length <= 0 || offset+length > s.sizes[i] {
+		s.t.Errorf("Open(%d, %d, %d) outside of %d bytes", i, offset, length, s.sizes[i])
+	}
+	if s.short {
+		length--
+	}
+
+	if s.contents[i] != nil {
+		return io.NopCloser(bytes.NewReader(s.contents[i][offset : offset+length])), nil
+	}
+	return io.NopCloser(io.LimitReader(&patternReader{pos: offset}, length)), nil
+}
+
+func (s *testSource) Checksum(i int) (uint32, error) {
+	s.checksums++
+	return crc32.ChecksumIEEE(s.contents[i]), nil
+}
+
+type patternReader struct {
+	pos int64
+}
+
+func (r *patternReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(r.pos % 251)
+		r.pos++
+	}
+	return len(p), nil
+}
+
+// rangeReaderAt reads an archive through WriteRange, the way resumed
+// downloads do.
+type rangeReaderAt struct {
+	m   *Manifest
+	src Source
+}
+
+func (r *rangeReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off >= r.m.Size() {
+		return 0, io.EOF
+	}
+
+	n := min(int64(len(p)), r.m.Size()-off)
+	w := &sliceWriter{buf: p[:n]}
+	if err := r.m.WriteRange(w, r.src, off, n); err != nil {
+		return 0, err
+	}
+	if int64(w.n) != n {
+		return w.n, fmt.Errorf("wrote %d bytes, want %d", w.n, n)
+	}
+	if n < int64(len(p)) {
+		return int(n), io.EOF
+	}
+	return int(n), nil
+}
+
+// sliceWriter fills buf. Unlike bytes.Buffer it never reallocates it.
+type sliceWriter struct {
+	buf []byte
+	n   int
+}
+
+func (w *sliceWriter) Write(p []byte) (int, error) {
+	if len(p) > len(w.buf)-w.n {
+		return 0, io.ErrShortWrite
+	}
+	w.n += copy(w.buf[w.n:], p)
+	return len(p), nil
+}
+
+func newTestArchive(t *testing.T) (*Manifest, *testSource) {
+	modified := time.Date(2024, time.March, 9, 14, 30, 20, 0, time.UTC)
+	hello := []byte("hello, world\n")
+	random := make([]byte, 5000)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	src := &testSource{
+		t:        t,
+		contents: [][]byte{nil, hello, random, {}},
+		sizes:    []int64{0, int64(len(hello)), int64(len(random)), 0},
+	}
+	m := NewManifest([]Entry{
+		{Name: "album", IsDirectory: true, Modified: modified},
+		{Name: "album/hello.txt", Size: int64(len(hello)), Modified: modified,
+			CRC32: crc32.ChecksumIEEE(hello), HasCRC32: true},
+		// The checksum of this one is only known to the source.
+		{Name: "album/ภาพ.bin", Size: int64(len(random)), Modified: modified},
+		{Name: "empty", Modified: modified, HasCRC32: true},
+	})
+	return m, src
+}
+
+func TestWriteRangeWritesReadableArchive(t *testing.T) {
+	m, src := newTestArchive(t)
+
+	var buf bytes.Buffer
+	if err := m.WriteRange(&buf, src, 0, m.Size()); err != nil {
+		t.Fatal(err)
+	}
+	if int64(buf.Len()) != m.Size() {
+		t.Fatalf("wrote %d bytes, Size is %d", buf.Len(), m.Size())
+	}
+	if src.checksums != 1 {
+		t.Errorf("asked for %d checksums, want 1", src.checksums)
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{"album/", "album/hello.txt", "album/ภาพ.bin", "empty"}
+	if len(r.File) != len(names) {
+		t.Fatalf("%d files, want %d", len(r.File), len(names))
+	}
+
+	for i, f := range r.File {
+		if f.Name != names[i] {
+			t.Errorf("file %d is %q, want %q", i, f.Name, names[i])
+		}
+		if f.Method != zip.Store || f.NonUTF8 {
+			t.Errorf("%s: method %d, non UTF-8 %v", f.Name, f.Method, f.NonUTF8)
+		}
+		if f.FileInfo().IsDir() != (i == 0) {
+			t.Errorf("%s: IsDir = %v", f.Name, f.FileInfo().IsDir())
+		}
+		if want := time.Date(2024, time.March, 9, 14, 30, 20, 0, time.UTC); !f.Modified.Equal(want) {
+			t.Errorf("%s: modified %v, want %v", f.Name, f.Modified, want)
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Reading to the end checks the CRC-32.
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", f.Name, err)
+		}
+		if want := src.contents[i]; !bytes.Equal(content, want) {
+			t.Errorf("%s: content differs", f.Name)
+		}
+	}
+}
+
+func TestWriteRangeResumes(t *testing.T) {
+	m, src := newTestArchive(t)
+
+	var full bytes.Buffer
+	if err := m.WriteRange(&full, src, 0, m.Size()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Resuming after any number of bytes yields the same archive.
+	for split := int64(0); split <= m.Size(); split++ {
+		var buf bytes.Buffer
+		if err := m.WriteRange(&buf, src, 0, split); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.WriteRange(&buf, src, split, m.Size()-split); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), full.Bytes()) {
+			t.Fatalf("resuming at %d gives a different archive", split)
+		}
+	}
+
+	random := rand.New(rand.NewSource(2))
+	for i := 0; i < 1000; i++ {
+		offset := random.Int63n(m.Size())
+		length := random.Int63n(m.Size() - offset + 1)
+
+		var buf bytes.Buffer
+		if err := m.WriteRange(&buf, src, offset, length); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), full.Bytes()[offset:offset+length]) {
+			t.Fatalf("range %d+%d differs", offset, length)
+		}
+	}
+}
+
+func TestWriteRangeRejectsInvalidRanges(t *testing.T) {
+	m, src := newTestArchive(t)
+
+	for _, r := range [][2]int64{{-1, 1}, {0, -1}, {0, m.Size() + 1}, {m.Size(), 1}} {
+		if err := m.WriteRange(io.Discard, src, r[0], r[1]); err == nil {
+			t.Errorf("WriteRange(%d, %d) succeeded", r[0], r[1])
+		}
+	}
+
+	src.short = true
+	if err := m.WriteRange(io.Discard, src, 0, m.Size()); !errors.Is(err, errShortContent) {
+		t.Errorf("short content: err = %v, want errShortContent", err)
+	}
+}
+
+func TestZip64LargeEntries(t *testing.T) {
+	const large = 5 << 30
+	tail := []byte("after the large entry")
+
+	src := &testSource{
+		t:        t,
+		contents: [][]byte{nil, tail},
+		sizes:    []int64{large, int64(len(tail))},
+	}
+	m := NewManifest([]Entry{
+		// The real checksum is never verified, the content is not read to the end.
+		{Name: "video.mp4", Size: large, CRC32: 0x12345678, HasCRC32: true},
+		// Starts past 4 GiB, its offset needs a ZIP64 field.
+		{Name: "notes.txt", Size: int64(len(tail)), CRC32: crc32.ChecksumIEEE(tail), HasCRC32: true},
+	})
+
+	r, err := zip.NewReader(&rangeReaderAt{m: m, src: src}, m.Size())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.File) != 2 {
+		t.Fatalf("%d files, want 2", len(r.File))
+	}
+
+	video := r.File[0]
+	if video.UncompressedSize64 != large || video.CompressedSize64 != large {
+		t.Errorf("video sizes = %d, %d, want %d", video.UncompressedSize64, video.CompressedSize64, int64(large))
+	}
+
+	rc, err := video.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	head := make([]byte, 300)
+	if _, err := io.ReadFull(rc, head); err != nil {
+		t.Fatal(err)
+	}
+	rc.Close()
+	for i, b := range head {
+		if b != byte(i%251) {
+			t.Fatalf("video byte %d = %d, want %d", i, b, i%251)
+		}
+	}
+
+	rc, err = r.File[1].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil || !bytes.Equal(content, tail) {
+		t.Errorf("notes = %q, %v", content, err)
+	}
+}
+
+func TestZip64ManyEntries(t *testing.T) {
+	entries := make([]Entry, max16+10)
+	for i := range entries {
+		entries[i] = Entry{Name: fmt.Sprintf("%05d", i), HasCRC32: true}
+	}
+	m := NewManifest(entries)
+
+	src := &testSource{t: t}
+	var buf bytes.Buffer
+	if err := m.WriteRange(&buf, src, 0, m.Size()); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.File) != len(entries) {
+		t.Fatalf("%d files, want %d", len(r.File), len(entries))
+	}
+	if last := r.File[len(r.File)-1].Name; last != entries[len(entries)-1].Name {
+		t.Errorf("last file is %q", last)
+	}
+	if !strings.HasPrefix(r.File[0].Name, "00000") {
+		t.Errorf("first file is %q", r.File[0].Name)
+	}
+}
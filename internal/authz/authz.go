@@ -0,0 +1,150 @@
+// Package authz decides which media files and groups a user may act on.
+// Handlers ask the Policy for a resource instead of loading it themselves, so
+// every endpoint applies the same rules.
+package authz
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/sangketkit01/media-library-api/internal/db/sqlc"
+)
+
+// ErrNotFound is returned both for missing resources and for resources the
+// user may not access, so their existence is not disclosed.
+var ErrNotFound = errors.New("resource not found")
+
+// Action is what a user wants to do with a resource.
+type Action int
+
+const (
+	Read Action = iota
+	Write
+	// Own is reserved to the owner of a resource, such as deleting, moving or
+	// sharing it. Read and Write may also be granted through group shares.
+	Own
+)
+
+type Policy struct {
+	store db.Store
+}
+
+func NewPolicy(store db.Store) *Policy {
+	return &Policy{
+		store: store,
+	}
+}
+
+func notFound(err error) error {
+	if err == pgx.ErrNoRows {
+		return ErrNotFound
+	}
+	return err
+}
+
+// Media returns the media file with the given id if the user may perform
+// action on it. Besides their own media, users may read the media of groups
+// shared with them and edit those of groups they are editors of.
+func (p *Policy) Media(ctx context.Context, user db.User, id pgtype.UUID, action Action) (db.MediaFile, error) {
+	media, err := p.store.GetMediaFileForUser(ctx, db.GetMediaFileForUserParams{
+		ID:     id,
+		UserID: user.ID,
+	})
+	if err != pgx.ErrNoRows || action == Own {
+		return media, notFound(err)
+	}
+
+	media, err = p.store.GetSharedMediaFileForUser(ctx, db.GetSharedMediaFileForUserParams{
+		ID:         id,
+		UserID:     user.ID,
+		EditorOnly: action == Write,
+	})
+	return media, notFound(err)
+}
+
+// Group returns the group with the given id if the user may perform action
+// on it. A share of a group also grants access to its descendants.
+func (p *Policy) Group(ctx context.Context, user db.User, id pgtype.UUID, action Action) (db.MediaGroup, error) {
+	group, err := p.store.GetGroupForUser(ctx, db.GetGroupForUserParams{
+		ID:     id,
+		UserID: user.ID,
+	})
+	if err != pgx.ErrNoRows || action == Own {
+		return group, notFound(err)
+	}
+
+	group, err = p.store.GetSharedGroupForUser(ctx, db.GetSharedGroupForUserParams{
+		ID:         id,
+		UserID:     user.ID,
+		EditorOnly: action == Write,
+	})
+	return group, notFound(err)
+}
+
+// TrashedMedia returns a media file of the user that is in the trash.
+func (p *Policy) TrashedMedia(ctx context.Context, user db.User, id pgtype.UUID) (db.MediaFile, error) {
+	media, err := p.store.GetTrashedMediaFileForUser(ctx, db.GetTrashedMediaFileForUserParams{
+		ID:     id,
+		UserID: user.ID,
+	})
+	return media, notFound(err)
+}
+
+// TrashedGroup returns a group of the user that is in the trash.
+func (p *Policy) TrashedGroup(ctx context.Context, user db.User, id pgtype.UUID) (db.MediaGroup, error) {
+	group, err := p.store.GetTrashedGroupForUser(ctx, db.GetTrashedGroupForUserParams{
+		ID:     id,
+		UserID: user.ID,
+	})
+	return group, notFound(err)
+}
+
+// AssignMediaToGroup appends a media file to a group. Ownership of both is
+// checked by the insert itself, so the check and the change cannot race.
+func (p *Policy) AssignMediaToGroup(ctx context.Context, user db.User, mediaID, groupID pgtype.UUID) (db.GroupMembership, error) {
+	memberships, err := p.store.AddMediaToGroupTx(ctx, db.AddMediaToGroupTxParams{
+		UserID:   user.ID,
+		GroupID:  groupID,
+		MediaIDs: []pgtype.UUID{mediaID},
+	})
+	if err != nil {
+		return db.GroupMembership{}, notFound(err)
+	}
+	return memberships[0], nil
+}
+
+// AddMediaToGroup appends several media files to a group the user may write
+// to, failing as a whole when any of them does not belong to the owner of the
+// group.
+func (p *Policy) AddMediaToGroup(ctx context.Context, group db.MediaGroup, mediaIDs []pgtype.UUID) ([]db.GroupMembership, error) {
+	memberships, err := p.store.AddMediaToGroupTx(ctx, db.AddMediaToGroupTxParams{
+		UserID:   group.UserID,
+		GroupID:  group.ID,
+		MediaIDs: mediaIDs,
+	})
+	return memberships, notFound(err)
+}
+
+// TagMedia adds tags to media files of the user, failing as a whole when any
+// of them is not accessible.
+func (p *Policy) TagMedia(ctx context.Context, user db.User, mediaIDs []pgtype.UUID, names []string) ([]db.Tag, error) {
+	tags, err := p.store.TagMediaTx(ctx, db.TagMediaTxParams{
+		UserID:   user.ID,
+		MediaIDs: mediaIDs,
+		Names:    names,
+	})
+	return tags, notFound(err)
+}
+
+// UntagMedia removes tags from media files of the user, failing as a whole
+// when any of them is not accessible.
+func (p *Policy) UntagMedia(ctx context.Context, user db.User, mediaIDs []pgtype.UUID, names []string) (int64, error) {
+	removed, err := p.store.UntagMediaTx(ctx, db.TagMediaTxParams{
+		UserID:   user.ID,
+		MediaIDs: mediaIDs,
+		Names:    names,
+	})
+	return removed, notFound(err)
+}
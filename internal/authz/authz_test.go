@@ -0,0 +1,233 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/sangketkit01/media-library-api/internal/db/sqlc"
+)
+
+const (
+	roleViewer = "viewer"
+	roleEditor = "editor"
+)
+
+// fakeStore answers the lookups of the policy from memory, the way the
+// queries filter rows. Calling any other method of db.Store panics.
+type fakeStore struct {
+	db.Store
+
+	// owners maps media files and groups to the user they belong to.
+	owners map[uuid.UUID]uuid.UUID
+	groups map[uuid.UUID]bool
+	// roles maps a resource to the roles it is shared with by user.
+	roles map[uuid.UUID]map[uuid.UUID]string
+	err   error
+	// sharedLookups counts the queries for shared resources.
+	sharedLookups int
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		owners: make(map[uuid.UUID]uuid.UUID),
+		groups: make(map[uuid.UUID]bool),
+		roles:  make(map[uuid.UUID]map[uuid.UUID]string),
+	}
+}
+
+func (s *fakeStore) addMedia(owner db.User) pgtype.UUID {
+	id := uuid.New()
+	s.owners[id] = owner.ID.Bytes
+	return pgtype.UUID{Bytes: id, Valid: true}
+}
+
+func (s *fakeStore) addGroup(owner db.User) pgtype.UUID {
+	id := s.addMedia(owner)
+	s.groups[id.Bytes] = true
+	return id
+}
+
+func (s *fakeStore) share(id pgtype.UUID, user db.User, role string) {
+	if s.roles[id.Bytes] == nil {
+		s.roles[id.Bytes] = make(map[uuid.UUID]string)
+	}
+	s.roles[id.Bytes][user.ID.Bytes] = role
+}
+
+func (s *fakeStore) owned(id, userID pgtype.UUID, group bool) bool {
+	owner, ok := s.owners[id.Bytes]
+	return ok && s.groups[id.Bytes] == group && owner == userID.Bytes
+}
+
+func (s *fakeStore) shared(id, userID pgtype.UUID, group, editorOnly bool) bool {
+	s.sharedLookups++
+	if _, ok := s.owners[id.Bytes]; !ok || s.groups[id.Bytes] != group {
+		return false
+	}
+
+	role, ok := s.roles[id.Bytes][userID.Bytes]
+	return ok && (!editorOnly || role == roleEditor)
+}
+
+func (s *fakeStore) GetMediaFileForUser(ctx context.Context, arg db.GetMediaFileForUserParams) (db.MediaFile, error) {
+	if s.err != nil {
+		return db.MediaFile{}, s.err
+	}
+	if !s.owned(arg.ID, arg.UserID, false) {
+		return db.MediaFile{}, pgx.ErrNoRows
+	}
+	return db.MediaFile{ID: arg.ID, UserID: arg.UserID}, nil
+}
+
+func (s *fakeStore) GetSharedMediaFileForUser(ctx context.Context, arg db.GetSharedMediaFileForUserParams) (db.MediaFile, error) {
+	if !s.shared(arg.ID, arg.UserID, false, arg.EditorOnly) {
+		return db.MediaFile{}, pgx.ErrNoRows
+	}
+	return db.MediaFile{ID: arg.ID, UserID: pgtype.UUID{Bytes: s.owners[arg.ID.Bytes], Valid: true}}, nil
+}
+
+func (s *fakeStore) GetGroupForUser(ctx context.Context, arg db.GetGroupForUserParams) (db.MediaGroup, error) {
+	if s.err != nil {
+		return db.MediaGroup{}, s.err
+	}
+	if !s.owned(arg.ID, arg.UserID, true) {
+		return db.MediaGroup{}, pgx.ErrNoRows
+	}
+	return db.MediaGroup{ID: arg.ID, UserID: arg.UserID}, nil
+}
+
+func (s *fakeStore) GetSharedGroupForUser(ctx context.Context, arg db.GetSharedGroupForUserParams) (db.MediaGroup, error) {
+	if !s.shared(arg.ID, arg.UserID, true, arg.EditorOnly) {
+		return db.MediaGroup{}, pgx.ErrNoRows
+	}
+	return db.MediaGroup{ID: arg.ID, UserID: pgtype.UUID{Bytes: s.owners[arg.ID.Bytes], Valid: true}}, nil
+}
+
+func newUser() db.User {
+	return db.User{ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}}
+}
+
+// access tries an action on a resource.
+type access func(user db.User, id pgtype.UUID, action Action) error
+
+func mediaAccess(p *Policy) access {
+	return func(user db.User, id pgtype.UUID, action Action) error {
+		_, err := p.Media(context.Background(), user, id, action)
+		return err
+	}
+}
+
+func groupAccess(p *Policy) access {
+	return func(user db.User, id pgtype.UUID, action Action) error {
+		_, err := p.Group(context.Background(), user, id, action)
+		return err
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	owner, viewer, editor, stranger := newUser(), newUser(), newUser(), newUser()
+
+	tests := []struct {
+		user db.User
+		name string
+		// allowed holds the actions the user may perform.
+		allowed map[Action]bool
+	}{
+		{owner, "owner", map[Action]bool{Read: true, Write: true, Own: true}},
+		{viewer, "viewer", map[Action]bool{Read: true}},
+		{editor, "editor", map[Action]bool{Read: true, Write: true}},
+		{stranger, "stranger", map[Action]bool{}},
+	}
+
+	for _, resource := range []string{"media", "group"} {
+		store := newFakeStore()
+		policy := NewPolicy(store)
+
+		var id pgtype.UUID
+		var try access
+		if resource == "media" {
+			id = store.addMedia(owner)
+			try = mediaAccess(policy)
+		} else {
+			id = store.addGroup(owner)
+			try = groupAccess(policy)
+		}
+		store.share(id, viewer, roleViewer)
+		store.share(id, editor, roleEditor)
+
+		for _, tt := range tests {
+			for _, action := range []Action{Read, Write, Own} {
+				err := try(tt.user, id, action)
+				if tt.allowed[action] {
+					if err != nil {
+						t.Errorf("%s of %s, action %d: %v", tt.name, resource, action, err)
+					}
+				} else if !errors.Is(err, ErrNotFound) {
+					t.Errorf("%s of %s, action %d: err = %v, want ErrNotFound", tt.name, resource, action, err)
+				}
+			}
+		}
+	}
+}
+
+func TestPolicyDoesNotMixMediaAndGroups(t *testing.T) {
+	store := newFakeStore()
+	policy := NewPolicy(store)
+	user := newUser()
+
+	media := store.addMedia(user)
+	group := store.addGroup(user)
+
+	if _, err := policy.Group(context.Background(), user, media, Read); !errors.Is(err, ErrNotFound) {
+		t.Errorf("media read as a group: err = %v", err)
+	}
+	if _, err := policy.Media(context.Background(), user, group, Read); !errors.Is(err, ErrNotFound) {
+		t.Errorf("group read as media: err = %v", err)
+	}
+}
+
+func TestPolicyOwnSkipsShares(t *testing.T) {
+	store := newFakeStore()
+	policy := NewPolicy(store)
+	owner, editor := newUser(), newUser()
+
+	media := store.addMedia(owner)
+	group := store.addGroup(owner)
+	store.share(media, editor, roleEditor)
+	store.share(group, editor, roleEditor)
+
+	if _, err := policy.Media(context.Background(), editor, media, Own); !errors.Is(err, ErrNotFound) {
+		t.Errorf("editor owns media: err = %v", err)
+	}
+	if _, err := policy.Group(context.Background(), editor, group, Own); !errors.Is(err, ErrNotFound) {
+		t.Errorf("editor owns group: err = %v", err)
+	}
+	if store.sharedLookups != 0 {
+		t.Errorf("%d shared lookups for Own, want none", store.sharedLookups)
+	}
+}
+
+func TestPolicyReturnsStoreErrors(t *testing.T) {
+	store := newFakeStore()
+	policy := NewPolicy(store)
+	user := newUser()
+	media := store.addMedia(user)
+	group := store.addGroup(user)
+
+	store.err = errors.New("connection refused")
+
+	// Failures are not reported as missing resources, nor retried as shares.
+	if _, err := policy.Media(context.Background(), user, media, Read); err != store.err {
+		t.Errorf("media: err = %v, want %v", err, store.err)
+	}
+	if _, err := policy.Group(context.Background(), user, group, Read); err != store.err {
+		t.Errorf("group: err = %v, want %v", err, store.err)
+	}
+	if store.sharedLookups != 0 {
+		t.Errorf("%d shared lookups after a failure, want none", store.sharedLookups)
+	}
+}
@@ -1,17 +1,86 @@
 package config
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/spf13/viper"
 )
 
+const (
+	UnverifiedAllow    = "allow"
+	UnverifiedReadOnly = "read_only"
+	UnverifiedDeny     = "deny"
+)
+
 type Config struct {
-	Environment   string        `mapstructure:"ENVIRONMENT"`
-	DatabaseUrl   string        `mapstructure:"DATABASE_URL"`
-	Secretkey     string        `mapstructure:"SECRETKEY"`
-	AccessTokenDuration time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	Environment          string        `mapstructure:"ENVIRONMENT"`
+	DatabaseUrl          string        `mapstructure:"DATABASE_URL"`
+	Secretkey            string        `mapstructure:"SECRETKEY"`
+	AccessTokenDuration  time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
 	RefreshTokenDuration time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+
+	StorageBackend   string `mapstructure:"STORAGE_BACKEND"`
+	StorageLocalPath string `mapstructure:"STORAGE_LOCAL_PATH"`
+	S3Endpoint       string `mapstructure:"S3_ENDPOINT"`
+	S3Region         string `mapstructure:"S3_REGION"`
+	S3Bucket         string `mapstructure:"S3_BUCKET"`
+	S3AccessKey      string `mapstructure:"S3_ACCESS_KEY"`
+	S3SecretKey      string `mapstructure:"S3_SECRET_KEY"`
+
+	TusMaxSize int64 `mapstructure:"TUS_MAX_SIZE"`
+	// RequestBodyLimit caps request bodies other than tus chunks, which are
+	// streamed to storage and only limited by TusMaxSize.
+	RequestBodyLimit int `mapstructure:"REQUEST_BODY_LIMIT"`
+
+	PipelineWorkers  int      `mapstructure:"PIPELINE_WORKERS"`
+	ThumbnailSizes   []int    `mapstructure:"THUMBNAIL_SIZES"`
+	ThumbnailFormats []string `mapstructure:"THUMBNAIL_FORMATS"`
+
+	UploadAllowedTypes string `mapstructure:"UPLOAD_ALLOWED_TYPES"`
+	UploadDeniedTypes  string `mapstructure:"UPLOAD_DENIED_TYPES"`
+	UploadTypeMismatch string `mapstructure:"UPLOAD_TYPE_MISMATCH_TIERS"`
+
+	QuotaDefaultBytes int64 `mapstructure:"QUOTA_DEFAULT_BYTES"`
+	QuotaDefaultFiles int64 `mapstructure:"QUOTA_DEFAULT_FILES"`
+
+	TrashRetention     time.Duration `mapstructure:"TRASH_RETENTION"`
+	TrashPurgeInterval time.Duration `mapstructure:"TRASH_PURGE_INTERVAL"`
+
+	SessionCacheSize int           `mapstructure:"SESSION_CACHE_SIZE"`
+	SessionCacheTTL  time.Duration `mapstructure:"SESSION_CACHE_TTL"`
+	SessionNotify    bool          `mapstructure:"SESSION_NOTIFY"`
+
+	MailerBackend  string `mapstructure:"MAILER_BACKEND"`
+	MailerFrom     string `mapstructure:"MAILER_FROM"`
+	MailerFilePath string `mapstructure:"MAILER_FILE_PATH"`
+	SMTPHost       string `mapstructure:"SMTP_HOST"`
+	SMTPPort       int    `mapstructure:"SMTP_PORT"`
+	SMTPUsername   string `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword   string `mapstructure:"SMTP_PASSWORD"`
+
+	// UnverifiedEmailPolicy is what users who have not verified their email
+	// may do: "allow" everything, "read_only" or "deny" login.
+	UnverifiedEmailPolicy     string        `mapstructure:"UNVERIFIED_EMAIL_POLICY"`
+	EmailVerificationTTL      time.Duration `mapstructure:"EMAIL_VERIFICATION_TTL"`
+	EmailVerificationCooldown time.Duration `mapstructure:"EMAIL_VERIFICATION_COOLDOWN"`
+	EmailVerificationURL      string        `mapstructure:"EMAIL_VERIFICATION_URL"`
+
+	PasswordResetTTL    time.Duration `mapstructure:"PASSWORD_RESET_TTL"`
+	PasswordResetURL    string        `mapstructure:"PASSWORD_RESET_URL"`
+	PasswordResetWindow time.Duration `mapstructure:"PASSWORD_RESET_WINDOW"`
+	// PasswordResetLimit is how many reset emails an account gets per
+	// window, PasswordResetIPLimit how many requests a client may make.
+	PasswordResetLimit   int `mapstructure:"PASSWORD_RESET_LIMIT"`
+	PasswordResetIPLimit int `mapstructure:"PASSWORD_RESET_IP_LIMIT"`
+
+	// ShareRateLimit is how many requests a client may make to share links
+	// per ShareRateWindow. SharePasswordMaxAttempts wrong passwords in a row
+	// lock a share link for SharePasswordLockout.
+	ShareRateLimit           int           `mapstructure:"SHARE_RATE_LIMIT"`
+	ShareRateWindow          time.Duration `mapstructure:"SHARE_RATE_WINDOW"`
+	SharePasswordMaxAttempts int           `mapstructure:"SHARE_PASSWORD_MAX_ATTEMPTS"`
+	SharePasswordLockout     time.Duration `mapstructure:"SHARE_PASSWORD_LOCKOUT"`
 }
 
 func NewConfig(path, env string) (*Config, error) {
@@ -19,6 +88,49 @@ func NewConfig(path, env string) (*Config, error) {
 	viper.AddConfigPath(path)
 	viper.SetConfigType("env")
 
+	viper.SetDefault("STORAGE_BACKEND", "local")
+	viper.SetDefault("STORAGE_LOCAL_PATH", "../../uploads")
+	viper.SetDefault("S3_ENDPOINT", "")
+	viper.SetDefault("S3_REGION", "us-east-1")
+	viper.SetDefault("S3_BUCKET", "")
+	viper.SetDefault("S3_ACCESS_KEY", "")
+	viper.SetDefault("S3_SECRET_KEY", "")
+	viper.SetDefault("TUS_MAX_SIZE", 10<<30)
+	viper.SetDefault("REQUEST_BODY_LIMIT", 4<<20)
+	viper.SetDefault("PIPELINE_WORKERS", 2)
+	viper.SetDefault("THUMBNAIL_SIZES", "128,256,512")
+	viper.SetDefault("THUMBNAIL_FORMATS", "jpeg,webp")
+	viper.SetDefault("UPLOAD_ALLOWED_TYPES", "*:image/*,video/*,audio/*,application/pdf,text/plain,text/csv;pro:*")
+	viper.SetDefault("UPLOAD_DENIED_TYPES", "*:application/x-msdownload,application/x-executable,application/x-mach-binary,text/x-shellscript,text/html,application/xhtml+xml,image/svg+xml")
+	viper.SetDefault("UPLOAD_TYPE_MISMATCH_TIERS", "")
+	viper.SetDefault("QUOTA_DEFAULT_BYTES", 10<<30)
+	viper.SetDefault("QUOTA_DEFAULT_FILES", 100000)
+	viper.SetDefault("TRASH_RETENTION", "720h")
+	viper.SetDefault("TRASH_PURGE_INTERVAL", "1h")
+	viper.SetDefault("SESSION_CACHE_SIZE", 10000)
+	viper.SetDefault("SESSION_CACHE_TTL", "10s")
+	viper.SetDefault("SESSION_NOTIFY", false)
+	viper.SetDefault("MAILER_BACKEND", "log")
+	viper.SetDefault("MAILER_FROM", "Media Library <no-reply@localhost>")
+	viper.SetDefault("MAILER_FILE_PATH", "../../mail")
+	viper.SetDefault("SMTP_HOST", "")
+	viper.SetDefault("SMTP_PORT", 587)
+	viper.SetDefault("SMTP_USERNAME", "")
+	viper.SetDefault("SMTP_PASSWORD", "")
+	viper.SetDefault("UNVERIFIED_EMAIL_POLICY", "read_only")
+	viper.SetDefault("EMAIL_VERIFICATION_TTL", "48h")
+	viper.SetDefault("EMAIL_VERIFICATION_COOLDOWN", "1m")
+	viper.SetDefault("EMAIL_VERIFICATION_URL", "")
+	viper.SetDefault("PASSWORD_RESET_TTL", "1h")
+	viper.SetDefault("PASSWORD_RESET_URL", "")
+	viper.SetDefault("PASSWORD_RESET_WINDOW", "1h")
+	viper.SetDefault("PASSWORD_RESET_LIMIT", 3)
+	viper.SetDefault("PASSWORD_RESET_IP_LIMIT", 20)
+	viper.SetDefault("SHARE_RATE_LIMIT", 120)
+	viper.SetDefault("SHARE_RATE_WINDOW", "1m")
+	viper.SetDefault("SHARE_PASSWORD_MAX_ATTEMPTS", 10)
+	viper.SetDefault("SHARE_PASSWORD_LOCKOUT", "15m")
+
 	viper.AutomaticEnv()
 
 	if err := viper.ReadInConfig(); err != nil {
@@ -30,5 +142,11 @@ func NewConfig(path, env string) (*Config, error) {
 		return nil, err
 	}
 
+	switch config.UnverifiedEmailPolicy {
+	case UnverifiedAllow, UnverifiedReadOnly, UnverifiedDeny:
+	default:
+		return nil, fmt.Errorf("unknown unverified email policy %q", config.UnverifiedEmailPolicy)
+	}
+
 	return &config, nil
 }
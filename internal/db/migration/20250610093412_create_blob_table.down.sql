@@ -0,0 +1,2 @@
+ALTER TABLE media_files DROP COLUMN IF EXISTS blob_id;
+DROP TABLE IF EXISTS blobs;
@@ -0,0 +1,24 @@
+CREATE TABLE blobs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    digest TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    storage_key TEXT NOT NULL,
+    ref_count INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (user_id, digest)
+);
+
+ALTER TABLE media_files ADD COLUMN blob_id UUID REFERENCES blobs(id);
+
+-- Files uploaded before hashing keep their original location, each one gets
+-- its own blob since their digest is unknown.
+INSERT INTO blobs (id, user_id, digest, size, storage_key, ref_count, created_at)
+SELECT id, user_id, 'legacy:' || id::text, size, user_id::text || '/' || filename, 1, COALESCE(uploaded_at, now())
+FROM media_files;
+
+UPDATE media_files SET blob_id = id;
+
+ALTER TABLE media_files ALTER COLUMN blob_id SET NOT NULL;
+
+CREATE INDEX media_files_blob_id_idx ON media_files (blob_id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS media_files_unprocessed_idx;
+ALTER TABLE media_files DROP COLUMN IF EXISTS processed_at;
+DROP TABLE IF EXISTS media_derivatives;
@@ -0,0 +1,17 @@
+CREATE TABLE media_derivatives (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    media_id UUID NOT NULL REFERENCES media_files(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    format TEXT NOT NULL,
+    storage_key TEXT NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    bytes BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (media_id, kind, size, format)
+);
+
+ALTER TABLE media_files ADD COLUMN processed_at TIMESTAMPTZ;
+
+CREATE INDEX media_files_unprocessed_idx ON media_files (uploaded_at) WHERE processed_at IS NULL;
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS media_files_user_dimensions_idx;
+DROP INDEX IF EXISTS media_files_user_taken_at_idx;
+
+ALTER TABLE media_files
+    DROP COLUMN IF EXISTS height,
+    DROP COLUMN IF EXISTS width,
+    DROP COLUMN IF EXISTS taken_at,
+    DROP COLUMN IF EXISTS metadata;
@@ -0,0 +1,11 @@
+ALTER TABLE media_files
+    ADD COLUMN metadata JSONB NOT NULL DEFAULT '{}',
+    ADD COLUMN taken_at TIMESTAMPTZ,
+    ADD COLUMN width INTEGER,
+    ADD COLUMN height INTEGER;
+
+CREATE INDEX media_files_user_taken_at_idx ON media_files (user_id, taken_at);
+CREATE INDEX media_files_user_dimensions_idx ON media_files (user_id, width, height);
+
+-- Run existing media through the pipeline again so their metadata is extracted.
+UPDATE media_files SET processed_at = NULL;
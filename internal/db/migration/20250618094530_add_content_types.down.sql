@@ -0,0 +1,5 @@
+ALTER TABLE media_files
+    DROP COLUMN IF EXISTS detected_type,
+    DROP COLUMN IF EXISTS declared_type;
+
+ALTER TABLE users DROP COLUMN IF EXISTS tier;
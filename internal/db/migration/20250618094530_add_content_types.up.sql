@@ -0,0 +1,8 @@
+ALTER TABLE users ADD COLUMN tier TEXT NOT NULL DEFAULT 'free';
+
+ALTER TABLE media_files
+    ADD COLUMN declared_type TEXT NOT NULL DEFAULT '',
+    ADD COLUMN detected_type TEXT NOT NULL DEFAULT '';
+
+-- Existing files were typed from their extension only.
+UPDATE media_files SET declared_type = file_type;
@@ -0,0 +1,14 @@
+-- Limits override the configured defaults for a single user, a null limit
+-- keeps the default and 0 means unlimited. There is no API to change them,
+-- they are managed with SQL:
+--
+--   INSERT INTO user_quotas (user_id, max_bytes, max_files)
+--   VALUES ('<user id>', 10737418240, NULL)
+--   ON CONFLICT (user_id) DO UPDATE
+--   SET max_bytes = EXCLUDED.max_bytes, max_files = EXCLUDED.max_files, updated_at = now();
+CREATE TABLE user_quotas (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    max_bytes BIGINT,
+    max_files BIGINT,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS media_groups_trash_idx;
+DROP INDEX IF EXISTS media_files_trash_idx;
+
+ALTER TABLE media_groups DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE media_files DROP COLUMN IF EXISTS deleted_at;
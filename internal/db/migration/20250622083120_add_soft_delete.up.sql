@@ -0,0 +1,5 @@
+ALTER TABLE media_files ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE media_groups ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE INDEX media_files_trash_idx ON media_files (user_id, deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX media_groups_trash_idx ON media_groups (user_id, deleted_at) WHERE deleted_at IS NOT NULL;
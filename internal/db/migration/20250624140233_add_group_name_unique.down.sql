@@ -0,0 +1 @@
+DROP INDEX IF EXISTS media_groups_user_id_name_key;
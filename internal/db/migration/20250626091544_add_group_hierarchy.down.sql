@@ -0,0 +1,44 @@
+DROP INDEX IF EXISTS media_groups_sibling_name_key;
+DROP INDEX IF EXISTS media_groups_parent_id_idx;
+DROP INDEX IF EXISTS media_groups_path_idx;
+
+DROP TRIGGER IF EXISTS media_groups_set_path ON media_groups;
+DROP FUNCTION IF EXISTS media_groups_set_path();
+
+-- Nested groups become top level groups again.
+ALTER TABLE media_groups
+    DROP COLUMN IF EXISTS path,
+    DROP COLUMN IF EXISTS parent_id;
+
+DO $$
+DECLARE
+    dup RECORD;
+    n INT;
+BEGIN
+    FOR dup IN
+        SELECT id, user_id, name FROM (
+            SELECT id, user_id, name,
+                ROW_NUMBER() OVER (PARTITION BY user_id, name ORDER BY created_at, id) AS rn
+            FROM media_groups
+            WHERE deleted_at IS NULL
+        ) d
+        WHERE rn > 1
+        ORDER BY user_id, name, rn
+    LOOP
+        -- Another group may already be called "name (n)", or get that name
+        -- from an earlier iteration, so take the lowest suffix still free.
+        n := 1;
+        WHILE EXISTS (
+            SELECT 1 FROM media_groups
+            WHERE user_id = dup.user_id AND deleted_at IS NULL
+                AND name = dup.name || ' (' || n || ')'
+        ) LOOP
+            n := n + 1;
+        END LOOP;
+
+        UPDATE media_groups SET name = dup.name || ' (' || n || ')' WHERE id = dup.id;
+    END LOOP;
+END;
+$$;
+
+CREATE UNIQUE INDEX media_groups_user_id_name_key ON media_groups (user_id, name) WHERE deleted_at IS NULL;
@@ -0,0 +1,33 @@
+ALTER TABLE media_groups
+    ADD COLUMN parent_id UUID REFERENCES media_groups(id) ON DELETE CASCADE,
+    ADD COLUMN path TEXT;
+
+-- path lists the ids from the root down to the group itself, e.g. "/<root>/<child>/".
+UPDATE media_groups SET path = '/' || id || '/';
+ALTER TABLE media_groups ALTER COLUMN path SET NOT NULL;
+
+CREATE FUNCTION media_groups_set_path() RETURNS trigger AS $$
+BEGIN
+    IF NEW.parent_id IS NULL THEN
+        NEW.path := '/' || NEW.id || '/';
+    ELSE
+        SELECT path || NEW.id || '/' INTO NEW.path
+        FROM media_groups
+        WHERE id = NEW.parent_id;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER media_groups_set_path
+BEFORE INSERT ON media_groups
+FOR EACH ROW EXECUTE FUNCTION media_groups_set_path();
+
+CREATE INDEX media_groups_path_idx ON media_groups (user_id, path text_pattern_ops);
+CREATE INDEX media_groups_parent_id_idx ON media_groups (parent_id);
+
+-- Names only have to be unique among siblings.
+DROP INDEX IF EXISTS media_groups_user_id_name_key;
+CREATE UNIQUE INDEX media_groups_sibling_name_key
+ON media_groups (user_id, COALESCE(parent_id, '00000000-0000-0000-0000-000000000000'::uuid), name)
+WHERE deleted_at IS NULL;
@@ -0,0 +1,13 @@
+ALTER TABLE media_files ADD COLUMN group_id UUID REFERENCES media_groups(id) ON DELETE SET NULL;
+
+-- A media file keeps only the first group it belongs to.
+UPDATE media_files m
+SET group_id = gm.group_id
+FROM (
+    SELECT DISTINCT ON (media_id) media_id, group_id
+    FROM group_memberships
+    ORDER BY media_id, added_at ASC, group_id
+) gm
+WHERE m.id = gm.media_id;
+
+DROP TABLE IF EXISTS group_memberships;
@@ -0,0 +1,20 @@
+CREATE TABLE group_memberships (
+    group_id UUID NOT NULL REFERENCES media_groups(id) ON DELETE CASCADE,
+    media_id UUID NOT NULL REFERENCES media_files(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    added_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (group_id, media_id)
+);
+
+CREATE INDEX group_memberships_media_id_idx ON group_memberships (media_id);
+CREATE INDEX group_memberships_position_idx ON group_memberships (group_id, position);
+
+-- Existing members keep the newest first order they were listed in.
+INSERT INTO group_memberships (group_id, media_id, position, added_at)
+SELECT group_id, id,
+    ROW_NUMBER() OVER (PARTITION BY group_id ORDER BY uploaded_at DESC, id),
+    COALESCE(uploaded_at, now())
+FROM media_files
+WHERE group_id IS NOT NULL;
+
+ALTER TABLE media_files DROP COLUMN group_id;
@@ -0,0 +1,19 @@
+CREATE TABLE tags (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (user_id, name)
+);
+
+-- Serves prefix lookups for autocomplete.
+CREATE INDEX tags_user_id_name_pattern_idx ON tags (user_id, name text_pattern_ops);
+
+CREATE TABLE media_tags (
+    media_id UUID NOT NULL REFERENCES media_files(id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (media_id, tag_id)
+);
+
+CREATE INDEX media_tags_tag_id_idx ON media_tags (tag_id);
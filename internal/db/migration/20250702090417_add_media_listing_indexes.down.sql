@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS group_memberships_position_idx;
+CREATE INDEX group_memberships_position_idx ON group_memberships (group_id, position);
+
+DROP INDEX IF EXISTS media_files_list_file_type_idx;
+DROP INDEX IF EXISTS media_files_list_filename_idx;
+DROP INDEX IF EXISTS media_files_list_size_idx;
+DROP INDEX IF EXISTS media_files_list_taken_at_idx;
+DROP INDEX IF EXISTS media_files_list_uploaded_at_idx;
+
+ALTER TABLE media_files
+    ALTER COLUMN uploaded_at DROP NOT NULL;
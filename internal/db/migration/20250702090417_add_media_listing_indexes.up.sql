@@ -0,0 +1,13 @@
+UPDATE media_files SET uploaded_at = now() WHERE uploaded_at IS NULL;
+ALTER TABLE media_files
+    ALTER COLUMN uploaded_at SET NOT NULL;
+
+-- Keyset pagination indexes, one per sort order of the media listing.
+CREATE INDEX media_files_list_uploaded_at_idx ON media_files (user_id, uploaded_at, id) WHERE deleted_at IS NULL;
+CREATE INDEX media_files_list_taken_at_idx ON media_files (user_id, (COALESCE(taken_at, uploaded_at)), id) WHERE deleted_at IS NULL;
+CREATE INDEX media_files_list_size_idx ON media_files (user_id, size, id) WHERE deleted_at IS NULL;
+CREATE INDEX media_files_list_filename_idx ON media_files (user_id, filename, id) WHERE deleted_at IS NULL;
+CREATE INDEX media_files_list_file_type_idx ON media_files (user_id, file_type text_pattern_ops) WHERE deleted_at IS NULL;
+
+DROP INDEX IF EXISTS group_memberships_position_idx;
+CREATE INDEX group_memberships_position_idx ON group_memberships (group_id, position, media_id);
@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS media_files_filename_trgm_idx;
+DROP INDEX IF EXISTS media_files_search_vector_idx;
+
+DROP TRIGGER IF EXISTS media_tags_refresh_search_vector ON media_tags;
+DROP FUNCTION IF EXISTS media_tags_refresh_search_vector();
+DROP TRIGGER IF EXISTS media_files_set_search_vector ON media_files;
+DROP FUNCTION IF EXISTS media_files_set_search_vector();
+DROP FUNCTION IF EXISTS media_search_vector(UUID, TEXT, TEXT, TEXT, JSONB);
+
+ALTER TABLE media_files
+    DROP COLUMN IF EXISTS search_vector,
+    DROP COLUMN IF EXISTS description,
+    DROP COLUMN IF EXISTS title;
@@ -0,0 +1,64 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE media_files
+    ADD COLUMN title TEXT NOT NULL DEFAULT '',
+    ADD COLUMN description TEXT NOT NULL DEFAULT '',
+    ADD COLUMN search_vector TSVECTOR NOT NULL DEFAULT '';
+
+-- The search document of a media file. Names are split on the separators
+-- common in filenames, so "trip_2025-beach.jpg" matches "beach". The simple
+-- configuration is used since the library holds text in any language.
+CREATE FUNCTION media_search_vector(media_id UUID, filename TEXT, title TEXT, description TEXT, metadata JSONB)
+RETURNS TSVECTOR AS $$
+    SELECT setweight(to_tsvector('simple', title), 'A')
+        || setweight(to_tsvector('simple', regexp_replace(filename, '[_.\-]+', ' ', 'g')), 'A')
+        || setweight(to_tsvector('simple', COALESCE((
+            SELECT string_agg(t.name, ' ')
+            FROM media_tags mt
+            JOIN tags t ON t.id = mt.tag_id
+            WHERE mt.media_id = $1
+        ), '')), 'B')
+        || setweight(to_tsvector('simple', description), 'C')
+        || setweight(jsonb_to_tsvector('simple', metadata, '["string"]'), 'D');
+$$ LANGUAGE SQL STABLE;
+
+CREATE FUNCTION media_files_set_search_vector() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.search_vector := media_search_vector(NEW.id, NEW.filename, NEW.title, NEW.description, NEW.metadata);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER media_files_set_search_vector
+BEFORE INSERT OR UPDATE OF filename, title, description, metadata ON media_files
+FOR EACH ROW EXECUTE FUNCTION media_files_set_search_vector();
+
+-- Tags live in their own table, changing them refreshes the document of the
+-- media file they are attached to.
+CREATE FUNCTION media_tags_refresh_search_vector() RETURNS TRIGGER AS $$
+DECLARE
+    changed UUID;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        changed := OLD.media_id;
+    ELSE
+        changed := NEW.media_id;
+    END IF;
+
+    UPDATE media_files m
+    SET search_vector = media_search_vector(m.id, m.filename, m.title, m.description, m.metadata)
+    WHERE m.id = changed;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER media_tags_refresh_search_vector
+AFTER INSERT OR DELETE ON media_tags
+FOR EACH ROW EXECUTE FUNCTION media_tags_refresh_search_vector();
+
+UPDATE media_files
+SET search_vector = media_search_vector(id, filename, title, description, metadata);
+
+CREATE INDEX media_files_search_vector_idx ON media_files USING GIN (search_vector);
+CREATE INDEX media_files_filename_trgm_idx ON media_files USING GIN (filename gin_trgm_ops);
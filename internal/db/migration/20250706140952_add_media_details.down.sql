@@ -0,0 +1,62 @@
+DROP INDEX IF EXISTS media_files_favourite_idx;
+DROP INDEX IF EXISTS media_files_list_display_name_idx;
+CREATE INDEX media_files_list_filename_idx ON media_files (user_id, filename, id) WHERE deleted_at IS NULL;
+
+DROP INDEX IF EXISTS media_files_display_name_trgm_idx;
+CREATE INDEX media_files_filename_trgm_idx ON media_files USING GIN (filename gin_trgm_ops);
+
+DROP TRIGGER IF EXISTS media_files_set_search_vector ON media_files;
+DROP FUNCTION IF EXISTS media_search_vector(UUID, TEXT, TEXT, TEXT, JSONB);
+
+CREATE FUNCTION media_search_vector(media_id UUID, filename TEXT, title TEXT, description TEXT, metadata JSONB)
+RETURNS TSVECTOR AS $$
+    SELECT setweight(to_tsvector('simple', title), 'A')
+        || setweight(to_tsvector('simple', regexp_replace(filename, '[_.\-]+', ' ', 'g')), 'A')
+        || setweight(to_tsvector('simple', COALESCE((
+            SELECT string_agg(t.name, ' ')
+            FROM media_tags mt
+            JOIN tags t ON t.id = mt.tag_id
+            WHERE mt.media_id = $1
+        ), '')), 'B')
+        || setweight(to_tsvector('simple', description), 'C')
+        || setweight(jsonb_to_tsvector('simple', metadata, '["string"]'), 'D');
+$$ LANGUAGE SQL STABLE;
+
+CREATE OR REPLACE FUNCTION media_files_set_search_vector() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.search_vector := media_search_vector(NEW.id, NEW.filename, NEW.title, NEW.description, NEW.metadata);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER media_files_set_search_vector
+BEFORE INSERT OR UPDATE OF filename, title, description, metadata ON media_files
+FOR EACH ROW EXECUTE FUNCTION media_files_set_search_vector();
+
+CREATE OR REPLACE FUNCTION media_tags_refresh_search_vector() RETURNS TRIGGER AS $$
+DECLARE
+    changed UUID;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        changed := OLD.media_id;
+    ELSE
+        changed := NEW.media_id;
+    END IF;
+
+    UPDATE media_files m
+    SET search_vector = media_search_vector(m.id, m.filename, m.title, m.description, m.metadata)
+    WHERE m.id = changed;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+UPDATE media_files
+SET search_vector = media_search_vector(id, filename, title, description, metadata);
+
+ALTER TABLE media_files
+    DROP COLUMN IF EXISTS version,
+    DROP COLUMN IF EXISTS custom_fields,
+    DROP COLUMN IF EXISTS favourite,
+    DROP COLUMN IF EXISTS display_name,
+    DROP COLUMN IF EXISTS original_filename;
@@ -0,0 +1,70 @@
+-- filename stays the unique name media was stored under, display_name is the
+-- name users see and edit.
+ALTER TABLE media_files
+    ADD COLUMN original_filename TEXT NOT NULL DEFAULT '',
+    ADD COLUMN display_name TEXT NOT NULL DEFAULT '',
+    ADD COLUMN favourite BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN custom_fields JSONB NOT NULL DEFAULT '{}',
+    ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+-- Stored names are the original name behind a nanosecond prefix.
+UPDATE media_files
+SET original_filename = regexp_replace(filename, '^[0-9]+_', ''),
+    display_name = regexp_replace(filename, '^[0-9]+_', '');
+
+-- Search the display name instead of the stored name.
+DROP TRIGGER IF EXISTS media_files_set_search_vector ON media_files;
+DROP FUNCTION IF EXISTS media_search_vector(UUID, TEXT, TEXT, TEXT, JSONB);
+
+CREATE FUNCTION media_search_vector(media_id UUID, display_name TEXT, title TEXT, description TEXT, metadata JSONB)
+RETURNS TSVECTOR AS $$
+    SELECT setweight(to_tsvector('simple', title), 'A')
+        || setweight(to_tsvector('simple', regexp_replace(display_name, '[_.\-]+', ' ', 'g')), 'A')
+        || setweight(to_tsvector('simple', COALESCE((
+            SELECT string_agg(t.name, ' ')
+            FROM media_tags mt
+            JOIN tags t ON t.id = mt.tag_id
+            WHERE mt.media_id = $1
+        ), '')), 'B')
+        || setweight(to_tsvector('simple', description), 'C')
+        || setweight(jsonb_to_tsvector('simple', metadata, '["string"]'), 'D');
+$$ LANGUAGE SQL STABLE;
+
+CREATE OR REPLACE FUNCTION media_files_set_search_vector() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.search_vector := media_search_vector(NEW.id, NEW.display_name, NEW.title, NEW.description, NEW.metadata);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER media_files_set_search_vector
+BEFORE INSERT OR UPDATE OF display_name, title, description, metadata ON media_files
+FOR EACH ROW EXECUTE FUNCTION media_files_set_search_vector();
+
+CREATE OR REPLACE FUNCTION media_tags_refresh_search_vector() RETURNS TRIGGER AS $$
+DECLARE
+    changed UUID;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        changed := OLD.media_id;
+    ELSE
+        changed := NEW.media_id;
+    END IF;
+
+    UPDATE media_files m
+    SET search_vector = media_search_vector(m.id, m.display_name, m.title, m.description, m.metadata)
+    WHERE m.id = changed;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+UPDATE media_files
+SET search_vector = media_search_vector(id, display_name, title, description, metadata);
+
+DROP INDEX IF EXISTS media_files_filename_trgm_idx;
+CREATE INDEX media_files_display_name_trgm_idx ON media_files USING GIN (display_name gin_trgm_ops);
+
+DROP INDEX IF EXISTS media_files_list_filename_idx;
+CREATE INDEX media_files_list_display_name_idx ON media_files (user_id, display_name, id) WHERE deleted_at IS NULL;
+CREATE INDEX media_files_favourite_idx ON media_files (user_id) WHERE favourite AND deleted_at IS NULL;
@@ -0,0 +1,17 @@
+CREATE TABLE share_links (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    media_id UUID REFERENCES media_files(id) ON DELETE CASCADE,
+    group_id UUID REFERENCES media_groups(id) ON DELETE CASCADE,
+    -- SHA-256 of the token, the token itself is only shown once.
+    token_hash BYTEA NOT NULL UNIQUE,
+    password_hash TEXT,
+    expires_at TIMESTAMPTZ,
+    max_downloads INTEGER,
+    download_count INTEGER NOT NULL DEFAULT 0,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK ((media_id IS NULL) <> (group_id IS NULL))
+);
+
+CREATE INDEX share_links_user_id_idx ON share_links (user_id, created_at);
@@ -0,0 +1,15 @@
+CREATE TABLE group_shares (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    group_id UUID NOT NULL REFERENCES media_groups(id) ON DELETE CASCADE,
+    -- owner_id is the owner of the group, who invited user_id.
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role TEXT NOT NULL CHECK (role IN ('viewer', 'editor')),
+    -- Invitations only grant access once they are accepted.
+    accepted_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (group_id, user_id),
+    CHECK (owner_id <> user_id)
+);
+
+CREATE INDEX group_shares_user_id_idx ON group_shares (user_id, created_at);
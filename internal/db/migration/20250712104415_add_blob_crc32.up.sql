@@ -0,0 +1,3 @@
+-- CRC-32 of the content, needed to lay out ZIP archives before streaming
+-- them. It is filled in lazily for blobs stored before it was added.
+ALTER TABLE blobs ADD COLUMN crc32 BIGINT;
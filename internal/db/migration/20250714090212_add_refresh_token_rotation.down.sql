@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS audit_events;
+ALTER TABLE sessions DROP COLUMN IF EXISTS generation;
@@ -0,0 +1,16 @@
+-- A session is a family of refresh tokens: every refresh replaces its token
+-- with the next generation, and presenting an earlier one blocks the session.
+ALTER TABLE sessions ADD COLUMN generation INTEGER NOT NULL DEFAULT 1;
+
+CREATE TABLE audit_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    session_id UUID,
+    event TEXT NOT NULL,
+    client_ip TEXT,
+    user_agent TEXT,
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX audit_events_user_id_idx ON audit_events (user_id, created_at);
@@ -0,0 +1,4 @@
+ALTER TABLE sessions ADD COLUMN last_used_at TIMESTAMPTZ NOT NULL DEFAULT now();
+UPDATE sessions SET last_used_at = created_at;
+
+CREATE INDEX sessions_user_id_idx ON sessions (user_id, last_used_at);
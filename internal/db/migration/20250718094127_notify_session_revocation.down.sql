@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS sessions_notify_deleted ON sessions;
+DROP TRIGGER IF EXISTS sessions_notify_blocked ON sessions;
+DROP FUNCTION IF EXISTS notify_session_revoked();
@@ -0,0 +1,17 @@
+-- Lets API replicas drop cached session state as soon as a session is
+-- blocked or deleted, the payload is the session id.
+CREATE FUNCTION notify_session_revoked() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('sessions_revoked', OLD.id::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER sessions_notify_blocked
+AFTER UPDATE OF is_blocked ON sessions
+FOR EACH ROW WHEN (NEW.is_blocked AND NOT COALESCE(OLD.is_blocked, false))
+EXECUTE FUNCTION notify_session_revoked();
+
+CREATE TRIGGER sessions_notify_deleted
+AFTER DELETE ON sessions
+FOR EACH ROW EXECUTE FUNCTION notify_session_revoked();
@@ -0,0 +1,5 @@
+-- Wrong passwords are counted per link, once too many are given the link
+-- refuses passwords until locked_until.
+ALTER TABLE share_links
+    ADD COLUMN failed_attempts INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN locked_until TIMESTAMPTZ;
@@ -0,0 +1,4 @@
+ALTER TABLE media_files
+    DROP COLUMN IF EXISTS process_error,
+    DROP COLUMN IF EXISTS process_retry_at,
+    DROP COLUMN IF EXISTS process_attempts;
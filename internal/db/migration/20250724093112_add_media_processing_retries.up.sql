@@ -0,0 +1,6 @@
+-- Media files whose processing failed are retried with backoff, up to a
+-- limit, instead of being marked processed.
+ALTER TABLE media_files
+    ADD COLUMN process_attempts INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN process_retry_at TIMESTAMPTZ,
+    ADD COLUMN process_error TEXT;
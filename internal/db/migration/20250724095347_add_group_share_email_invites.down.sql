@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS group_shares_email_idx;
+DELETE FROM group_shares WHERE user_id IS NULL;
+ALTER TABLE group_shares
+    DROP CONSTRAINT IF EXISTS group_shares_accepted_user_check,
+    DROP CONSTRAINT IF EXISTS group_shares_group_id_email_key,
+    ALTER COLUMN user_id SET NOT NULL,
+    DROP COLUMN IF EXISTS email;
@@ -0,0 +1,13 @@
+-- Invitations are addressed to an email. They are bound to the account with
+-- that address right away when it is verified, otherwise once it is, so the
+-- inviter cannot tell whether an account exists.
+ALTER TABLE group_shares ADD COLUMN email TEXT;
+UPDATE group_shares s SET email = u.email FROM users u WHERE u.id = s.user_id;
+ALTER TABLE group_shares
+    ALTER COLUMN email SET NOT NULL,
+    ALTER COLUMN user_id DROP NOT NULL,
+    ADD CONSTRAINT group_shares_group_id_email_key UNIQUE (group_id, email),
+    -- Only bound invitations can be accepted.
+    ADD CONSTRAINT group_shares_accepted_user_check CHECK (accepted_at IS NULL OR user_id IS NOT NULL);
+
+CREATE INDEX group_shares_email_idx ON group_shares (email) WHERE user_id IS NULL;
@@ -0,0 +1,4 @@
+-- name: CreateAuditEvent :one
+INSERT INTO audit_events (user_id, session_id, event, client_ip, user_agent, details)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
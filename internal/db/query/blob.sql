@@ -0,0 +1,38 @@
+-- name: AcquireBlob :one
+INSERT INTO blobs (user_id, digest, size, storage_key, crc32)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (user_id, digest)
+DO UPDATE SET ref_count = blobs.ref_count + 1, crc32 = COALESCE(blobs.crc32, EXCLUDED.crc32)
+RETURNING *;
+
+-- name: GetBlobByID :one
+SELECT * FROM blobs
+WHERE id = $1;
+
+-- name: ReleaseBlob :one
+UPDATE blobs
+SET ref_count = ref_count - 1
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteUnreferencedBlob :exec
+DELETE FROM blobs
+WHERE id = $1 AND ref_count <= 0;
+
+-- name: GetBlobByDigest :one
+SELECT * FROM blobs
+WHERE user_id = $1 AND digest = $2;
+
+-- name: ListBlobsByIDs :many
+SELECT * FROM blobs
+WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: SetBlobCRC32 :exec
+UPDATE blobs
+SET crc32 = $2
+WHERE id = $1;
+
+-- name: LockBlobStorageKey :exec
+-- Serializes writing and deleting the content of a blob until the end of the
+-- transaction, as the blob row may not exist yet or anymore.
+SELECT pg_advisory_xact_lock(hashtextextended(sqlc.arg(storage_key)::text, 0));
@@ -0,0 +1,24 @@
+-- name: CreateEmailVerification :one
+INSERT INTO email_verifications (user_id, email, expires_at)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetLatestEmailVerification :one
+SELECT * FROM email_verifications
+WHERE user_id = $1
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: UseEmailVerification :one
+-- Spends a verification token, it only succeeds once and before it expires.
+UPDATE email_verifications
+SET used_at = now()
+WHERE id = $1 AND used_at IS NULL AND expires_at > now()
+RETURNING *;
+
+-- name: ExpireEmailVerificationsByUser :exec
+-- Invalidates the tokens of a user that were not used, e.g. when a new one is
+-- sent.
+UPDATE email_verifications
+SET expires_at = now()
+WHERE user_id = $1 AND used_at IS NULL AND expires_at > now();
@@ -0,0 +1,47 @@
+-- name: AssignMediaToGroup :one
+-- Appends a media file to a group. Both must belong to the user, media that
+-- is already a member keeps its position. Callers hold LockGroupTree so that
+-- concurrent appends do not take the same position.
+INSERT INTO group_memberships (group_id, media_id, position)
+SELECT g.id, m.id, COALESCE((
+        SELECT MAX(position) FROM group_memberships WHERE group_id = g.id
+    ), 0) + 1
+FROM media_files m, media_groups g
+WHERE m.id = sqlc.arg(media_id)
+    AND g.id = sqlc.arg(group_id)
+    AND m.user_id = sqlc.arg(user_id)
+    AND g.user_id = sqlc.arg(user_id)
+    AND m.deleted_at IS NULL
+    AND g.deleted_at IS NULL
+ON CONFLICT (group_id, media_id)
+DO UPDATE SET position = group_memberships.position
+RETURNING *;
+
+-- name: RemoveMediaFromGroup :one
+DELETE FROM group_memberships
+WHERE group_id = $1 AND media_id = $2
+RETURNING *;
+
+-- name: ListGroupMemberships :many
+SELECT * FROM group_memberships
+WHERE group_id = $1
+ORDER BY position ASC, added_at ASC;
+
+-- name: ListMediaByGroupMemberships :many
+SELECT sqlc.embed(m), gm.group_id FROM group_memberships gm
+JOIN media_files m ON m.id = gm.media_id
+WHERE gm.group_id = ANY(sqlc.arg(group_ids)::uuid[]) AND m.deleted_at IS NULL
+ORDER BY gm.group_id, gm.position ASC, gm.added_at ASC;
+
+-- name: SetGroupMembershipPosition :exec
+UPDATE group_memberships
+SET position = $3
+WHERE group_id = $1 AND media_id = $2;
+
+-- name: CopyGroupMemberships :exec
+-- Adds the media of the source group to the target group in the same order.
+INSERT INTO group_memberships (group_id, media_id, position)
+SELECT sqlc.arg(target_id), gm.media_id, gm.position
+FROM group_memberships gm
+JOIN media_files m ON m.id = gm.media_id
+WHERE gm.group_id = sqlc.arg(source_id) AND m.deleted_at IS NULL;
@@ -0,0 +1,75 @@
+-- name: CreateGroupShare :one
+-- Inviting an email again changes the role of the existing share. user_id is
+-- null until an account with the email is verified.
+INSERT INTO group_shares (group_id, owner_id, user_id, email, role)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (group_id, email) DO UPDATE
+SET role = EXCLUDED.role, user_id = COALESCE(group_shares.user_id, EXCLUDED.user_id)
+RETURNING *;
+
+-- name: ClaimGroupSharesByEmail :exec
+-- Binds the invitations sent to an email to the user who verified it.
+UPDATE group_shares
+SET user_id = sqlc.arg(user_id)
+WHERE email = sqlc.arg(email) AND user_id IS NULL AND owner_id <> sqlc.arg(user_id);
+
+-- name: AcceptGroupShare :one
+UPDATE group_shares
+SET accepted_at = COALESCE(accepted_at, now())
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: RevokeGroupShare :one
+DELETE FROM group_shares
+WHERE id = $1 AND group_id = $2
+RETURNING *;
+
+-- name: LeaveGroupShare :one
+-- Declines an invitation or gives up an accepted share.
+DELETE FROM group_shares
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: ListGroupSharesByGroup :many
+SELECT * FROM group_shares
+WHERE group_id = $1
+ORDER BY created_at ASC;
+
+-- name: ListGroupSharesByUser :many
+-- Lists the shares of live groups with a user, pending invitations included.
+SELECT s.*, g.name AS group_name, u.email AS owner_email
+FROM group_shares s
+JOIN media_groups g ON g.id = s.group_id
+JOIN users u ON u.id = s.owner_id
+WHERE s.user_id = $1 AND g.deleted_at IS NULL
+ORDER BY s.created_at DESC;
+
+-- name: GetSharedGroupForUser :one
+-- Returns a group the user was given access to through an accepted share of
+-- the group or of one of its ancestors. Only editor shares count when
+-- editor_only is set.
+SELECT g.* FROM media_groups g
+WHERE g.id = sqlc.arg(id) AND g.deleted_at IS NULL
+    AND EXISTS (
+        SELECT 1 FROM group_shares s
+        JOIN media_groups sg ON sg.id = s.group_id
+        WHERE s.user_id = sqlc.arg(user_id) AND s.accepted_at IS NOT NULL
+            AND (s.role = 'editor' OR NOT sqlc.arg(editor_only)::bool)
+            AND sg.user_id = g.user_id AND sg.deleted_at IS NULL
+            AND g.path LIKE sg.path || '%'
+    );
+
+-- name: GetSharedMediaFileForUser :one
+-- Returns a media file belonging to a group the user was given access to, see
+-- GetSharedGroupForUser.
+SELECT m.* FROM media_files m
+WHERE m.id = sqlc.arg(id) AND m.deleted_at IS NULL
+    AND EXISTS (
+        SELECT 1 FROM group_memberships gm
+        JOIN media_groups g ON g.id = gm.group_id AND g.deleted_at IS NULL
+        JOIN media_groups sg ON sg.user_id = g.user_id AND g.path LIKE sg.path || '%'
+        JOIN group_shares s ON s.group_id = sg.id
+        WHERE gm.media_id = m.id AND sg.deleted_at IS NULL
+            AND s.user_id = sqlc.arg(user_id) AND s.accepted_at IS NOT NULL
+            AND (s.role = 'editor' OR NOT sqlc.arg(editor_only)::bool)
+    );
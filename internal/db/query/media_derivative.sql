@@ -0,0 +1,12 @@
+-- name: UpsertMediaDerivative :one
+INSERT INTO media_derivatives (media_id, kind, size, format, storage_key, width, height, bytes)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (media_id, kind, size, format)
+DO UPDATE SET storage_key = EXCLUDED.storage_key, width = EXCLUDED.width,
+    height = EXCLUDED.height, bytes = EXCLUDED.bytes, created_at = now()
+RETURNING *;
+
+-- name: ListMediaDerivatives :many
+SELECT * FROM media_derivatives
+WHERE media_id = $1
+ORDER BY kind, size, format;
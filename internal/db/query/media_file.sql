@@ -1,33 +1,169 @@
 -- name: CreateMediaFile :one
-INSERT INTO media_files (user_id, group_id, filename, file_type, size)
-VALUES ($1, $2, $3, $4, $5)
+INSERT INTO media_files (
+    user_id, blob_id, filename, file_type, declared_type, detected_type, size,
+    original_filename, display_name
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
 RETURNING *;
 
 -- name: GetMediaFileByID :one
 SELECT * FROM media_files
-WHERE id = $1;
+WHERE id = $1 AND deleted_at IS NULL;
 
--- name: ListMediaByUser :many
+-- name: GetMediaFileForUser :one
 SELECT * FROM media_files
-WHERE user_id = $1
-ORDER BY uploaded_at DESC;
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
 
--- name: ListMediaByGroup :many
-SELECT * FROM media_files
-WHERE user_id = $1 AND group_id = $2
-ORDER BY uploaded_at DESC;
+-- name: CountMediaFilesForUser :one
+SELECT COUNT(*) FROM media_files
+WHERE id = ANY(sqlc.arg(ids)::uuid[]) AND user_id = sqlc.arg(user_id) AND deleted_at IS NULL;
 
--- name: AssignMediaToGroup :exec
-UPDATE media_files
-SET group_id = $2
-WHERE id = $1;
+-- name: GetMediaFileInGroupTree :one
+SELECT m.* FROM media_files m
+WHERE m.id = sqlc.arg(id)
+    AND m.deleted_at IS NULL
+    AND EXISTS (
+        SELECT 1 FROM group_memberships gm
+        JOIN media_groups g ON g.id = gm.group_id
+        WHERE gm.media_id = m.id
+            AND g.user_id = sqlc.arg(user_id)
+            AND g.path LIKE sqlc.arg(path)::text || '%'
+            AND g.deleted_at IS NULL
+    );
 
 -- name: CountMediaSizeByUser :one
-SELECT COALESCE(SUM(size), 0) AS total_size
+SELECT COALESCE(SUM(size), 0)::bigint AS total_size
+FROM blobs
+WHERE user_id = $1;
+
+-- name: CountMediaFilesByUser :one
+SELECT COUNT(*) AS total_files
 FROM media_files
 WHERE user_id = $1;
 
--- name: DeleteMediaFile :exec
+-- name: ListMediaUsageByType :many
+-- bytes counts each blob once, under the type of its oldest media file, so
+-- that it adds up to CountMediaSizeByUser. logical_bytes counts every copy.
+WITH stored AS (
+    SELECT DISTINCT ON (m.blob_id) m.file_type, b.size
+    FROM media_files m
+    JOIN blobs b ON b.id = m.blob_id
+    WHERE m.user_id = $1
+    ORDER BY m.blob_id, m.uploaded_at, m.id
+)
+SELECT t.file_type, t.files, t.logical_bytes, COALESCE(s.bytes, 0)::bigint AS bytes
+FROM (
+    SELECT file_type, COUNT(*) AS files, COALESCE(SUM(size), 0)::bigint AS logical_bytes
+    FROM media_files
+    WHERE media_files.user_id = $1
+    GROUP BY file_type
+) t
+LEFT JOIN (
+    SELECT file_type, SUM(size)::bigint AS bytes
+    FROM stored
+    GROUP BY file_type
+) s ON s.file_type = t.file_type
+ORDER BY t.file_type;
+
+-- name: DeleteMediaFile :one
 DELETE FROM media_files
+WHERE id = $1
+RETURNING *;
+
+
+-- name: ListUnprocessedMediaFiles :many
+-- Lists media files due for processing, leaving out those given up on after
+-- max_attempts failures.
+SELECT * FROM media_files
+WHERE processed_at IS NULL AND process_attempts < sqlc.arg(max_attempts)::int
+    AND (process_retry_at IS NULL OR process_retry_at <= now())
+ORDER BY uploaded_at ASC
+LIMIT sqlc.arg(max_count);
+
+-- name: MarkMediaFileProcessed :exec
+UPDATE media_files
+SET processed_at = now(), process_retry_at = NULL, process_error = NULL
+WHERE id = $1;
+
+-- name: RecordMediaFileProcessingFailure :one
+UPDATE media_files
+SET process_attempts = process_attempts + 1, process_retry_at = sqlc.arg(retry_at), process_error = sqlc.arg(process_error)
+WHERE id = sqlc.arg(id)
+RETURNING process_attempts;
+
+-- name: UpdateMediaFileMetadata :exec
+UPDATE media_files
+SET metadata = $2, taken_at = $3, width = $4, height = $5
 WHERE id = $1;
 
+-- name: UpdateMediaFileDetails :one
+-- Only succeeds while the media file is still at the expected version.
+UPDATE media_files
+SET display_name = sqlc.arg(display_name),
+    title = sqlc.arg(title),
+    description = sqlc.arg(description),
+    favourite = sqlc.arg(favourite),
+    custom_fields = sqlc.arg(custom_fields),
+    version = version + 1
+WHERE id = sqlc.arg(id) AND version = sqlc.arg(version) AND deleted_at IS NULL
+RETURNING *;
+
+-- name: TrashMediaFile :one
+UPDATE media_files
+SET deleted_at = now()
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: TrashMediaByGroupTree :exec
+-- Trashes the media of the groups under path that were trashed at deleted_at.
+-- Media that still belongs to a group outside the trash is left alone.
+UPDATE media_files m
+SET deleted_at = sqlc.arg(deleted_at)
+WHERE m.deleted_at IS NULL
+    AND EXISTS (
+        SELECT 1 FROM group_memberships gm
+        JOIN media_groups g ON g.id = gm.group_id
+        WHERE gm.media_id = m.id
+            AND g.user_id = sqlc.arg(user_id)
+            AND g.path LIKE sqlc.arg(path)::text || '%'
+            AND g.deleted_at = sqlc.arg(deleted_at)
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM group_memberships gm
+        JOIN media_groups g ON g.id = gm.group_id
+        WHERE gm.media_id = m.id AND g.deleted_at IS NULL
+    );
+
+-- name: GetTrashedMediaFileForUser :one
+SELECT * FROM media_files
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL;
+
+-- name: ListTrashedMediaByUser :many
+SELECT * FROM media_files
+WHERE user_id = $1 AND deleted_at IS NOT NULL
+ORDER BY deleted_at DESC;
+
+-- name: RestoreMediaFile :one
+UPDATE media_files
+SET deleted_at = NULL
+WHERE id = $1 AND deleted_at IS NOT NULL
+RETURNING *;
+
+-- name: RestoreMediaByGroupTree :exec
+UPDATE media_files m
+SET deleted_at = NULL
+WHERE m.deleted_at = sqlc.arg(deleted_at)
+    AND EXISTS (
+        SELECT 1 FROM group_memberships gm
+        JOIN media_groups g ON g.id = gm.group_id
+        WHERE gm.media_id = m.id
+            AND g.user_id = sqlc.arg(user_id)
+            AND g.path LIKE sqlc.arg(path)::text || '%'
+    );
+
+-- name: ListExpiredTrashedMedia :many
+-- Media files in skip_ids, which failed to be purged, are left out.
+SELECT * FROM media_files
+WHERE deleted_at < sqlc.arg(deleted_at) AND NOT (id = ANY(sqlc.arg(skip_ids)::uuid[]))
+ORDER BY deleted_at ASC
+LIMIT sqlc.arg(max_count);
@@ -1,17 +1,119 @@
 -- name: CreateMediaGroup :one
-INSERT INTO media_groups (user_id, name)
-VALUES ($1, $2)
+INSERT INTO media_groups (user_id, name, parent_id)
+VALUES ($1, $2, $3)
 RETURNING *;
 
 -- name: GetGroupByID :one
 SELECT * FROM media_groups
-WHERE id = $1;
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: GetGroupForUser :one
+SELECT * FROM media_groups
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
 
 -- name: ListGroupsByUser :many
+SELECT g.id, g.user_id, g.name, g.created_at, g.parent_id, g.path,
+    COUNT(m.id) AS media_count,
+    COALESCE(SUM(m.size), 0)::bigint AS total_size
+FROM media_groups g
+LEFT JOIN group_memberships gm ON gm.group_id = g.id
+LEFT JOIN media_files m ON m.id = gm.media_id AND m.deleted_at IS NULL
+WHERE g.user_id = $1 AND g.deleted_at IS NULL
+GROUP BY g.id
+ORDER BY g.created_at DESC;
+
+-- name: ListChildGroups :many
+SELECT g.id, g.user_id, g.name, g.created_at, g.parent_id, g.path,
+    COUNT(m.id) AS media_count,
+    COALESCE(SUM(m.size), 0)::bigint AS total_size
+FROM media_groups g
+LEFT JOIN group_memberships gm ON gm.group_id = g.id
+LEFT JOIN media_files m ON m.id = gm.media_id AND m.deleted_at IS NULL
+WHERE g.parent_id = $1 AND g.deleted_at IS NULL
+GROUP BY g.id
+ORDER BY g.name ASC;
+
+-- name: ListGroupAncestors :many
+-- Returns the groups on the path from the root down to the group itself.
+SELECT * FROM media_groups
+WHERE user_id = sqlc.arg(user_id) AND sqlc.arg(path)::text LIKE path || '%'
+ORDER BY length(path) ASC;
+
+-- name: ListGroupSubtree :many
 SELECT * FROM media_groups
-WHERE user_id = $1
-ORDER BY created_at DESC;
+WHERE user_id = sqlc.arg(user_id) AND path LIKE sqlc.arg(path)::text || '%' AND deleted_at IS NULL
+ORDER BY length(path) ASC, created_at ASC;
+
+-- name: LockGroupTree :exec
+-- Serializes changes to the folder tree of a user until the transaction ends.
+SELECT pg_advisory_xact_lock(hashtextextended(sqlc.arg(user_id)::uuid::text, 0));
+
+-- name: SetGroupParent :one
+UPDATE media_groups
+SET parent_id = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: MoveGroupSubtree :exec
+-- Rewrites the path of a group and its descendants from old_path to new_path.
+UPDATE media_groups
+SET path = sqlc.arg(new_path)::text || substr(path, length(sqlc.arg(old_path)::text) + 1)
+WHERE user_id = sqlc.arg(user_id) AND path LIKE sqlc.arg(old_path)::text || '%';
+
+-- name: GetGroupStats :one
+SELECT COUNT(m.id) AS media_count, COALESCE(SUM(m.size), 0)::bigint AS total_size
+FROM group_memberships gm
+JOIN media_files m ON m.id = gm.media_id
+WHERE gm.group_id = $1 AND m.deleted_at IS NULL;
+
+-- name: RenameMediaGroup :one
+UPDATE media_groups
+SET name = $2
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING *;
 
 -- name: DeleteMediaGroup :exec
 DELETE FROM media_groups
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: TrashMediaGroup :one
+UPDATE media_groups
+SET deleted_at = now()
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: TrashGroupDescendants :exec
+UPDATE media_groups
+SET deleted_at = sqlc.arg(deleted_at)
+WHERE user_id = sqlc.arg(user_id) AND path LIKE sqlc.arg(path)::text || '%' AND deleted_at IS NULL;
+
+-- name: GetTrashedGroupByID :one
+SELECT * FROM media_groups
+WHERE id = $1 AND deleted_at IS NOT NULL;
+
+-- name: GetTrashedGroupForUser :one
+SELECT * FROM media_groups
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL;
+
+-- name: ListTrashedGroupsByUser :many
+-- Groups trashed along with their parent are left out, restoring the parent
+-- restores them.
+SELECT * FROM media_groups g
+WHERE g.user_id = $1 AND g.deleted_at IS NOT NULL
+    AND NOT EXISTS (
+        SELECT 1 FROM media_groups p
+        WHERE p.id = g.parent_id AND p.deleted_at = g.deleted_at
+    )
+ORDER BY g.deleted_at DESC;
+
+-- name: RestoreGroupDescendants :exec
+UPDATE media_groups
+SET deleted_at = NULL
+WHERE user_id = sqlc.arg(user_id) AND path LIKE sqlc.arg(path)::text || '%' AND deleted_at = sqlc.arg(deleted_at);
+
+-- name: ListExpiredTrashedGroups :many
+-- Groups in skip_ids, which failed to be purged, are left out.
+SELECT * FROM media_groups
+WHERE deleted_at < sqlc.arg(deleted_at) AND NOT (id = ANY(sqlc.arg(skip_ids)::uuid[]))
+ORDER BY deleted_at ASC
+LIMIT sqlc.arg(max_count);
@@ -0,0 +1,318 @@
+-- name: ListMediaByUploadedAtAsc :many
+SELECT m.* FROM media_files m
+WHERE m.user_id = sqlc.arg(user_id)
+    AND m.deleted_at IS NULL
+    AND (NOT sqlc.arg(favourites_only)::bool OR m.favourite)
+    AND m.file_type LIKE sqlc.arg(type_prefix)::text || '%'
+    AND m.size BETWEEN sqlc.arg(min_size)::bigint AND sqlc.arg(max_size)::bigint
+    AND m.uploaded_at >= sqlc.arg(uploaded_from)::timestamptz
+    AND m.uploaded_at < sqlc.arg(uploaded_to)::timestamptz
+    AND (sqlc.narg(group_id)::uuid IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        WHERE gm.media_id = m.id AND gm.group_id = sqlc.narg(group_id)::uuid
+    ))
+    AND (sqlc.narg(group_path)::text IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        JOIN media_groups g ON g.id = gm.group_id
+        WHERE gm.media_id = m.id
+            AND g.path LIKE sqlc.narg(group_path)::text || '%'
+            AND g.deleted_at IS NULL
+    ))
+    AND (sqlc.arg(min_matches)::int = 0 OR (
+        SELECT COUNT(*) FROM media_tags mt
+        JOIN tags t ON t.id = mt.tag_id
+        WHERE mt.media_id = m.id AND t.name = ANY(sqlc.arg(tags)::text[])
+    ) >= sqlc.arg(min_matches)::int)
+    AND (sqlc.narg(cursor_id)::uuid IS NULL OR (m.uploaded_at, m.id) > (sqlc.narg(cursor_time)::timestamptz, sqlc.narg(cursor_id)::uuid))
+ORDER BY m.uploaded_at ASC, m.id ASC
+LIMIT sqlc.arg(row_limit);
+
+-- name: ListMediaByUploadedAtDesc :many
+SELECT m.* FROM media_files m
+WHERE m.user_id = sqlc.arg(user_id)
+    AND m.deleted_at IS NULL
+    AND (NOT sqlc.arg(favourites_only)::bool OR m.favourite)
+    AND m.file_type LIKE sqlc.arg(type_prefix)::text || '%'
+    AND m.size BETWEEN sqlc.arg(min_size)::bigint AND sqlc.arg(max_size)::bigint
+    AND m.uploaded_at >= sqlc.arg(uploaded_from)::timestamptz
+    AND m.uploaded_at < sqlc.arg(uploaded_to)::timestamptz
+    AND (sqlc.narg(group_id)::uuid IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        WHERE gm.media_id = m.id AND gm.group_id = sqlc.narg(group_id)::uuid
+    ))
+    AND (sqlc.narg(group_path)::text IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        JOIN media_groups g ON g.id = gm.group_id
+        WHERE gm.media_id = m.id
+            AND g.path LIKE sqlc.narg(group_path)::text || '%'
+            AND g.deleted_at IS NULL
+    ))
+    AND (sqlc.arg(min_matches)::int = 0 OR (
+        SELECT COUNT(*) FROM media_tags mt
+        JOIN tags t ON t.id = mt.tag_id
+        WHERE mt.media_id = m.id AND t.name = ANY(sqlc.arg(tags)::text[])
+    ) >= sqlc.arg(min_matches)::int)
+    AND (sqlc.narg(cursor_id)::uuid IS NULL OR (m.uploaded_at, m.id) < (sqlc.narg(cursor_time)::timestamptz, sqlc.narg(cursor_id)::uuid))
+ORDER BY m.uploaded_at DESC, m.id DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: ListMediaByTakenAtAsc :many
+SELECT m.* FROM media_files m
+WHERE m.user_id = sqlc.arg(user_id)
+    AND m.deleted_at IS NULL
+    AND (NOT sqlc.arg(favourites_only)::bool OR m.favourite)
+    AND m.file_type LIKE sqlc.arg(type_prefix)::text || '%'
+    AND m.size BETWEEN sqlc.arg(min_size)::bigint AND sqlc.arg(max_size)::bigint
+    AND m.uploaded_at >= sqlc.arg(uploaded_from)::timestamptz
+    AND m.uploaded_at < sqlc.arg(uploaded_to)::timestamptz
+    AND (sqlc.narg(group_id)::uuid IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        WHERE gm.media_id = m.id AND gm.group_id = sqlc.narg(group_id)::uuid
+    ))
+    AND (sqlc.narg(group_path)::text IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        JOIN media_groups g ON g.id = gm.group_id
+        WHERE gm.media_id = m.id
+            AND g.path LIKE sqlc.narg(group_path)::text || '%'
+            AND g.deleted_at IS NULL
+    ))
+    AND (sqlc.arg(min_matches)::int = 0 OR (
+        SELECT COUNT(*) FROM media_tags mt
+        JOIN tags t ON t.id = mt.tag_id
+        WHERE mt.media_id = m.id AND t.name = ANY(sqlc.arg(tags)::text[])
+    ) >= sqlc.arg(min_matches)::int)
+    AND (sqlc.narg(cursor_id)::uuid IS NULL OR (COALESCE(m.taken_at, m.uploaded_at), m.id) > (sqlc.narg(cursor_time)::timestamptz, sqlc.narg(cursor_id)::uuid))
+ORDER BY COALESCE(m.taken_at, m.uploaded_at) ASC, m.id ASC
+LIMIT sqlc.arg(row_limit);
+
+-- name: ListMediaByTakenAtDesc :many
+SELECT m.* FROM media_files m
+WHERE m.user_id = sqlc.arg(user_id)
+    AND m.deleted_at IS NULL
+    AND (NOT sqlc.arg(favourites_only)::bool OR m.favourite)
+    AND m.file_type LIKE sqlc.arg(type_prefix)::text || '%'
+    AND m.size BETWEEN sqlc.arg(min_size)::bigint AND sqlc.arg(max_size)::bigint
+    AND m.uploaded_at >= sqlc.arg(uploaded_from)::timestamptz
+    AND m.uploaded_at < sqlc.arg(uploaded_to)::timestamptz
+    AND (sqlc.narg(group_id)::uuid IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        WHERE gm.media_id = m.id AND gm.group_id = sqlc.narg(group_id)::uuid
+    ))
+    AND (sqlc.narg(group_path)::text IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        JOIN media_groups g ON g.id = gm.group_id
+        WHERE gm.media_id = m.id
+            AND g.path LIKE sqlc.narg(group_path)::text || '%'
+            AND g.deleted_at IS NULL
+    ))
+    AND (sqlc.arg(min_matches)::int = 0 OR (
+        SELECT COUNT(*) FROM media_tags mt
+        JOIN tags t ON t.id = mt.tag_id
+        WHERE mt.media_id = m.id AND t.name = ANY(sqlc.arg(tags)::text[])
+    ) >= sqlc.arg(min_matches)::int)
+    AND (sqlc.narg(cursor_id)::uuid IS NULL OR (COALESCE(m.taken_at, m.uploaded_at), m.id) < (sqlc.narg(cursor_time)::timestamptz, sqlc.narg(cursor_id)::uuid))
+ORDER BY COALESCE(m.taken_at, m.uploaded_at) DESC, m.id DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: ListMediaBySizeAsc :many
+SELECT m.* FROM media_files m
+WHERE m.user_id = sqlc.arg(user_id)
+    AND m.deleted_at IS NULL
+    AND (NOT sqlc.arg(favourites_only)::bool OR m.favourite)
+    AND m.file_type LIKE sqlc.arg(type_prefix)::text || '%'
+    AND m.size BETWEEN sqlc.arg(min_size)::bigint AND sqlc.arg(max_size)::bigint
+    AND m.uploaded_at >= sqlc.arg(uploaded_from)::timestamptz
+    AND m.uploaded_at < sqlc.arg(uploaded_to)::timestamptz
+    AND (sqlc.narg(group_id)::uuid IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        WHERE gm.media_id = m.id AND gm.group_id = sqlc.narg(group_id)::uuid
+    ))
+    AND (sqlc.narg(group_path)::text IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        JOIN media_groups g ON g.id = gm.group_id
+        WHERE gm.media_id = m.id
+            AND g.path LIKE sqlc.narg(group_path)::text || '%'
+            AND g.deleted_at IS NULL
+    ))
+    AND (sqlc.arg(min_matches)::int = 0 OR (
+        SELECT COUNT(*) FROM media_tags mt
+        JOIN tags t ON t.id = mt.tag_id
+        WHERE mt.media_id = m.id AND t.name = ANY(sqlc.arg(tags)::text[])
+    ) >= sqlc.arg(min_matches)::int)
+    AND (sqlc.narg(cursor_id)::uuid IS NULL OR (m.size, m.id) > (sqlc.narg(cursor_size)::bigint, sqlc.narg(cursor_id)::uuid))
+ORDER BY m.size ASC, m.id ASC
+LIMIT sqlc.arg(row_limit);
+
+-- name: ListMediaBySizeDesc :many
+SELECT m.* FROM media_files m
+WHERE m.user_id = sqlc.arg(user_id)
+    AND m.deleted_at IS NULL
+    AND (NOT sqlc.arg(favourites_only)::bool OR m.favourite)
+    AND m.file_type LIKE sqlc.arg(type_prefix)::text || '%'
+    AND m.size BETWEEN sqlc.arg(min_size)::bigint AND sqlc.arg(max_size)::bigint
+    AND m.uploaded_at >= sqlc.arg(uploaded_from)::timestamptz
+    AND m.uploaded_at < sqlc.arg(uploaded_to)::timestamptz
+    AND (sqlc.narg(group_id)::uuid IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        WHERE gm.media_id = m.id AND gm.group_id = sqlc.narg(group_id)::uuid
+    ))
+    AND (sqlc.narg(group_path)::text IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        JOIN media_groups g ON g.id = gm.group_id
+        WHERE gm.media_id = m.id
+            AND g.path LIKE sqlc.narg(group_path)::text || '%'
+            AND g.deleted_at IS NULL
+    ))
+    AND (sqlc.arg(min_matches)::int = 0 OR (
+        SELECT COUNT(*) FROM media_tags mt
+        JOIN tags t ON t.id = mt.tag_id
+        WHERE mt.media_id = m.id AND t.name = ANY(sqlc.arg(tags)::text[])
+    ) >= sqlc.arg(min_matches)::int)
+    AND (sqlc.narg(cursor_id)::uuid IS NULL OR (m.size, m.id) < (sqlc.narg(cursor_size)::bigint, sqlc.narg(cursor_id)::uuid))
+ORDER BY m.size DESC, m.id DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: ListMediaByFilenameAsc :many
+SELECT m.* FROM media_files m
+WHERE m.user_id = sqlc.arg(user_id)
+    AND m.deleted_at IS NULL
+    AND (NOT sqlc.arg(favourites_only)::bool OR m.favourite)
+    AND m.file_type LIKE sqlc.arg(type_prefix)::text || '%'
+    AND m.size BETWEEN sqlc.arg(min_size)::bigint AND sqlc.arg(max_size)::bigint
+    AND m.uploaded_at >= sqlc.arg(uploaded_from)::timestamptz
+    AND m.uploaded_at < sqlc.arg(uploaded_to)::timestamptz
+    AND (sqlc.narg(group_id)::uuid IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        WHERE gm.media_id = m.id AND gm.group_id = sqlc.narg(group_id)::uuid
+    ))
+    AND (sqlc.narg(group_path)::text IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        JOIN media_groups g ON g.id = gm.group_id
+        WHERE gm.media_id = m.id
+            AND g.path LIKE sqlc.narg(group_path)::text || '%'
+            AND g.deleted_at IS NULL
+    ))
+    AND (sqlc.arg(min_matches)::int = 0 OR (
+        SELECT COUNT(*) FROM media_tags mt
+        JOIN tags t ON t.id = mt.tag_id
+        WHERE mt.media_id = m.id AND t.name = ANY(sqlc.arg(tags)::text[])
+    ) >= sqlc.arg(min_matches)::int)
+    AND (sqlc.narg(cursor_id)::uuid IS NULL OR (m.display_name, m.id) > (sqlc.narg(cursor_name)::text, sqlc.narg(cursor_id)::uuid))
+ORDER BY m.display_name ASC, m.id ASC
+LIMIT sqlc.arg(row_limit);
+
+-- name: ListMediaByFilenameDesc :many
+SELECT m.* FROM media_files m
+WHERE m.user_id = sqlc.arg(user_id)
+    AND m.deleted_at IS NULL
+    AND (NOT sqlc.arg(favourites_only)::bool OR m.favourite)
+    AND m.file_type LIKE sqlc.arg(type_prefix)::text || '%'
+    AND m.size BETWEEN sqlc.arg(min_size)::bigint AND sqlc.arg(max_size)::bigint
+    AND m.uploaded_at >= sqlc.arg(uploaded_from)::timestamptz
+    AND m.uploaded_at < sqlc.arg(uploaded_to)::timestamptz
+    AND (sqlc.narg(group_id)::uuid IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        WHERE gm.media_id = m.id AND gm.group_id = sqlc.narg(group_id)::uuid
+    ))
+    AND (sqlc.narg(group_path)::text IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        JOIN media_groups g ON g.id = gm.group_id
+        WHERE gm.media_id = m.id
+            AND g.path LIKE sqlc.narg(group_path)::text || '%'
+            AND g.deleted_at IS NULL
+    ))
+    AND (sqlc.arg(min_matches)::int = 0 OR (
+        SELECT COUNT(*) FROM media_tags mt
+        JOIN tags t ON t.id = mt.tag_id
+        WHERE mt.media_id = m.id AND t.name = ANY(sqlc.arg(tags)::text[])
+    ) >= sqlc.arg(min_matches)::int)
+    AND (sqlc.narg(cursor_id)::uuid IS NULL OR (m.display_name, m.id) < (sqlc.narg(cursor_name)::text, sqlc.narg(cursor_id)::uuid))
+ORDER BY m.display_name DESC, m.id DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: ListMediaByPositionAsc :many
+SELECT sqlc.embed(m), gm.position FROM media_files m
+JOIN group_memberships gm ON gm.media_id = m.id AND gm.group_id = sqlc.arg(position_group_id)::uuid
+WHERE m.user_id = sqlc.arg(user_id)
+    AND m.deleted_at IS NULL
+    AND (NOT sqlc.arg(favourites_only)::bool OR m.favourite)
+    AND m.file_type LIKE sqlc.arg(type_prefix)::text || '%'
+    AND m.size BETWEEN sqlc.arg(min_size)::bigint AND sqlc.arg(max_size)::bigint
+    AND m.uploaded_at >= sqlc.arg(uploaded_from)::timestamptz
+    AND m.uploaded_at < sqlc.arg(uploaded_to)::timestamptz
+    AND (sqlc.narg(group_id)::uuid IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        WHERE gm.media_id = m.id AND gm.group_id = sqlc.narg(group_id)::uuid
+    ))
+    AND (sqlc.narg(group_path)::text IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        JOIN media_groups g ON g.id = gm.group_id
+        WHERE gm.media_id = m.id
+            AND g.path LIKE sqlc.narg(group_path)::text || '%'
+            AND g.deleted_at IS NULL
+    ))
+    AND (sqlc.arg(min_matches)::int = 0 OR (
+        SELECT COUNT(*) FROM media_tags mt
+        JOIN tags t ON t.id = mt.tag_id
+        WHERE mt.media_id = m.id AND t.name = ANY(sqlc.arg(tags)::text[])
+    ) >= sqlc.arg(min_matches)::int)
+    AND (sqlc.narg(cursor_id)::uuid IS NULL OR (gm.position, m.id) > (sqlc.narg(cursor_position)::int, sqlc.narg(cursor_id)::uuid))
+ORDER BY gm.position ASC, m.id ASC
+LIMIT sqlc.arg(row_limit);
+
+-- name: ListMediaByPositionDesc :many
+SELECT sqlc.embed(m), gm.position FROM media_files m
+JOIN group_memberships gm ON gm.media_id = m.id AND gm.group_id = sqlc.arg(position_group_id)::uuid
+WHERE m.user_id = sqlc.arg(user_id)
+    AND m.deleted_at IS NULL
+    AND (NOT sqlc.arg(favourites_only)::bool OR m.favourite)
+    AND m.file_type LIKE sqlc.arg(type_prefix)::text || '%'
+    AND m.size BETWEEN sqlc.arg(min_size)::bigint AND sqlc.arg(max_size)::bigint
+    AND m.uploaded_at >= sqlc.arg(uploaded_from)::timestamptz
+    AND m.uploaded_at < sqlc.arg(uploaded_to)::timestamptz
+    AND (sqlc.narg(group_id)::uuid IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        WHERE gm.media_id = m.id AND gm.group_id = sqlc.narg(group_id)::uuid
+    ))
+    AND (sqlc.narg(group_path)::text IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        JOIN media_groups g ON g.id = gm.group_id
+        WHERE gm.media_id = m.id
+            AND g.path LIKE sqlc.narg(group_path)::text || '%'
+            AND g.deleted_at IS NULL
+    ))
+    AND (sqlc.arg(min_matches)::int = 0 OR (
+        SELECT COUNT(*) FROM media_tags mt
+        JOIN tags t ON t.id = mt.tag_id
+        WHERE mt.media_id = m.id AND t.name = ANY(sqlc.arg(tags)::text[])
+    ) >= sqlc.arg(min_matches)::int)
+    AND (sqlc.narg(cursor_id)::uuid IS NULL OR (gm.position, m.id) < (sqlc.narg(cursor_position)::int, sqlc.narg(cursor_id)::uuid))
+ORDER BY gm.position DESC, m.id DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: CountMediaPage :one
+SELECT COUNT(*) AS total_count, COALESCE(SUM(m.size), 0)::bigint AS total_size
+FROM media_files m
+WHERE m.user_id = sqlc.arg(user_id)
+    AND m.deleted_at IS NULL
+    AND (NOT sqlc.arg(favourites_only)::bool OR m.favourite)
+    AND m.file_type LIKE sqlc.arg(type_prefix)::text || '%'
+    AND m.size BETWEEN sqlc.arg(min_size)::bigint AND sqlc.arg(max_size)::bigint
+    AND m.uploaded_at >= sqlc.arg(uploaded_from)::timestamptz
+    AND m.uploaded_at < sqlc.arg(uploaded_to)::timestamptz
+    AND (sqlc.narg(group_id)::uuid IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        WHERE gm.media_id = m.id AND gm.group_id = sqlc.narg(group_id)::uuid
+    ))
+    AND (sqlc.narg(group_path)::text IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        JOIN media_groups g ON g.id = gm.group_id
+        WHERE gm.media_id = m.id
+            AND g.path LIKE sqlc.narg(group_path)::text || '%'
+            AND g.deleted_at IS NULL
+    ))
+    AND (sqlc.arg(min_matches)::int = 0 OR (
+        SELECT COUNT(*) FROM media_tags mt
+        JOIN tags t ON t.id = mt.tag_id
+        WHERE mt.media_id = m.id AND t.name = ANY(sqlc.arg(tags)::text[])
+    ) >= sqlc.arg(min_matches)::int);
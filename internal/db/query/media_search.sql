@@ -0,0 +1,32 @@
+-- name: SearchMedia :many
+-- Ranks the media of a user matching a web search style query. Highlights are
+-- only built for the rows of the page.
+SELECT sqlc.embed(m), r.rank, r.total,
+    ts_headline('simple', concat_ws(' ', NULLIF(m.title, ''), m.display_name, NULLIF(m.description, '')),
+        websearch_to_tsquery('simple', sqlc.arg(query)::text),
+        sqlc.arg(headline_options)::text)::text AS highlight
+FROM (
+    SELECT s.id,
+        ts_rank_cd(s.search_vector, websearch_to_tsquery('simple', sqlc.arg(query)::text)) AS rank,
+        COUNT(*) OVER () AS total
+    FROM media_files s
+    WHERE s.user_id = sqlc.arg(user_id)
+        AND s.deleted_at IS NULL
+        AND s.search_vector @@ websearch_to_tsquery('simple', sqlc.arg(query)::text)
+    ORDER BY rank DESC, s.id
+    LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset)
+) r
+JOIN media_files m ON m.id = r.id
+ORDER BY r.rank DESC, m.id;
+
+-- name: SearchMediaByFilename :many
+-- Matches part of a display name or title, for queries that are not whole words.
+SELECT sqlc.embed(m),
+    GREATEST(similarity(m.display_name, sqlc.arg(query)::text), similarity(m.title, sqlc.arg(query)::text))::real AS rank,
+    COUNT(*) OVER () AS total
+FROM media_files m
+WHERE m.user_id = sqlc.arg(user_id)
+    AND m.deleted_at IS NULL
+    AND (m.display_name ILIKE '%' || sqlc.arg(pattern)::text || '%' OR m.title ILIKE '%' || sqlc.arg(pattern)::text || '%')
+ORDER BY rank DESC, m.id
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
@@ -0,0 +1,20 @@
+-- name: CreatePasswordReset :one
+INSERT INTO password_resets (user_id, token_hash, client_ip, expires_at)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: CountPasswordResetsSince :one
+SELECT count(*) FROM password_resets
+WHERE user_id = $1 AND created_at > $2;
+
+-- name: UsePasswordReset :one
+-- Spends a reset token, it only succeeds once and before it expires.
+UPDATE password_resets
+SET used_at = now()
+WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now()
+RETURNING *;
+
+-- name: ExpirePasswordResetsByUser :exec
+UPDATE password_resets
+SET expires_at = now()
+WHERE user_id = $1 AND used_at IS NULL AND expires_at > now();
@@ -15,17 +15,38 @@ UPDATE sessions
 SET is_blocked = true
 WHERE id = $1;
 
--- name: GetReusableSessionByUserID :one
+-- name: RotateSessionRefreshToken :one
+-- Replaces the refresh token of a session with the next generation, provided
+-- the current one is presented.
+UPDATE sessions
+SET refresh_token = sqlc.arg(new_refresh_token), generation = generation + 1, last_used_at = now()
+WHERE id = sqlc.arg(id) AND refresh_token = sqlc.arg(refresh_token)
+    AND is_blocked = false AND expires_at > now()
+RETURNING *;
+
+-- name: ListSessionsByUser :many
+-- Lists the sessions of a user that can still be used.
 SELECT * FROM sessions
-WHERE user_id = $1 AND is_blocked = false AND expires_at > $2
-ORDER BY expires_at DESC
-LIMIT 1;
+WHERE user_id = $1 AND is_blocked = false AND expires_at > now()
+ORDER BY last_used_at DESC;
 
--- name: UpdateSessionTokenAndExpiry :exec
+-- name: BlockSessionForUser :execrows
 UPDATE sessions
-SET refresh_token = $1, expires_at = $2
-WHERE id = $3;
+SET is_blocked = true
+WHERE id = $1 AND user_id = $2 AND is_blocked = false;
 
+-- name: BlockOtherSessionsByUser :many
+-- Blocks the sessions of a user except the one with the given id.
+UPDATE sessions
+SET is_blocked = true
+WHERE user_id = sqlc.arg(user_id) AND id <> sqlc.arg(id) AND is_blocked = false
+RETURNING id;
+
+-- name: BlockSessionsByUser :many
+UPDATE sessions
+SET is_blocked = true
+WHERE user_id = $1 AND is_blocked = false
+RETURNING id;
 
 -- name: DeleteSession :exec
 DELETE FROM sessions WHERE id = $1;
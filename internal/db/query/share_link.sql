@@ -0,0 +1,40 @@
+-- name: CreateShareLink :one
+INSERT INTO share_links (user_id, media_id, group_id, token_hash, password_hash, expires_at, max_downloads)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetShareLinkByTokenHash :one
+SELECT * FROM share_links
+WHERE token_hash = $1;
+
+-- name: ListShareLinksByUser :many
+SELECT * FROM share_links
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+-- name: RevokeShareLink :one
+UPDATE share_links
+SET revoked_at = now()
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+RETURNING *;
+
+-- name: CountShareLinkDownload :one
+-- Fails once the link is used up, so concurrent downloads cannot exceed it.
+UPDATE share_links
+SET download_count = download_count + 1
+WHERE id = $1 AND (max_downloads IS NULL OR download_count < max_downloads)
+RETURNING *;
+
+-- name: RecordShareLinkPasswordFailure :one
+-- Counts a wrong password, locking the link until lock_until once
+-- max_attempts are reached in a row.
+UPDATE share_links
+SET failed_attempts = CASE WHEN failed_attempts + 1 >= sqlc.arg(max_attempts)::int THEN 0 ELSE failed_attempts + 1 END,
+    locked_until = CASE WHEN failed_attempts + 1 >= sqlc.arg(max_attempts)::int THEN sqlc.arg(lock_until)::timestamptz ELSE locked_until END
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: ResetShareLinkPasswordFailures :exec
+UPDATE share_links
+SET failed_attempts = 0
+WHERE id = $1 AND failed_attempts > 0;
@@ -0,0 +1,38 @@
+-- name: UpsertTags :many
+INSERT INTO tags (user_id, name)
+SELECT sqlc.arg(user_id), unnest(sqlc.arg(names)::text[])
+ON CONFLICT (user_id, name)
+DO UPDATE SET name = EXCLUDED.name
+RETURNING *;
+
+-- name: AddMediaTags :exec
+INSERT INTO media_tags (media_id, tag_id)
+SELECT m.id, t.id
+FROM unnest(sqlc.arg(media_ids)::uuid[]) AS m(id)
+CROSS JOIN unnest(sqlc.arg(tag_ids)::uuid[]) AS t(id)
+ON CONFLICT (media_id, tag_id) DO NOTHING;
+
+-- name: RemoveMediaTags :execrows
+DELETE FROM media_tags mt
+USING tags t
+WHERE mt.tag_id = t.id
+    AND t.user_id = sqlc.arg(user_id)
+    AND t.name = ANY(sqlc.arg(names)::text[])
+    AND mt.media_id = ANY(sqlc.arg(media_ids)::uuid[]);
+
+-- name: ListTagsByMedia :many
+SELECT t.* FROM tags t
+JOIN media_tags mt ON mt.tag_id = t.id
+WHERE mt.media_id = $1
+ORDER BY t.name ASC;
+
+-- name: ListTagsByPrefix :many
+-- Tags only used by media in the trash are left out.
+SELECT t.id, t.name, COUNT(m.id) AS media_count
+FROM tags t
+JOIN media_tags mt ON mt.tag_id = t.id
+JOIN media_files m ON m.id = mt.media_id AND m.deleted_at IS NULL
+WHERE t.user_id = sqlc.arg(user_id) AND t.name LIKE sqlc.arg(prefix)::text || '%'
+GROUP BY t.id
+ORDER BY media_count DESC, t.name ASC
+LIMIT sqlc.arg(row_limit);
@@ -0,0 +1,38 @@
+-- name: CreateUpload :one
+INSERT INTO uploads (user_id, filename, file_type, metadata, upload_length)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetUploadByID :one
+SELECT * FROM uploads
+WHERE id = $1;
+
+-- name: AdvanceUploadOffset :one
+UPDATE uploads
+SET upload_offset = upload_offset + sqlc.arg(size)::bigint, updated_at = now()
+WHERE id = sqlc.arg(id) AND upload_offset = sqlc.arg(upload_offset)
+RETURNING *;
+
+-- name: CompleteUpload :one
+UPDATE uploads
+SET media_file_id = $2, completed_at = now(), updated_at = now()
+WHERE id = $1 AND completed_at IS NULL
+RETURNING *;
+
+-- name: DeleteUpload :exec
+DELETE FROM uploads
+WHERE id = $1;
+
+-- name: CreateUploadPart :one
+INSERT INTO upload_parts (upload_id, upload_offset, size, storage_key)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: ListUploadParts :many
+SELECT * FROM upload_parts
+WHERE upload_id = $1
+ORDER BY upload_offset ASC;
+
+-- name: DeleteUploadParts :exec
+DELETE FROM upload_parts
+WHERE upload_id = $1;
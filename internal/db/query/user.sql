@@ -12,3 +12,17 @@ LIMIT 1;
 SELECT * FROM users
 WHERE id = $1
 LIMIT 1;
+
+-- name: MarkUserEmailVerified :one
+-- Verifies the email of a user, provided it is still the address the token
+-- was sent to.
+UPDATE users
+SET email_verified_at = COALESCE(email_verified_at, now())
+WHERE id = $1 AND email = $2
+RETURNING *;
+
+-- name: UpdateUserPassword :one
+UPDATE users
+SET password = $2
+WHERE id = $1
+RETURNING *;
@@ -0,0 +1,10 @@
+-- name: GetUserQuota :one
+SELECT * FROM user_quotas
+WHERE user_id = $1;
+
+-- name: LockUserQuota :one
+INSERT INTO user_quotas (user_id)
+VALUES ($1)
+ON CONFLICT (user_id)
+DO UPDATE SET user_id = EXCLUDED.user_id
+RETURNING *;
@@ -0,0 +1,51 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: audit_event.sql
+
+package db
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const createAuditEvent = `-- name: CreateAuditEvent :one
+INSERT INTO audit_events (user_id, session_id, event, client_ip, user_agent, details)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, user_id, session_id, event, client_ip, user_agent, details, created_at
+`
+
+type CreateAuditEventParams struct {
+	UserID    pgtype.UUID     `json:"user_id"`
+	SessionID pgtype.UUID     `json:"session_id"`
+	Event     string          `json:"event"`
+	ClientIp  pgtype.Text     `json:"client_ip"`
+	UserAgent pgtype.Text     `json:"user_agent"`
+	Details   json.RawMessage `json:"details"`
+}
+
+func (q *Queries) CreateAuditEvent(ctx context.Context, arg CreateAuditEventParams) (AuditEvent, error) {
+	row := q.db.QueryRow(ctx, createAuditEvent,
+		arg.UserID,
+		arg.SessionID,
+		arg.Event,
+		arg.ClientIp,
+		arg.UserAgent,
+		arg.Details,
+	)
+	var i AuditEvent
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.SessionID,
+		&i.Event,
+		&i.ClientIp,
+		&i.UserAgent,
+		&i.Details,
+		&i.CreatedAt,
+	)
+	return i, err
+}
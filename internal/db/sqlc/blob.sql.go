@@ -0,0 +1,191 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: blob.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const acquireBlob = `-- name: AcquireBlob :one
+INSERT INTO blobs (user_id, digest, size, storage_key, crc32)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (user_id, digest)
+DO UPDATE SET ref_count = blobs.ref_count + 1, crc32 = COALESCE(blobs.crc32, EXCLUDED.crc32)
+RETURNING id, user_id, digest, size, storage_key, ref_count, created_at, crc32
+`
+
+type AcquireBlobParams struct {
+	UserID     pgtype.UUID `json:"user_id"`
+	Digest     string      `json:"digest"`
+	Size       int64       `json:"size"`
+	StorageKey string      `json:"storage_key"`
+	Crc32      pgtype.Int8 `json:"crc32"`
+}
+
+func (q *Queries) AcquireBlob(ctx context.Context, arg AcquireBlobParams) (Blob, error) {
+	row := q.db.QueryRow(ctx, acquireBlob,
+		arg.UserID,
+		arg.Digest,
+		arg.Size,
+		arg.StorageKey,
+		arg.Crc32,
+	)
+	var i Blob
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Digest,
+		&i.Size,
+		&i.StorageKey,
+		&i.RefCount,
+		&i.CreatedAt,
+		&i.Crc32,
+	)
+	return i, err
+}
+
+const deleteUnreferencedBlob = `-- name: DeleteUnreferencedBlob :exec
+DELETE FROM blobs
+WHERE id = $1 AND ref_count <= 0
+`
+
+func (q *Queries) DeleteUnreferencedBlob(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteUnreferencedBlob, id)
+	return err
+}
+
+const getBlobByDigest = `-- name: GetBlobByDigest :one
+SELECT id, user_id, digest, size, storage_key, ref_count, created_at, crc32 FROM blobs
+WHERE user_id = $1 AND digest = $2
+`
+
+type GetBlobByDigestParams struct {
+	UserID pgtype.UUID `json:"user_id"`
+	Digest string      `json:"digest"`
+}
+
+func (q *Queries) GetBlobByDigest(ctx context.Context, arg GetBlobByDigestParams) (Blob, error) {
+	row := q.db.QueryRow(ctx, getBlobByDigest, arg.UserID, arg.Digest)
+	var i Blob
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Digest,
+		&i.Size,
+		&i.StorageKey,
+		&i.RefCount,
+		&i.CreatedAt,
+		&i.Crc32,
+	)
+	return i, err
+}
+
+const getBlobByID = `-- name: GetBlobByID :one
+SELECT id, user_id, digest, size, storage_key, ref_count, created_at, crc32 FROM blobs
+WHERE id = $1
+`
+
+func (q *Queries) GetBlobByID(ctx context.Context, id pgtype.UUID) (Blob, error) {
+	row := q.db.QueryRow(ctx, getBlobByID, id)
+	var i Blob
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Digest,
+		&i.Size,
+		&i.StorageKey,
+		&i.RefCount,
+		&i.CreatedAt,
+		&i.Crc32,
+	)
+	return i, err
+}
+
+const listBlobsByIDs = `-- name: ListBlobsByIDs :many
+SELECT id, user_id, digest, size, storage_key, ref_count, created_at, crc32 FROM blobs
+WHERE id = ANY($1::uuid[])
+`
+
+func (q *Queries) ListBlobsByIDs(ctx context.Context, ids []pgtype.UUID) ([]Blob, error) {
+	rows, err := q.db.Query(ctx, listBlobsByIDs, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Blob{}
+	for rows.Next() {
+		var i Blob
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Digest,
+			&i.Size,
+			&i.StorageKey,
+			&i.RefCount,
+			&i.CreatedAt,
+			&i.Crc32,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockBlobStorageKey = `-- name: LockBlobStorageKey :exec
+SELECT pg_advisory_xact_lock(hashtextextended($1::text, 0))
+`
+
+// Serializes writing and deleting the content of a blob until the end of the
+// transaction, as the blob row may not exist yet or anymore.
+func (q *Queries) LockBlobStorageKey(ctx context.Context, storageKey string) error {
+	_, err := q.db.Exec(ctx, lockBlobStorageKey, storageKey)
+	return err
+}
+
+const releaseBlob = `-- name: ReleaseBlob :one
+UPDATE blobs
+SET ref_count = ref_count - 1
+WHERE id = $1
+RETURNING id, user_id, digest, size, storage_key, ref_count, created_at, crc32
+`
+
+func (q *Queries) ReleaseBlob(ctx context.Context, id pgtype.UUID) (Blob, error) {
+	row := q.db.QueryRow(ctx, releaseBlob, id)
+	var i Blob
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Digest,
+		&i.Size,
+		&i.StorageKey,
+		&i.RefCount,
+		&i.CreatedAt,
+		&i.Crc32,
+	)
+	return i, err
+}
+
+const setBlobCRC32 = `-- name: SetBlobCRC32 :exec
+UPDATE blobs
+SET crc32 = $2
+WHERE id = $1
+`
+
+type SetBlobCRC32Params struct {
+	ID    pgtype.UUID `json:"id"`
+	Crc32 pgtype.Int8 `json:"crc32"`
+}
+
+func (q *Queries) SetBlobCRC32(ctx context.Context, arg SetBlobCRC32Params) error {
+	_, err := q.db.Exec(ctx, setBlobCRC32, arg.ID, arg.Crc32)
+	return err
+}
@@ -0,0 +1,94 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: email_verification.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const createEmailVerification = `-- name: CreateEmailVerification :one
+INSERT INTO email_verifications (user_id, email, expires_at)
+VALUES ($1, $2, $3)
+RETURNING id, user_id, email, expires_at, used_at, created_at
+`
+
+type CreateEmailVerificationParams struct {
+	UserID    pgtype.UUID        `json:"user_id"`
+	Email     string             `json:"email"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+}
+
+func (q *Queries) CreateEmailVerification(ctx context.Context, arg CreateEmailVerificationParams) (EmailVerification, error) {
+	row := q.db.QueryRow(ctx, createEmailVerification, arg.UserID, arg.Email, arg.ExpiresAt)
+	var i EmailVerification
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Email,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const expireEmailVerificationsByUser = `-- name: ExpireEmailVerificationsByUser :exec
+UPDATE email_verifications
+SET expires_at = now()
+WHERE user_id = $1 AND used_at IS NULL AND expires_at > now()
+`
+
+// Invalidates the tokens of a user that were not used, e.g. when a new one is
+// sent.
+func (q *Queries) ExpireEmailVerificationsByUser(ctx context.Context, userID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, expireEmailVerificationsByUser, userID)
+	return err
+}
+
+const getLatestEmailVerification = `-- name: GetLatestEmailVerification :one
+SELECT id, user_id, email, expires_at, used_at, created_at FROM email_verifications
+WHERE user_id = $1
+ORDER BY created_at DESC
+LIMIT 1
+`
+
+func (q *Queries) GetLatestEmailVerification(ctx context.Context, userID pgtype.UUID) (EmailVerification, error) {
+	row := q.db.QueryRow(ctx, getLatestEmailVerification, userID)
+	var i EmailVerification
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Email,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const useEmailVerification = `-- name: UseEmailVerification :one
+UPDATE email_verifications
+SET used_at = now()
+WHERE id = $1 AND used_at IS NULL AND expires_at > now()
+RETURNING id, user_id, email, expires_at, used_at, created_at
+`
+
+// Spends a verification token, it only succeeds once and before it expires.
+func (q *Queries) UseEmailVerification(ctx context.Context, id pgtype.UUID) (EmailVerification, error) {
+	row := q.db.QueryRow(ctx, useEmailVerification, id)
+	var i EmailVerification
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Email,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
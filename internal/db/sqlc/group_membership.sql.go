@@ -0,0 +1,200 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: group_membership.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const assignMediaToGroup = `-- name: AssignMediaToGroup :one
+INSERT INTO group_memberships (group_id, media_id, position)
+SELECT g.id, m.id, COALESCE((
+        SELECT MAX(position) FROM group_memberships WHERE group_id = g.id
+    ), 0) + 1
+FROM media_files m, media_groups g
+WHERE m.id = $1
+    AND g.id = $2
+    AND m.user_id = $3
+    AND g.user_id = $3
+    AND m.deleted_at IS NULL
+    AND g.deleted_at IS NULL
+ON CONFLICT (group_id, media_id)
+DO UPDATE SET position = group_memberships.position
+RETURNING group_id, media_id, position, added_at
+`
+
+type AssignMediaToGroupParams struct {
+	MediaID pgtype.UUID `json:"media_id"`
+	GroupID pgtype.UUID `json:"group_id"`
+	UserID  pgtype.UUID `json:"user_id"`
+}
+
+// Appends a media file to a group. Both must belong to the user, media that
+// is already a member keeps its position. Callers hold LockGroupTree so that
+// concurrent appends do not take the same position.
+func (q *Queries) AssignMediaToGroup(ctx context.Context, arg AssignMediaToGroupParams) (GroupMembership, error) {
+	row := q.db.QueryRow(ctx, assignMediaToGroup, arg.MediaID, arg.GroupID, arg.UserID)
+	var i GroupMembership
+	err := row.Scan(
+		&i.GroupID,
+		&i.MediaID,
+		&i.Position,
+		&i.AddedAt,
+	)
+	return i, err
+}
+
+const copyGroupMemberships = `-- name: CopyGroupMemberships :exec
+INSERT INTO group_memberships (group_id, media_id, position)
+SELECT $1, gm.media_id, gm.position
+FROM group_memberships gm
+JOIN media_files m ON m.id = gm.media_id
+WHERE gm.group_id = $2 AND m.deleted_at IS NULL
+`
+
+type CopyGroupMembershipsParams struct {
+	TargetID pgtype.UUID `json:"target_id"`
+	SourceID pgtype.UUID `json:"source_id"`
+}
+
+// Adds the media of the source group to the target group in the same order.
+func (q *Queries) CopyGroupMemberships(ctx context.Context, arg CopyGroupMembershipsParams) error {
+	_, err := q.db.Exec(ctx, copyGroupMemberships, arg.TargetID, arg.SourceID)
+	return err
+}
+
+const listGroupMemberships = `-- name: ListGroupMemberships :many
+SELECT group_id, media_id, position, added_at FROM group_memberships
+WHERE group_id = $1
+ORDER BY position ASC, added_at ASC
+`
+
+func (q *Queries) ListGroupMemberships(ctx context.Context, groupID pgtype.UUID) ([]GroupMembership, error) {
+	rows, err := q.db.Query(ctx, listGroupMemberships, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GroupMembership{}
+	for rows.Next() {
+		var i GroupMembership
+		if err := rows.Scan(
+			&i.GroupID,
+			&i.MediaID,
+			&i.Position,
+			&i.AddedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMediaByGroupMemberships = `-- name: ListMediaByGroupMemberships :many
+SELECT m.id, m.user_id, m.filename, m.file_type, m.size, m.uploaded_at, m.blob_id, m.processed_at, m.metadata, m.taken_at, m.width, m.height, m.declared_type, m.detected_type, m.deleted_at, m.title, m.description, m.search_vector, m.original_filename, m.display_name, m.favourite, m.custom_fields, m.version, m.process_attempts, m.process_retry_at, m.process_error, gm.group_id FROM group_memberships gm
+JOIN media_files m ON m.id = gm.media_id
+WHERE gm.group_id = ANY($1::uuid[]) AND m.deleted_at IS NULL
+ORDER BY gm.group_id, gm.position ASC, gm.added_at ASC
+`
+
+type ListMediaByGroupMembershipsRow struct {
+	MediaFile MediaFile   `json:"media_file"`
+	GroupID   pgtype.UUID `json:"group_id"`
+}
+
+func (q *Queries) ListMediaByGroupMemberships(ctx context.Context, groupIds []pgtype.UUID) ([]ListMediaByGroupMembershipsRow, error) {
+	rows, err := q.db.Query(ctx, listMediaByGroupMemberships, groupIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListMediaByGroupMembershipsRow{}
+	for rows.Next() {
+		var i ListMediaByGroupMembershipsRow
+		if err := rows.Scan(
+			&i.MediaFile.ID,
+			&i.MediaFile.UserID,
+			&i.MediaFile.Filename,
+			&i.MediaFile.FileType,
+			&i.MediaFile.Size,
+			&i.MediaFile.UploadedAt,
+			&i.MediaFile.BlobID,
+			&i.MediaFile.ProcessedAt,
+			&i.MediaFile.Metadata,
+			&i.MediaFile.TakenAt,
+			&i.MediaFile.Width,
+			&i.MediaFile.Height,
+			&i.MediaFile.DeclaredType,
+			&i.MediaFile.DetectedType,
+			&i.MediaFile.DeletedAt,
+			&i.MediaFile.Title,
+			&i.MediaFile.Description,
+			&i.MediaFile.SearchVector,
+			&i.MediaFile.OriginalFilename,
+			&i.MediaFile.DisplayName,
+			&i.MediaFile.Favourite,
+			&i.MediaFile.CustomFields,
+			&i.MediaFile.Version,
+			&i.MediaFile.ProcessAttempts,
+			&i.MediaFile.ProcessRetryAt,
+			&i.MediaFile.ProcessError,
+			&i.GroupID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const removeMediaFromGroup = `-- name: RemoveMediaFromGroup :one
+DELETE FROM group_memberships
+WHERE group_id = $1 AND media_id = $2
+RETURNING group_id, media_id, position, added_at
+`
+
+type RemoveMediaFromGroupParams struct {
+	GroupID pgtype.UUID `json:"group_id"`
+	MediaID pgtype.UUID `json:"media_id"`
+}
+
+func (q *Queries) RemoveMediaFromGroup(ctx context.Context, arg RemoveMediaFromGroupParams) (GroupMembership, error) {
+	row := q.db.QueryRow(ctx, removeMediaFromGroup, arg.GroupID, arg.MediaID)
+	var i GroupMembership
+	err := row.Scan(
+		&i.GroupID,
+		&i.MediaID,
+		&i.Position,
+		&i.AddedAt,
+	)
+	return i, err
+}
+
+const setGroupMembershipPosition = `-- name: SetGroupMembershipPosition :exec
+UPDATE group_memberships
+SET position = $3
+WHERE group_id = $1 AND media_id = $2
+`
+
+type SetGroupMembershipPositionParams struct {
+	GroupID  pgtype.UUID `json:"group_id"`
+	MediaID  pgtype.UUID `json:"media_id"`
+	Position int32       `json:"position"`
+}
+
+func (q *Queries) SetGroupMembershipPosition(ctx context.Context, arg SetGroupMembershipPositionParams) error {
+	_, err := q.db.Exec(ctx, setGroupMembershipPosition, arg.GroupID, arg.MediaID, arg.Position)
+	return err
+}
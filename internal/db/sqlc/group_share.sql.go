@@ -0,0 +1,334 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: group_share.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const acceptGroupShare = `-- name: AcceptGroupShare :one
+UPDATE group_shares
+SET accepted_at = COALESCE(accepted_at, now())
+WHERE id = $1 AND user_id = $2
+RETURNING id, group_id, owner_id, user_id, role, accepted_at, created_at, email
+`
+
+type AcceptGroupShareParams struct {
+	ID     pgtype.UUID `json:"id"`
+	UserID pgtype.UUID `json:"user_id"`
+}
+
+func (q *Queries) AcceptGroupShare(ctx context.Context, arg AcceptGroupShareParams) (GroupShare, error) {
+	row := q.db.QueryRow(ctx, acceptGroupShare, arg.ID, arg.UserID)
+	var i GroupShare
+	err := row.Scan(
+		&i.ID,
+		&i.GroupID,
+		&i.OwnerID,
+		&i.UserID,
+		&i.Role,
+		&i.AcceptedAt,
+		&i.CreatedAt,
+		&i.Email,
+	)
+	return i, err
+}
+
+const claimGroupSharesByEmail = `-- name: ClaimGroupSharesByEmail :exec
+UPDATE group_shares
+SET user_id = $1
+WHERE email = $2 AND user_id IS NULL AND owner_id <> $1
+`
+
+type ClaimGroupSharesByEmailParams struct {
+	UserID pgtype.UUID `json:"user_id"`
+	Email  string      `json:"email"`
+}
+
+// Binds the invitations sent to an email to the user who verified it.
+func (q *Queries) ClaimGroupSharesByEmail(ctx context.Context, arg ClaimGroupSharesByEmailParams) error {
+	_, err := q.db.Exec(ctx, claimGroupSharesByEmail, arg.UserID, arg.Email)
+	return err
+}
+
+const createGroupShare = `-- name: CreateGroupShare :one
+INSERT INTO group_shares (group_id, owner_id, user_id, email, role)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (group_id, email) DO UPDATE
+SET role = EXCLUDED.role, user_id = COALESCE(group_shares.user_id, EXCLUDED.user_id)
+RETURNING id, group_id, owner_id, user_id, role, accepted_at, created_at, email
+`
+
+type CreateGroupShareParams struct {
+	GroupID pgtype.UUID `json:"group_id"`
+	OwnerID pgtype.UUID `json:"owner_id"`
+	UserID  pgtype.UUID `json:"user_id"`
+	Email   string      `json:"email"`
+	Role    string      `json:"role"`
+}
+
+// Inviting an email again changes the role of the existing share. user_id is
+// null until an account with the email is verified.
+func (q *Queries) CreateGroupShare(ctx context.Context, arg CreateGroupShareParams) (GroupShare, error) {
+	row := q.db.QueryRow(ctx, createGroupShare,
+		arg.GroupID,
+		arg.OwnerID,
+		arg.UserID,
+		arg.Email,
+		arg.Role,
+	)
+	var i GroupShare
+	err := row.Scan(
+		&i.ID,
+		&i.GroupID,
+		&i.OwnerID,
+		&i.UserID,
+		&i.Role,
+		&i.AcceptedAt,
+		&i.CreatedAt,
+		&i.Email,
+	)
+	return i, err
+}
+
+const getSharedGroupForUser = `-- name: GetSharedGroupForUser :one
+SELECT g.id, g.user_id, g.name, g.created_at, g.deleted_at, g.parent_id, g.path FROM media_groups g
+WHERE g.id = $1 AND g.deleted_at IS NULL
+    AND EXISTS (
+        SELECT 1 FROM group_shares s
+        JOIN media_groups sg ON sg.id = s.group_id
+        WHERE s.user_id = $2 AND s.accepted_at IS NOT NULL
+            AND (s.role = 'editor' OR NOT $3::bool)
+            AND sg.user_id = g.user_id AND sg.deleted_at IS NULL
+            AND g.path LIKE sg.path || '%'
+    )
+`
+
+type GetSharedGroupForUserParams struct {
+	ID         pgtype.UUID `json:"id"`
+	UserID     pgtype.UUID `json:"user_id"`
+	EditorOnly bool        `json:"editor_only"`
+}
+
+// Returns a group the user was given access to through an accepted share of
+// the group or of one of its ancestors. Only editor shares count when
+// editor_only is set.
+func (q *Queries) GetSharedGroupForUser(ctx context.Context, arg GetSharedGroupForUserParams) (MediaGroup, error) {
+	row := q.db.QueryRow(ctx, getSharedGroupForUser, arg.ID, arg.UserID, arg.EditorOnly)
+	var i MediaGroup
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.ParentID,
+		&i.Path,
+	)
+	return i, err
+}
+
+const getSharedMediaFileForUser = `-- name: GetSharedMediaFileForUser :one
+SELECT m.id, m.user_id, m.filename, m.file_type, m.size, m.uploaded_at, m.blob_id, m.processed_at, m.metadata, m.taken_at, m.width, m.height, m.declared_type, m.detected_type, m.deleted_at, m.title, m.description, m.search_vector, m.original_filename, m.display_name, m.favourite, m.custom_fields, m.version, m.process_attempts, m.process_retry_at, m.process_error FROM media_files m
+WHERE m.id = $1 AND m.deleted_at IS NULL
+    AND EXISTS (
+        SELECT 1 FROM group_memberships gm
+        JOIN media_groups g ON g.id = gm.group_id AND g.deleted_at IS NULL
+        JOIN media_groups sg ON sg.user_id = g.user_id AND g.path LIKE sg.path || '%'
+        JOIN group_shares s ON s.group_id = sg.id
+        WHERE gm.media_id = m.id AND sg.deleted_at IS NULL
+            AND s.user_id = $2 AND s.accepted_at IS NOT NULL
+            AND (s.role = 'editor' OR NOT $3::bool)
+    )
+`
+
+type GetSharedMediaFileForUserParams struct {
+	ID         pgtype.UUID `json:"id"`
+	UserID     pgtype.UUID `json:"user_id"`
+	EditorOnly bool        `json:"editor_only"`
+}
+
+// Returns a media file belonging to a group the user was given access to, see
+// GetSharedGroupForUser.
+func (q *Queries) GetSharedMediaFileForUser(ctx context.Context, arg GetSharedMediaFileForUserParams) (MediaFile, error) {
+	row := q.db.QueryRow(ctx, getSharedMediaFileForUser, arg.ID, arg.UserID, arg.EditorOnly)
+	var i MediaFile
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Filename,
+		&i.FileType,
+		&i.Size,
+		&i.UploadedAt,
+		&i.BlobID,
+		&i.ProcessedAt,
+		&i.Metadata,
+		&i.TakenAt,
+		&i.Width,
+		&i.Height,
+		&i.DeclaredType,
+		&i.DetectedType,
+		&i.DeletedAt,
+		&i.Title,
+		&i.Description,
+		&i.SearchVector,
+		&i.OriginalFilename,
+		&i.DisplayName,
+		&i.Favourite,
+		&i.CustomFields,
+		&i.Version,
+		&i.ProcessAttempts,
+		&i.ProcessRetryAt,
+		&i.ProcessError,
+	)
+	return i, err
+}
+
+const leaveGroupShare = `-- name: LeaveGroupShare :one
+DELETE FROM group_shares
+WHERE id = $1 AND user_id = $2
+RETURNING id, group_id, owner_id, user_id, role, accepted_at, created_at, email
+`
+
+type LeaveGroupShareParams struct {
+	ID     pgtype.UUID `json:"id"`
+	UserID pgtype.UUID `json:"user_id"`
+}
+
+// Declines an invitation or gives up an accepted share.
+func (q *Queries) LeaveGroupShare(ctx context.Context, arg LeaveGroupShareParams) (GroupShare, error) {
+	row := q.db.QueryRow(ctx, leaveGroupShare, arg.ID, arg.UserID)
+	var i GroupShare
+	err := row.Scan(
+		&i.ID,
+		&i.GroupID,
+		&i.OwnerID,
+		&i.UserID,
+		&i.Role,
+		&i.AcceptedAt,
+		&i.CreatedAt,
+		&i.Email,
+	)
+	return i, err
+}
+
+const listGroupSharesByGroup = `-- name: ListGroupSharesByGroup :many
+SELECT id, group_id, owner_id, user_id, role, accepted_at, created_at, email FROM group_shares
+WHERE group_id = $1
+ORDER BY created_at ASC
+`
+
+func (q *Queries) ListGroupSharesByGroup(ctx context.Context, groupID pgtype.UUID) ([]GroupShare, error) {
+	rows, err := q.db.Query(ctx, listGroupSharesByGroup, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GroupShare{}
+	for rows.Next() {
+		var i GroupShare
+		if err := rows.Scan(
+			&i.ID,
+			&i.GroupID,
+			&i.OwnerID,
+			&i.UserID,
+			&i.Role,
+			&i.AcceptedAt,
+			&i.CreatedAt,
+			&i.Email,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listGroupSharesByUser = `-- name: ListGroupSharesByUser :many
+SELECT s.id, s.group_id, s.owner_id, s.user_id, s.role, s.accepted_at, s.created_at, s.email, g.name AS group_name, u.email AS owner_email
+FROM group_shares s
+JOIN media_groups g ON g.id = s.group_id
+JOIN users u ON u.id = s.owner_id
+WHERE s.user_id = $1 AND g.deleted_at IS NULL
+ORDER BY s.created_at DESC
+`
+
+type ListGroupSharesByUserRow struct {
+	ID         pgtype.UUID        `json:"id"`
+	GroupID    pgtype.UUID        `json:"group_id"`
+	OwnerID    pgtype.UUID        `json:"owner_id"`
+	UserID     pgtype.UUID        `json:"user_id"`
+	Role       string             `json:"role"`
+	AcceptedAt pgtype.Timestamptz `json:"accepted_at"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	Email      string             `json:"email"`
+	GroupName  string             `json:"group_name"`
+	OwnerEmail string             `json:"owner_email"`
+}
+
+// Lists the shares of live groups with a user, pending invitations included.
+func (q *Queries) ListGroupSharesByUser(ctx context.Context, userID pgtype.UUID) ([]ListGroupSharesByUserRow, error) {
+	rows, err := q.db.Query(ctx, listGroupSharesByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListGroupSharesByUserRow{}
+	for rows.Next() {
+		var i ListGroupSharesByUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.GroupID,
+			&i.OwnerID,
+			&i.UserID,
+			&i.Role,
+			&i.AcceptedAt,
+			&i.CreatedAt,
+			&i.Email,
+			&i.GroupName,
+			&i.OwnerEmail,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeGroupShare = `-- name: RevokeGroupShare :one
+DELETE FROM group_shares
+WHERE id = $1 AND group_id = $2
+RETURNING id, group_id, owner_id, user_id, role, accepted_at, created_at, email
+`
+
+type RevokeGroupShareParams struct {
+	ID      pgtype.UUID `json:"id"`
+	GroupID pgtype.UUID `json:"group_id"`
+}
+
+func (q *Queries) RevokeGroupShare(ctx context.Context, arg RevokeGroupShareParams) (GroupShare, error) {
+	row := q.db.QueryRow(ctx, revokeGroupShare, arg.ID, arg.GroupID)
+	var i GroupShare
+	err := row.Scan(
+		&i.ID,
+		&i.GroupID,
+		&i.OwnerID,
+		&i.UserID,
+		&i.Role,
+		&i.AcceptedAt,
+		&i.CreatedAt,
+		&i.Email,
+	)
+	return i, err
+}
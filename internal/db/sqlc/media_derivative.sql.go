@@ -0,0 +1,96 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: media_derivative.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const listMediaDerivatives = `-- name: ListMediaDerivatives :many
+SELECT id, media_id, kind, size, format, storage_key, width, height, bytes, created_at FROM media_derivatives
+WHERE media_id = $1
+ORDER BY kind, size, format
+`
+
+func (q *Queries) ListMediaDerivatives(ctx context.Context, mediaID pgtype.UUID) ([]MediaDerivative, error) {
+	rows, err := q.db.Query(ctx, listMediaDerivatives, mediaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []MediaDerivative{}
+	for rows.Next() {
+		var i MediaDerivative
+		if err := rows.Scan(
+			&i.ID,
+			&i.MediaID,
+			&i.Kind,
+			&i.Size,
+			&i.Format,
+			&i.StorageKey,
+			&i.Width,
+			&i.Height,
+			&i.Bytes,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const upsertMediaDerivative = `-- name: UpsertMediaDerivative :one
+INSERT INTO media_derivatives (media_id, kind, size, format, storage_key, width, height, bytes)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (media_id, kind, size, format)
+DO UPDATE SET storage_key = EXCLUDED.storage_key, width = EXCLUDED.width,
+    height = EXCLUDED.height, bytes = EXCLUDED.bytes, created_at = now()
+RETURNING id, media_id, kind, size, format, storage_key, width, height, bytes, created_at
+`
+
+type UpsertMediaDerivativeParams struct {
+	MediaID    pgtype.UUID `json:"media_id"`
+	Kind       string      `json:"kind"`
+	Size       int32       `json:"size"`
+	Format     string      `json:"format"`
+	StorageKey string      `json:"storage_key"`
+	Width      int32       `json:"width"`
+	Height     int32       `json:"height"`
+	Bytes      int64       `json:"bytes"`
+}
+
+func (q *Queries) UpsertMediaDerivative(ctx context.Context, arg UpsertMediaDerivativeParams) (MediaDerivative, error) {
+	row := q.db.QueryRow(ctx, upsertMediaDerivative,
+		arg.MediaID,
+		arg.Kind,
+		arg.Size,
+		arg.Format,
+		arg.StorageKey,
+		arg.Width,
+		arg.Height,
+		arg.Bytes,
+	)
+	var i MediaDerivative
+	err := row.Scan(
+		&i.ID,
+		&i.MediaID,
+		&i.Kind,
+		&i.Size,
+		&i.Format,
+		&i.StorageKey,
+		&i.Width,
+		&i.Height,
+		&i.Bytes,
+		&i.CreatedAt,
+	)
+	return i, err
+}
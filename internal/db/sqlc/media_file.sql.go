@@ -7,87 +7,160 @@ package db
 
 import (
 	"context"
+	"encoding/json"
 
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-const assignMediaToGroup = `-- name: AssignMediaToGroup :exec
-UPDATE media_files
-SET group_id = $2
-WHERE id = $1
+const countMediaFilesByUser = `-- name: CountMediaFilesByUser :one
+SELECT COUNT(*) AS total_files
+FROM media_files
+WHERE user_id = $1
 `
 
-type AssignMediaToGroupParams struct {
-	ID      pgtype.UUID `json:"id"`
-	GroupID pgtype.UUID `json:"group_id"`
+func (q *Queries) CountMediaFilesByUser(ctx context.Context, userID pgtype.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countMediaFilesByUser, userID)
+	var total_files int64
+	err := row.Scan(&total_files)
+	return total_files, err
 }
 
-func (q *Queries) AssignMediaToGroup(ctx context.Context, arg AssignMediaToGroupParams) error {
-	_, err := q.db.Exec(ctx, assignMediaToGroup, arg.ID, arg.GroupID)
-	return err
+const countMediaFilesForUser = `-- name: CountMediaFilesForUser :one
+SELECT COUNT(*) FROM media_files
+WHERE id = ANY($1::uuid[]) AND user_id = $2 AND deleted_at IS NULL
+`
+
+type CountMediaFilesForUserParams struct {
+	Ids    []pgtype.UUID `json:"ids"`
+	UserID pgtype.UUID   `json:"user_id"`
+}
+
+func (q *Queries) CountMediaFilesForUser(ctx context.Context, arg CountMediaFilesForUserParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countMediaFilesForUser, arg.Ids, arg.UserID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
 }
 
 const countMediaSizeByUser = `-- name: CountMediaSizeByUser :one
-SELECT COALESCE(SUM(size), 0) AS total_size
-FROM media_files
+SELECT COALESCE(SUM(size), 0)::bigint AS total_size
+FROM blobs
 WHERE user_id = $1
 `
 
-func (q *Queries) CountMediaSizeByUser(ctx context.Context, userID pgtype.UUID) (interface{}, error) {
+func (q *Queries) CountMediaSizeByUser(ctx context.Context, userID pgtype.UUID) (int64, error) {
 	row := q.db.QueryRow(ctx, countMediaSizeByUser, userID)
-	var total_size interface{}
+	var total_size int64
 	err := row.Scan(&total_size)
 	return total_size, err
 }
 
 const createMediaFile = `-- name: CreateMediaFile :one
-INSERT INTO media_files (user_id, group_id, filename, file_type, size)
-VALUES ($1, $2, $3, $4, $5)
-RETURNING id, user_id, group_id, filename, file_type, size, uploaded_at
+INSERT INTO media_files (
+    user_id, blob_id, filename, file_type, declared_type, detected_type, size,
+    original_filename, display_name
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
+RETURNING id, user_id, filename, file_type, size, uploaded_at, blob_id, processed_at, metadata, taken_at, width, height, declared_type, detected_type, deleted_at, title, description, search_vector, original_filename, display_name, favourite, custom_fields, version, process_attempts, process_retry_at, process_error
 `
 
 type CreateMediaFileParams struct {
-	UserID   pgtype.UUID `json:"user_id"`
-	GroupID  pgtype.UUID `json:"group_id"`
-	Filename string      `json:"filename"`
-	FileType string      `json:"file_type"`
-	Size     int64       `json:"size"`
+	UserID           pgtype.UUID `json:"user_id"`
+	BlobID           pgtype.UUID `json:"blob_id"`
+	Filename         string      `json:"filename"`
+	FileType         string      `json:"file_type"`
+	DeclaredType     string      `json:"declared_type"`
+	DetectedType     string      `json:"detected_type"`
+	Size             int64       `json:"size"`
+	OriginalFilename string      `json:"original_filename"`
 }
 
 func (q *Queries) CreateMediaFile(ctx context.Context, arg CreateMediaFileParams) (MediaFile, error) {
 	row := q.db.QueryRow(ctx, createMediaFile,
 		arg.UserID,
-		arg.GroupID,
+		arg.BlobID,
 		arg.Filename,
 		arg.FileType,
+		arg.DeclaredType,
+		arg.DetectedType,
 		arg.Size,
+		arg.OriginalFilename,
 	)
 	var i MediaFile
 	err := row.Scan(
 		&i.ID,
 		&i.UserID,
-		&i.GroupID,
 		&i.Filename,
 		&i.FileType,
 		&i.Size,
 		&i.UploadedAt,
+		&i.BlobID,
+		&i.ProcessedAt,
+		&i.Metadata,
+		&i.TakenAt,
+		&i.Width,
+		&i.Height,
+		&i.DeclaredType,
+		&i.DetectedType,
+		&i.DeletedAt,
+		&i.Title,
+		&i.Description,
+		&i.SearchVector,
+		&i.OriginalFilename,
+		&i.DisplayName,
+		&i.Favourite,
+		&i.CustomFields,
+		&i.Version,
+		&i.ProcessAttempts,
+		&i.ProcessRetryAt,
+		&i.ProcessError,
 	)
 	return i, err
 }
 
-const deleteMediaFile = `-- name: DeleteMediaFile :exec
+const deleteMediaFile = `-- name: DeleteMediaFile :one
 DELETE FROM media_files
 WHERE id = $1
+RETURNING id, user_id, filename, file_type, size, uploaded_at, blob_id, processed_at, metadata, taken_at, width, height, declared_type, detected_type, deleted_at, title, description, search_vector, original_filename, display_name, favourite, custom_fields, version, process_attempts, process_retry_at, process_error
 `
 
-func (q *Queries) DeleteMediaFile(ctx context.Context, id pgtype.UUID) error {
-	_, err := q.db.Exec(ctx, deleteMediaFile, id)
-	return err
+func (q *Queries) DeleteMediaFile(ctx context.Context, id pgtype.UUID) (MediaFile, error) {
+	row := q.db.QueryRow(ctx, deleteMediaFile, id)
+	var i MediaFile
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Filename,
+		&i.FileType,
+		&i.Size,
+		&i.UploadedAt,
+		&i.BlobID,
+		&i.ProcessedAt,
+		&i.Metadata,
+		&i.TakenAt,
+		&i.Width,
+		&i.Height,
+		&i.DeclaredType,
+		&i.DetectedType,
+		&i.DeletedAt,
+		&i.Title,
+		&i.Description,
+		&i.SearchVector,
+		&i.OriginalFilename,
+		&i.DisplayName,
+		&i.Favourite,
+		&i.CustomFields,
+		&i.Version,
+		&i.ProcessAttempts,
+		&i.ProcessRetryAt,
+		&i.ProcessError,
+	)
+	return i, err
 }
 
 const getMediaFileByID = `-- name: GetMediaFileByID :one
-SELECT id, user_id, group_id, filename, file_type, size, uploaded_at FROM media_files
-WHERE id = $1
+SELECT id, user_id, filename, file_type, size, uploaded_at, blob_id, processed_at, metadata, taken_at, width, height, declared_type, detected_type, deleted_at, title, description, search_vector, original_filename, display_name, favourite, custom_fields, version, process_attempts, process_retry_at, process_error FROM media_files
+WHERE id = $1 AND deleted_at IS NULL
 `
 
 func (q *Queries) GetMediaFileByID(ctx context.Context, id pgtype.UUID) (MediaFile, error) {
@@ -96,28 +169,192 @@ func (q *Queries) GetMediaFileByID(ctx context.Context, id pgtype.UUID) (MediaFi
 	err := row.Scan(
 		&i.ID,
 		&i.UserID,
-		&i.GroupID,
 		&i.Filename,
 		&i.FileType,
 		&i.Size,
 		&i.UploadedAt,
+		&i.BlobID,
+		&i.ProcessedAt,
+		&i.Metadata,
+		&i.TakenAt,
+		&i.Width,
+		&i.Height,
+		&i.DeclaredType,
+		&i.DetectedType,
+		&i.DeletedAt,
+		&i.Title,
+		&i.Description,
+		&i.SearchVector,
+		&i.OriginalFilename,
+		&i.DisplayName,
+		&i.Favourite,
+		&i.CustomFields,
+		&i.Version,
+		&i.ProcessAttempts,
+		&i.ProcessRetryAt,
+		&i.ProcessError,
 	)
 	return i, err
 }
 
-const listMediaByGroup = `-- name: ListMediaByGroup :many
-SELECT id, user_id, group_id, filename, file_type, size, uploaded_at FROM media_files
-WHERE user_id = $1 AND group_id = $2
-ORDER BY uploaded_at DESC
+const getMediaFileForUser = `-- name: GetMediaFileForUser :one
+SELECT id, user_id, filename, file_type, size, uploaded_at, blob_id, processed_at, metadata, taken_at, width, height, declared_type, detected_type, deleted_at, title, description, search_vector, original_filename, display_name, favourite, custom_fields, version, process_attempts, process_retry_at, process_error FROM media_files
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
 `
 
-type ListMediaByGroupParams struct {
-	UserID  pgtype.UUID `json:"user_id"`
-	GroupID pgtype.UUID `json:"group_id"`
+type GetMediaFileForUserParams struct {
+	ID     pgtype.UUID `json:"id"`
+	UserID pgtype.UUID `json:"user_id"`
 }
 
-func (q *Queries) ListMediaByGroup(ctx context.Context, arg ListMediaByGroupParams) ([]MediaFile, error) {
-	rows, err := q.db.Query(ctx, listMediaByGroup, arg.UserID, arg.GroupID)
+func (q *Queries) GetMediaFileForUser(ctx context.Context, arg GetMediaFileForUserParams) (MediaFile, error) {
+	row := q.db.QueryRow(ctx, getMediaFileForUser, arg.ID, arg.UserID)
+	var i MediaFile
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Filename,
+		&i.FileType,
+		&i.Size,
+		&i.UploadedAt,
+		&i.BlobID,
+		&i.ProcessedAt,
+		&i.Metadata,
+		&i.TakenAt,
+		&i.Width,
+		&i.Height,
+		&i.DeclaredType,
+		&i.DetectedType,
+		&i.DeletedAt,
+		&i.Title,
+		&i.Description,
+		&i.SearchVector,
+		&i.OriginalFilename,
+		&i.DisplayName,
+		&i.Favourite,
+		&i.CustomFields,
+		&i.Version,
+		&i.ProcessAttempts,
+		&i.ProcessRetryAt,
+		&i.ProcessError,
+	)
+	return i, err
+}
+
+const getMediaFileInGroupTree = `-- name: GetMediaFileInGroupTree :one
+SELECT m.id, m.user_id, m.filename, m.file_type, m.size, m.uploaded_at, m.blob_id, m.processed_at, m.metadata, m.taken_at, m.width, m.height, m.declared_type, m.detected_type, m.deleted_at, m.title, m.description, m.search_vector, m.original_filename, m.display_name, m.favourite, m.custom_fields, m.version, m.process_attempts, m.process_retry_at, m.process_error FROM media_files m
+WHERE m.id = $1
+    AND m.deleted_at IS NULL
+    AND EXISTS (
+        SELECT 1 FROM group_memberships gm
+        JOIN media_groups g ON g.id = gm.group_id
+        WHERE gm.media_id = m.id
+            AND g.user_id = $2
+            AND g.path LIKE $3::text || '%'
+            AND g.deleted_at IS NULL
+    )
+`
+
+type GetMediaFileInGroupTreeParams struct {
+	ID     pgtype.UUID `json:"id"`
+	UserID pgtype.UUID `json:"user_id"`
+	Path   string      `json:"path"`
+}
+
+func (q *Queries) GetMediaFileInGroupTree(ctx context.Context, arg GetMediaFileInGroupTreeParams) (MediaFile, error) {
+	row := q.db.QueryRow(ctx, getMediaFileInGroupTree, arg.ID, arg.UserID, arg.Path)
+	var i MediaFile
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Filename,
+		&i.FileType,
+		&i.Size,
+		&i.UploadedAt,
+		&i.BlobID,
+		&i.ProcessedAt,
+		&i.Metadata,
+		&i.TakenAt,
+		&i.Width,
+		&i.Height,
+		&i.DeclaredType,
+		&i.DetectedType,
+		&i.DeletedAt,
+		&i.Title,
+		&i.Description,
+		&i.SearchVector,
+		&i.OriginalFilename,
+		&i.DisplayName,
+		&i.Favourite,
+		&i.CustomFields,
+		&i.Version,
+		&i.ProcessAttempts,
+		&i.ProcessRetryAt,
+		&i.ProcessError,
+	)
+	return i, err
+}
+
+const getTrashedMediaFileForUser = `-- name: GetTrashedMediaFileForUser :one
+SELECT id, user_id, filename, file_type, size, uploaded_at, blob_id, processed_at, metadata, taken_at, width, height, declared_type, detected_type, deleted_at, title, description, search_vector, original_filename, display_name, favourite, custom_fields, version, process_attempts, process_retry_at, process_error FROM media_files
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+`
+
+type GetTrashedMediaFileForUserParams struct {
+	ID     pgtype.UUID `json:"id"`
+	UserID pgtype.UUID `json:"user_id"`
+}
+
+func (q *Queries) GetTrashedMediaFileForUser(ctx context.Context, arg GetTrashedMediaFileForUserParams) (MediaFile, error) {
+	row := q.db.QueryRow(ctx, getTrashedMediaFileForUser, arg.ID, arg.UserID)
+	var i MediaFile
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Filename,
+		&i.FileType,
+		&i.Size,
+		&i.UploadedAt,
+		&i.BlobID,
+		&i.ProcessedAt,
+		&i.Metadata,
+		&i.TakenAt,
+		&i.Width,
+		&i.Height,
+		&i.DeclaredType,
+		&i.DetectedType,
+		&i.DeletedAt,
+		&i.Title,
+		&i.Description,
+		&i.SearchVector,
+		&i.OriginalFilename,
+		&i.DisplayName,
+		&i.Favourite,
+		&i.CustomFields,
+		&i.Version,
+		&i.ProcessAttempts,
+		&i.ProcessRetryAt,
+		&i.ProcessError,
+	)
+	return i, err
+}
+
+const listExpiredTrashedMedia = `-- name: ListExpiredTrashedMedia :many
+SELECT id, user_id, filename, file_type, size, uploaded_at, blob_id, processed_at, metadata, taken_at, width, height, declared_type, detected_type, deleted_at, title, description, search_vector, original_filename, display_name, favourite, custom_fields, version, process_attempts, process_retry_at, process_error FROM media_files
+WHERE deleted_at < $1 AND NOT (id = ANY($2::uuid[]))
+ORDER BY deleted_at ASC
+LIMIT $3
+`
+
+type ListExpiredTrashedMediaParams struct {
+	DeletedAt pgtype.Timestamptz `json:"deleted_at"`
+	SkipIds   []pgtype.UUID      `json:"skip_ids"`
+	MaxCount  int32              `json:"max_count"`
+}
+
+// Media files in skip_ids, which failed to be purged, are left out.
+func (q *Queries) ListExpiredTrashedMedia(ctx context.Context, arg ListExpiredTrashedMediaParams) ([]MediaFile, error) {
+	rows, err := q.db.Query(ctx, listExpiredTrashedMedia, arg.DeletedAt, arg.SkipIds, arg.MaxCount)
 	if err != nil {
 		return nil, err
 	}
@@ -128,11 +365,30 @@ func (q *Queries) ListMediaByGroup(ctx context.Context, arg ListMediaByGroupPara
 		if err := rows.Scan(
 			&i.ID,
 			&i.UserID,
-			&i.GroupID,
 			&i.Filename,
 			&i.FileType,
 			&i.Size,
 			&i.UploadedAt,
+			&i.BlobID,
+			&i.ProcessedAt,
+			&i.Metadata,
+			&i.TakenAt,
+			&i.Width,
+			&i.Height,
+			&i.DeclaredType,
+			&i.DetectedType,
+			&i.DeletedAt,
+			&i.Title,
+			&i.Description,
+			&i.SearchVector,
+			&i.OriginalFilename,
+			&i.DisplayName,
+			&i.Favourite,
+			&i.CustomFields,
+			&i.Version,
+			&i.ProcessAttempts,
+			&i.ProcessRetryAt,
+			&i.ProcessError,
 		); err != nil {
 			return nil, err
 		}
@@ -144,14 +400,133 @@ func (q *Queries) ListMediaByGroup(ctx context.Context, arg ListMediaByGroupPara
 	return items, nil
 }
 
-const listMediaByUser = `-- name: ListMediaByUser :many
-SELECT id, user_id, group_id, filename, file_type, size, uploaded_at FROM media_files
-WHERE user_id = $1
-ORDER BY uploaded_at DESC
+const listMediaUsageByType = `-- name: ListMediaUsageByType :many
+WITH stored AS (
+    SELECT DISTINCT ON (m.blob_id) m.file_type, b.size
+    FROM media_files m
+    JOIN blobs b ON b.id = m.blob_id
+    WHERE m.user_id = $1
+    ORDER BY m.blob_id, m.uploaded_at, m.id
+)
+SELECT t.file_type, t.files, t.logical_bytes, COALESCE(s.bytes, 0)::bigint AS bytes
+FROM (
+    SELECT file_type, COUNT(*) AS files, COALESCE(SUM(size), 0)::bigint AS logical_bytes
+    FROM media_files
+    WHERE media_files.user_id = $1
+    GROUP BY file_type
+) t
+LEFT JOIN (
+    SELECT file_type, SUM(size)::bigint AS bytes
+    FROM stored
+    GROUP BY file_type
+) s ON s.file_type = t.file_type
+ORDER BY t.file_type
+`
+
+type ListMediaUsageByTypeRow struct {
+	FileType     string `json:"file_type"`
+	Files        int64  `json:"files"`
+	LogicalBytes int64  `json:"logical_bytes"`
+	Bytes        int64  `json:"bytes"`
+}
+
+// bytes counts each blob once, under the type of its oldest media file, so
+// that it adds up to CountMediaSizeByUser. logical_bytes counts every copy.
+func (q *Queries) ListMediaUsageByType(ctx context.Context, userID pgtype.UUID) ([]ListMediaUsageByTypeRow, error) {
+	rows, err := q.db.Query(ctx, listMediaUsageByType, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListMediaUsageByTypeRow{}
+	for rows.Next() {
+		var i ListMediaUsageByTypeRow
+		if err := rows.Scan(
+			&i.FileType,
+			&i.Files,
+			&i.LogicalBytes,
+			&i.Bytes,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTrashedMediaByUser = `-- name: ListTrashedMediaByUser :many
+SELECT id, user_id, filename, file_type, size, uploaded_at, blob_id, processed_at, metadata, taken_at, width, height, declared_type, detected_type, deleted_at, title, description, search_vector, original_filename, display_name, favourite, custom_fields, version, process_attempts, process_retry_at, process_error FROM media_files
+WHERE user_id = $1 AND deleted_at IS NOT NULL
+ORDER BY deleted_at DESC
+`
+
+func (q *Queries) ListTrashedMediaByUser(ctx context.Context, userID pgtype.UUID) ([]MediaFile, error) {
+	rows, err := q.db.Query(ctx, listTrashedMediaByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []MediaFile{}
+	for rows.Next() {
+		var i MediaFile
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Filename,
+			&i.FileType,
+			&i.Size,
+			&i.UploadedAt,
+			&i.BlobID,
+			&i.ProcessedAt,
+			&i.Metadata,
+			&i.TakenAt,
+			&i.Width,
+			&i.Height,
+			&i.DeclaredType,
+			&i.DetectedType,
+			&i.DeletedAt,
+			&i.Title,
+			&i.Description,
+			&i.SearchVector,
+			&i.OriginalFilename,
+			&i.DisplayName,
+			&i.Favourite,
+			&i.CustomFields,
+			&i.Version,
+			&i.ProcessAttempts,
+			&i.ProcessRetryAt,
+			&i.ProcessError,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUnprocessedMediaFiles = `-- name: ListUnprocessedMediaFiles :many
+SELECT id, user_id, filename, file_type, size, uploaded_at, blob_id, processed_at, metadata, taken_at, width, height, declared_type, detected_type, deleted_at, title, description, search_vector, original_filename, display_name, favourite, custom_fields, version, process_attempts, process_retry_at, process_error FROM media_files
+WHERE processed_at IS NULL AND process_attempts < $1::int
+    AND (process_retry_at IS NULL OR process_retry_at <= now())
+ORDER BY uploaded_at ASC
+LIMIT $2
 `
 
-func (q *Queries) ListMediaByUser(ctx context.Context, userID pgtype.UUID) ([]MediaFile, error) {
-	rows, err := q.db.Query(ctx, listMediaByUser, userID)
+type ListUnprocessedMediaFilesParams struct {
+	MaxAttempts int32 `json:"max_attempts"`
+	MaxCount    int32 `json:"max_count"`
+}
+
+// Lists media files due for processing, leaving out those given up on after
+// max_attempts failures.
+func (q *Queries) ListUnprocessedMediaFiles(ctx context.Context, arg ListUnprocessedMediaFilesParams) ([]MediaFile, error) {
+	rows, err := q.db.Query(ctx, listUnprocessedMediaFiles, arg.MaxAttempts, arg.MaxCount)
 	if err != nil {
 		return nil, err
 	}
@@ -162,11 +537,30 @@ func (q *Queries) ListMediaByUser(ctx context.Context, userID pgtype.UUID) ([]Me
 		if err := rows.Scan(
 			&i.ID,
 			&i.UserID,
-			&i.GroupID,
 			&i.Filename,
 			&i.FileType,
 			&i.Size,
 			&i.UploadedAt,
+			&i.BlobID,
+			&i.ProcessedAt,
+			&i.Metadata,
+			&i.TakenAt,
+			&i.Width,
+			&i.Height,
+			&i.DeclaredType,
+			&i.DetectedType,
+			&i.DeletedAt,
+			&i.Title,
+			&i.Description,
+			&i.SearchVector,
+			&i.OriginalFilename,
+			&i.DisplayName,
+			&i.Favourite,
+			&i.CustomFields,
+			&i.Version,
+			&i.ProcessAttempts,
+			&i.ProcessRetryAt,
+			&i.ProcessError,
 		); err != nil {
 			return nil, err
 		}
@@ -177,3 +571,262 @@ func (q *Queries) ListMediaByUser(ctx context.Context, userID pgtype.UUID) ([]Me
 	}
 	return items, nil
 }
+
+const markMediaFileProcessed = `-- name: MarkMediaFileProcessed :exec
+UPDATE media_files
+SET processed_at = now(), process_retry_at = NULL, process_error = NULL
+WHERE id = $1
+`
+
+func (q *Queries) MarkMediaFileProcessed(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, markMediaFileProcessed, id)
+	return err
+}
+
+const recordMediaFileProcessingFailure = `-- name: RecordMediaFileProcessingFailure :one
+UPDATE media_files
+SET process_attempts = process_attempts + 1, process_retry_at = $1, process_error = $2
+WHERE id = $3
+RETURNING process_attempts
+`
+
+type RecordMediaFileProcessingFailureParams struct {
+	RetryAt      pgtype.Timestamptz `json:"retry_at"`
+	ProcessError pgtype.Text        `json:"process_error"`
+	ID           pgtype.UUID        `json:"id"`
+}
+
+func (q *Queries) RecordMediaFileProcessingFailure(ctx context.Context, arg RecordMediaFileProcessingFailureParams) (int32, error) {
+	row := q.db.QueryRow(ctx, recordMediaFileProcessingFailure, arg.RetryAt, arg.ProcessError, arg.ID)
+	var process_attempts int32
+	err := row.Scan(&process_attempts)
+	return process_attempts, err
+}
+
+const restoreMediaByGroupTree = `-- name: RestoreMediaByGroupTree :exec
+UPDATE media_files m
+SET deleted_at = NULL
+WHERE m.deleted_at = $1
+    AND EXISTS (
+        SELECT 1 FROM group_memberships gm
+        JOIN media_groups g ON g.id = gm.group_id
+        WHERE gm.media_id = m.id
+            AND g.user_id = $2
+            AND g.path LIKE $3::text || '%'
+    )
+`
+
+type RestoreMediaByGroupTreeParams struct {
+	DeletedAt pgtype.Timestamptz `json:"deleted_at"`
+	UserID    pgtype.UUID        `json:"user_id"`
+	Path      string             `json:"path"`
+}
+
+func (q *Queries) RestoreMediaByGroupTree(ctx context.Context, arg RestoreMediaByGroupTreeParams) error {
+	_, err := q.db.Exec(ctx, restoreMediaByGroupTree, arg.DeletedAt, arg.UserID, arg.Path)
+	return err
+}
+
+const restoreMediaFile = `-- name: RestoreMediaFile :one
+UPDATE media_files
+SET deleted_at = NULL
+WHERE id = $1 AND deleted_at IS NOT NULL
+RETURNING id, user_id, filename, file_type, size, uploaded_at, blob_id, processed_at, metadata, taken_at, width, height, declared_type, detected_type, deleted_at, title, description, search_vector, original_filename, display_name, favourite, custom_fields, version, process_attempts, process_retry_at, process_error
+`
+
+func (q *Queries) RestoreMediaFile(ctx context.Context, id pgtype.UUID) (MediaFile, error) {
+	row := q.db.QueryRow(ctx, restoreMediaFile, id)
+	var i MediaFile
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Filename,
+		&i.FileType,
+		&i.Size,
+		&i.UploadedAt,
+		&i.BlobID,
+		&i.ProcessedAt,
+		&i.Metadata,
+		&i.TakenAt,
+		&i.Width,
+		&i.Height,
+		&i.DeclaredType,
+		&i.DetectedType,
+		&i.DeletedAt,
+		&i.Title,
+		&i.Description,
+		&i.SearchVector,
+		&i.OriginalFilename,
+		&i.DisplayName,
+		&i.Favourite,
+		&i.CustomFields,
+		&i.Version,
+		&i.ProcessAttempts,
+		&i.ProcessRetryAt,
+		&i.ProcessError,
+	)
+	return i, err
+}
+
+const trashMediaByGroupTree = `-- name: TrashMediaByGroupTree :exec
+UPDATE media_files m
+SET deleted_at = $1
+WHERE m.deleted_at IS NULL
+    AND EXISTS (
+        SELECT 1 FROM group_memberships gm
+        JOIN media_groups g ON g.id = gm.group_id
+        WHERE gm.media_id = m.id
+            AND g.user_id = $2
+            AND g.path LIKE $3::text || '%'
+            AND g.deleted_at = $1
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM group_memberships gm
+        JOIN media_groups g ON g.id = gm.group_id
+        WHERE gm.media_id = m.id AND g.deleted_at IS NULL
+    )
+`
+
+type TrashMediaByGroupTreeParams struct {
+	DeletedAt pgtype.Timestamptz `json:"deleted_at"`
+	UserID    pgtype.UUID        `json:"user_id"`
+	Path      string             `json:"path"`
+}
+
+// Trashes the media of the groups under path that were trashed at deleted_at.
+// Media that still belongs to a group outside the trash is left alone.
+func (q *Queries) TrashMediaByGroupTree(ctx context.Context, arg TrashMediaByGroupTreeParams) error {
+	_, err := q.db.Exec(ctx, trashMediaByGroupTree, arg.DeletedAt, arg.UserID, arg.Path)
+	return err
+}
+
+const trashMediaFile = `-- name: TrashMediaFile :one
+UPDATE media_files
+SET deleted_at = now()
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING id, user_id, filename, file_type, size, uploaded_at, blob_id, processed_at, metadata, taken_at, width, height, declared_type, detected_type, deleted_at, title, description, search_vector, original_filename, display_name, favourite, custom_fields, version, process_attempts, process_retry_at, process_error
+`
+
+func (q *Queries) TrashMediaFile(ctx context.Context, id pgtype.UUID) (MediaFile, error) {
+	row := q.db.QueryRow(ctx, trashMediaFile, id)
+	var i MediaFile
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Filename,
+		&i.FileType,
+		&i.Size,
+		&i.UploadedAt,
+		&i.BlobID,
+		&i.ProcessedAt,
+		&i.Metadata,
+		&i.TakenAt,
+		&i.Width,
+		&i.Height,
+		&i.DeclaredType,
+		&i.DetectedType,
+		&i.DeletedAt,
+		&i.Title,
+		&i.Description,
+		&i.SearchVector,
+		&i.OriginalFilename,
+		&i.DisplayName,
+		&i.Favourite,
+		&i.CustomFields,
+		&i.Version,
+		&i.ProcessAttempts,
+		&i.ProcessRetryAt,
+		&i.ProcessError,
+	)
+	return i, err
+}
+
+const updateMediaFileDetails = `-- name: UpdateMediaFileDetails :one
+UPDATE media_files
+SET display_name = $1,
+    title = $2,
+    description = $3,
+    favourite = $4,
+    custom_fields = $5,
+    version = version + 1
+WHERE id = $6 AND version = $7 AND deleted_at IS NULL
+RETURNING id, user_id, filename, file_type, size, uploaded_at, blob_id, processed_at, metadata, taken_at, width, height, declared_type, detected_type, deleted_at, title, description, search_vector, original_filename, display_name, favourite, custom_fields, version, process_attempts, process_retry_at, process_error
+`
+
+type UpdateMediaFileDetailsParams struct {
+	DisplayName  string          `json:"display_name"`
+	Title        string          `json:"title"`
+	Description  string          `json:"description"`
+	Favourite    bool            `json:"favourite"`
+	CustomFields json.RawMessage `json:"custom_fields"`
+	ID           pgtype.UUID     `json:"id"`
+	Version      int32           `json:"version"`
+}
+
+// Only succeeds while the media file is still at the expected version.
+func (q *Queries) UpdateMediaFileDetails(ctx context.Context, arg UpdateMediaFileDetailsParams) (MediaFile, error) {
+	row := q.db.QueryRow(ctx, updateMediaFileDetails,
+		arg.DisplayName,
+		arg.Title,
+		arg.Description,
+		arg.Favourite,
+		arg.CustomFields,
+		arg.ID,
+		arg.Version,
+	)
+	var i MediaFile
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Filename,
+		&i.FileType,
+		&i.Size,
+		&i.UploadedAt,
+		&i.BlobID,
+		&i.ProcessedAt,
+		&i.Metadata,
+		&i.TakenAt,
+		&i.Width,
+		&i.Height,
+		&i.DeclaredType,
+		&i.DetectedType,
+		&i.DeletedAt,
+		&i.Title,
+		&i.Description,
+		&i.SearchVector,
+		&i.OriginalFilename,
+		&i.DisplayName,
+		&i.Favourite,
+		&i.CustomFields,
+		&i.Version,
+		&i.ProcessAttempts,
+		&i.ProcessRetryAt,
+		&i.ProcessError,
+	)
+	return i, err
+}
+
+const updateMediaFileMetadata = `-- name: UpdateMediaFileMetadata :exec
+UPDATE media_files
+SET metadata = $2, taken_at = $3, width = $4, height = $5
+WHERE id = $1
+`
+
+type UpdateMediaFileMetadataParams struct {
+	ID       pgtype.UUID        `json:"id"`
+	Metadata json.RawMessage    `json:"metadata"`
+	TakenAt  pgtype.Timestamptz `json:"taken_at"`
+	Width    pgtype.Int4        `json:"width"`
+	Height   pgtype.Int4        `json:"height"`
+}
+
+func (q *Queries) UpdateMediaFileMetadata(ctx context.Context, arg UpdateMediaFileMetadataParams) error {
+	_, err := q.db.Exec(ctx, updateMediaFileMetadata,
+		arg.ID,
+		arg.Metadata,
+		arg.TakenAt,
+		arg.Width,
+		arg.Height,
+	)
+	return err
+}
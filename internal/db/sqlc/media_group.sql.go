@@ -12,24 +12,28 @@ import (
 )
 
 const createMediaGroup = `-- name: CreateMediaGroup :one
-INSERT INTO media_groups (user_id, name)
-VALUES ($1, $2)
-RETURNING id, user_id, name, created_at
+INSERT INTO media_groups (user_id, name, parent_id)
+VALUES ($1, $2, $3)
+RETURNING id, user_id, name, created_at, deleted_at, parent_id, path
 `
 
 type CreateMediaGroupParams struct {
-	UserID pgtype.UUID `json:"user_id"`
-	Name   string      `json:"name"`
+	UserID   pgtype.UUID `json:"user_id"`
+	Name     string      `json:"name"`
+	ParentID pgtype.UUID `json:"parent_id"`
 }
 
 func (q *Queries) CreateMediaGroup(ctx context.Context, arg CreateMediaGroupParams) (MediaGroup, error) {
-	row := q.db.QueryRow(ctx, createMediaGroup, arg.UserID, arg.Name)
+	row := q.db.QueryRow(ctx, createMediaGroup, arg.UserID, arg.Name, arg.ParentID)
 	var i MediaGroup
 	err := row.Scan(
 		&i.ID,
 		&i.UserID,
 		&i.Name,
 		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.ParentID,
+		&i.Path,
 	)
 	return i, err
 }
@@ -45,8 +49,8 @@ func (q *Queries) DeleteMediaGroup(ctx context.Context, id pgtype.UUID) error {
 }
 
 const getGroupByID = `-- name: GetGroupByID :one
-SELECT id, user_id, name, created_at FROM media_groups
-WHERE id = $1
+SELECT id, user_id, name, created_at, deleted_at, parent_id, path FROM media_groups
+WHERE id = $1 AND deleted_at IS NULL
 `
 
 func (q *Queries) GetGroupByID(ctx context.Context, id pgtype.UUID) (MediaGroup, error) {
@@ -57,22 +61,345 @@ func (q *Queries) GetGroupByID(ctx context.Context, id pgtype.UUID) (MediaGroup,
 		&i.UserID,
 		&i.Name,
 		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.ParentID,
+		&i.Path,
+	)
+	return i, err
+}
+
+const getGroupForUser = `-- name: GetGroupForUser :one
+SELECT id, user_id, name, created_at, deleted_at, parent_id, path FROM media_groups
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+`
+
+type GetGroupForUserParams struct {
+	ID     pgtype.UUID `json:"id"`
+	UserID pgtype.UUID `json:"user_id"`
+}
+
+func (q *Queries) GetGroupForUser(ctx context.Context, arg GetGroupForUserParams) (MediaGroup, error) {
+	row := q.db.QueryRow(ctx, getGroupForUser, arg.ID, arg.UserID)
+	var i MediaGroup
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.ParentID,
+		&i.Path,
 	)
 	return i, err
 }
 
+const getGroupStats = `-- name: GetGroupStats :one
+SELECT COUNT(m.id) AS media_count, COALESCE(SUM(m.size), 0)::bigint AS total_size
+FROM group_memberships gm
+JOIN media_files m ON m.id = gm.media_id
+WHERE gm.group_id = $1 AND m.deleted_at IS NULL
+`
+
+type GetGroupStatsRow struct {
+	MediaCount int64 `json:"media_count"`
+	TotalSize  int64 `json:"total_size"`
+}
+
+func (q *Queries) GetGroupStats(ctx context.Context, groupID pgtype.UUID) (GetGroupStatsRow, error) {
+	row := q.db.QueryRow(ctx, getGroupStats, groupID)
+	var i GetGroupStatsRow
+	err := row.Scan(&i.MediaCount, &i.TotalSize)
+	return i, err
+}
+
+const getTrashedGroupByID = `-- name: GetTrashedGroupByID :one
+SELECT id, user_id, name, created_at, deleted_at, parent_id, path FROM media_groups
+WHERE id = $1 AND deleted_at IS NOT NULL
+`
+
+func (q *Queries) GetTrashedGroupByID(ctx context.Context, id pgtype.UUID) (MediaGroup, error) {
+	row := q.db.QueryRow(ctx, getTrashedGroupByID, id)
+	var i MediaGroup
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.ParentID,
+		&i.Path,
+	)
+	return i, err
+}
+
+const getTrashedGroupForUser = `-- name: GetTrashedGroupForUser :one
+SELECT id, user_id, name, created_at, deleted_at, parent_id, path FROM media_groups
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+`
+
+type GetTrashedGroupForUserParams struct {
+	ID     pgtype.UUID `json:"id"`
+	UserID pgtype.UUID `json:"user_id"`
+}
+
+func (q *Queries) GetTrashedGroupForUser(ctx context.Context, arg GetTrashedGroupForUserParams) (MediaGroup, error) {
+	row := q.db.QueryRow(ctx, getTrashedGroupForUser, arg.ID, arg.UserID)
+	var i MediaGroup
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.ParentID,
+		&i.Path,
+	)
+	return i, err
+}
+
+const listChildGroups = `-- name: ListChildGroups :many
+SELECT g.id, g.user_id, g.name, g.created_at, g.parent_id, g.path,
+    COUNT(m.id) AS media_count,
+    COALESCE(SUM(m.size), 0)::bigint AS total_size
+FROM media_groups g
+LEFT JOIN group_memberships gm ON gm.group_id = g.id
+LEFT JOIN media_files m ON m.id = gm.media_id AND m.deleted_at IS NULL
+WHERE g.parent_id = $1 AND g.deleted_at IS NULL
+GROUP BY g.id
+ORDER BY g.name ASC
+`
+
+type ListChildGroupsRow struct {
+	ID         pgtype.UUID        `json:"id"`
+	UserID     pgtype.UUID        `json:"user_id"`
+	Name       string             `json:"name"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	ParentID   pgtype.UUID        `json:"parent_id"`
+	Path       string             `json:"path"`
+	MediaCount int64              `json:"media_count"`
+	TotalSize  int64              `json:"total_size"`
+}
+
+func (q *Queries) ListChildGroups(ctx context.Context, parentID pgtype.UUID) ([]ListChildGroupsRow, error) {
+	rows, err := q.db.Query(ctx, listChildGroups, parentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListChildGroupsRow{}
+	for rows.Next() {
+		var i ListChildGroupsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.CreatedAt,
+			&i.ParentID,
+			&i.Path,
+			&i.MediaCount,
+			&i.TotalSize,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listExpiredTrashedGroups = `-- name: ListExpiredTrashedGroups :many
+SELECT id, user_id, name, created_at, deleted_at, parent_id, path FROM media_groups
+WHERE deleted_at < $1 AND NOT (id = ANY($2::uuid[]))
+ORDER BY deleted_at ASC
+LIMIT $3
+`
+
+type ListExpiredTrashedGroupsParams struct {
+	DeletedAt pgtype.Timestamptz `json:"deleted_at"`
+	SkipIds   []pgtype.UUID      `json:"skip_ids"`
+	MaxCount  int32              `json:"max_count"`
+}
+
+// Groups in skip_ids, which failed to be purged, are left out.
+func (q *Queries) ListExpiredTrashedGroups(ctx context.Context, arg ListExpiredTrashedGroupsParams) ([]MediaGroup, error) {
+	rows, err := q.db.Query(ctx, listExpiredTrashedGroups, arg.DeletedAt, arg.SkipIds, arg.MaxCount)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []MediaGroup{}
+	for rows.Next() {
+		var i MediaGroup
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.CreatedAt,
+			&i.DeletedAt,
+			&i.ParentID,
+			&i.Path,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listGroupAncestors = `-- name: ListGroupAncestors :many
+SELECT id, user_id, name, created_at, deleted_at, parent_id, path FROM media_groups
+WHERE user_id = $1 AND $2::text LIKE path || '%'
+ORDER BY length(path) ASC
+`
+
+type ListGroupAncestorsParams struct {
+	UserID pgtype.UUID `json:"user_id"`
+	Path   string      `json:"path"`
+}
+
+// Returns the groups on the path from the root down to the group itself.
+func (q *Queries) ListGroupAncestors(ctx context.Context, arg ListGroupAncestorsParams) ([]MediaGroup, error) {
+	rows, err := q.db.Query(ctx, listGroupAncestors, arg.UserID, arg.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []MediaGroup{}
+	for rows.Next() {
+		var i MediaGroup
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.CreatedAt,
+			&i.DeletedAt,
+			&i.ParentID,
+			&i.Path,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listGroupSubtree = `-- name: ListGroupSubtree :many
+SELECT id, user_id, name, created_at, deleted_at, parent_id, path FROM media_groups
+WHERE user_id = $1 AND path LIKE $2::text || '%' AND deleted_at IS NULL
+ORDER BY length(path) ASC, created_at ASC
+`
+
+type ListGroupSubtreeParams struct {
+	UserID pgtype.UUID `json:"user_id"`
+	Path   string      `json:"path"`
+}
+
+func (q *Queries) ListGroupSubtree(ctx context.Context, arg ListGroupSubtreeParams) ([]MediaGroup, error) {
+	rows, err := q.db.Query(ctx, listGroupSubtree, arg.UserID, arg.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []MediaGroup{}
+	for rows.Next() {
+		var i MediaGroup
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.CreatedAt,
+			&i.DeletedAt,
+			&i.ParentID,
+			&i.Path,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listGroupsByUser = `-- name: ListGroupsByUser :many
-SELECT id, user_id, name, created_at FROM media_groups
-WHERE user_id = $1
-ORDER BY created_at DESC
+SELECT g.id, g.user_id, g.name, g.created_at, g.parent_id, g.path,
+    COUNT(m.id) AS media_count,
+    COALESCE(SUM(m.size), 0)::bigint AS total_size
+FROM media_groups g
+LEFT JOIN group_memberships gm ON gm.group_id = g.id
+LEFT JOIN media_files m ON m.id = gm.media_id AND m.deleted_at IS NULL
+WHERE g.user_id = $1 AND g.deleted_at IS NULL
+GROUP BY g.id
+ORDER BY g.created_at DESC
 `
 
-func (q *Queries) ListGroupsByUser(ctx context.Context, userID pgtype.UUID) ([]MediaGroup, error) {
+type ListGroupsByUserRow struct {
+	ID         pgtype.UUID        `json:"id"`
+	UserID     pgtype.UUID        `json:"user_id"`
+	Name       string             `json:"name"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	ParentID   pgtype.UUID        `json:"parent_id"`
+	Path       string             `json:"path"`
+	MediaCount int64              `json:"media_count"`
+	TotalSize  int64              `json:"total_size"`
+}
+
+func (q *Queries) ListGroupsByUser(ctx context.Context, userID pgtype.UUID) ([]ListGroupsByUserRow, error) {
 	rows, err := q.db.Query(ctx, listGroupsByUser, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
+	items := []ListGroupsByUserRow{}
+	for rows.Next() {
+		var i ListGroupsByUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.CreatedAt,
+			&i.ParentID,
+			&i.Path,
+			&i.MediaCount,
+			&i.TotalSize,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTrashedGroupsByUser = `-- name: ListTrashedGroupsByUser :many
+SELECT id, user_id, name, created_at, deleted_at, parent_id, path FROM media_groups g
+WHERE g.user_id = $1 AND g.deleted_at IS NOT NULL
+    AND NOT EXISTS (
+        SELECT 1 FROM media_groups p
+        WHERE p.id = g.parent_id AND p.deleted_at = g.deleted_at
+    )
+ORDER BY g.deleted_at DESC
+`
+
+// Groups trashed along with their parent are left out, restoring the parent
+// restores them.
+func (q *Queries) ListTrashedGroupsByUser(ctx context.Context, userID pgtype.UUID) ([]MediaGroup, error) {
+	rows, err := q.db.Query(ctx, listTrashedGroupsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 	items := []MediaGroup{}
 	for rows.Next() {
 		var i MediaGroup
@@ -81,6 +408,9 @@ func (q *Queries) ListGroupsByUser(ctx context.Context, userID pgtype.UUID) ([]M
 			&i.UserID,
 			&i.Name,
 			&i.CreatedAt,
+			&i.DeletedAt,
+			&i.ParentID,
+			&i.Path,
 		); err != nil {
 			return nil, err
 		}
@@ -91,3 +421,141 @@ func (q *Queries) ListGroupsByUser(ctx context.Context, userID pgtype.UUID) ([]M
 	}
 	return items, nil
 }
+
+const lockGroupTree = `-- name: LockGroupTree :exec
+SELECT pg_advisory_xact_lock(hashtextextended($1::uuid::text, 0))
+`
+
+// Serializes changes to the folder tree of a user until the transaction ends.
+func (q *Queries) LockGroupTree(ctx context.Context, userID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, lockGroupTree, userID)
+	return err
+}
+
+const moveGroupSubtree = `-- name: MoveGroupSubtree :exec
+UPDATE media_groups
+SET path = $1::text || substr(path, length($2::text) + 1)
+WHERE user_id = $3 AND path LIKE $2::text || '%'
+`
+
+type MoveGroupSubtreeParams struct {
+	NewPath string      `json:"new_path"`
+	OldPath string      `json:"old_path"`
+	UserID  pgtype.UUID `json:"user_id"`
+}
+
+// Rewrites the path of a group and its descendants from old_path to new_path.
+func (q *Queries) MoveGroupSubtree(ctx context.Context, arg MoveGroupSubtreeParams) error {
+	_, err := q.db.Exec(ctx, moveGroupSubtree, arg.NewPath, arg.OldPath, arg.UserID)
+	return err
+}
+
+const renameMediaGroup = `-- name: RenameMediaGroup :one
+UPDATE media_groups
+SET name = $2
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING id, user_id, name, created_at, deleted_at, parent_id, path
+`
+
+type RenameMediaGroupParams struct {
+	ID   pgtype.UUID `json:"id"`
+	Name string      `json:"name"`
+}
+
+func (q *Queries) RenameMediaGroup(ctx context.Context, arg RenameMediaGroupParams) (MediaGroup, error) {
+	row := q.db.QueryRow(ctx, renameMediaGroup, arg.ID, arg.Name)
+	var i MediaGroup
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.ParentID,
+		&i.Path,
+	)
+	return i, err
+}
+
+const restoreGroupDescendants = `-- name: RestoreGroupDescendants :exec
+UPDATE media_groups
+SET deleted_at = NULL
+WHERE user_id = $1 AND path LIKE $2::text || '%' AND deleted_at = $3
+`
+
+type RestoreGroupDescendantsParams struct {
+	UserID    pgtype.UUID        `json:"user_id"`
+	Path      string             `json:"path"`
+	DeletedAt pgtype.Timestamptz `json:"deleted_at"`
+}
+
+func (q *Queries) RestoreGroupDescendants(ctx context.Context, arg RestoreGroupDescendantsParams) error {
+	_, err := q.db.Exec(ctx, restoreGroupDescendants, arg.UserID, arg.Path, arg.DeletedAt)
+	return err
+}
+
+const setGroupParent = `-- name: SetGroupParent :one
+UPDATE media_groups
+SET parent_id = $2
+WHERE id = $1
+RETURNING id, user_id, name, created_at, deleted_at, parent_id, path
+`
+
+type SetGroupParentParams struct {
+	ID       pgtype.UUID `json:"id"`
+	ParentID pgtype.UUID `json:"parent_id"`
+}
+
+func (q *Queries) SetGroupParent(ctx context.Context, arg SetGroupParentParams) (MediaGroup, error) {
+	row := q.db.QueryRow(ctx, setGroupParent, arg.ID, arg.ParentID)
+	var i MediaGroup
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.ParentID,
+		&i.Path,
+	)
+	return i, err
+}
+
+const trashGroupDescendants = `-- name: TrashGroupDescendants :exec
+UPDATE media_groups
+SET deleted_at = $1
+WHERE user_id = $2 AND path LIKE $3::text || '%' AND deleted_at IS NULL
+`
+
+type TrashGroupDescendantsParams struct {
+	DeletedAt pgtype.Timestamptz `json:"deleted_at"`
+	UserID    pgtype.UUID        `json:"user_id"`
+	Path      string             `json:"path"`
+}
+
+func (q *Queries) TrashGroupDescendants(ctx context.Context, arg TrashGroupDescendantsParams) error {
+	_, err := q.db.Exec(ctx, trashGroupDescendants, arg.DeletedAt, arg.UserID, arg.Path)
+	return err
+}
+
+const trashMediaGroup = `-- name: TrashMediaGroup :one
+UPDATE media_groups
+SET deleted_at = now()
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING id, user_id, name, created_at, deleted_at, parent_id, path
+`
+
+func (q *Queries) TrashMediaGroup(ctx context.Context, id pgtype.UUID) (MediaGroup, error) {
+	row := q.db.QueryRow(ctx, trashMediaGroup, id)
+	var i MediaGroup
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.ParentID,
+		&i.Path,
+	)
+	return i, err
+}
@@ -0,0 +1,265 @@
+package db
+
+import (
+	"context"
+	"math"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// MediaSort is an order media listings can be sorted in. Each order has its
+// own keyset query, so that pages are read from an index instead of sorting
+// every media file of the user.
+type MediaSort string
+
+const (
+	MediaSortUploadedAt MediaSort = "uploaded_at"
+	// MediaSortTakenAt falls back to the upload time for media without a
+	// capture time.
+	MediaSortTakenAt MediaSort = "taken_at"
+	MediaSortSize    MediaSort = "size"
+	// MediaSortFilename sorts by the display name.
+	MediaSortFilename MediaSort = "filename"
+	// MediaSortPosition follows the order of the media in a group.
+	MediaSortPosition MediaSort = "position"
+)
+
+// MediaFilter selects the media files of a listing.
+type MediaFilter struct {
+	UserID         pgtype.UUID
+	FavouritesOnly bool
+	// TypePrefix is a LIKE pattern matched against the start of the MIME type,
+	// its wildcards must be escaped.
+	TypePrefix   string
+	MinSize      int64
+	MaxSize      int64
+	UploadedFrom pgtype.Timestamptz
+	UploadedTo   pgtype.Timestamptz
+	// GroupID limits the listing to the members of a group.
+	GroupID pgtype.UUID
+	// GroupPath limits the listing to the members of the groups under a path.
+	GroupPath pgtype.Text
+	// Tags are matched by at least MinMatches of them, zero disables tags.
+	Tags       []string
+	MinMatches int32
+}
+
+// NewMediaFilter returns a filter that matches every media file of a user.
+func NewMediaFilter(userID pgtype.UUID) MediaFilter {
+	return MediaFilter{
+		UserID:       userID,
+		MaxSize:      math.MaxInt64,
+		UploadedFrom: pgtype.Timestamptz{InfinityModifier: pgtype.NegativeInfinity, Valid: true},
+		UploadedTo:   pgtype.Timestamptz{InfinityModifier: pgtype.Infinity, Valid: true},
+	}
+}
+
+// MediaCursor is the position of a media file in a listing. Only the field of
+// the listing's sort order is set, along with the id breaking ties.
+type MediaCursor struct {
+	Time     pgtype.Timestamptz
+	Size     int64
+	Name     string
+	Position int32
+	ID       pgtype.UUID
+}
+
+type ListMediaPageParams struct {
+	MediaFilter
+	Sort MediaSort
+	Desc bool
+	// PositionGroupID is the group whose order MediaSortPosition follows.
+	PositionGroupID pgtype.UUID
+	// After is the cursor of the last media file of the previous page, the
+	// first page is returned when it is nil.
+	After *MediaCursor
+	Limit int32
+}
+
+type MediaPage struct {
+	Media []MediaFile
+	// Next is the cursor of the following page, nil on the last page.
+	Next *MediaCursor
+}
+
+// ListMediaPage returns a page of media files matching the filter.
+func (q *Queries) ListMediaPage(ctx context.Context, arg ListMediaPageParams) (MediaPage, error) {
+	var page MediaPage
+	var positions []int32
+	var err error
+
+	after := MediaCursor{}
+	if arg.After != nil {
+		after = *arg.After
+	}
+
+	f := arg.MediaFilter
+	limit := arg.Limit + 1
+
+	switch arg.Sort {
+	case MediaSortUploadedAt, MediaSortTakenAt:
+		params := ListMediaByUploadedAtAscParams{
+			UserID:         f.UserID,
+			FavouritesOnly: f.FavouritesOnly,
+			TypePrefix:     f.TypePrefix,
+			MinSize:        f.MinSize,
+			MaxSize:        f.MaxSize,
+			UploadedFrom:   f.UploadedFrom,
+			UploadedTo:     f.UploadedTo,
+			GroupID:        f.GroupID,
+			GroupPath:      f.GroupPath,
+			MinMatches:     f.MinMatches,
+			Tags:           f.Tags,
+			CursorID:       after.ID,
+			CursorTime:     after.Time,
+			RowLimit:       limit,
+		}
+
+		switch {
+		case arg.Sort == MediaSortTakenAt && arg.Desc:
+			page.Media, err = q.ListMediaByTakenAtDesc(ctx, ListMediaByTakenAtDescParams(params))
+		case arg.Sort == MediaSortTakenAt:
+			page.Media, err = q.ListMediaByTakenAtAsc(ctx, ListMediaByTakenAtAscParams(params))
+		case arg.Desc:
+			page.Media, err = q.ListMediaByUploadedAtDesc(ctx, ListMediaByUploadedAtDescParams(params))
+		default:
+			page.Media, err = q.ListMediaByUploadedAtAsc(ctx, params)
+		}
+
+	case MediaSortSize:
+		params := ListMediaBySizeAscParams{
+			UserID:         f.UserID,
+			FavouritesOnly: f.FavouritesOnly,
+			TypePrefix:     f.TypePrefix,
+			MinSize:        f.MinSize,
+			MaxSize:        f.MaxSize,
+			UploadedFrom:   f.UploadedFrom,
+			UploadedTo:     f.UploadedTo,
+			GroupID:        f.GroupID,
+			GroupPath:      f.GroupPath,
+			MinMatches:     f.MinMatches,
+			Tags:           f.Tags,
+			CursorID:       after.ID,
+			CursorSize:     pgtype.Int8{Int64: after.Size, Valid: after.ID.Valid},
+			RowLimit:       limit,
+		}
+
+		if arg.Desc {
+			page.Media, err = q.ListMediaBySizeDesc(ctx, ListMediaBySizeDescParams(params))
+		} else {
+			page.Media, err = q.ListMediaBySizeAsc(ctx, params)
+		}
+
+	case MediaSortFilename:
+		params := ListMediaByFilenameAscParams{
+			UserID:         f.UserID,
+			FavouritesOnly: f.FavouritesOnly,
+			TypePrefix:     f.TypePrefix,
+			MinSize:        f.MinSize,
+			MaxSize:        f.MaxSize,
+			UploadedFrom:   f.UploadedFrom,
+			UploadedTo:     f.UploadedTo,
+			GroupID:        f.GroupID,
+			GroupPath:      f.GroupPath,
+			MinMatches:     f.MinMatches,
+			Tags:           f.Tags,
+			CursorID:       after.ID,
+			CursorName:     pgtype.Text{String: after.Name, Valid: after.ID.Valid},
+			RowLimit:       limit,
+		}
+
+		if arg.Desc {
+			page.Media, err = q.ListMediaByFilenameDesc(ctx, ListMediaByFilenameDescParams(params))
+		} else {
+			page.Media, err = q.ListMediaByFilenameAsc(ctx, params)
+		}
+
+	case MediaSortPosition:
+		params := ListMediaByPositionAscParams{
+			PositionGroupID: arg.PositionGroupID,
+			UserID:          f.UserID,
+			FavouritesOnly:  f.FavouritesOnly,
+			TypePrefix:      f.TypePrefix,
+			MinSize:         f.MinSize,
+			MaxSize:         f.MaxSize,
+			UploadedFrom:    f.UploadedFrom,
+			UploadedTo:      f.UploadedTo,
+			GroupID:         f.GroupID,
+			GroupPath:       f.GroupPath,
+			MinMatches:      f.MinMatches,
+			Tags:            f.Tags,
+			CursorID:        after.ID,
+			CursorPosition:  pgtype.Int4{Int32: after.Position, Valid: after.ID.Valid},
+			RowLimit:        limit,
+		}
+
+		var rows []ListMediaByPositionAscRow
+		if arg.Desc {
+			var desc []ListMediaByPositionDescRow
+			desc, err = q.ListMediaByPositionDesc(ctx, ListMediaByPositionDescParams(params))
+			for _, row := range desc {
+				rows = append(rows, ListMediaByPositionAscRow(row))
+			}
+		} else {
+			rows, err = q.ListMediaByPositionAsc(ctx, params)
+		}
+
+		for _, row := range rows {
+			page.Media = append(page.Media, row.MediaFile)
+			positions = append(positions, row.Position)
+		}
+	}
+
+	if err != nil {
+		return page, err
+	}
+
+	if len(page.Media) > int(arg.Limit) {
+		page.Media = page.Media[:arg.Limit]
+
+		last := page.Media[len(page.Media)-1]
+		next := MediaCursor{ID: last.ID}
+
+		switch arg.Sort {
+		case MediaSortUploadedAt:
+			next.Time = last.UploadedAt
+		case MediaSortTakenAt:
+			next.Time = last.UploadedAt
+			if last.TakenAt.Valid {
+				next.Time = last.TakenAt
+			}
+		case MediaSortSize:
+			next.Size = last.Size
+		case MediaSortFilename:
+			next.Name = last.DisplayName
+		case MediaSortPosition:
+			next.Position = positions[len(page.Media)-1]
+		}
+
+		page.Next = &next
+	}
+
+	if page.Media == nil {
+		page.Media = []MediaFile{}
+	}
+
+	return page, nil
+}
+
+// CountMediaMatching counts the media files matching a filter and their total
+// size.
+func (q *Queries) CountMediaMatching(ctx context.Context, f MediaFilter) (CountMediaPageRow, error) {
+	return q.CountMediaPage(ctx, CountMediaPageParams{
+		UserID:         f.UserID,
+		FavouritesOnly: f.FavouritesOnly,
+		TypePrefix:     f.TypePrefix,
+		MinSize:        f.MinSize,
+		MaxSize:        f.MaxSize,
+		UploadedFrom:   f.UploadedFrom,
+		UploadedTo:     f.UploadedTo,
+		GroupID:        f.GroupID,
+		GroupPath:      f.GroupPath,
+		MinMatches:     f.MinMatches,
+		Tags:           f.Tags,
+	})
+}
@@ -0,0 +1,1186 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: media_page.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const countMediaPage = `-- name: CountMediaPage :one
+SELECT COUNT(*) AS total_count, COALESCE(SUM(m.size), 0)::bigint AS total_size
+FROM media_files m
+WHERE m.user_id = $1
+    AND m.deleted_at IS NULL
+    AND (NOT $2::bool OR m.favourite)
+    AND m.file_type LIKE $3::text || '%'
+    AND m.size BETWEEN $4::bigint AND $5::bigint
+    AND m.uploaded_at >= $6::timestamptz
+    AND m.uploaded_at < $7::timestamptz
+    AND ($8::uuid IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        WHERE gm.media_id = m.id AND gm.group_id = $8::uuid
+    ))
+    AND ($9::text IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        JOIN media_groups g ON g.id = gm.group_id
+        WHERE gm.media_id = m.id
+            AND g.path LIKE $9::text || '%'
+            AND g.deleted_at IS NULL
+    ))
+    AND ($10::int = 0 OR (
+        SELECT COUNT(*) FROM media_tags mt
+        JOIN tags t ON t.id = mt.tag_id
+        WHERE mt.media_id = m.id AND t.name = ANY($11::text[])
+    ) >= $10::int)
+`
+
+type CountMediaPageParams struct {
+	UserID         pgtype.UUID        `json:"user_id"`
+	FavouritesOnly bool               `json:"favourites_only"`
+	TypePrefix     string             `json:"type_prefix"`
+	MinSize        int64              `json:"min_size"`
+	MaxSize        int64              `json:"max_size"`
+	UploadedFrom   pgtype.Timestamptz `json:"uploaded_from"`
+	UploadedTo     pgtype.Timestamptz `json:"uploaded_to"`
+	GroupID        pgtype.UUID        `json:"group_id"`
+	GroupPath      pgtype.Text        `json:"group_path"`
+	MinMatches     int32              `json:"min_matches"`
+	Tags           []string           `json:"tags"`
+}
+
+type CountMediaPageRow struct {
+	TotalCount int64 `json:"total_count"`
+	TotalSize  int64 `json:"total_size"`
+}
+
+func (q *Queries) CountMediaPage(ctx context.Context, arg CountMediaPageParams) (CountMediaPageRow, error) {
+	row := q.db.QueryRow(ctx, countMediaPage,
+		arg.UserID,
+		arg.FavouritesOnly,
+		arg.TypePrefix,
+		arg.MinSize,
+		arg.MaxSize,
+		arg.UploadedFrom,
+		arg.UploadedTo,
+		arg.GroupID,
+		arg.GroupPath,
+		arg.MinMatches,
+		arg.Tags,
+	)
+	var i CountMediaPageRow
+	err := row.Scan(&i.TotalCount, &i.TotalSize)
+	return i, err
+}
+
+const listMediaByFilenameAsc = `-- name: ListMediaByFilenameAsc :many
+SELECT m.id, m.user_id, m.filename, m.file_type, m.size, m.uploaded_at, m.blob_id, m.processed_at, m.metadata, m.taken_at, m.width, m.height, m.declared_type, m.detected_type, m.deleted_at, m.title, m.description, m.search_vector, m.original_filename, m.display_name, m.favourite, m.custom_fields, m.version, m.process_attempts, m.process_retry_at, m.process_error FROM media_files m
+WHERE m.user_id = $1
+    AND m.deleted_at IS NULL
+    AND (NOT $2::bool OR m.favourite)
+    AND m.file_type LIKE $3::text || '%'
+    AND m.size BETWEEN $4::bigint AND $5::bigint
+    AND m.uploaded_at >= $6::timestamptz
+    AND m.uploaded_at < $7::timestamptz
+    AND ($8::uuid IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        WHERE gm.media_id = m.id AND gm.group_id = $8::uuid
+    ))
+    AND ($9::text IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        JOIN media_groups g ON g.id = gm.group_id
+        WHERE gm.media_id = m.id
+            AND g.path LIKE $9::text || '%'
+            AND g.deleted_at IS NULL
+    ))
+    AND ($10::int = 0 OR (
+        SELECT COUNT(*) FROM media_tags mt
+        JOIN tags t ON t.id = mt.tag_id
+        WHERE mt.media_id = m.id AND t.name = ANY($11::text[])
+    ) >= $10::int)
+    AND ($12::uuid IS NULL OR (m.display_name, m.id) > ($13::text, $12::uuid))
+ORDER BY m.display_name ASC, m.id ASC
+LIMIT $14
+`
+
+type ListMediaByFilenameAscParams struct {
+	UserID         pgtype.UUID        `json:"user_id"`
+	FavouritesOnly bool               `json:"favourites_only"`
+	TypePrefix     string             `json:"type_prefix"`
+	MinSize        int64              `json:"min_size"`
+	MaxSize        int64              `json:"max_size"`
+	UploadedFrom   pgtype.Timestamptz `json:"uploaded_from"`
+	UploadedTo     pgtype.Timestamptz `json:"uploaded_to"`
+	GroupID        pgtype.UUID        `json:"group_id"`
+	GroupPath      pgtype.Text        `json:"group_path"`
+	MinMatches     int32              `json:"min_matches"`
+	Tags           []string           `json:"tags"`
+	CursorID       pgtype.UUID        `json:"cursor_id"`
+	CursorName     pgtype.Text        `json:"cursor_name"`
+	RowLimit       int32              `json:"row_limit"`
+}
+
+func (q *Queries) ListMediaByFilenameAsc(ctx context.Context, arg ListMediaByFilenameAscParams) ([]MediaFile, error) {
+	rows, err := q.db.Query(ctx, listMediaByFilenameAsc,
+		arg.UserID,
+		arg.FavouritesOnly,
+		arg.TypePrefix,
+		arg.MinSize,
+		arg.MaxSize,
+		arg.UploadedFrom,
+		arg.UploadedTo,
+		arg.GroupID,
+		arg.GroupPath,
+		arg.MinMatches,
+		arg.Tags,
+		arg.CursorID,
+		arg.CursorName,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []MediaFile{}
+	for rows.Next() {
+		var i MediaFile
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Filename,
+			&i.FileType,
+			&i.Size,
+			&i.UploadedAt,
+			&i.BlobID,
+			&i.ProcessedAt,
+			&i.Metadata,
+			&i.TakenAt,
+			&i.Width,
+			&i.Height,
+			&i.DeclaredType,
+			&i.DetectedType,
+			&i.DeletedAt,
+			&i.Title,
+			&i.Description,
+			&i.SearchVector,
+			&i.OriginalFilename,
+			&i.DisplayName,
+			&i.Favourite,
+			&i.CustomFields,
+			&i.Version,
+			&i.ProcessAttempts,
+			&i.ProcessRetryAt,
+			&i.ProcessError,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMediaByFilenameDesc = `-- name: ListMediaByFilenameDesc :many
+SELECT m.id, m.user_id, m.filename, m.file_type, m.size, m.uploaded_at, m.blob_id, m.processed_at, m.metadata, m.taken_at, m.width, m.height, m.declared_type, m.detected_type, m.deleted_at, m.title, m.description, m.search_vector, m.original_filename, m.display_name, m.favourite, m.custom_fields, m.version, m.process_attempts, m.process_retry_at, m.process_error FROM media_files m
+WHERE m.user_id = $1
+    AND m.deleted_at IS NULL
+    AND (NOT $2::bool OR m.favourite)
+    AND m.file_type LIKE $3::text || '%'
+    AND m.size BETWEEN $4::bigint AND $5::bigint
+    AND m.uploaded_at >= $6::timestamptz
+    AND m.uploaded_at < $7::timestamptz
+    AND ($8::uuid IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        WHERE gm.media_id = m.id AND gm.group_id = $8::uuid
+    ))
+    AND ($9::text IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        JOIN media_groups g ON g.id = gm.group_id
+        WHERE gm.media_id = m.id
+            AND g.path LIKE $9::text || '%'
+            AND g.deleted_at IS NULL
+    ))
+    AND ($10::int = 0 OR (
+        SELECT COUNT(*) FROM media_tags mt
+        JOIN tags t ON t.id = mt.tag_id
+        WHERE mt.media_id = m.id AND t.name = ANY($11::text[])
+    ) >= $10::int)
+    AND ($12::uuid IS NULL OR (m.display_name, m.id) < ($13::text, $12::uuid))
+ORDER BY m.display_name DESC, m.id DESC
+LIMIT $14
+`
+
+type ListMediaByFilenameDescParams struct {
+	UserID         pgtype.UUID        `json:"user_id"`
+	FavouritesOnly bool               `json:"favourites_only"`
+	TypePrefix     string             `json:"type_prefix"`
+	MinSize        int64              `json:"min_size"`
+	MaxSize        int64              `json:"max_size"`
+	UploadedFrom   pgtype.Timestamptz `json:"uploaded_from"`
+	UploadedTo     pgtype.Timestamptz `json:"uploaded_to"`
+	GroupID        pgtype.UUID        `json:"group_id"`
+	GroupPath      pgtype.Text        `json:"group_path"`
+	MinMatches     int32              `json:"min_matches"`
+	Tags           []string           `json:"tags"`
+	CursorID       pgtype.UUID        `json:"cursor_id"`
+	CursorName     pgtype.Text        `json:"cursor_name"`
+	RowLimit       int32              `json:"row_limit"`
+}
+
+func (q *Queries) ListMediaByFilenameDesc(ctx context.Context, arg ListMediaByFilenameDescParams) ([]MediaFile, error) {
+	rows, err := q.db.Query(ctx, listMediaByFilenameDesc,
+		arg.UserID,
+		arg.FavouritesOnly,
+		arg.TypePrefix,
+		arg.MinSize,
+		arg.MaxSize,
+		arg.UploadedFrom,
+		arg.UploadedTo,
+		arg.GroupID,
+		arg.GroupPath,
+		arg.MinMatches,
+		arg.Tags,
+		arg.CursorID,
+		arg.CursorName,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []MediaFile{}
+	for rows.Next() {
+		var i MediaFile
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Filename,
+			&i.FileType,
+			&i.Size,
+			&i.UploadedAt,
+			&i.BlobID,
+			&i.ProcessedAt,
+			&i.Metadata,
+			&i.TakenAt,
+			&i.Width,
+			&i.Height,
+			&i.DeclaredType,
+			&i.DetectedType,
+			&i.DeletedAt,
+			&i.Title,
+			&i.Description,
+			&i.SearchVector,
+			&i.OriginalFilename,
+			&i.DisplayName,
+			&i.Favourite,
+			&i.CustomFields,
+			&i.Version,
+			&i.ProcessAttempts,
+			&i.ProcessRetryAt,
+			&i.ProcessError,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMediaByPositionAsc = `-- name: ListMediaByPositionAsc :many
+SELECT m.id, m.user_id, m.filename, m.file_type, m.size, m.uploaded_at, m.blob_id, m.processed_at, m.metadata, m.taken_at, m.width, m.height, m.declared_type, m.detected_type, m.deleted_at, m.title, m.description, m.search_vector, m.original_filename, m.display_name, m.favourite, m.custom_fields, m.version, m.process_attempts, m.process_retry_at, m.process_error, gm.position FROM media_files m
+JOIN group_memberships gm ON gm.media_id = m.id AND gm.group_id = $1::uuid
+WHERE m.user_id = $2
+    AND m.deleted_at IS NULL
+    AND (NOT $3::bool OR m.favourite)
+    AND m.file_type LIKE $4::text || '%'
+    AND m.size BETWEEN $5::bigint AND $6::bigint
+    AND m.uploaded_at >= $7::timestamptz
+    AND m.uploaded_at < $8::timestamptz
+    AND ($9::uuid IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        WHERE gm.media_id = m.id AND gm.group_id = $9::uuid
+    ))
+    AND ($10::text IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        JOIN media_groups g ON g.id = gm.group_id
+        WHERE gm.media_id = m.id
+            AND g.path LIKE $10::text || '%'
+            AND g.deleted_at IS NULL
+    ))
+    AND ($11::int = 0 OR (
+        SELECT COUNT(*) FROM media_tags mt
+        JOIN tags t ON t.id = mt.tag_id
+        WHERE mt.media_id = m.id AND t.name = ANY($12::text[])
+    ) >= $11::int)
+    AND ($13::uuid IS NULL OR (gm.position, m.id) > ($14::int, $13::uuid))
+ORDER BY gm.position ASC, m.id ASC
+LIMIT $15
+`
+
+type ListMediaByPositionAscParams struct {
+	PositionGroupID pgtype.UUID        `json:"position_group_id"`
+	UserID          pgtype.UUID        `json:"user_id"`
+	FavouritesOnly  bool               `json:"favourites_only"`
+	TypePrefix      string             `json:"type_prefix"`
+	MinSize         int64              `json:"min_size"`
+	MaxSize         int64              `json:"max_size"`
+	UploadedFrom    pgtype.Timestamptz `json:"uploaded_from"`
+	UploadedTo      pgtype.Timestamptz `json:"uploaded_to"`
+	GroupID         pgtype.UUID        `json:"group_id"`
+	GroupPath       pgtype.Text        `json:"group_path"`
+	MinMatches      int32              `json:"min_matches"`
+	Tags            []string           `json:"tags"`
+	CursorID        pgtype.UUID        `json:"cursor_id"`
+	CursorPosition  pgtype.Int4        `json:"cursor_position"`
+	RowLimit        int32              `json:"row_limit"`
+}
+
+type ListMediaByPositionAscRow struct {
+	MediaFile MediaFile `json:"media_file"`
+	Position  int32     `json:"position"`
+}
+
+func (q *Queries) ListMediaByPositionAsc(ctx context.Context, arg ListMediaByPositionAscParams) ([]ListMediaByPositionAscRow, error) {
+	rows, err := q.db.Query(ctx, listMediaByPositionAsc,
+		arg.PositionGroupID,
+		arg.UserID,
+		arg.FavouritesOnly,
+		arg.TypePrefix,
+		arg.MinSize,
+		arg.MaxSize,
+		arg.UploadedFrom,
+		arg.UploadedTo,
+		arg.GroupID,
+		arg.GroupPath,
+		arg.MinMatches,
+		arg.Tags,
+		arg.CursorID,
+		arg.CursorPosition,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListMediaByPositionAscRow{}
+	for rows.Next() {
+		var i ListMediaByPositionAscRow
+		if err := rows.Scan(
+			&i.MediaFile.ID,
+			&i.MediaFile.UserID,
+			&i.MediaFile.Filename,
+			&i.MediaFile.FileType,
+			&i.MediaFile.Size,
+			&i.MediaFile.UploadedAt,
+			&i.MediaFile.BlobID,
+			&i.MediaFile.ProcessedAt,
+			&i.MediaFile.Metadata,
+			&i.MediaFile.TakenAt,
+			&i.MediaFile.Width,
+			&i.MediaFile.Height,
+			&i.MediaFile.DeclaredType,
+			&i.MediaFile.DetectedType,
+			&i.MediaFile.DeletedAt,
+			&i.MediaFile.Title,
+			&i.MediaFile.Description,
+			&i.MediaFile.SearchVector,
+			&i.MediaFile.OriginalFilename,
+			&i.MediaFile.DisplayName,
+			&i.MediaFile.Favourite,
+			&i.MediaFile.CustomFields,
+			&i.MediaFile.Version,
+			&i.MediaFile.ProcessAttempts,
+			&i.MediaFile.ProcessRetryAt,
+			&i.MediaFile.ProcessError,
+			&i.Position,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMediaByPositionDesc = `-- name: ListMediaByPositionDesc :many
+SELECT m.id, m.user_id, m.filename, m.file_type, m.size, m.uploaded_at, m.blob_id, m.processed_at, m.metadata, m.taken_at, m.width, m.height, m.declared_type, m.detected_type, m.deleted_at, m.title, m.description, m.search_vector, m.original_filename, m.display_name, m.favourite, m.custom_fields, m.version, m.process_attempts, m.process_retry_at, m.process_error, gm.position FROM media_files m
+JOIN group_memberships gm ON gm.media_id = m.id AND gm.group_id = $1::uuid
+WHERE m.user_id = $2
+    AND m.deleted_at IS NULL
+    AND (NOT $3::bool OR m.favourite)
+    AND m.file_type LIKE $4::text || '%'
+    AND m.size BETWEEN $5::bigint AND $6::bigint
+    AND m.uploaded_at >= $7::timestamptz
+    AND m.uploaded_at < $8::timestamptz
+    AND ($9::uuid IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        WHERE gm.media_id = m.id AND gm.group_id = $9::uuid
+    ))
+    AND ($10::text IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        JOIN media_groups g ON g.id = gm.group_id
+        WHERE gm.media_id = m.id
+            AND g.path LIKE $10::text || '%'
+            AND g.deleted_at IS NULL
+    ))
+    AND ($11::int = 0 OR (
+        SELECT COUNT(*) FROM media_tags mt
+        JOIN tags t ON t.id = mt.tag_id
+        WHERE mt.media_id = m.id AND t.name = ANY($12::text[])
+    ) >= $11::int)
+    AND ($13::uuid IS NULL OR (gm.position, m.id) < ($14::int, $13::uuid))
+ORDER BY gm.position DESC, m.id DESC
+LIMIT $15
+`
+
+type ListMediaByPositionDescParams struct {
+	PositionGroupID pgtype.UUID        `json:"position_group_id"`
+	UserID          pgtype.UUID        `json:"user_id"`
+	FavouritesOnly  bool               `json:"favourites_only"`
+	TypePrefix      string             `json:"type_prefix"`
+	MinSize         int64              `json:"min_size"`
+	MaxSize         int64              `json:"max_size"`
+	UploadedFrom    pgtype.Timestamptz `json:"uploaded_from"`
+	UploadedTo      pgtype.Timestamptz `json:"uploaded_to"`
+	GroupID         pgtype.UUID        `json:"group_id"`
+	GroupPath       pgtype.Text        `json:"group_path"`
+	MinMatches      int32              `json:"min_matches"`
+	Tags            []string           `json:"tags"`
+	CursorID        pgtype.UUID        `json:"cursor_id"`
+	CursorPosition  pgtype.Int4        `json:"cursor_position"`
+	RowLimit        int32              `json:"row_limit"`
+}
+
+type ListMediaByPositionDescRow struct {
+	MediaFile MediaFile `json:"media_file"`
+	Position  int32     `json:"position"`
+}
+
+func (q *Queries) ListMediaByPositionDesc(ctx context.Context, arg ListMediaByPositionDescParams) ([]ListMediaByPositionDescRow, error) {
+	rows, err := q.db.Query(ctx, listMediaByPositionDesc,
+		arg.PositionGroupID,
+		arg.UserID,
+		arg.FavouritesOnly,
+		arg.TypePrefix,
+		arg.MinSize,
+		arg.MaxSize,
+		arg.UploadedFrom,
+		arg.UploadedTo,
+		arg.GroupID,
+		arg.GroupPath,
+		arg.MinMatches,
+		arg.Tags,
+		arg.CursorID,
+		arg.CursorPosition,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListMediaByPositionDescRow{}
+	for rows.Next() {
+		var i ListMediaByPositionDescRow
+		if err := rows.Scan(
+			&i.MediaFile.ID,
+			&i.MediaFile.UserID,
+			&i.MediaFile.Filename,
+			&i.MediaFile.FileType,
+			&i.MediaFile.Size,
+			&i.MediaFile.UploadedAt,
+			&i.MediaFile.BlobID,
+			&i.MediaFile.ProcessedAt,
+			&i.MediaFile.Metadata,
+			&i.MediaFile.TakenAt,
+			&i.MediaFile.Width,
+			&i.MediaFile.Height,
+			&i.MediaFile.DeclaredType,
+			&i.MediaFile.DetectedType,
+			&i.MediaFile.DeletedAt,
+			&i.MediaFile.Title,
+			&i.MediaFile.Description,
+			&i.MediaFile.SearchVector,
+			&i.MediaFile.OriginalFilename,
+			&i.MediaFile.DisplayName,
+			&i.MediaFile.Favourite,
+			&i.MediaFile.CustomFields,
+			&i.MediaFile.Version,
+			&i.MediaFile.ProcessAttempts,
+			&i.MediaFile.ProcessRetryAt,
+			&i.MediaFile.ProcessError,
+			&i.Position,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMediaBySizeAsc = `-- name: ListMediaBySizeAsc :many
+SELECT m.id, m.user_id, m.filename, m.file_type, m.size, m.uploaded_at, m.blob_id, m.processed_at, m.metadata, m.taken_at, m.width, m.height, m.declared_type, m.detected_type, m.deleted_at, m.title, m.description, m.search_vector, m.original_filename, m.display_name, m.favourite, m.custom_fields, m.version, m.process_attempts, m.process_retry_at, m.process_error FROM media_files m
+WHERE m.user_id = $1
+    AND m.deleted_at IS NULL
+    AND (NOT $2::bool OR m.favourite)
+    AND m.file_type LIKE $3::text || '%'
+    AND m.size BETWEEN $4::bigint AND $5::bigint
+    AND m.uploaded_at >= $6::timestamptz
+    AND m.uploaded_at < $7::timestamptz
+    AND ($8::uuid IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        WHERE gm.media_id = m.id AND gm.group_id = $8::uuid
+    ))
+    AND ($9::text IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        JOIN media_groups g ON g.id = gm.group_id
+        WHERE gm.media_id = m.id
+            AND g.path LIKE $9::text || '%'
+            AND g.deleted_at IS NULL
+    ))
+    AND ($10::int = 0 OR (
+        SELECT COUNT(*) FROM media_tags mt
+        JOIN tags t ON t.id = mt.tag_id
+        WHERE mt.media_id = m.id AND t.name = ANY($11::text[])
+    ) >= $10::int)
+    AND ($12::uuid IS NULL OR (m.size, m.id) > ($13::bigint, $12::uuid))
+ORDER BY m.size ASC, m.id ASC
+LIMIT $14
+`
+
+type ListMediaBySizeAscParams struct {
+	UserID         pgtype.UUID        `json:"user_id"`
+	FavouritesOnly bool               `json:"favourites_only"`
+	TypePrefix     string             `json:"type_prefix"`
+	MinSize        int64              `json:"min_size"`
+	MaxSize        int64              `json:"max_size"`
+	UploadedFrom   pgtype.Timestamptz `json:"uploaded_from"`
+	UploadedTo     pgtype.Timestamptz `json:"uploaded_to"`
+	GroupID        pgtype.UUID        `json:"group_id"`
+	GroupPath      pgtype.Text        `json:"group_path"`
+	MinMatches     int32              `json:"min_matches"`
+	Tags           []string           `json:"tags"`
+	CursorID       pgtype.UUID        `json:"cursor_id"`
+	CursorSize     pgtype.Int8        `json:"cursor_size"`
+	RowLimit       int32              `json:"row_limit"`
+}
+
+func (q *Queries) ListMediaBySizeAsc(ctx context.Context, arg ListMediaBySizeAscParams) ([]MediaFile, error) {
+	rows, err := q.db.Query(ctx, listMediaBySizeAsc,
+		arg.UserID,
+		arg.FavouritesOnly,
+		arg.TypePrefix,
+		arg.MinSize,
+		arg.MaxSize,
+		arg.UploadedFrom,
+		arg.UploadedTo,
+		arg.GroupID,
+		arg.GroupPath,
+		arg.MinMatches,
+		arg.Tags,
+		arg.CursorID,
+		arg.CursorSize,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []MediaFile{}
+	for rows.Next() {
+		var i MediaFile
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Filename,
+			&i.FileType,
+			&i.Size,
+			&i.UploadedAt,
+			&i.BlobID,
+			&i.ProcessedAt,
+			&i.Metadata,
+			&i.TakenAt,
+			&i.Width,
+			&i.Height,
+			&i.DeclaredType,
+			&i.DetectedType,
+			&i.DeletedAt,
+			&i.Title,
+			&i.Description,
+			&i.SearchVector,
+			&i.OriginalFilename,
+			&i.DisplayName,
+			&i.Favourite,
+			&i.CustomFields,
+			&i.Version,
+			&i.ProcessAttempts,
+			&i.ProcessRetryAt,
+			&i.ProcessError,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMediaBySizeDesc = `-- name: ListMediaBySizeDesc :many
+SELECT m.id, m.user_id, m.filename, m.file_type, m.size, m.uploaded_at, m.blob_id, m.processed_at, m.metadata, m.taken_at, m.width, m.height, m.declared_type, m.detected_type, m.deleted_at, m.title, m.description, m.search_vector, m.original_filename, m.display_name, m.favourite, m.custom_fields, m.version, m.process_attempts, m.process_retry_at, m.process_error FROM media_files m
+WHERE m.user_id = $1
+    AND m.deleted_at IS NULL
+    AND (NOT $2::bool OR m.favourite)
+    AND m.file_type LIKE $3::text || '%'
+    AND m.size BETWEEN $4::bigint AND $5::bigint
+    AND m.uploaded_at >= $6::timestamptz
+    AND m.uploaded_at < $7::timestamptz
+    AND ($8::uuid IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        WHERE gm.media_id = m.id AND gm.group_id = $8::uuid
+    ))
+    AND ($9::text IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        JOIN media_groups g ON g.id = gm.group_id
+        WHERE gm.media_id = m.id
+            AND g.path LIKE $9::text || '%'
+            AND g.deleted_at IS NULL
+    ))
+    AND ($10::int = 0 OR (
+        SELECT COUNT(*) FROM media_tags mt
+        JOIN tags t ON t.id = mt.tag_id
+        WHERE mt.media_id = m.id AND t.name = ANY($11::text[])
+    ) >= $10::int)
+    AND ($12::uuid IS NULL OR (m.size, m.id) < ($13::bigint, $12::uuid))
+ORDER BY m.size DESC, m.id DESC
+LIMIT $14
+`
+
+type ListMediaBySizeDescParams struct {
+	UserID         pgtype.UUID        `json:"user_id"`
+	FavouritesOnly bool               `json:"favourites_only"`
+	TypePrefix     string             `json:"type_prefix"`
+	MinSize        int64              `json:"min_size"`
+	MaxSize        int64              `json:"max_size"`
+	UploadedFrom   pgtype.Timestamptz `json:"uploaded_from"`
+	UploadedTo     pgtype.Timestamptz `json:"uploaded_to"`
+	GroupID        pgtype.UUID        `json:"group_id"`
+	GroupPath      pgtype.Text        `json:"group_path"`
+	MinMatches     int32              `json:"min_matches"`
+	Tags           []string           `json:"tags"`
+	CursorID       pgtype.UUID        `json:"cursor_id"`
+	CursorSize     pgtype.Int8        `json:"cursor_size"`
+	RowLimit       int32              `json:"row_limit"`
+}
+
+func (q *Queries) ListMediaBySizeDesc(ctx context.Context, arg ListMediaBySizeDescParams) ([]MediaFile, error) {
+	rows, err := q.db.Query(ctx, listMediaBySizeDesc,
+		arg.UserID,
+		arg.FavouritesOnly,
+		arg.TypePrefix,
+		arg.MinSize,
+		arg.MaxSize,
+		arg.UploadedFrom,
+		arg.UploadedTo,
+		arg.GroupID,
+		arg.GroupPath,
+		arg.MinMatches,
+		arg.Tags,
+		arg.CursorID,
+		arg.CursorSize,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []MediaFile{}
+	for rows.Next() {
+		var i MediaFile
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Filename,
+			&i.FileType,
+			&i.Size,
+			&i.UploadedAt,
+			&i.BlobID,
+			&i.ProcessedAt,
+			&i.Metadata,
+			&i.TakenAt,
+			&i.Width,
+			&i.Height,
+			&i.DeclaredType,
+			&i.DetectedType,
+			&i.DeletedAt,
+			&i.Title,
+			&i.Description,
+			&i.SearchVector,
+			&i.OriginalFilename,
+			&i.DisplayName,
+			&i.Favourite,
+			&i.CustomFields,
+			&i.Version,
+			&i.ProcessAttempts,
+			&i.ProcessRetryAt,
+			&i.ProcessError,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMediaByTakenAtAsc = `-- name: ListMediaByTakenAtAsc :many
+SELECT m.id, m.user_id, m.filename, m.file_type, m.size, m.uploaded_at, m.blob_id, m.processed_at, m.metadata, m.taken_at, m.width, m.height, m.declared_type, m.detected_type, m.deleted_at, m.title, m.description, m.search_vector, m.original_filename, m.display_name, m.favourite, m.custom_fields, m.version, m.process_attempts, m.process_retry_at, m.process_error FROM media_files m
+WHERE m.user_id = $1
+    AND m.deleted_at IS NULL
+    AND (NOT $2::bool OR m.favourite)
+    AND m.file_type LIKE $3::text || '%'
+    AND m.size BETWEEN $4::bigint AND $5::bigint
+    AND m.uploaded_at >= $6::timestamptz
+    AND m.uploaded_at < $7::timestamptz
+    AND ($8::uuid IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        WHERE gm.media_id = m.id AND gm.group_id = $8::uuid
+    ))
+    AND ($9::text IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        JOIN media_groups g ON g.id = gm.group_id
+        WHERE gm.media_id = m.id
+            AND g.path LIKE $9::text || '%'
+            AND g.deleted_at IS NULL
+    ))
+    AND ($10::int = 0 OR (
+        SELECT COUNT(*) FROM media_tags mt
+        JOIN tags t ON t.id = mt.tag_id
+        WHERE mt.media_id = m.id AND t.name = ANY($11::text[])
+    ) >= $10::int)
+    AND ($12::uuid IS NULL OR (COALESCE(m.taken_at, m.uploaded_at), m.id) > ($13::timestamptz, $12::uuid))
+ORDER BY COALESCE(m.taken_at, m.uploaded_at) ASC, m.id ASC
+LIMIT $14
+`
+
+type ListMediaByTakenAtAscParams struct {
+	UserID         pgtype.UUID        `json:"user_id"`
+	FavouritesOnly bool               `json:"favourites_only"`
+	TypePrefix     string             `json:"type_prefix"`
+	MinSize        int64              `json:"min_size"`
+	MaxSize        int64              `json:"max_size"`
+	UploadedFrom   pgtype.Timestamptz `json:"uploaded_from"`
+	UploadedTo     pgtype.Timestamptz `json:"uploaded_to"`
+	GroupID        pgtype.UUID        `json:"group_id"`
+	GroupPath      pgtype.Text        `json:"group_path"`
+	MinMatches     int32              `json:"min_matches"`
+	Tags           []string           `json:"tags"`
+	CursorID       pgtype.UUID        `json:"cursor_id"`
+	CursorTime     pgtype.Timestamptz `json:"cursor_time"`
+	RowLimit       int32              `json:"row_limit"`
+}
+
+func (q *Queries) ListMediaByTakenAtAsc(ctx context.Context, arg ListMediaByTakenAtAscParams) ([]MediaFile, error) {
+	rows, err := q.db.Query(ctx, listMediaByTakenAtAsc,
+		arg.UserID,
+		arg.FavouritesOnly,
+		arg.TypePrefix,
+		arg.MinSize,
+		arg.MaxSize,
+		arg.UploadedFrom,
+		arg.UploadedTo,
+		arg.GroupID,
+		arg.GroupPath,
+		arg.MinMatches,
+		arg.Tags,
+		arg.CursorID,
+		arg.CursorTime,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []MediaFile{}
+	for rows.Next() {
+		var i MediaFile
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Filename,
+			&i.FileType,
+			&i.Size,
+			&i.UploadedAt,
+			&i.BlobID,
+			&i.ProcessedAt,
+			&i.Metadata,
+			&i.TakenAt,
+			&i.Width,
+			&i.Height,
+			&i.DeclaredType,
+			&i.DetectedType,
+			&i.DeletedAt,
+			&i.Title,
+			&i.Description,
+			&i.SearchVector,
+			&i.OriginalFilename,
+			&i.DisplayName,
+			&i.Favourite,
+			&i.CustomFields,
+			&i.Version,
+			&i.ProcessAttempts,
+			&i.ProcessRetryAt,
+			&i.ProcessError,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMediaByTakenAtDesc = `-- name: ListMediaByTakenAtDesc :many
+SELECT m.id, m.user_id, m.filename, m.file_type, m.size, m.uploaded_at, m.blob_id, m.processed_at, m.metadata, m.taken_at, m.width, m.height, m.declared_type, m.detected_type, m.deleted_at, m.title, m.description, m.search_vector, m.original_filename, m.display_name, m.favourite, m.custom_fields, m.version, m.process_attempts, m.process_retry_at, m.process_error FROM media_files m
+WHERE m.user_id = $1
+    AND m.deleted_at IS NULL
+    AND (NOT $2::bool OR m.favourite)
+    AND m.file_type LIKE $3::text || '%'
+    AND m.size BETWEEN $4::bigint AND $5::bigint
+    AND m.uploaded_at >= $6::timestamptz
+    AND m.uploaded_at < $7::timestamptz
+    AND ($8::uuid IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        WHERE gm.media_id = m.id AND gm.group_id = $8::uuid
+    ))
+    AND ($9::text IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        JOIN media_groups g ON g.id = gm.group_id
+        WHERE gm.media_id = m.id
+            AND g.path LIKE $9::text || '%'
+            AND g.deleted_at IS NULL
+    ))
+    AND ($10::int = 0 OR (
+        SELECT COUNT(*) FROM media_tags mt
+        JOIN tags t ON t.id = mt.tag_id
+        WHERE mt.media_id = m.id AND t.name = ANY($11::text[])
+    ) >= $10::int)
+    AND ($12::uuid IS NULL OR (COALESCE(m.taken_at, m.uploaded_at), m.id) < ($13::timestamptz, $12::uuid))
+ORDER BY COALESCE(m.taken_at, m.uploaded_at) DESC, m.id DESC
+LIMIT $14
+`
+
+type ListMediaByTakenAtDescParams struct {
+	UserID         pgtype.UUID        `json:"user_id"`
+	FavouritesOnly bool               `json:"favourites_only"`
+	TypePrefix     string             `json:"type_prefix"`
+	MinSize        int64              `json:"min_size"`
+	MaxSize        int64              `json:"max_size"`
+	UploadedFrom   pgtype.Timestamptz `json:"uploaded_from"`
+	UploadedTo     pgtype.Timestamptz `json:"uploaded_to"`
+	GroupID        pgtype.UUID        `json:"group_id"`
+	GroupPath      pgtype.Text        `json:"group_path"`
+	MinMatches     int32              `json:"min_matches"`
+	Tags           []string           `json:"tags"`
+	CursorID       pgtype.UUID        `json:"cursor_id"`
+	CursorTime     pgtype.Timestamptz `json:"cursor_time"`
+	RowLimit       int32              `json:"row_limit"`
+}
+
+func (q *Queries) ListMediaByTakenAtDesc(ctx context.Context, arg ListMediaByTakenAtDescParams) ([]MediaFile, error) {
+	rows, err := q.db.Query(ctx, listMediaByTakenAtDesc,
+		arg.UserID,
+		arg.FavouritesOnly,
+		arg.TypePrefix,
+		arg.MinSize,
+		arg.MaxSize,
+		arg.UploadedFrom,
+		arg.UploadedTo,
+		arg.GroupID,
+		arg.GroupPath,
+		arg.MinMatches,
+		arg.Tags,
+		arg.CursorID,
+		arg.CursorTime,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []MediaFile{}
+	for rows.Next() {
+		var i MediaFile
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Filename,
+			&i.FileType,
+			&i.Size,
+			&i.UploadedAt,
+			&i.BlobID,
+			&i.ProcessedAt,
+			&i.Metadata,
+			&i.TakenAt,
+			&i.Width,
+			&i.Height,
+			&i.DeclaredType,
+			&i.DetectedType,
+			&i.DeletedAt,
+			&i.Title,
+			&i.Description,
+			&i.SearchVector,
+			&i.OriginalFilename,
+			&i.DisplayName,
+			&i.Favourite,
+			&i.CustomFields,
+			&i.Version,
+			&i.ProcessAttempts,
+			&i.ProcessRetryAt,
+			&i.ProcessError,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMediaByUploadedAtAsc = `-- name: ListMediaByUploadedAtAsc :many
+SELECT m.id, m.user_id, m.filename, m.file_type, m.size, m.uploaded_at, m.blob_id, m.processed_at, m.metadata, m.taken_at, m.width, m.height, m.declared_type, m.detected_type, m.deleted_at, m.title, m.description, m.search_vector, m.original_filename, m.display_name, m.favourite, m.custom_fields, m.version, m.process_attempts, m.process_retry_at, m.process_error FROM media_files m
+WHERE m.user_id = $1
+    AND m.deleted_at IS NULL
+    AND (NOT $2::bool OR m.favourite)
+    AND m.file_type LIKE $3::text || '%'
+    AND m.size BETWEEN $4::bigint AND $5::bigint
+    AND m.uploaded_at >= $6::timestamptz
+    AND m.uploaded_at < $7::timestamptz
+    AND ($8::uuid IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        WHERE gm.media_id = m.id AND gm.group_id = $8::uuid
+    ))
+    AND ($9::text IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        JOIN media_groups g ON g.id = gm.group_id
+        WHERE gm.media_id = m.id
+            AND g.path LIKE $9::text || '%'
+            AND g.deleted_at IS NULL
+    ))
+    AND ($10::int = 0 OR (
+        SELECT COUNT(*) FROM media_tags mt
+        JOIN tags t ON t.id = mt.tag_id
+        WHERE mt.media_id = m.id AND t.name = ANY($11::text[])
+    ) >= $10::int)
+    AND ($12::uuid IS NULL OR (m.uploaded_at, m.id) > ($13::timestamptz, $12::uuid))
+ORDER BY m.uploaded_at ASC, m.id ASC
+LIMIT $14
+`
+
+type ListMediaByUploadedAtAscParams struct {
+	UserID         pgtype.UUID        `json:"user_id"`
+	FavouritesOnly bool               `json:"favourites_only"`
+	TypePrefix     string             `json:"type_prefix"`
+	MinSize        int64              `json:"min_size"`
+	MaxSize        int64              `json:"max_size"`
+	UploadedFrom   pgtype.Timestamptz `json:"uploaded_from"`
+	UploadedTo     pgtype.Timestamptz `json:"uploaded_to"`
+	GroupID        pgtype.UUID        `json:"group_id"`
+	GroupPath      pgtype.Text        `json:"group_path"`
+	MinMatches     int32              `json:"min_matches"`
+	Tags           []string           `json:"tags"`
+	CursorID       pgtype.UUID        `json:"cursor_id"`
+	CursorTime     pgtype.Timestamptz `json:"cursor_time"`
+	RowLimit       int32              `json:"row_limit"`
+}
+
+func (q *Queries) ListMediaByUploadedAtAsc(ctx context.Context, arg ListMediaByUploadedAtAscParams) ([]MediaFile, error) {
+	rows, err := q.db.Query(ctx, listMediaByUploadedAtAsc,
+		arg.UserID,
+		arg.FavouritesOnly,
+		arg.TypePrefix,
+		arg.MinSize,
+		arg.MaxSize,
+		arg.UploadedFrom,
+		arg.UploadedTo,
+		arg.GroupID,
+		arg.GroupPath,
+		arg.MinMatches,
+		arg.Tags,
+		arg.CursorID,
+		arg.CursorTime,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []MediaFile{}
+	for rows.Next() {
+		var i MediaFile
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Filename,
+			&i.FileType,
+			&i.Size,
+			&i.UploadedAt,
+			&i.BlobID,
+			&i.ProcessedAt,
+			&i.Metadata,
+			&i.TakenAt,
+			&i.Width,
+			&i.Height,
+			&i.DeclaredType,
+			&i.DetectedType,
+			&i.DeletedAt,
+			&i.Title,
+			&i.Description,
+			&i.SearchVector,
+			&i.OriginalFilename,
+			&i.DisplayName,
+			&i.Favourite,
+			&i.CustomFields,
+			&i.Version,
+			&i.ProcessAttempts,
+			&i.ProcessRetryAt,
+			&i.ProcessError,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMediaByUploadedAtDesc = `-- name: ListMediaByUploadedAtDesc :many
+SELECT m.id, m.user_id, m.filename, m.file_type, m.size, m.uploaded_at, m.blob_id, m.processed_at, m.metadata, m.taken_at, m.width, m.height, m.declared_type, m.detected_type, m.deleted_at, m.title, m.description, m.search_vector, m.original_filename, m.display_name, m.favourite, m.custom_fields, m.version, m.process_attempts, m.process_retry_at, m.process_error FROM media_files m
+WHERE m.user_id = $1
+    AND m.deleted_at IS NULL
+    AND (NOT $2::bool OR m.favourite)
+    AND m.file_type LIKE $3::text || '%'
+    AND m.size BETWEEN $4::bigint AND $5::bigint
+    AND m.uploaded_at >= $6::timestamptz
+    AND m.uploaded_at < $7::timestamptz
+    AND ($8::uuid IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        WHERE gm.media_id = m.id AND gm.group_id = $8::uuid
+    ))
+    AND ($9::text IS NULL OR EXISTS (
+        SELECT 1 FROM group_memberships gm
+        JOIN media_groups g ON g.id = gm.group_id
+        WHERE gm.media_id = m.id
+            AND g.path LIKE $9::text || '%'
+            AND g.deleted_at IS NULL
+    ))
+    AND ($10::int = 0 OR (
+        SELECT COUNT(*) FROM media_tags mt
+        JOIN tags t ON t.id = mt.tag_id
+        WHERE mt.media_id = m.id AND t.name = ANY($11::text[])
+    ) >= $10::int)
+    AND ($12::uuid IS NULL OR (m.uploaded_at, m.id) < ($13::timestamptz, $12::uuid))
+ORDER BY m.uploaded_at DESC, m.id DESC
+LIMIT $14
+`
+
+type ListMediaByUploadedAtDescParams struct {
+	UserID         pgtype.UUID        `json:"user_id"`
+	FavouritesOnly bool               `json:"favourites_only"`
+	TypePrefix     string             `json:"type_prefix"`
+	MinSize        int64              `json:"min_size"`
+	MaxSize        int64              `json:"max_size"`
+	UploadedFrom   pgtype.Timestamptz `json:"uploaded_from"`
+	UploadedTo     pgtype.Timestamptz `json:"uploaded_to"`
+	GroupID        pgtype.UUID        `json:"group_id"`
+	GroupPath      pgtype.Text        `json:"group_path"`
+	MinMatches     int32              `json:"min_matches"`
+	Tags           []string           `json:"tags"`
+	CursorID       pgtype.UUID        `json:"cursor_id"`
+	CursorTime     pgtype.Timestamptz `json:"cursor_time"`
+	RowLimit       int32              `json:"row_limit"`
+}
+
+func (q *Queries) ListMediaByUploadedAtDesc(ctx context.Context, arg ListMediaByUploadedAtDescParams) ([]MediaFile, error) {
+	rows, err := q.db.Query(ctx, listMediaByUploadedAtDesc,
+		arg.UserID,
+		arg.FavouritesOnly,
+		arg.TypePrefix,
+		arg.MinSize,
+		arg.MaxSize,
+		arg.UploadedFrom,
+		arg.UploadedTo,
+		arg.GroupID,
+		arg.GroupPath,
+		arg.MinMatches,
+		arg.Tags,
+		arg.CursorID,
+		arg.CursorTime,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []MediaFile{}
+	for rows.Next() {
+		var i MediaFile
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Filename,
+			&i.FileType,
+			&i.Size,
+			&i.UploadedAt,
+			&i.BlobID,
+			&i.ProcessedAt,
+			&i.Metadata,
+			&i.TakenAt,
+			&i.Width,
+			&i.Height,
+			&i.DeclaredType,
+			&i.DetectedType,
+			&i.DeletedAt,
+			&i.Title,
+			&i.Description,
+			&i.SearchVector,
+			&i.OriginalFilename,
+			&i.DisplayName,
+			&i.Favourite,
+			&i.CustomFields,
+			&i.Version,
+			&i.ProcessAttempts,
+			&i.ProcessRetryAt,
+			&i.ProcessError,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,187 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: media_search.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const searchMedia = `-- name: SearchMedia :many
+SELECT m.id, m.user_id, m.filename, m.file_type, m.size, m.uploaded_at, m.blob_id, m.processed_at, m.metadata, m.taken_at, m.width, m.height, m.declared_type, m.detected_type, m.deleted_at, m.title, m.description, m.search_vector, m.original_filename, m.display_name, m.favourite, m.custom_fields, m.version, m.process_attempts, m.process_retry_at, m.process_error, r.rank, r.total,
+    ts_headline('simple', concat_ws(' ', NULLIF(m.title, ''), m.display_name, NULLIF(m.description, '')),
+        websearch_to_tsquery('simple', $1::text),
+        $2::text)::text AS highlight
+FROM (
+    SELECT s.id,
+        ts_rank_cd(s.search_vector, websearch_to_tsquery('simple', $1::text)) AS rank,
+        COUNT(*) OVER () AS total
+    FROM media_files s
+    WHERE s.user_id = $3
+        AND s.deleted_at IS NULL
+        AND s.search_vector @@ websearch_to_tsquery('simple', $1::text)
+    ORDER BY rank DESC, s.id
+    LIMIT $5 OFFSET $4
+) r
+JOIN media_files m ON m.id = r.id
+ORDER BY r.rank DESC, m.id
+`
+
+type SearchMediaParams struct {
+	Query           string      `json:"query"`
+	HeadlineOptions string      `json:"headline_options"`
+	UserID          pgtype.UUID `json:"user_id"`
+	RowOffset       int32       `json:"row_offset"`
+	RowLimit        int32       `json:"row_limit"`
+}
+
+type SearchMediaRow struct {
+	MediaFile MediaFile `json:"media_file"`
+	Rank      float32   `json:"rank"`
+	Total     int64     `json:"total"`
+	Highlight string    `json:"highlight"`
+}
+
+// Ranks the media of a user matching a web search style query. Highlights are
+// only built for the rows of the page.
+func (q *Queries) SearchMedia(ctx context.Context, arg SearchMediaParams) ([]SearchMediaRow, error) {
+	rows, err := q.db.Query(ctx, searchMedia,
+		arg.Query,
+		arg.HeadlineOptions,
+		arg.UserID,
+		arg.RowOffset,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SearchMediaRow{}
+	for rows.Next() {
+		var i SearchMediaRow
+		if err := rows.Scan(
+			&i.MediaFile.ID,
+			&i.MediaFile.UserID,
+			&i.MediaFile.Filename,
+			&i.MediaFile.FileType,
+			&i.MediaFile.Size,
+			&i.MediaFile.UploadedAt,
+			&i.MediaFile.BlobID,
+			&i.MediaFile.ProcessedAt,
+			&i.MediaFile.Metadata,
+			&i.MediaFile.TakenAt,
+			&i.MediaFile.Width,
+			&i.MediaFile.Height,
+			&i.MediaFile.DeclaredType,
+			&i.MediaFile.DetectedType,
+			&i.MediaFile.DeletedAt,
+			&i.MediaFile.Title,
+			&i.MediaFile.Description,
+			&i.MediaFile.SearchVector,
+			&i.MediaFile.OriginalFilename,
+			&i.MediaFile.DisplayName,
+			&i.MediaFile.Favourite,
+			&i.MediaFile.CustomFields,
+			&i.MediaFile.Version,
+			&i.MediaFile.ProcessAttempts,
+			&i.MediaFile.ProcessRetryAt,
+			&i.MediaFile.ProcessError,
+			&i.Rank,
+			&i.Total,
+			&i.Highlight,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchMediaByFilename = `-- name: SearchMediaByFilename :many
+SELECT m.id, m.user_id, m.filename, m.file_type, m.size, m.uploaded_at, m.blob_id, m.processed_at, m.metadata, m.taken_at, m.width, m.height, m.declared_type, m.detected_type, m.deleted_at, m.title, m.description, m.search_vector, m.original_filename, m.display_name, m.favourite, m.custom_fields, m.version, m.process_attempts, m.process_retry_at, m.process_error,
+    GREATEST(similarity(m.display_name, $1::text), similarity(m.title, $1::text))::real AS rank,
+    COUNT(*) OVER () AS total
+FROM media_files m
+WHERE m.user_id = $2
+    AND m.deleted_at IS NULL
+    AND (m.display_name ILIKE '%' || $3::text || '%' OR m.title ILIKE '%' || $3::text || '%')
+ORDER BY rank DESC, m.id
+LIMIT $5 OFFSET $4
+`
+
+type SearchMediaByFilenameParams struct {
+	Query     string      `json:"query"`
+	UserID    pgtype.UUID `json:"user_id"`
+	Pattern   string      `json:"pattern"`
+	RowOffset int32       `json:"row_offset"`
+	RowLimit  int32       `json:"row_limit"`
+}
+
+type SearchMediaByFilenameRow struct {
+	MediaFile MediaFile `json:"media_file"`
+	Rank      float32   `json:"rank"`
+	Total     int64     `json:"total"`
+}
+
+// Matches part of a display name or title, for queries that are not whole words.
+func (q *Queries) SearchMediaByFilename(ctx context.Context, arg SearchMediaByFilenameParams) ([]SearchMediaByFilenameRow, error) {
+	rows, err := q.db.Query(ctx, searchMediaByFilename,
+		arg.Query,
+		arg.UserID,
+		arg.Pattern,
+		arg.RowOffset,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SearchMediaByFilenameRow{}
+	for rows.Next() {
+		var i SearchMediaByFilenameRow
+		if err := rows.Scan(
+			&i.MediaFile.ID,
+			&i.MediaFile.UserID,
+			&i.MediaFile.Filename,
+			&i.MediaFile.FileType,
+			&i.MediaFile.Size,
+			&i.MediaFile.UploadedAt,
+			&i.MediaFile.BlobID,
+			&i.MediaFile.ProcessedAt,
+			&i.MediaFile.Metadata,
+			&i.MediaFile.TakenAt,
+			&i.MediaFile.Width,
+			&i.MediaFile.Height,
+			&i.MediaFile.DeclaredType,
+			&i.MediaFile.DetectedType,
+			&i.MediaFile.DeletedAt,
+			&i.MediaFile.Title,
+			&i.MediaFile.Description,
+			&i.MediaFile.SearchVector,
+			&i.MediaFile.OriginalFilename,
+			&i.MediaFile.DisplayName,
+			&i.MediaFile.Favourite,
+			&i.MediaFile.CustomFields,
+			&i.MediaFile.Version,
+			&i.MediaFile.ProcessAttempts,
+			&i.MediaFile.ProcessRetryAt,
+			&i.MediaFile.ProcessError,
+			&i.Rank,
+			&i.Total,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
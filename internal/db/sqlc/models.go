@@ -5,17 +5,100 @@
 package db
 
 import (
+	"encoding/json"
+
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-type MediaFile struct {
+type AuditEvent struct {
+	ID        pgtype.UUID        `json:"id"`
+	UserID    pgtype.UUID        `json:"user_id"`
+	SessionID pgtype.UUID        `json:"session_id"`
+	Event     string             `json:"event"`
+	ClientIp  pgtype.Text        `json:"client_ip"`
+	UserAgent pgtype.Text        `json:"user_agent"`
+	Details   json.RawMessage    `json:"details"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type Blob struct {
 	ID         pgtype.UUID        `json:"id"`
 	UserID     pgtype.UUID        `json:"user_id"`
-	GroupID    pgtype.UUID        `json:"group_id"`
-	Filename   string             `json:"filename"`
-	FileType   string             `json:"file_type"`
+	Digest     string             `json:"digest"`
 	Size       int64              `json:"size"`
-	UploadedAt pgtype.Timestamptz `json:"uploaded_at"`
+	StorageKey string             `json:"storage_key"`
+	RefCount   int32              `json:"ref_count"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	Crc32      pgtype.Int8        `json:"crc32"`
+}
+
+type EmailVerification struct {
+	ID        pgtype.UUID        `json:"id"`
+	UserID    pgtype.UUID        `json:"user_id"`
+	Email     string             `json:"email"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	UsedAt    pgtype.Timestamptz `json:"used_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type GroupMembership struct {
+	GroupID  pgtype.UUID        `json:"group_id"`
+	MediaID  pgtype.UUID        `json:"media_id"`
+	Position int32              `json:"position"`
+	AddedAt  pgtype.Timestamptz `json:"added_at"`
+}
+
+type GroupShare struct {
+	ID         pgtype.UUID        `json:"id"`
+	GroupID    pgtype.UUID        `json:"group_id"`
+	OwnerID    pgtype.UUID        `json:"owner_id"`
+	UserID     pgtype.UUID        `json:"user_id"`
+	Role       string             `json:"role"`
+	AcceptedAt pgtype.Timestamptz `json:"accepted_at"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	Email      string             `json:"email"`
+}
+
+type MediaDerivative struct {
+	ID         pgtype.UUID        `json:"id"`
+	MediaID    pgtype.UUID        `json:"media_id"`
+	Kind       string             `json:"kind"`
+	Size       int32              `json:"size"`
+	Format     string             `json:"format"`
+	StorageKey string             `json:"storage_key"`
+	Width      int32              `json:"width"`
+	Height     int32              `json:"height"`
+	Bytes      int64              `json:"bytes"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+type MediaFile struct {
+	ID               pgtype.UUID        `json:"id"`
+	UserID           pgtype.UUID        `json:"user_id"`
+	Filename         string             `json:"filename"`
+	FileType         string             `json:"file_type"`
+	Size             int64              `json:"size"`
+	UploadedAt       pgtype.Timestamptz `json:"uploaded_at"`
+	BlobID           pgtype.UUID        `json:"blob_id"`
+	ProcessedAt      pgtype.Timestamptz `json:"processed_at"`
+	Metadata         json.RawMessage    `json:"metadata"`
+	TakenAt          pgtype.Timestamptz `json:"taken_at"`
+	Width            pgtype.Int4        `json:"width"`
+	Height           pgtype.Int4        `json:"height"`
+	DeclaredType     string             `json:"declared_type"`
+	DetectedType     string             `json:"detected_type"`
+	DeletedAt        pgtype.Timestamptz `json:"deleted_at"`
+	Title            string             `json:"title"`
+	Description      string             `json:"description"`
+	SearchVector     string             `json:"-"`
+	OriginalFilename string             `json:"original_filename"`
+	DisplayName      string             `json:"display_name"`
+	Favourite        bool               `json:"favourite"`
+	CustomFields     json.RawMessage    `json:"custom_fields"`
+	Version          int32              `json:"version"`
+	ProcessAttempts  int32              `json:"process_attempts"`
+	ProcessRetryAt   pgtype.Timestamptz `json:"process_retry_at"`
+	ProcessError     pgtype.Text        `json:"process_error"`
 }
 
 type MediaGroup struct {
@@ -23,6 +106,25 @@ type MediaGroup struct {
 	UserID    pgtype.UUID        `json:"user_id"`
 	Name      string             `json:"name"`
 	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	DeletedAt pgtype.Timestamptz `json:"deleted_at"`
+	ParentID  pgtype.UUID        `json:"parent_id"`
+	Path      string             `json:"path"`
+}
+
+type MediaTag struct {
+	MediaID   pgtype.UUID        `json:"media_id"`
+	TagID     pgtype.UUID        `json:"tag_id"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type PasswordReset struct {
+	ID        pgtype.UUID        `json:"id"`
+	UserID    pgtype.UUID        `json:"user_id"`
+	TokenHash []byte             `json:"token_hash"`
+	ClientIp  pgtype.Text        `json:"client_ip"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	UsedAt    pgtype.Timestamptz `json:"used_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
 }
 
 type Session struct {
@@ -34,11 +136,66 @@ type Session struct {
 	IsBlocked    pgtype.Bool        `json:"is_blocked"`
 	ExpiresAt    pgtype.Timestamptz `json:"expires_at"`
 	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+	Generation   int32              `json:"generation"`
+	LastUsedAt   pgtype.Timestamptz `json:"last_used_at"`
 }
 
-type User struct {
+type ShareLink struct {
+	ID             pgtype.UUID        `json:"id"`
+	UserID         pgtype.UUID        `json:"user_id"`
+	MediaID        pgtype.UUID        `json:"media_id"`
+	GroupID        pgtype.UUID        `json:"group_id"`
+	TokenHash      []byte             `json:"token_hash"`
+	PasswordHash   pgtype.Text        `json:"password_hash"`
+	ExpiresAt      pgtype.Timestamptz `json:"expires_at"`
+	MaxDownloads   pgtype.Int4        `json:"max_downloads"`
+	DownloadCount  int32              `json:"download_count"`
+	RevokedAt      pgtype.Timestamptz `json:"revoked_at"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	FailedAttempts int32              `json:"failed_attempts"`
+	LockedUntil    pgtype.Timestamptz `json:"locked_until"`
+}
+
+type Tag struct {
 	ID        pgtype.UUID        `json:"id"`
-	Email     string             `json:"email"`
-	Password  string             `json:"password"`
+	UserID    pgtype.UUID        `json:"user_id"`
+	Name      string             `json:"name"`
 	CreatedAt pgtype.Timestamptz `json:"created_at"`
 }
+
+type Upload struct {
+	ID           pgtype.UUID        `json:"id"`
+	UserID       pgtype.UUID        `json:"user_id"`
+	Filename     string             `json:"filename"`
+	FileType     string             `json:"file_type"`
+	Metadata     string             `json:"metadata"`
+	UploadLength int64              `json:"upload_length"`
+	UploadOffset int64              `json:"upload_offset"`
+	MediaFileID  pgtype.UUID        `json:"media_file_id"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
+	CompletedAt  pgtype.Timestamptz `json:"completed_at"`
+}
+
+type UploadPart struct {
+	UploadID     pgtype.UUID `json:"upload_id"`
+	UploadOffset int64       `json:"upload_offset"`
+	Size         int64       `json:"size"`
+	StorageKey   string      `json:"storage_key"`
+}
+
+type User struct {
+	ID              pgtype.UUID        `json:"id"`
+	Email           string             `json:"email"`
+	Password        string             `json:"password"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	Tier            string             `json:"tier"`
+	EmailVerifiedAt pgtype.Timestamptz `json:"email_verified_at"`
+}
+
+type UserQuota struct {
+	UserID    pgtype.UUID        `json:"user_id"`
+	MaxBytes  pgtype.Int8        `json:"max_bytes"`
+	MaxFiles  pgtype.Int8        `json:"max_files"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
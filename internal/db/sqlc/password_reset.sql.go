@@ -0,0 +1,96 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: password_reset.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const countPasswordResetsSince = `-- name: CountPasswordResetsSince :one
+SELECT count(*) FROM password_resets
+WHERE user_id = $1 AND created_at > $2
+`
+
+type CountPasswordResetsSinceParams struct {
+	UserID    pgtype.UUID        `json:"user_id"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+func (q *Queries) CountPasswordResetsSince(ctx context.Context, arg CountPasswordResetsSinceParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countPasswordResetsSince, arg.UserID, arg.CreatedAt)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createPasswordReset = `-- name: CreatePasswordReset :one
+INSERT INTO password_resets (user_id, token_hash, client_ip, expires_at)
+VALUES ($1, $2, $3, $4)
+RETURNING id, user_id, token_hash, client_ip, expires_at, used_at, created_at
+`
+
+type CreatePasswordResetParams struct {
+	UserID    pgtype.UUID        `json:"user_id"`
+	TokenHash []byte             `json:"token_hash"`
+	ClientIp  pgtype.Text        `json:"client_ip"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+}
+
+func (q *Queries) CreatePasswordReset(ctx context.Context, arg CreatePasswordResetParams) (PasswordReset, error) {
+	row := q.db.QueryRow(ctx, createPasswordReset,
+		arg.UserID,
+		arg.TokenHash,
+		arg.ClientIp,
+		arg.ExpiresAt,
+	)
+	var i PasswordReset
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.TokenHash,
+		&i.ClientIp,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const expirePasswordResetsByUser = `-- name: ExpirePasswordResetsByUser :exec
+UPDATE password_resets
+SET expires_at = now()
+WHERE user_id = $1 AND used_at IS NULL AND expires_at > now()
+`
+
+func (q *Queries) ExpirePasswordResetsByUser(ctx context.Context, userID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, expirePasswordResetsByUser, userID)
+	return err
+}
+
+const usePasswordReset = `-- name: UsePasswordReset :one
+UPDATE password_resets
+SET used_at = now()
+WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now()
+RETURNING id, user_id, token_hash, client_ip, expires_at, used_at, created_at
+`
+
+// Spends a reset token, it only succeeds once and before it expires.
+func (q *Queries) UsePasswordReset(ctx context.Context, tokenHash []byte) (PasswordReset, error) {
+	row := q.db.QueryRow(ctx, usePasswordReset, tokenHash)
+	var i PasswordReset
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.TokenHash,
+		&i.ClientIp,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
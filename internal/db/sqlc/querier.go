@@ -11,26 +11,177 @@ import (
 )
 
 type Querier interface {
-	AssignMediaToGroup(ctx context.Context, arg AssignMediaToGroupParams) error
+	AcceptGroupShare(ctx context.Context, arg AcceptGroupShareParams) (GroupShare, error)
+	AcquireBlob(ctx context.Context, arg AcquireBlobParams) (Blob, error)
+	AddMediaTags(ctx context.Context, arg AddMediaTagsParams) error
+	AdvanceUploadOffset(ctx context.Context, arg AdvanceUploadOffsetParams) (Upload, error)
+	// Appends a media file to a group. Both must belong to the user, media that
+	// is already a member keeps its position. Callers hold LockGroupTree so that
+	// concurrent appends do not take the same position.
+	AssignMediaToGroup(ctx context.Context, arg AssignMediaToGroupParams) (GroupMembership, error)
+	// Blocks the sessions of a user except the one with the given id.
+	BlockOtherSessionsByUser(ctx context.Context, arg BlockOtherSessionsByUserParams) ([]pgtype.UUID, error)
 	BlockSessionByID(ctx context.Context, id pgtype.UUID) error
-	CountMediaSizeByUser(ctx context.Context, userID pgtype.UUID) (interface{}, error)
+	BlockSessionForUser(ctx context.Context, arg BlockSessionForUserParams) (int64, error)
+	BlockSessionsByUser(ctx context.Context, userID pgtype.UUID) ([]pgtype.UUID, error)
+	// Binds the invitations sent to an email to the user who verified it.
+	ClaimGroupSharesByEmail(ctx context.Context, arg ClaimGroupSharesByEmailParams) error
+	CompleteUpload(ctx context.Context, arg CompleteUploadParams) (Upload, error)
+	// Adds the media of the source group to the target group in the same order.
+	CopyGroupMemberships(ctx context.Context, arg CopyGroupMembershipsParams) error
+	CountMediaFilesByUser(ctx context.Context, userID pgtype.UUID) (int64, error)
+	CountMediaFilesForUser(ctx context.Context, arg CountMediaFilesForUserParams) (int64, error)
+	CountMediaPage(ctx context.Context, arg CountMediaPageParams) (CountMediaPageRow, error)
+	CountMediaSizeByUser(ctx context.Context, userID pgtype.UUID) (int64, error)
+	CountPasswordResetsSince(ctx context.Context, arg CountPasswordResetsSinceParams) (int64, error)
+	// Fails once the link is used up, so concurrent downloads cannot exceed it.
+	CountShareLinkDownload(ctx context.Context, id pgtype.UUID) (ShareLink, error)
+	CreateAuditEvent(ctx context.Context, arg CreateAuditEventParams) (AuditEvent, error)
+	CreateEmailVerification(ctx context.Context, arg CreateEmailVerificationParams) (EmailVerification, error)
+	// Inviting an email again changes the role of the existing share. user_id is
+	// null until an account with the email is verified.
+	CreateGroupShare(ctx context.Context, arg CreateGroupShareParams) (GroupShare, error)
 	CreateMediaFile(ctx context.Context, arg CreateMediaFileParams) (MediaFile, error)
 	CreateMediaGroup(ctx context.Context, arg CreateMediaGroupParams) (MediaGroup, error)
+	CreatePasswordReset(ctx context.Context, arg CreatePasswordResetParams) (PasswordReset, error)
 	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
+	CreateShareLink(ctx context.Context, arg CreateShareLinkParams) (ShareLink, error)
+	CreateUpload(ctx context.Context, arg CreateUploadParams) (Upload, error)
+	CreateUploadPart(ctx context.Context, arg CreateUploadPartParams) (UploadPart, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
-	DeleteMediaFile(ctx context.Context, id pgtype.UUID) error
+	DeleteMediaFile(ctx context.Context, id pgtype.UUID) (MediaFile, error)
 	DeleteMediaGroup(ctx context.Context, id pgtype.UUID) error
 	DeleteSession(ctx context.Context, id pgtype.UUID) error
+	DeleteUnreferencedBlob(ctx context.Context, id pgtype.UUID) error
+	DeleteUpload(ctx context.Context, id pgtype.UUID) error
+	DeleteUploadParts(ctx context.Context, uploadID pgtype.UUID) error
+	// Invalidates the tokens of a user that were not used, e.g. when a new one is
+	// sent.
+	ExpireEmailVerificationsByUser(ctx context.Context, userID pgtype.UUID) error
+	ExpirePasswordResetsByUser(ctx context.Context, userID pgtype.UUID) error
+	GetBlobByDigest(ctx context.Context, arg GetBlobByDigestParams) (Blob, error)
+	GetBlobByID(ctx context.Context, id pgtype.UUID) (Blob, error)
 	GetGroupByID(ctx context.Context, id pgtype.UUID) (MediaGroup, error)
+	GetGroupForUser(ctx context.Context, arg GetGroupForUserParams) (MediaGroup, error)
+	GetGroupStats(ctx context.Context, groupID pgtype.UUID) (GetGroupStatsRow, error)
+	GetLatestEmailVerification(ctx context.Context, userID pgtype.UUID) (EmailVerification, error)
 	GetMediaFileByID(ctx context.Context, id pgtype.UUID) (MediaFile, error)
-	GetReusableSessionByUserID(ctx context.Context, arg GetReusableSessionByUserIDParams) (Session, error)
+	GetMediaFileForUser(ctx context.Context, arg GetMediaFileForUserParams) (MediaFile, error)
+	GetMediaFileInGroupTree(ctx context.Context, arg GetMediaFileInGroupTreeParams) (MediaFile, error)
 	GetSession(ctx context.Context, id pgtype.UUID) (Session, error)
+	GetShareLinkByTokenHash(ctx context.Context, tokenHash []byte) (ShareLink, error)
+	// Returns a group the user was given access to through an accepted share of
+	// the group or of one of its ancestors. Only editor shares count when
+	// editor_only is set.
+	GetSharedGroupForUser(ctx context.Context, arg GetSharedGroupForUserParams) (MediaGroup, error)
+	// Returns a media file belonging to a group the user was given access to, see
+	// GetSharedGroupForUser.
+	GetSharedMediaFileForUser(ctx context.Context, arg GetSharedMediaFileForUserParams) (MediaFile, error)
+	GetTrashedGroupByID(ctx context.Context, id pgtype.UUID) (MediaGroup, error)
+	GetTrashedGroupForUser(ctx context.Context, arg GetTrashedGroupForUserParams) (MediaGroup, error)
+	GetTrashedMediaFileForUser(ctx context.Context, arg GetTrashedMediaFileForUserParams) (MediaFile, error)
+	GetUploadByID(ctx context.Context, id pgtype.UUID) (Upload, error)
 	GetUserByEmail(ctx context.Context, email string) (User, error)
 	GetUserByID(ctx context.Context, id pgtype.UUID) (User, error)
-	ListGroupsByUser(ctx context.Context, userID pgtype.UUID) ([]MediaGroup, error)
-	ListMediaByGroup(ctx context.Context, arg ListMediaByGroupParams) ([]MediaFile, error)
-	ListMediaByUser(ctx context.Context, userID pgtype.UUID) ([]MediaFile, error)
-	UpdateSessionTokenAndExpiry(ctx context.Context, arg UpdateSessionTokenAndExpiryParams) error
+	GetUserQuota(ctx context.Context, userID pgtype.UUID) (UserQuota, error)
+	// Declines an invitation or gives up an accepted share.
+	LeaveGroupShare(ctx context.Context, arg LeaveGroupShareParams) (GroupShare, error)
+	ListBlobsByIDs(ctx context.Context, ids []pgtype.UUID) ([]Blob, error)
+	ListChildGroups(ctx context.Context, parentID pgtype.UUID) ([]ListChildGroupsRow, error)
+	// Groups in skip_ids, which failed to be purged, are left out.
+	ListExpiredTrashedGroups(ctx context.Context, arg ListExpiredTrashedGroupsParams) ([]MediaGroup, error)
+	// Media files in skip_ids, which failed to be purged, are left out.
+	ListExpiredTrashedMedia(ctx context.Context, arg ListExpiredTrashedMediaParams) ([]MediaFile, error)
+	// Returns the groups on the path from the root down to the group itself.
+	ListGroupAncestors(ctx context.Context, arg ListGroupAncestorsParams) ([]MediaGroup, error)
+	ListGroupMemberships(ctx context.Context, groupID pgtype.UUID) ([]GroupMembership, error)
+	ListGroupSharesByGroup(ctx context.Context, groupID pgtype.UUID) ([]GroupShare, error)
+	// Lists the shares of live groups with a user, pending invitations included.
+	ListGroupSharesByUser(ctx context.Context, userID pgtype.UUID) ([]ListGroupSharesByUserRow, error)
+	ListGroupSubtree(ctx context.Context, arg ListGroupSubtreeParams) ([]MediaGroup, error)
+	ListGroupsByUser(ctx context.Context, userID pgtype.UUID) ([]ListGroupsByUserRow, error)
+	ListMediaByFilenameAsc(ctx context.Context, arg ListMediaByFilenameAscParams) ([]MediaFile, error)
+	ListMediaByFilenameDesc(ctx context.Context, arg ListMediaByFilenameDescParams) ([]MediaFile, error)
+	ListMediaByGroupMemberships(ctx context.Context, groupIds []pgtype.UUID) ([]ListMediaByGroupMembershipsRow, error)
+	ListMediaByPositionAsc(ctx context.Context, arg ListMediaByPositionAscParams) ([]ListMediaByPositionAscRow, error)
+	ListMediaByPositionDesc(ctx context.Context, arg ListMediaByPositionDescParams) ([]ListMediaByPositionDescRow, error)
+	ListMediaBySizeAsc(ctx context.Context, arg ListMediaBySizeAscParams) ([]MediaFile, error)
+	ListMediaBySizeDesc(ctx context.Context, arg ListMediaBySizeDescParams) ([]MediaFile, error)
+	ListMediaByTakenAtAsc(ctx context.Context, arg ListMediaByTakenAtAscParams) ([]MediaFile, error)
+	ListMediaByTakenAtDesc(ctx context.Context, arg ListMediaByTakenAtDescParams) ([]MediaFile, error)
+	ListMediaByUploadedAtAsc(ctx context.Context, arg ListMediaByUploadedAtAscParams) ([]MediaFile, error)
+	ListMediaByUploadedAtDesc(ctx context.Context, arg ListMediaByUploadedAtDescParams) ([]MediaFile, error)
+	ListMediaDerivatives(ctx context.Context, mediaID pgtype.UUID) ([]MediaDerivative, error)
+	// bytes counts each blob once, under the type of its oldest media file, so
+	// that it adds up to CountMediaSizeByUser. logical_bytes counts every copy.
+	ListMediaUsageByType(ctx context.Context, userID pgtype.UUID) ([]ListMediaUsageByTypeRow, error)
+	// Lists the sessions of a user that can still be used.
+	ListSessionsByUser(ctx context.Context, userID pgtype.UUID) ([]Session, error)
+	ListShareLinksByUser(ctx context.Context, userID pgtype.UUID) ([]ShareLink, error)
+	ListTagsByMedia(ctx context.Context, mediaID pgtype.UUID) ([]Tag, error)
+	// Tags only used by media in the trash are left out.
+	ListTagsByPrefix(ctx context.Context, arg ListTagsByPrefixParams) ([]ListTagsByPrefixRow, error)
+	// Groups trashed along with their parent are left out, restoring the parent
+	// restores them.
+	ListTrashedGroupsByUser(ctx context.Context, userID pgtype.UUID) ([]MediaGroup, error)
+	ListTrashedMediaByUser(ctx context.Context, userID pgtype.UUID) ([]MediaFile, error)
+	// Lists media files due for processing, leaving out those given up on after
+	// max_attempts failures.
+	ListUnprocessedMediaFiles(ctx context.Context, arg ListUnprocessedMediaFilesParams) ([]MediaFile, error)
+	ListUploadParts(ctx context.Context, uploadID pgtype.UUID) ([]UploadPart, error)
+	// Serializes writing and deleting the content of a blob until the end of the
+	// transaction, as the blob row may not exist yet or anymore.
+	LockBlobStorageKey(ctx context.Context, storageKey string) error
+	// Serializes changes to the folder tree of a user until the transaction ends.
+	LockGroupTree(ctx context.Context, userID pgtype.UUID) error
+	LockUserQuota(ctx context.Context, userID pgtype.UUID) (UserQuota, error)
+	MarkMediaFileProcessed(ctx context.Context, id pgtype.UUID) error
+	// Verifies the email of a user, provided it is still the address the token
+	// was sent to.
+	MarkUserEmailVerified(ctx context.Context, arg MarkUserEmailVerifiedParams) (User, error)
+	// Rewrites the path of a group and its descendants from old_path to new_path.
+	MoveGroupSubtree(ctx context.Context, arg MoveGroupSubtreeParams) error
+	RecordMediaFileProcessingFailure(ctx context.Context, arg RecordMediaFileProcessingFailureParams) (int32, error)
+	// Counts a wrong password, locking the link until lock_until once
+	// max_attempts are reached in a row.
+	RecordShareLinkPasswordFailure(ctx context.Context, arg RecordShareLinkPasswordFailureParams) (ShareLink, error)
+	ReleaseBlob(ctx context.Context, id pgtype.UUID) (Blob, error)
+	RemoveMediaFromGroup(ctx context.Context, arg RemoveMediaFromGroupParams) (GroupMembership, error)
+	RemoveMediaTags(ctx context.Context, arg RemoveMediaTagsParams) (int64, error)
+	RenameMediaGroup(ctx context.Context, arg RenameMediaGroupParams) (MediaGroup, error)
+	ResetShareLinkPasswordFailures(ctx context.Context, id pgtype.UUID) error
+	RestoreGroupDescendants(ctx context.Context, arg RestoreGroupDescendantsParams) error
+	RestoreMediaByGroupTree(ctx context.Context, arg RestoreMediaByGroupTreeParams) error
+	RestoreMediaFile(ctx context.Context, id pgtype.UUID) (MediaFile, error)
+	RevokeGroupShare(ctx context.Context, arg RevokeGroupShareParams) (GroupShare, error)
+	RevokeShareLink(ctx context.Context, arg RevokeShareLinkParams) (ShareLink, error)
+	// Replaces the refresh token of a session with the next generation, provided
+	// the current one is presented.
+	RotateSessionRefreshToken(ctx context.Context, arg RotateSessionRefreshTokenParams) (Session, error)
+	// Ranks the media of a user matching a web search style query. Highlights are
+	// only built for the rows of the page.
+	SearchMedia(ctx context.Context, arg SearchMediaParams) ([]SearchMediaRow, error)
+	// Matches part of a display name or title, for queries that are not whole words.
+	SearchMediaByFilename(ctx context.Context, arg SearchMediaByFilenameParams) ([]SearchMediaByFilenameRow, error)
+	SetBlobCRC32(ctx context.Context, arg SetBlobCRC32Params) error
+	SetGroupMembershipPosition(ctx context.Context, arg SetGroupMembershipPositionParams) error
+	SetGroupParent(ctx context.Context, arg SetGroupParentParams) (MediaGroup, error)
+	TrashGroupDescendants(ctx context.Context, arg TrashGroupDescendantsParams) error
+	// Trashes the media of the groups under path that were trashed at deleted_at.
+	// Media that still belongs to a group outside the trash is left alone.
+	TrashMediaByGroupTree(ctx context.Context, arg TrashMediaByGroupTreeParams) error
+	TrashMediaFile(ctx context.Context, id pgtype.UUID) (MediaFile, error)
+	TrashMediaGroup(ctx context.Context, id pgtype.UUID) (MediaGroup, error)
+	// Only succeeds while the media file is still at the expected version.
+	UpdateMediaFileDetails(ctx context.Context, arg UpdateMediaFileDetailsParams) (MediaFile, error)
+	UpdateMediaFileMetadata(ctx context.Context, arg UpdateMediaFileMetadataParams) error
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error)
+	UpsertMediaDerivative(ctx context.Context, arg UpsertMediaDerivativeParams) (MediaDerivative, error)
+	UpsertTags(ctx context.Context, arg UpsertTagsParams) ([]Tag, error)
+	// Spends a verification token, it only succeeds once and before it expires.
+	UseEmailVerification(ctx context.Context, id pgtype.UUID) (EmailVerification, error)
+	// Spends a reset token, it only succeeds once and before it expires.
+	UsePasswordReset(ctx context.Context, tokenHash []byte) (PasswordReset, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// QuotaLimits bounds the storage of a user. A zero limit means unlimited.
+type QuotaLimits struct {
+	MaxBytes int64
+	MaxFiles int64
+}
+
+// ResolveQuota returns the limits of a quota row, falling back to defaults
+// for the limits it leaves unset.
+func ResolveQuota(quota UserQuota, defaults QuotaLimits) QuotaLimits {
+	limits := defaults
+	if quota.MaxBytes.Valid {
+		limits.MaxBytes = quota.MaxBytes.Int64
+	}
+	if quota.MaxFiles.Valid {
+		limits.MaxFiles = quota.MaxFiles.Int64
+	}
+	return limits
+}
+
+// QuotaUsage is what a user currently stores. Bytes count deduplicated blob
+// content, so identical uploads are only counted once.
+type QuotaUsage struct {
+	Bytes int64
+	Files int64
+}
+
+type QuotaExceededError struct {
+	Limits         QuotaLimits
+	Usage          QuotaUsage
+	RequestedBytes int64
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("storage quota exceeded: %d of %d bytes and %d of %d files used, %d bytes requested",
+		e.Usage.Bytes, e.Limits.MaxBytes, e.Usage.Files, e.Limits.MaxFiles, e.RequestedBytes)
+}
+
+func getQuotaUsage(ctx context.Context, q *Queries, userID pgtype.UUID) (QuotaUsage, error) {
+	var usage QuotaUsage
+	var err error
+
+	usage.Bytes, err = q.CountMediaSizeByUser(ctx, userID)
+	if err != nil {
+		return usage, err
+	}
+
+	usage.Files, err = q.CountMediaFilesByUser(ctx, userID)
+	return usage, err
+}
+
+// checkQuota locks the user's quota row, so concurrent uploads of the same
+// user are checked one after another, and fails with a QuotaExceededError when
+// adding `bytes` bytes and `files` files would exceed the limits.
+func checkQuota(ctx context.Context, q *Queries, userID pgtype.UUID, bytes, files int64, defaults QuotaLimits) error {
+	quota, err := q.LockUserQuota(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	limits := ResolveQuota(quota, defaults)
+	usage, err := getQuotaUsage(ctx, q, userID)
+	if err != nil {
+		return err
+	}
+
+	if (limits.MaxBytes > 0 && usage.Bytes+bytes > limits.MaxBytes) ||
+		(limits.MaxFiles > 0 && usage.Files+files > limits.MaxFiles) {
+		return &QuotaExceededError{
+			Limits:         limits,
+			Usage:          usage,
+			RequestedBytes: bytes,
+		}
+	}
+
+	return nil
+}
@@ -11,6 +11,39 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const blockOtherSessionsByUser = `-- name: BlockOtherSessionsByUser :many
+UPDATE sessions
+SET is_blocked = true
+WHERE user_id = $1 AND id <> $2 AND is_blocked = false
+RETURNING id
+`
+
+type BlockOtherSessionsByUserParams struct {
+	UserID pgtype.UUID `json:"user_id"`
+	ID     pgtype.UUID `json:"id"`
+}
+
+// Blocks the sessions of a user except the one with the given id.
+func (q *Queries) BlockOtherSessionsByUser(ctx context.Context, arg BlockOtherSessionsByUserParams) ([]pgtype.UUID, error) {
+	rows, err := q.db.Query(ctx, blockOtherSessionsByUser, arg.UserID, arg.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []pgtype.UUID{}
+	for rows.Next() {
+		var id pgtype.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const blockSessionByID = `-- name: BlockSessionByID :exec
 UPDATE sessions 
 SET is_blocked = true
@@ -22,6 +55,52 @@ func (q *Queries) BlockSessionByID(ctx context.Context, id pgtype.UUID) error {
 	return err
 }
 
+const blockSessionForUser = `-- name: BlockSessionForUser :execrows
+UPDATE sessions
+SET is_blocked = true
+WHERE id = $1 AND user_id = $2 AND is_blocked = false
+`
+
+type BlockSessionForUserParams struct {
+	ID     pgtype.UUID `json:"id"`
+	UserID pgtype.UUID `json:"user_id"`
+}
+
+func (q *Queries) BlockSessionForUser(ctx context.Context, arg BlockSessionForUserParams) (int64, error) {
+	result, err := q.db.Exec(ctx, blockSessionForUser, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const blockSessionsByUser = `-- name: BlockSessionsByUser :many
+UPDATE sessions
+SET is_blocked = true
+WHERE user_id = $1 AND is_blocked = false
+RETURNING id
+`
+
+func (q *Queries) BlockSessionsByUser(ctx context.Context, userID pgtype.UUID) ([]pgtype.UUID, error) {
+	rows, err := q.db.Query(ctx, blockSessionsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []pgtype.UUID{}
+	for rows.Next() {
+		var id pgtype.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createSession = `-- name: CreateSession :one
 INSERT INTO sessions (
   id, user_id, refresh_token, user_agent, client_ip, is_blocked, expires_at
@@ -29,7 +108,7 @@ INSERT INTO sessions (
 VALUES (
   $1, $2, $3, $4, $5, $6, $7
 )
-RETURNING id, user_id, refresh_token, user_agent, client_ip, is_blocked, expires_at, created_at
+RETURNING id, user_id, refresh_token, user_agent, client_ip, is_blocked, expires_at, created_at, generation, last_used_at
 `
 
 type CreateSessionParams struct {
@@ -62,6 +141,8 @@ func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (S
 		&i.IsBlocked,
 		&i.ExpiresAt,
 		&i.CreatedAt,
+		&i.Generation,
+		&i.LastUsedAt,
 	)
 	return i, err
 }
@@ -75,20 +156,12 @@ func (q *Queries) DeleteSession(ctx context.Context, id pgtype.UUID) error {
 	return err
 }
 
-const getReusableSessionByUserID = `-- name: GetReusableSessionByUserID :one
-SELECT id, user_id, refresh_token, user_agent, client_ip, is_blocked, expires_at, created_at FROM sessions
-WHERE user_id = $1 AND is_blocked = false AND expires_at > $2
-ORDER BY expires_at DESC
-LIMIT 1
+const getSession = `-- name: GetSession :one
+SELECT id, user_id, refresh_token, user_agent, client_ip, is_blocked, expires_at, created_at, generation, last_used_at FROM sessions WHERE id = $1
 `
 
-type GetReusableSessionByUserIDParams struct {
-	UserID    pgtype.UUID        `json:"user_id"`
-	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
-}
-
-func (q *Queries) GetReusableSessionByUserID(ctx context.Context, arg GetReusableSessionByUserIDParams) (Session, error) {
-	row := q.db.QueryRow(ctx, getReusableSessionByUserID, arg.UserID, arg.ExpiresAt)
+func (q *Queries) GetSession(ctx context.Context, id pgtype.UUID) (Session, error) {
+	row := q.db.QueryRow(ctx, getSession, id)
 	var i Session
 	err := row.Scan(
 		&i.ID,
@@ -99,16 +172,68 @@ func (q *Queries) GetReusableSessionByUserID(ctx context.Context, arg GetReusabl
 		&i.IsBlocked,
 		&i.ExpiresAt,
 		&i.CreatedAt,
+		&i.Generation,
+		&i.LastUsedAt,
 	)
 	return i, err
 }
 
-const getSession = `-- name: GetSession :one
-SELECT id, user_id, refresh_token, user_agent, client_ip, is_blocked, expires_at, created_at FROM sessions WHERE id = $1
+const listSessionsByUser = `-- name: ListSessionsByUser :many
+SELECT id, user_id, refresh_token, user_agent, client_ip, is_blocked, expires_at, created_at, generation, last_used_at FROM sessions
+WHERE user_id = $1 AND is_blocked = false AND expires_at > now()
+ORDER BY last_used_at DESC
 `
 
-func (q *Queries) GetSession(ctx context.Context, id pgtype.UUID) (Session, error) {
-	row := q.db.QueryRow(ctx, getSession, id)
+// Lists the sessions of a user that can still be used.
+func (q *Queries) ListSessionsByUser(ctx context.Context, userID pgtype.UUID) ([]Session, error) {
+	rows, err := q.db.Query(ctx, listSessionsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Session{}
+	for rows.Next() {
+		var i Session
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.RefreshToken,
+			&i.UserAgent,
+			&i.ClientIp,
+			&i.IsBlocked,
+			&i.ExpiresAt,
+			&i.CreatedAt,
+			&i.Generation,
+			&i.LastUsedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const rotateSessionRefreshToken = `-- name: RotateSessionRefreshToken :one
+UPDATE sessions
+SET refresh_token = $1, generation = generation + 1, last_used_at = now()
+WHERE id = $2 AND refresh_token = $3
+    AND is_blocked = false AND expires_at > now()
+RETURNING id, user_id, refresh_token, user_agent, client_ip, is_blocked, expires_at, created_at, generation, last_used_at
+`
+
+type RotateSessionRefreshTokenParams struct {
+	NewRefreshToken string      `json:"new_refresh_token"`
+	ID              pgtype.UUID `json:"id"`
+	RefreshToken    string      `json:"refresh_token"`
+}
+
+// Replaces the refresh token of a session with the next generation, provided
+// the current one is presented.
+func (q *Queries) RotateSessionRefreshToken(ctx context.Context, arg RotateSessionRefreshTokenParams) (Session, error) {
+	row := q.db.QueryRow(ctx, rotateSessionRefreshToken, arg.NewRefreshToken, arg.ID, arg.RefreshToken)
 	var i Session
 	err := row.Scan(
 		&i.ID,
@@ -119,23 +244,8 @@ func (q *Queries) GetSession(ctx context.Context, id pgtype.UUID) (Session, erro
 		&i.IsBlocked,
 		&i.ExpiresAt,
 		&i.CreatedAt,
+		&i.Generation,
+		&i.LastUsedAt,
 	)
 	return i, err
 }
-
-const updateSessionTokenAndExpiry = `-- name: UpdateSessionTokenAndExpiry :exec
-UPDATE sessions
-SET refresh_token = $1, expires_at = $2
-WHERE id = $3
-`
-
-type UpdateSessionTokenAndExpiryParams struct {
-	RefreshToken string             `json:"refresh_token"`
-	ExpiresAt    pgtype.Timestamptz `json:"expires_at"`
-	ID           pgtype.UUID        `json:"id"`
-}
-
-func (q *Queries) UpdateSessionTokenAndExpiry(ctx context.Context, arg UpdateSessionTokenAndExpiryParams) error {
-	_, err := q.db.Exec(ctx, updateSessionTokenAndExpiry, arg.RefreshToken, arg.ExpiresAt, arg.ID)
-	return err
-}
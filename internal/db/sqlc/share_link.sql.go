@@ -0,0 +1,233 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: share_link.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const countShareLinkDownload = `-- name: CountShareLinkDownload :one
+UPDATE share_links
+SET download_count = download_count + 1
+WHERE id = $1 AND (max_downloads IS NULL OR download_count < max_downloads)
+RETURNING id, user_id, media_id, group_id, token_hash, password_hash, expires_at, max_downloads, download_count, revoked_at, created_at, failed_attempts, locked_until
+`
+
+// Fails once the link is used up, so concurrent downloads cannot exceed it.
+func (q *Queries) CountShareLinkDownload(ctx context.Context, id pgtype.UUID) (ShareLink, error) {
+	row := q.db.QueryRow(ctx, countShareLinkDownload, id)
+	var i ShareLink
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.MediaID,
+		&i.GroupID,
+		&i.TokenHash,
+		&i.PasswordHash,
+		&i.ExpiresAt,
+		&i.MaxDownloads,
+		&i.DownloadCount,
+		&i.RevokedAt,
+		&i.CreatedAt,
+		&i.FailedAttempts,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const createShareLink = `-- name: CreateShareLink :one
+INSERT INTO share_links (user_id, media_id, group_id, token_hash, password_hash, expires_at, max_downloads)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, user_id, media_id, group_id, token_hash, password_hash, expires_at, max_downloads, download_count, revoked_at, created_at, failed_attempts, locked_until
+`
+
+type CreateShareLinkParams struct {
+	UserID       pgtype.UUID        `json:"user_id"`
+	MediaID      pgtype.UUID        `json:"media_id"`
+	GroupID      pgtype.UUID        `json:"group_id"`
+	TokenHash    []byte             `json:"token_hash"`
+	PasswordHash pgtype.Text        `json:"password_hash"`
+	ExpiresAt    pgtype.Timestamptz `json:"expires_at"`
+	MaxDownloads pgtype.Int4        `json:"max_downloads"`
+}
+
+func (q *Queries) CreateShareLink(ctx context.Context, arg CreateShareLinkParams) (ShareLink, error) {
+	row := q.db.QueryRow(ctx, createShareLink,
+		arg.UserID,
+		arg.MediaID,
+		arg.GroupID,
+		arg.TokenHash,
+		arg.PasswordHash,
+		arg.ExpiresAt,
+		arg.MaxDownloads,
+	)
+	var i ShareLink
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.MediaID,
+		&i.GroupID,
+		&i.TokenHash,
+		&i.PasswordHash,
+		&i.ExpiresAt,
+		&i.MaxDownloads,
+		&i.DownloadCount,
+		&i.RevokedAt,
+		&i.CreatedAt,
+		&i.FailedAttempts,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const getShareLinkByTokenHash = `-- name: GetShareLinkByTokenHash :one
+SELECT id, user_id, media_id, group_id, token_hash, password_hash, expires_at, max_downloads, download_count, revoked_at, created_at, failed_attempts, locked_until FROM share_links
+WHERE token_hash = $1
+`
+
+func (q *Queries) GetShareLinkByTokenHash(ctx context.Context, tokenHash []byte) (ShareLink, error) {
+	row := q.db.QueryRow(ctx, getShareLinkByTokenHash, tokenHash)
+	var i ShareLink
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.MediaID,
+		&i.GroupID,
+		&i.TokenHash,
+		&i.PasswordHash,
+		&i.ExpiresAt,
+		&i.MaxDownloads,
+		&i.DownloadCount,
+		&i.RevokedAt,
+		&i.CreatedAt,
+		&i.FailedAttempts,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const listShareLinksByUser = `-- name: ListShareLinksByUser :many
+SELECT id, user_id, media_id, group_id, token_hash, password_hash, expires_at, max_downloads, download_count, revoked_at, created_at, failed_attempts, locked_until FROM share_links
+WHERE user_id = $1
+ORDER BY created_at DESC
+`
+
+func (q *Queries) ListShareLinksByUser(ctx context.Context, userID pgtype.UUID) ([]ShareLink, error) {
+	rows, err := q.db.Query(ctx, listShareLinksByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ShareLink{}
+	for rows.Next() {
+		var i ShareLink
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.MediaID,
+			&i.GroupID,
+			&i.TokenHash,
+			&i.PasswordHash,
+			&i.ExpiresAt,
+			&i.MaxDownloads,
+			&i.DownloadCount,
+			&i.RevokedAt,
+			&i.CreatedAt,
+			&i.FailedAttempts,
+			&i.LockedUntil,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const recordShareLinkPasswordFailure = `-- name: RecordShareLinkPasswordFailure :one
+UPDATE share_links
+SET failed_attempts = CASE WHEN failed_attempts + 1 >= $1::int THEN 0 ELSE failed_attempts + 1 END,
+    locked_until = CASE WHEN failed_attempts + 1 >= $1::int THEN $2::timestamptz ELSE locked_until END
+WHERE id = $3
+RETURNING id, user_id, media_id, group_id, token_hash, password_hash, expires_at, max_downloads, download_count, revoked_at, created_at, failed_attempts, locked_until
+`
+
+type RecordShareLinkPasswordFailureParams struct {
+	MaxAttempts int32              `json:"max_attempts"`
+	LockUntil   pgtype.Timestamptz `json:"lock_until"`
+	ID          pgtype.UUID        `json:"id"`
+}
+
+// Counts a wrong password, locking the link until lock_until once
+// max_attempts are reached in a row.
+func (q *Queries) RecordShareLinkPasswordFailure(ctx context.Context, arg RecordShareLinkPasswordFailureParams) (ShareLink, error) {
+	row := q.db.QueryRow(ctx, recordShareLinkPasswordFailure, arg.MaxAttempts, arg.LockUntil, arg.ID)
+	var i ShareLink
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.MediaID,
+		&i.GroupID,
+		&i.TokenHash,
+		&i.PasswordHash,
+		&i.ExpiresAt,
+		&i.MaxDownloads,
+		&i.DownloadCount,
+		&i.RevokedAt,
+		&i.CreatedAt,
+		&i.FailedAttempts,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const resetShareLinkPasswordFailures = `-- name: ResetShareLinkPasswordFailures :exec
+UPDATE share_links
+SET failed_attempts = 0
+WHERE id = $1 AND failed_attempts > 0
+`
+
+func (q *Queries) ResetShareLinkPasswordFailures(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, resetShareLinkPasswordFailures, id)
+	return err
+}
+
+const revokeShareLink = `-- name: RevokeShareLink :one
+UPDATE share_links
+SET revoked_at = now()
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+RETURNING id, user_id, media_id, group_id, token_hash, password_hash, expires_at, max_downloads, download_count, revoked_at, created_at, failed_attempts, locked_until
+`
+
+type RevokeShareLinkParams struct {
+	ID     pgtype.UUID `json:"id"`
+	UserID pgtype.UUID `json:"user_id"`
+}
+
+func (q *Queries) RevokeShareLink(ctx context.Context, arg RevokeShareLinkParams) (ShareLink, error) {
+	row := q.db.QueryRow(ctx, revokeShareLink, arg.ID, arg.UserID)
+	var i ShareLink
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.MediaID,
+		&i.GroupID,
+		&i.TokenHash,
+		&i.PasswordHash,
+		&i.ExpiresAt,
+		&i.MaxDownloads,
+		&i.DownloadCount,
+		&i.RevokedAt,
+		&i.CreatedAt,
+		&i.FailedAttempts,
+		&i.LockedUntil,
+	)
+	return i, err
+}
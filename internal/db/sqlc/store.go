@@ -2,19 +2,40 @@ package db
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Queryer interface {
 	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
 	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row 
 	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Begin(ctx context.Context) (pgx.Tx, error)
 }
 
 type Store interface {
 	Querier
+	CreateMediaFileTx(ctx context.Context, arg CreateMediaFileTxParams) (CreateMediaFileTxResult, error)
+	DeleteMediaFileTx(ctx context.Context, arg DeleteMediaFileTxParams) (DeleteMediaFileTxResult, error)
+	DeleteBlobContentTx(ctx context.Context, blob Blob, deleteContent func() error) error
+	AppendUploadPartTx(ctx context.Context, arg AppendUploadPartTxParams) (Upload, error)
+	TrashMediaGroupTx(ctx context.Context, id pgtype.UUID) (MediaGroup, error)
+	RestoreMediaGroupTx(ctx context.Context, id pgtype.UUID) (MediaGroup, error)
+	MoveMediaGroupTx(ctx context.Context, arg MoveMediaGroupTxParams) (MediaGroup, error)
+	CopyMediaGroupTx(ctx context.Context, arg CopyMediaGroupTxParams) (MediaGroup, error)
+	AddMediaToGroupTx(ctx context.Context, arg AddMediaToGroupTxParams) ([]GroupMembership, error)
+	ReorderGroupMediaTx(ctx context.Context, arg ReorderGroupMediaTxParams) ([]GroupMembership, error)
+	TagMediaTx(ctx context.Context, arg TagMediaTxParams) ([]Tag, error)
+	UntagMediaTx(ctx context.Context, arg TagMediaTxParams) (int64, error)
+	ListMediaPage(ctx context.Context, arg ListMediaPageParams) (MediaPage, error)
+	CountMediaMatching(ctx context.Context, f MediaFilter) (CountMediaPageRow, error)
+	BlockSessionTx(ctx context.Context, arg BlockSessionTxParams) error
+	VerifyEmailTx(ctx context.Context, id pgtype.UUID) (User, error)
+	ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (PasswordTxResult, error)
+	ChangePasswordTx(ctx context.Context, arg ChangePasswordTxParams) (PasswordTxResult, error)
 }
 
 type SQLStore struct {
@@ -28,3 +49,20 @@ func NewStore(db Queryer) Store {
 		Queries: New(db),
 	}
 }
+
+func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) error {
+	tx, err := store.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	q := New(tx)
+	if err := fn(q); err != nil {
+		if rbErr := tx.Rollback(ctx); rbErr != nil {
+			return fmt.Errorf("tx err: %v, rb err: %v", err, rbErr)
+		}
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
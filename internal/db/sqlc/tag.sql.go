@@ -0,0 +1,167 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: tag.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const addMediaTags = `-- name: AddMediaTags :exec
+INSERT INTO media_tags (media_id, tag_id)
+SELECT m.id, t.id
+FROM unnest($1::uuid[]) AS m(id)
+CROSS JOIN unnest($2::uuid[]) AS t(id)
+ON CONFLICT (media_id, tag_id) DO NOTHING
+`
+
+type AddMediaTagsParams struct {
+	MediaIds []pgtype.UUID `json:"media_ids"`
+	TagIds   []pgtype.UUID `json:"tag_ids"`
+}
+
+func (q *Queries) AddMediaTags(ctx context.Context, arg AddMediaTagsParams) error {
+	_, err := q.db.Exec(ctx, addMediaTags, arg.MediaIds, arg.TagIds)
+	return err
+}
+
+const listTagsByMedia = `-- name: ListTagsByMedia :many
+SELECT t.id, t.user_id, t.name, t.created_at FROM tags t
+JOIN media_tags mt ON mt.tag_id = t.id
+WHERE mt.media_id = $1
+ORDER BY t.name ASC
+`
+
+func (q *Queries) ListTagsByMedia(ctx context.Context, mediaID pgtype.UUID) ([]Tag, error) {
+	rows, err := q.db.Query(ctx, listTagsByMedia, mediaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Tag{}
+	for rows.Next() {
+		var i Tag
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTagsByPrefix = `-- name: ListTagsByPrefix :many
+SELECT t.id, t.name, COUNT(m.id) AS media_count
+FROM tags t
+JOIN media_tags mt ON mt.tag_id = t.id
+JOIN media_files m ON m.id = mt.media_id AND m.deleted_at IS NULL
+WHERE t.user_id = $1 AND t.name LIKE $2::text || '%'
+GROUP BY t.id
+ORDER BY media_count DESC, t.name ASC
+LIMIT $3
+`
+
+type ListTagsByPrefixParams struct {
+	UserID   pgtype.UUID `json:"user_id"`
+	Prefix   string      `json:"prefix"`
+	RowLimit int32       `json:"row_limit"`
+}
+
+type ListTagsByPrefixRow struct {
+	ID         pgtype.UUID `json:"id"`
+	Name       string      `json:"name"`
+	MediaCount int64       `json:"media_count"`
+}
+
+// Tags only used by media in the trash are left out.
+func (q *Queries) ListTagsByPrefix(ctx context.Context, arg ListTagsByPrefixParams) ([]ListTagsByPrefixRow, error) {
+	rows, err := q.db.Query(ctx, listTagsByPrefix, arg.UserID, arg.Prefix, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListTagsByPrefixRow{}
+	for rows.Next() {
+		var i ListTagsByPrefixRow
+		if err := rows.Scan(&i.ID, &i.Name, &i.MediaCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const removeMediaTags = `-- name: RemoveMediaTags :execrows
+DELETE FROM media_tags mt
+USING tags t
+WHERE mt.tag_id = t.id
+    AND t.user_id = $1
+    AND t.name = ANY($2::text[])
+    AND mt.media_id = ANY($3::uuid[])
+`
+
+type RemoveMediaTagsParams struct {
+	UserID   pgtype.UUID   `json:"user_id"`
+	Names    []string      `json:"names"`
+	MediaIds []pgtype.UUID `json:"media_ids"`
+}
+
+func (q *Queries) RemoveMediaTags(ctx context.Context, arg RemoveMediaTagsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, removeMediaTags, arg.UserID, arg.Names, arg.MediaIds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const upsertTags = `-- name: UpsertTags :many
+INSERT INTO tags (user_id, name)
+SELECT $1, unnest($2::text[])
+ON CONFLICT (user_id, name)
+DO UPDATE SET name = EXCLUDED.name
+RETURNING id, user_id, name, created_at
+`
+
+type UpsertTagsParams struct {
+	UserID pgtype.UUID `json:"user_id"`
+	Names  []string    `json:"names"`
+}
+
+func (q *Queries) UpsertTags(ctx context.Context, arg UpsertTagsParams) ([]Tag, error) {
+	rows, err := q.db.Query(ctx, upsertTags, arg.UserID, arg.Names)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Tag{}
+	for rows.Next() {
+		var i Tag
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
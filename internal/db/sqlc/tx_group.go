@@ -0,0 +1,182 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var ErrGroupCycle = errors.New("a group cannot be moved into itself or one of its descendants")
+
+func rootGroupPath(id pgtype.UUID) string {
+	return "/" + id.String() + "/"
+}
+
+func childGroupPath(parent MediaGroup, id pgtype.UUID) string {
+	return parent.Path + id.String() + "/"
+}
+
+// moveGroup attaches group to parentID, which is invalid for the top level,
+// and rewrites the paths of its subtree so they start with path.
+func moveGroup(ctx context.Context, q *Queries, group MediaGroup, parentID pgtype.UUID, path string) error {
+	_, err := q.SetGroupParent(ctx, SetGroupParentParams{
+		ID:       group.ID,
+		ParentID: parentID,
+	})
+	if err != nil {
+		return err
+	}
+
+	return q.MoveGroupSubtree(ctx, MoveGroupSubtreeParams{
+		NewPath: path,
+		OldPath: group.Path,
+		UserID:  group.UserID,
+	})
+}
+
+type MoveMediaGroupTxParams struct {
+	UserID  pgtype.UUID
+	GroupID pgtype.UUID
+	// ParentID is the new parent of the group, the group becomes a top level
+	// group when it is not valid.
+	ParentID pgtype.UUID
+}
+
+// MoveMediaGroupTx moves a group with its descendants under another group of
+// the same user. Moving a group below itself fails with ErrGroupCycle.
+func (store *SQLStore) MoveMediaGroupTx(ctx context.Context, arg MoveMediaGroupTxParams) (MediaGroup, error) {
+	var group MediaGroup
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		if err := q.LockGroupTree(ctx, arg.UserID); err != nil {
+			return err
+		}
+
+		current, err := q.GetGroupForUser(ctx, GetGroupForUserParams{
+			ID:     arg.GroupID,
+			UserID: arg.UserID,
+		})
+		if err != nil {
+			return err
+		}
+
+		path := rootGroupPath(current.ID)
+		if arg.ParentID.Valid {
+			parent, err := q.GetGroupForUser(ctx, GetGroupForUserParams{
+				ID:     arg.ParentID,
+				UserID: arg.UserID,
+			})
+			if err != nil {
+				return err
+			}
+
+			if strings.HasPrefix(parent.Path, current.Path) {
+				return ErrGroupCycle
+			}
+
+			path = childGroupPath(parent, current.ID)
+		}
+
+		if err := moveGroup(ctx, q, current, arg.ParentID, path); err != nil {
+			return err
+		}
+
+		group, err = q.GetGroupForUser(ctx, GetGroupForUserParams{
+			ID:     current.ID,
+			UserID: arg.UserID,
+		})
+		return err
+	})
+
+	return group, err
+}
+
+type CopyMediaGroupTxParams struct {
+	UserID  pgtype.UUID
+	GroupID pgtype.UUID
+	// ParentID is the group the copy is created in, the copy is a top level
+	// group when it is not valid.
+	ParentID pgtype.UUID
+	// Name of the copy, the name of the group is kept when empty.
+	Name string
+}
+
+// CopyMediaGroupTx copies a group with its descendants. The copies contain the
+// same media files as the originals, no media file is duplicated.
+func (store *SQLStore) CopyMediaGroupTx(ctx context.Context, arg CopyMediaGroupTxParams) (MediaGroup, error) {
+	var result MediaGroup
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		if err := q.LockGroupTree(ctx, arg.UserID); err != nil {
+			return err
+		}
+
+		source, err := q.GetGroupForUser(ctx, GetGroupForUserParams{
+			ID:     arg.GroupID,
+			UserID: arg.UserID,
+		})
+		if err != nil {
+			return err
+		}
+
+		if arg.ParentID.Valid {
+			_, err := q.GetGroupForUser(ctx, GetGroupForUserParams{
+				ID:     arg.ParentID,
+				UserID: arg.UserID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		// The subtree is read before anything is inserted, so copying a group
+		// into one of its descendants terminates.
+		groups, err := q.ListGroupSubtree(ctx, ListGroupSubtreeParams{
+			UserID: arg.UserID,
+			Path:   source.Path,
+		})
+		if err != nil {
+			return err
+		}
+
+		copies := make(map[pgtype.UUID]pgtype.UUID, len(groups))
+		for _, group := range groups {
+			parentID := copies[group.ParentID]
+			name := group.Name
+			if group.ID == source.ID {
+				parentID = arg.ParentID
+				if arg.Name != "" {
+					name = arg.Name
+				}
+			}
+
+			copied, err := q.CreateMediaGroup(ctx, CreateMediaGroupParams{
+				UserID:   arg.UserID,
+				Name:     name,
+				ParentID: parentID,
+			})
+			if err != nil {
+				return err
+			}
+
+			err = q.CopyGroupMemberships(ctx, CopyGroupMembershipsParams{
+				TargetID: copied.ID,
+				SourceID: group.ID,
+			})
+			if err != nil {
+				return err
+			}
+
+			copies[group.ID] = copied.ID
+			if group.ID == source.ID {
+				result = copied
+			}
+		}
+
+		return nil
+	})
+
+	return result, err
+}
@@ -0,0 +1,155 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type CreateMediaFileTxParams struct {
+	CreateMediaFileParams
+	Digest     string
+	StorageKey string
+	// CRC32 is the CRC-32 checksum of the content.
+	CRC32 uint32
+	// AfterAcquireBlob is called while the blob row is locked. It must make sure
+	// the blob content exists in storage before the transaction commits, the
+	// content is best written beforehand so that this is only a cheap check.
+	AfterAcquireBlob func(blob Blob) error
+	// Quota, when set, holds the default limits enforced before the blob is
+	// acquired. Limits stored for the user take precedence.
+	Quota *QuotaLimits
+}
+
+type CreateMediaFileTxResult struct {
+	MediaFile MediaFile
+	Blob      Blob
+}
+
+// CreateMediaFileTx references the user's blob with the given digest, creating
+// it when missing, and inserts a media file pointing at it.
+func (store *SQLStore) CreateMediaFileTx(ctx context.Context, arg CreateMediaFileTxParams) (CreateMediaFileTxResult, error) {
+	var result CreateMediaFileTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		if err := q.LockBlobStorageKey(ctx, arg.StorageKey); err != nil {
+			return err
+		}
+
+		var err error
+
+		if arg.Quota != nil {
+			// Content the user already stores does not take more space.
+			requested := arg.Size
+			_, err = q.GetBlobByDigest(ctx, GetBlobByDigestParams{
+				UserID: arg.UserID,
+				Digest: arg.Digest,
+			})
+			if err == nil {
+				requested = 0
+			} else if err != pgx.ErrNoRows {
+				return err
+			}
+
+			if err := checkQuota(ctx, q, arg.UserID, requested, 1, *arg.Quota); err != nil {
+				return err
+			}
+		}
+
+		result.Blob, err = q.AcquireBlob(ctx, AcquireBlobParams{
+			UserID:     arg.UserID,
+			Digest:     arg.Digest,
+			Size:       arg.Size,
+			StorageKey: arg.StorageKey,
+			Crc32:      pgtype.Int8{Int64: int64(arg.CRC32), Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+
+		if arg.AfterAcquireBlob != nil {
+			if err := arg.AfterAcquireBlob(result.Blob); err != nil {
+				return err
+			}
+		}
+
+		mediaArg := arg.CreateMediaFileParams
+		mediaArg.BlobID = result.Blob.ID
+
+		result.MediaFile, err = q.CreateMediaFile(ctx, mediaArg)
+		return err
+	})
+
+	return result, err
+}
+
+type DeleteMediaFileTxParams struct {
+	ID pgtype.UUID
+}
+
+type DeleteMediaFileTxResult struct {
+	MediaFile MediaFile
+	Blob      Blob
+	// BlobDeleted is set when the blob row was removed, its content is left
+	// for DeleteBlobContentTx once the deletion is committed.
+	BlobDeleted bool
+}
+
+// DeleteMediaFileTx deletes a media file and drops its blob reference, removing
+// the blob once nothing points at it anymore.
+func (store *SQLStore) DeleteMediaFileTx(ctx context.Context, arg DeleteMediaFileTxParams) (DeleteMediaFileTxResult, error) {
+	var result DeleteMediaFileTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.MediaFile, err = q.DeleteMediaFile(ctx, arg.ID)
+		if err != nil {
+			return err
+		}
+
+		result.Blob, err = q.ReleaseBlob(ctx, result.MediaFile.BlobID)
+		if err != nil {
+			return err
+		}
+
+		if result.Blob.RefCount > 0 {
+			return nil
+		}
+
+		if err := q.DeleteUnreferencedBlob(ctx, result.Blob.ID); err != nil {
+			return err
+		}
+		result.BlobDeleted = true
+
+		return nil
+	})
+
+	return result, err
+}
+
+// DeleteBlobContentTx calls deleteContent to remove the content of a deleted
+// blob from storage, unless the same content was stored again since. It holds
+// the lock CreateMediaFileTx takes, so the content cannot be acquired while it
+// is being deleted.
+func (store *SQLStore) DeleteBlobContentTx(ctx context.Context, blob Blob, deleteContent func() error) error {
+	return store.execTx(ctx, func(q *Queries) error {
+		if err := q.LockBlobStorageKey(ctx, blob.StorageKey); err != nil {
+			return err
+		}
+
+		_, err := q.GetBlobByDigest(ctx, GetBlobByDigestParams{
+			UserID: blob.UserID,
+			Digest: blob.Digest,
+		})
+		if err == nil {
+			return nil
+		}
+		if err != pgx.ErrNoRows {
+			return err
+		}
+
+		return deleteContent()
+	})
+}
@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var ErrNotGroupMember = errors.New("media file does not belong to the group")
+
+type AddMediaToGroupTxParams struct {
+	UserID   pgtype.UUID
+	GroupID  pgtype.UUID
+	MediaIDs []pgtype.UUID
+}
+
+// AddMediaToGroupTx appends media files to a group in the given order. Either
+// all of them are added or, when one of them or the group does not belong to
+// the user, none is and pgx.ErrNoRows is returned.
+func (store *SQLStore) AddMediaToGroupTx(ctx context.Context, arg AddMediaToGroupTxParams) ([]GroupMembership, error) {
+	memberships := make([]GroupMembership, 0, len(arg.MediaIDs))
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		// Appending reads the last position of the group, concurrent appends
+		// and reorders would otherwise hand out the same one.
+		if err := q.LockGroupTree(ctx, arg.UserID); err != nil {
+			return err
+		}
+
+		for _, mediaID := range arg.MediaIDs {
+			membership, err := q.AssignMediaToGroup(ctx, AssignMediaToGroupParams{
+				MediaID: mediaID,
+				GroupID: arg.GroupID,
+				UserID:  arg.UserID,
+			})
+			if err != nil {
+				return err
+			}
+
+			memberships = append(memberships, membership)
+		}
+
+		return nil
+	})
+
+	return memberships, err
+}
+
+type ReorderGroupMediaTxParams struct {
+	UserID  pgtype.UUID
+	GroupID pgtype.UUID
+	// MediaIDs are moved to the front of the group in the given order, the
+	// other members follow in their current order.
+	MediaIDs []pgtype.UUID
+}
+
+// ReorderGroupMediaTx renumbers the members of a group. It fails with
+// ErrNotGroupMember when one of the media files is not in the group.
+func (store *SQLStore) ReorderGroupMediaTx(ctx context.Context, arg ReorderGroupMediaTxParams) ([]GroupMembership, error) {
+	var result []GroupMembership
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		if err := q.LockGroupTree(ctx, arg.UserID); err != nil {
+			return err
+		}
+
+		memberships, err := q.ListGroupMemberships(ctx, arg.GroupID)
+		if err != nil {
+			return err
+		}
+
+		members := make(map[pgtype.UUID]GroupMembership, len(memberships))
+		for _, membership := range memberships {
+			members[membership.MediaID] = membership
+		}
+
+		result = make([]GroupMembership, 0, len(memberships))
+		placed := make(map[pgtype.UUID]bool, len(arg.MediaIDs))
+		for _, mediaID := range arg.MediaIDs {
+			membership, ok := members[mediaID]
+			if !ok {
+				return ErrNotGroupMember
+			}
+			if placed[mediaID] {
+				continue
+			}
+
+			placed[mediaID] = true
+			result = append(result, membership)
+		}
+
+		for _, membership := range memberships {
+			if !placed[membership.MediaID] {
+				result = append(result, membership)
+			}
+		}
+
+		for i := range result {
+			position := int32(i + 1)
+			if result[i].Position == position {
+				continue
+			}
+
+			err := q.SetGroupMembershipPosition(ctx, SetGroupMembershipPositionParams{
+				GroupID:  arg.GroupID,
+				MediaID:  result[i].MediaID,
+				Position: position,
+			})
+			if err != nil {
+				return err
+			}
+
+			result[i].Position = position
+		}
+
+		return nil
+	})
+
+	return result, err
+}
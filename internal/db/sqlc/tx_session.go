@@ -0,0 +1,25 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type BlockSessionTxParams struct {
+	SessionID pgtype.UUID
+	// Audit is the event recorded for blocking the session.
+	Audit CreateAuditEventParams
+}
+
+// BlockSessionTx blocks a session and records why in the audit log.
+func (store *SQLStore) BlockSessionTx(ctx context.Context, arg BlockSessionTxParams) error {
+	return store.execTx(ctx, func(q *Queries) error {
+		if err := q.BlockSessionByID(ctx, arg.SessionID); err != nil {
+			return err
+		}
+
+		_, err := q.CreateAuditEvent(ctx, arg.Audit)
+		return err
+	})
+}
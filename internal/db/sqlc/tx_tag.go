@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type TagMediaTxParams struct {
+	UserID pgtype.UUID
+	// MediaIDs and Names must not contain duplicates.
+	MediaIDs []pgtype.UUID
+	Names    []string
+}
+
+// checkMediaOwnership fails with pgx.ErrNoRows unless every media file exists
+// outside the trash and belongs to the user.
+func checkMediaOwnership(ctx context.Context, q *Queries, userID pgtype.UUID, ids []pgtype.UUID) error {
+	count, err := q.CountMediaFilesForUser(ctx, CountMediaFilesForUserParams{
+		Ids:    ids,
+		UserID: userID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if count != int64(len(ids)) {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// TagMediaTx adds tags to media files of a user, creating the tags that do not
+// exist yet. It returns the tags.
+func (store *SQLStore) TagMediaTx(ctx context.Context, arg TagMediaTxParams) ([]Tag, error) {
+	var tags []Tag
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		if err := checkMediaOwnership(ctx, q, arg.UserID, arg.MediaIDs); err != nil {
+			return err
+		}
+
+		var err error
+		tags, err = q.UpsertTags(ctx, UpsertTagsParams{
+			UserID: arg.UserID,
+			Names:  arg.Names,
+		})
+		if err != nil {
+			return err
+		}
+
+		tagIDs := make([]pgtype.UUID, 0, len(tags))
+		for _, tag := range tags {
+			tagIDs = append(tagIDs, tag.ID)
+		}
+
+		return q.AddMediaTags(ctx, AddMediaTagsParams{
+			MediaIds: arg.MediaIDs,
+			TagIds:   tagIDs,
+		})
+	})
+
+	return tags, err
+}
+
+// UntagMediaTx removes tags from media files of a user and returns how many
+// tags were removed.
+func (store *SQLStore) UntagMediaTx(ctx context.Context, arg TagMediaTxParams) (int64, error) {
+	var removed int64
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		if err := checkMediaOwnership(ctx, q, arg.UserID, arg.MediaIDs); err != nil {
+			return err
+		}
+
+		var err error
+		removed, err = q.RemoveMediaTags(ctx, RemoveMediaTagsParams{
+			UserID:   arg.UserID,
+			Names:    arg.Names,
+			MediaIds: arg.MediaIDs,
+		})
+		return err
+	})
+
+	return removed, err
+}
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// TrashMediaGroupTx moves a group to the trash together with its descendants
+// and the media files they contain. They all share the group's deletion time,
+// which is how RestoreMediaGroupTx finds them again.
+func (store *SQLStore) TrashMediaGroupTx(ctx context.Context, id pgtype.UUID) (MediaGroup, error) {
+	var group MediaGroup
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		group, err = q.TrashMediaGroup(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		err = q.TrashGroupDescendants(ctx, TrashGroupDescendantsParams{
+			DeletedAt: group.DeletedAt,
+			UserID:    group.UserID,
+			Path:      group.Path,
+		})
+		if err != nil {
+			return err
+		}
+
+		return q.TrashMediaByGroupTree(ctx, TrashMediaByGroupTreeParams{
+			DeletedAt: group.DeletedAt,
+			UserID:    group.UserID,
+			Path:      group.Path,
+		})
+	})
+
+	return group, err
+}
+
+// RestoreMediaGroupTx takes a group out of the trash along with the groups
+// and media files that were trashed with it. Anything trashed on its own
+// before the group stays in the trash. A group whose parent is still in the
+// trash is restored at the top level.
+func (store *SQLStore) RestoreMediaGroupTx(ctx context.Context, id pgtype.UUID) (MediaGroup, error) {
+	var group MediaGroup
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		trashed, err := q.GetTrashedGroupByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := q.LockGroupTree(ctx, trashed.UserID); err != nil {
+			return err
+		}
+
+		err = q.RestoreMediaByGroupTree(ctx, RestoreMediaByGroupTreeParams{
+			UserID:    trashed.UserID,
+			Path:      trashed.Path,
+			DeletedAt: trashed.DeletedAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = q.RestoreGroupDescendants(ctx, RestoreGroupDescendantsParams{
+			UserID:    trashed.UserID,
+			Path:      trashed.Path,
+			DeletedAt: trashed.DeletedAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		if trashed.ParentID.Valid {
+			_, err := q.GetGroupForUser(ctx, GetGroupForUserParams{
+				ID:     trashed.ParentID,
+				UserID: trashed.UserID,
+			})
+			if err == pgx.ErrNoRows {
+				if err := moveGroup(ctx, q, trashed, pgtype.UUID{}, rootGroupPath(trashed.ID)); err != nil {
+					return err
+				}
+			} else if err != nil {
+				return err
+			}
+		}
+
+		group, err = q.GetGroupForUser(ctx, GetGroupForUserParams{
+			ID:     trashed.ID,
+			UserID: trashed.UserID,
+		})
+		return err
+	})
+
+	return group, err
+}
@@ -0,0 +1,43 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type AppendUploadPartTxParams struct {
+	UploadID     pgtype.UUID
+	UploadOffset int64
+	Size         int64
+	StorageKey   string
+}
+
+// AppendUploadPartTx records a stored chunk and advances the upload offset. It
+// returns pgx.ErrNoRows when the upload is no longer at the expected offset.
+func (store *SQLStore) AppendUploadPartTx(ctx context.Context, arg AppendUploadPartTxParams) (Upload, error) {
+	var upload Upload
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		upload, err = q.AdvanceUploadOffset(ctx, AdvanceUploadOffsetParams{
+			ID:           arg.UploadID,
+			UploadOffset: arg.UploadOffset,
+			Size:         arg.Size,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = q.CreateUploadPart(ctx, CreateUploadPartParams{
+			UploadID:     arg.UploadID,
+			UploadOffset: arg.UploadOffset,
+			Size:         arg.Size,
+			StorageKey:   arg.StorageKey,
+		})
+		return err
+	})
+
+	return upload, err
+}
@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// VerifyEmailTx spends a verification token, verifies the address it was
+// sent to and binds the group invitations sent to it. It returns
+// pgx.ErrNoRows when the token was already used, has expired or the user has
+// changed their address since.
+func (store *SQLStore) VerifyEmailTx(ctx context.Context, id pgtype.UUID) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		verification, err := q.UseEmailVerification(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		user, err = q.MarkUserEmailVerified(ctx, MarkUserEmailVerifiedParams{
+			ID:    verification.UserID,
+			Email: verification.Email,
+		})
+		if err != nil {
+			return err
+		}
+
+		// Group invitations sent to the address are now known to be theirs.
+		return q.ClaimGroupSharesByEmail(ctx, ClaimGroupSharesByEmailParams{
+			UserID: user.ID,
+			Email:  user.Email,
+		})
+	})
+
+	return user, err
+}
+
+type ResetPasswordTxParams struct {
+	TokenHash []byte
+	// Password is the new password, already hashed.
+	Password string
+	// Audit is the event recorded for the reset, its user is the owner of
+	// the token.
+	Audit CreateAuditEventParams
+}
+
+type ChangePasswordTxParams struct {
+	UserID pgtype.UUID
+	// Password is the new password, already hashed.
+	Password string
+	// KeepSessionID is the session the password was changed from, it stays
+	// signed in.
+	KeepSessionID pgtype.UUID
+	Audit         CreateAuditEventParams
+}
+
+type PasswordTxResult struct {
+	User User
+	// BlockedSessions are the sessions signed out by the new password.
+	BlockedSessions []pgtype.UUID
+}
+
+// ResetPasswordTx spends a password reset token, sets the new password of its
+// user and signs them out everywhere. It returns pgx.ErrNoRows when the token
+// is unknown, was already used or has expired.
+func (store *SQLStore) ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (PasswordTxResult, error) {
+	var result PasswordTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		reset, err := q.UsePasswordReset(ctx, arg.TokenHash)
+		if err != nil {
+			return err
+		}
+
+		result.User, err = q.UpdateUserPassword(ctx, UpdateUserPasswordParams{
+			ID:       reset.UserID,
+			Password: arg.Password,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := q.ExpirePasswordResetsByUser(ctx, reset.UserID); err != nil {
+			return err
+		}
+
+		result.BlockedSessions, err = q.BlockSessionsByUser(ctx, reset.UserID)
+		if err != nil {
+			return err
+		}
+
+		arg.Audit.UserID = reset.UserID
+		return createPasswordAuditEvent(ctx, q, arg.Audit, len(result.BlockedSessions))
+	})
+
+	return result, err
+}
+
+// ChangePasswordTx sets the new password of a user and signs them out of
+// every other session. Pending reset tokens are invalidated too.
+func (store *SQLStore) ChangePasswordTx(ctx context.Context, arg ChangePasswordTxParams) (PasswordTxResult, error) {
+	var result PasswordTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		result.User, err = q.UpdateUserPassword(ctx, UpdateUserPasswordParams{
+			ID:       arg.UserID,
+			Password: arg.Password,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := q.ExpirePasswordResetsByUser(ctx, arg.UserID); err != nil {
+			return err
+		}
+
+		result.BlockedSessions, err = q.BlockOtherSessionsByUser(ctx, BlockOtherSessionsByUserParams{
+			UserID: arg.UserID,
+			ID:     arg.KeepSessionID,
+		})
+		if err != nil {
+			return err
+		}
+
+		return createPasswordAuditEvent(ctx, q, arg.Audit, len(result.BlockedSessions))
+	})
+
+	return result, err
+}
+
+func createPasswordAuditEvent(ctx context.Context, q *Queries, audit CreateAuditEventParams, blocked int) error {
+	details, err := json.Marshal(map[string]int{"blocked_sessions": blocked})
+	if err != nil {
+		return err
+	}
+
+	audit.Details = details
+	_, err = q.CreateAuditEvent(ctx, audit)
+	return err
+}
@@ -0,0 +1,219 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: upload.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const advanceUploadOffset = `-- name: AdvanceUploadOffset :one
+UPDATE uploads
+SET upload_offset = upload_offset + $1::bigint, updated_at = now()
+WHERE id = $2 AND upload_offset = $3
+RETURNING id, user_id, filename, file_type, metadata, upload_length, upload_offset, media_file_id, created_at, updated_at, completed_at
+`
+
+type AdvanceUploadOffsetParams struct {
+	Size         int64       `json:"size"`
+	ID           pgtype.UUID `json:"id"`
+	UploadOffset int64       `json:"upload_offset"`
+}
+
+func (q *Queries) AdvanceUploadOffset(ctx context.Context, arg AdvanceUploadOffsetParams) (Upload, error) {
+	row := q.db.QueryRow(ctx, advanceUploadOffset, arg.Size, arg.ID, arg.UploadOffset)
+	var i Upload
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Filename,
+		&i.FileType,
+		&i.Metadata,
+		&i.UploadLength,
+		&i.UploadOffset,
+		&i.MediaFileID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CompletedAt,
+	)
+	return i, err
+}
+
+const completeUpload = `-- name: CompleteUpload :one
+UPDATE uploads
+SET media_file_id = $2, completed_at = now(), updated_at = now()
+WHERE id = $1 AND completed_at IS NULL
+RETURNING id, user_id, filename, file_type, metadata, upload_length, upload_offset, media_file_id, created_at, updated_at, completed_at
+`
+
+type CompleteUploadParams struct {
+	ID          pgtype.UUID `json:"id"`
+	MediaFileID pgtype.UUID `json:"media_file_id"`
+}
+
+func (q *Queries) CompleteUpload(ctx context.Context, arg CompleteUploadParams) (Upload, error) {
+	row := q.db.QueryRow(ctx, completeUpload, arg.ID, arg.MediaFileID)
+	var i Upload
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Filename,
+		&i.FileType,
+		&i.Metadata,
+		&i.UploadLength,
+		&i.UploadOffset,
+		&i.MediaFileID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CompletedAt,
+	)
+	return i, err
+}
+
+const createUpload = `-- name: CreateUpload :one
+INSERT INTO uploads (user_id, filename, file_type, metadata, upload_length)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, user_id, filename, file_type, metadata, upload_length, upload_offset, media_file_id, created_at, updated_at, completed_at
+`
+
+type CreateUploadParams struct {
+	UserID       pgtype.UUID `json:"user_id"`
+	Filename     string      `json:"filename"`
+	FileType     string      `json:"file_type"`
+	Metadata     string      `json:"metadata"`
+	UploadLength int64       `json:"upload_length"`
+}
+
+func (q *Queries) CreateUpload(ctx context.Context, arg CreateUploadParams) (Upload, error) {
+	row := q.db.QueryRow(ctx, createUpload,
+		arg.UserID,
+		arg.Filename,
+		arg.FileType,
+		arg.Metadata,
+		arg.UploadLength,
+	)
+	var i Upload
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Filename,
+		&i.FileType,
+		&i.Metadata,
+		&i.UploadLength,
+		&i.UploadOffset,
+		&i.MediaFileID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CompletedAt,
+	)
+	return i, err
+}
+
+const createUploadPart = `-- name: CreateUploadPart :one
+INSERT INTO upload_parts (upload_id, upload_offset, size, storage_key)
+VALUES ($1, $2, $3, $4)
+RETURNING upload_id, upload_offset, size, storage_key
+`
+
+type CreateUploadPartParams struct {
+	UploadID     pgtype.UUID `json:"upload_id"`
+	UploadOffset int64       `json:"upload_offset"`
+	Size         int64       `json:"size"`
+	StorageKey   string      `json:"storage_key"`
+}
+
+func (q *Queries) CreateUploadPart(ctx context.Context, arg CreateUploadPartParams) (UploadPart, error) {
+	row := q.db.QueryRow(ctx, createUploadPart,
+		arg.UploadID,
+		arg.UploadOffset,
+		arg.Size,
+		arg.StorageKey,
+	)
+	var i UploadPart
+	err := row.Scan(
+		&i.UploadID,
+		&i.UploadOffset,
+		&i.Size,
+		&i.StorageKey,
+	)
+	return i, err
+}
+
+const deleteUpload = `-- name: DeleteUpload :exec
+DELETE FROM uploads
+WHERE id = $1
+`
+
+func (q *Queries) DeleteUpload(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteUpload, id)
+	return err
+}
+
+const deleteUploadParts = `-- name: DeleteUploadParts :exec
+DELETE FROM upload_parts
+WHERE upload_id = $1
+`
+
+func (q *Queries) DeleteUploadParts(ctx context.Context, uploadID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteUploadParts, uploadID)
+	return err
+}
+
+const getUploadByID = `-- name: GetUploadByID :one
+SELECT id, user_id, filename, file_type, metadata, upload_length, upload_offset, media_file_id, created_at, updated_at, completed_at FROM uploads
+WHERE id = $1
+`
+
+func (q *Queries) GetUploadByID(ctx context.Context, id pgtype.UUID) (Upload, error) {
+	row := q.db.QueryRow(ctx, getUploadByID, id)
+	var i Upload
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Filename,
+		&i.FileType,
+		&i.Metadata,
+		&i.UploadLength,
+		&i.UploadOffset,
+		&i.MediaFileID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CompletedAt,
+	)
+	return i, err
+}
+
+const listUploadParts = `-- name: ListUploadParts :many
+SELECT upload_id, upload_offset, size, storage_key FROM upload_parts
+WHERE upload_id = $1
+ORDER BY upload_offset ASC
+`
+
+func (q *Queries) ListUploadParts(ctx context.Context, uploadID pgtype.UUID) ([]UploadPart, error) {
+	rows, err := q.db.Query(ctx, listUploadParts, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []UploadPart{}
+	for rows.Next() {
+		var i UploadPart
+		if err := rows.Scan(
+			&i.UploadID,
+			&i.UploadOffset,
+			&i.Size,
+			&i.StorageKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,49 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: user_quota.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const getUserQuota = `-- name: GetUserQuota :one
+SELECT user_id, max_bytes, max_files, updated_at FROM user_quotas
+WHERE user_id = $1
+`
+
+func (q *Queries) GetUserQuota(ctx context.Context, userID pgtype.UUID) (UserQuota, error) {
+	row := q.db.QueryRow(ctx, getUserQuota, userID)
+	var i UserQuota
+	err := row.Scan(
+		&i.UserID,
+		&i.MaxBytes,
+		&i.MaxFiles,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const lockUserQuota = `-- name: LockUserQuota :one
+INSERT INTO user_quotas (user_id)
+VALUES ($1)
+ON CONFLICT (user_id)
+DO UPDATE SET user_id = EXCLUDED.user_id
+RETURNING user_id, max_bytes, max_files, updated_at
+`
+
+func (q *Queries) LockUserQuota(ctx context.Context, userID pgtype.UUID) (UserQuota, error) {
+	row := q.db.QueryRow(ctx, lockUserQuota, userID)
+	var i UserQuota
+	err := row.Scan(
+		&i.UserID,
+		&i.MaxBytes,
+		&i.MaxFiles,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
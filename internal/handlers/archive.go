@@ -0,0 +1,332 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"mime"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sangketkit01/media-library-api/internal/archive"
+	"github.com/sangketkit01/media-library-api/internal/authz"
+	db "github.com/sangketkit01/media-library-api/internal/db/sqlc"
+	"github.com/sangketkit01/media-library-api/internal/util"
+)
+
+const defaultArchiveName = "media"
+
+type ArchiveMediaRequest struct {
+	MediaIDs []uuid.UUID `json:"media_ids" validate:"required,min=1,max=1000"`
+}
+
+// archiveItem is a file or folder to be put in an archive, folders have no
+// media.
+type archiveItem struct {
+	name     string
+	media    *db.MediaFile
+	modified time.Time
+}
+
+// archiveFolder hands out unique names for the entries of a folder of an
+// archive. Names are compared case insensitively, as most file systems the
+// archive is extracted on do.
+type archiveFolder struct {
+	path string
+	used map[string]bool
+}
+
+func newArchiveFolder(path string) *archiveFolder {
+	return &archiveFolder{
+		path: path,
+		used: map[string]bool{},
+	}
+}
+
+// claim returns the path of an entry named name in the folder, numbering the
+// name as in "photo (1).jpg" when it is taken already.
+func (f *archiveFolder) claim(name string) string {
+	name = archiveName(name)
+
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	if base == "" {
+		base, ext = name, ""
+	}
+
+	candidate := name
+	for n := 1; f.used[strings.ToLower(candidate)]; n++ {
+		candidate = fmt.Sprintf("%s (%d)%s", base, n, ext)
+	}
+	f.used[strings.ToLower(candidate)] = true
+
+	return f.path + candidate
+}
+
+// archiveName turns a display or group name into a single path element.
+func archiveName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || unicode.IsControl(r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+
+	if name == "" || name == "." || name == ".." {
+		return "_"
+	}
+
+	return name
+}
+
+// ArchiveMedia streams a ZIP archive of the given media files.
+func (h *Handler) ArchiveMedia(c *fiber.Ctx) error {
+	var req ArchiveMediaRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "bad request")
+	}
+
+	validator := validator.New()
+	if err := validator.Struct(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "bad request")
+	}
+
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	folder := newArchiveFolder("")
+	items := make([]archiveItem, 0, len(req.MediaIDs))
+	for _, id := range uniqueUUIDs(req.MediaIDs) {
+		media, err := h.Authz.Media(c.Context(), user, pgtype.UUID{
+			Bytes: id,
+			Valid: true,
+		}, authz.Read)
+		if err != nil {
+			return authzError(c, err, "media")
+		}
+
+		items = append(items, archiveItem{
+			name:     folder.claim(media.DisplayName),
+			media:    &media,
+			modified: media.UploadedAt.Time,
+		})
+	}
+
+	return h.sendArchive(c, defaultArchiveName, items)
+}
+
+// ArchiveGroup streams a ZIP archive of a group, with a folder for it and
+// for each of its descendants.
+func (h *Handler) ArchiveGroup(c *fiber.Ctx) error {
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	group, err := h.authorizeGroup(c, user, "id", authz.Read)
+	if err != nil {
+		return err
+	}
+
+	groups, err := h.Store.ListGroupSubtree(c.Context(), db.ListGroupSubtreeParams{
+		UserID: group.UserID,
+		Path:   group.Path,
+	})
+	if err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to retreive group.")
+	}
+
+	// Groups are listed parents first, so the folder of a parent exists by the
+	// time its children are named.
+	root := newArchiveFolder("")
+	folders := make(map[pgtype.UUID]*archiveFolder, len(groups))
+	items := make([]archiveItem, 0, len(groups))
+	groupIDs := make([]pgtype.UUID, 0, len(groups))
+
+	for _, subgroup := range groups {
+		parent, ok := folders[subgroup.ParentID]
+		if subgroup.ID == group.ID || !ok {
+			parent = root
+		}
+
+		name := parent.claim(subgroup.Name)
+		folders[subgroup.ID] = newArchiveFolder(name + "/")
+		groupIDs = append(groupIDs, subgroup.ID)
+
+		items = append(items, archiveItem{
+			name:     name,
+			modified: subgroup.CreatedAt.Time,
+		})
+	}
+
+	rows, err := h.Store.ListMediaByGroupMemberships(c.Context(), groupIDs)
+	if err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to retreive group.")
+	}
+
+	for _, row := range rows {
+		media := row.MediaFile
+		items = append(items, archiveItem{
+			name:     folders[row.GroupID].claim(media.DisplayName),
+			media:    &media,
+			modified: media.UploadedAt.Time,
+		})
+	}
+
+	return h.sendArchive(c, archiveName(group.Name), items)
+}
+
+// sendArchive streams a ZIP archive of items. Its layout is computed up front,
+// so the response has an exact length and an entity tag, and single byte
+// ranges are served to resume interrupted downloads.
+func (h *Handler) sendArchive(c *fiber.Ctx, name string, items []archiveItem) error {
+	blobIDs := make([]pgtype.UUID, 0, len(items))
+	for _, item := range items {
+		if item.media != nil {
+			blobIDs = append(blobIDs, item.media.BlobID)
+		}
+	}
+
+	blobs, err := h.Store.ListBlobsByIDs(c.Context(), blobIDs)
+	if err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to retreive media.")
+	}
+
+	blobsByID := make(map[pgtype.UUID]db.Blob, len(blobs))
+	for _, blob := range blobs {
+		blobsByID[blob.ID] = blob
+	}
+
+	// The entity tag identifies the content of the archive, which is fully
+	// determined by the names, times and blobs of its entries.
+	hasher := sha256.New()
+	src := &archiveSource{
+		ctx:   c.Context(),
+		h:     h,
+		blobs: make([]db.Blob, len(items)),
+	}
+	entries := make([]archive.Entry, 0, len(items))
+
+	for i, item := range items {
+		entry := archive.Entry{
+			Name:        item.name,
+			Modified:    item.modified,
+			IsDirectory: item.media == nil,
+		}
+
+		var digest string
+		if item.media != nil {
+			blob, ok := blobsByID[item.media.BlobID]
+			if !ok {
+				return fiber.NewError(fiber.StatusNotFound, "media file not found")
+			}
+
+			src.blobs[i] = blob
+			digest = blob.Digest
+			entry.Size = blob.Size
+			entry.CRC32 = uint32(blob.Crc32.Int64)
+			entry.HasCRC32 = blob.Crc32.Valid
+		}
+
+		hasher.Write([]byte(entry.Name))
+		hasher.Write([]byte{0})
+		hasher.Write([]byte(digest))
+		hasher.Write(binary.BigEndian.AppendUint64(nil, uint64(entry.Modified.Unix())))
+
+		entries = append(entries, entry)
+	}
+
+	manifest := archive.NewManifest(entries)
+	size := manifest.Size()
+	etag := strconv.Quote(hex.EncodeToString(hasher.Sum(nil))[:32])
+
+	setContentSecurityHeaders(c)
+	c.Set(fiber.HeaderETag, etag)
+	c.Set(fiber.HeaderAcceptRanges, "bytes")
+	c.Set(fiber.HeaderCacheControl, "private, no-cache")
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, mime.FormatMediaType(dispositionAttachment, map[string]string{
+		"filename": name + ".zip",
+	}))
+
+	r := byteRange{start: 0, length: size}
+	rangeHeader := c.Get(fiber.HeaderRange)
+	if rangeHeader != "" && c.Method() != fiber.MethodHead && ifRangeMatches(c.Get(fiber.HeaderIfRange), etag, time.Time{}) {
+		parsed, err := parseByteRanges(rangeHeader, size)
+		if err != nil {
+			if errors.Is(err, errUnsatisfiableRange) {
+				c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes */%d", size))
+				return fiber.NewError(fiber.StatusRequestedRangeNotSatisfiable, "requested range not satisfiable")
+			}
+		} else if len(parsed) == 1 {
+			r = parsed[0]
+			c.Status(fiber.StatusPartialContent)
+			c.Set(fiber.HeaderContentRange, r.contentRange(size))
+		}
+	}
+
+	if c.Method() == fiber.MethodHead {
+		c.Response().Header.SetContentLength(int(r.length))
+		return nil
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(manifest.WriteRange(pw, src, r.start, r.length))
+	}()
+
+	return c.SendStream(pr, int(r.length))
+}
+
+// archiveSource reads the content of archive entries from storage.
+type archiveSource struct {
+	ctx   context.Context
+	h     *Handler
+	blobs []db.Blob
+}
+
+func (s *archiveSource) Open(i int, offset, length int64) (io.ReadCloser, error) {
+	reader, _, err := s.h.Storage.GetRange(s.ctx, s.blobs[i].StorageKey, offset, length)
+	return reader, err
+}
+
+// Checksum computes the CRC-32 of a blob stored before checksums were kept
+// and saves it for later archives.
+func (s *archiveSource) Checksum(i int) (uint32, error) {
+	blob := s.blobs[i]
+
+	reader, _, err := s.h.Storage.Get(s.ctx, blob.StorageKey)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	checksum := crc32.NewIEEE()
+	if _, err := io.Copy(checksum, reader); err != nil {
+		return 0, err
+	}
+
+	sum := checksum.Sum32()
+	err = s.h.Store.SetBlobCRC32(s.ctx, db.SetBlobCRC32Params{
+		ID:    blob.ID,
+		Crc32: pgtype.Int8{Int64: int64(sum), Valid: true},
+	})
+	return sum, err
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sangketkit01/media-library-api/internal/authz"
+	db "github.com/sangketkit01/media-library-api/internal/db/sqlc"
+	"github.com/sangketkit01/media-library-api/internal/util"
+)
+
+// uuidParam parses the route parameter param as the id of a resource.
+func uuidParam(c *fiber.Ctx, param, resource string) (pgtype.UUID, error) {
+	id, err := uuid.Parse(c.Params(param))
+	if err != nil {
+		return pgtype.UUID{}, fiber.NewError(fiber.StatusBadRequest, "invalid "+resource+" id")
+	}
+
+	return pgtype.UUID{
+		Bytes: id,
+		Valid: true,
+	}, nil
+}
+
+// optionalUUID converts an optional id of a request, which is not valid when
+// id is nil.
+func optionalUUID(id *uuid.UUID) pgtype.UUID {
+	if id == nil {
+		return pgtype.UUID{}
+	}
+
+	return pgtype.UUID{
+		Bytes: *id,
+		Valid: true,
+	}
+}
+
+// uuidList converts the ids of a request.
+func uuidList(ids []uuid.UUID) []pgtype.UUID {
+	list := make([]pgtype.UUID, 0, len(ids))
+	for _, id := range ids {
+		list = append(list, pgtype.UUID{
+			Bytes: id,
+			Valid: true,
+		})
+	}
+
+	return list
+}
+
+// uuidPtr converts an optional id for a response, which is nil when id is not
+// valid.
+func uuidPtr(id pgtype.UUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+
+	value := uuid.UUID(id.Bytes)
+	return &value
+}
+
+// authzError turns an error of the authorization policy into a response.
+// Resources of other users are reported as missing.
+func authzError(c *fiber.Ctx, err error, resource string) error {
+	if errors.Is(err, authz.ErrNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, resource+" not found")
+	}
+
+	util.RouteCustomError(err, c.Path())
+	return fiber.NewError(fiber.StatusInternalServerError, "failed to retreive "+resource+".")
+}
+
+// authorizeMedia loads the media file named by the route parameter param if
+// the user may perform action on it.
+func (h *Handler) authorizeMedia(c *fiber.Ctx, user db.User, param string, action authz.Action) (db.MediaFile, error) {
+	id, err := uuidParam(c, param, "media")
+	if err != nil {
+		return db.MediaFile{}, err
+	}
+
+	media, err := h.Authz.Media(c.Context(), user, id, action)
+	if err != nil {
+		return db.MediaFile{}, authzError(c, err, "media")
+	}
+
+	return media, nil
+}
+
+// authorizeGroup loads the group named by the route parameter param if the
+// user may perform action on it.
+func (h *Handler) authorizeGroup(c *fiber.Ctx, user db.User, param string, action authz.Action) (db.MediaGroup, error) {
+	id, err := uuidParam(c, param, "group")
+	if err != nil {
+		return db.MediaGroup{}, err
+	}
+
+	group, err := h.Authz.Group(c.Context(), user, id, action)
+	if err != nil {
+		return db.MediaGroup{}, authzError(c, err, "group")
+	}
+
+	return group, nil
+}
@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sangketkit01/media-library-api/internal/authz"
+	db "github.com/sangketkit01/media-library-api/internal/db/sqlc"
+	"github.com/sangketkit01/media-library-api/internal/token"
+)
+
+// testUserHeader names the user a test request is authenticated as.
+const testUserHeader = "X-Test-User"
+
+// authzStore holds users, their media and groups, and the roles of group
+// shares. Calling any other method of db.Store panics.
+type authzStore struct {
+	db.Store
+
+	users  map[uuid.UUID]db.User
+	owners map[uuid.UUID]uuid.UUID
+	groups map[uuid.UUID]bool
+	// roles maps a resource to the share roles of users.
+	roles map[uuid.UUID]map[uuid.UUID]string
+	err   error
+}
+
+func newAuthzStore() *authzStore {
+	return &authzStore{
+		users:  make(map[uuid.UUID]db.User),
+		owners: make(map[uuid.UUID]uuid.UUID),
+		groups: make(map[uuid.UUID]bool),
+		roles:  make(map[uuid.UUID]map[uuid.UUID]string),
+	}
+}
+
+func (s *authzStore) addUser() db.User {
+	user := db.User{ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}}
+	s.users[user.ID.Bytes] = user
+	return user
+}
+
+func (s *authzStore) add(owner db.User, group bool) uuid.UUID {
+	id := uuid.New()
+	s.owners[id] = owner.ID.Bytes
+	s.groups[id] = group
+	return id
+}
+
+func (s *authzStore) share(id uuid.UUID, user db.User, role string) {
+	if s.roles[id] == nil {
+		s.roles[id] = make(map[uuid.UUID]string)
+	}
+	s.roles[id][user.ID.Bytes] = role
+}
+
+func (s *authzStore) lookup(id, userID pgtype.UUID, group, shared, editorOnly bool) (uuid.UUID, error) {
+	if s.err != nil {
+		return uuid.UUID{}, s.err
+	}
+
+	owner, ok := s.owners[id.Bytes]
+	if !ok || s.groups[id.Bytes] != group {
+		return uuid.UUID{}, pgx.ErrNoRows
+	}
+
+	if !shared {
+		if owner != userID.Bytes {
+			return uuid.UUID{}, pgx.ErrNoRows
+		}
+		return owner, nil
+	}
+
+	role, ok := s.roles[id.Bytes][userID.Bytes]
+	if !ok || (editorOnly && role != "editor") {
+		return uuid.UUID{}, pgx.ErrNoRows
+	}
+	return owner, nil
+}
+
+func (s *authzStore) GetUserByID(ctx context.Context, id pgtype.UUID) (db.User, error) {
+	user, ok := s.users[id.Bytes]
+	if !ok {
+		return db.User{}, pgx.ErrNoRows
+	}
+	return user, nil
+}
+
+func (s *authzStore) GetMediaFileForUser(ctx context.Context, arg db.GetMediaFileForUserParams) (db.MediaFile, error) {
+	owner, err := s.lookup(arg.ID, arg.UserID, false, false, false)
+	return db.MediaFile{ID: arg.ID, UserID: pgtype.UUID{Bytes: owner, Valid: err == nil}}, err
+}
+
+func (s *authzStore) GetSharedMediaFileForUser(ctx context.Context, arg db.GetSharedMediaFileForUserParams) (db.MediaFile, error) {
+	owner, err := s.lookup(arg.ID, arg.UserID, false, true, arg.EditorOnly)
+	return db.MediaFile{ID: arg.ID, UserID: pgtype.UUID{Bytes: owner, Valid: err == nil}}, err
+}
+
+func (s *authzStore) GetGroupForUser(ctx context.Context, arg db.GetGroupForUserParams) (db.MediaGroup, error) {
+	owner, err := s.lookup(arg.ID, arg.UserID, true, false, false)
+	return db.MediaGroup{ID: arg.ID, UserID: pgtype.UUID{Bytes: owner, Valid: err == nil}}, err
+}
+
+func (s *authzStore) GetSharedGroupForUser(ctx context.Context, arg db.GetSharedGroupForUserParams) (db.MediaGroup, error) {
+	owner, err := s.lookup(arg.ID, arg.UserID, true, true, arg.EditorOnly)
+	return db.MediaGroup{ID: arg.ID, UserID: pgtype.UUID{Bytes: owner, Valid: err == nil}}, err
+}
+
+// newAuthzApp serves authorization checks of every action, next to a few
+// endpoints, for users named by testUserHeader.
+func newAuthzApp(store db.Store) *fiber.App {
+	h := &Handler{Store: store, Authz: authz.NewPolicy(store)}
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		if id, err := uuid.Parse(c.Get(testUserHeader)); err == nil {
+			c.Locals("payload", &token.Payload{ID: id})
+		}
+		return c.Next()
+	})
+
+	actions := map[string]authz.Action{"read": authz.Read, "write": authz.Write, "own": authz.Own}
+	app.Get("/check/media/:id/:action", func(c *fiber.Ctx) error {
+		user, err := h.getCurrentUser(c)
+		if err != nil {
+			return err
+		}
+		if _, err := h.authorizeMedia(c, user, "id", actions[c.Params("action")]); err != nil {
+			return err
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+	app.Get("/check/groups/:id/:action", func(c *fiber.Ctx) error {
+		user, err := h.getCurrentUser(c)
+		if err != nil {
+			return err
+		}
+		if _, err := h.authorizeGroup(c, user, "id", actions[c.Params("action")]); err != nil {
+			return err
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	app.Get("/media/:id", h.GetMedia)
+	app.Delete("/media/:id", h.DeleteMedia)
+	app.Get("/groups/:id", h.GetGroup)
+	app.Delete("/groups/:id", h.DeleteGroup)
+	return app
+}
+
+func requestAs(t *testing.T, app *fiber.App, user db.User, method, path string) (int, string) {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set(testUserHeader, uuid.UUID(user.ID.Bytes).String())
+	res, body := doRequest(t, app, req)
+	return res.StatusCode, body
+}
+
+func TestAuthorizeSharedResources(t *testing.T) {
+	store := newAuthzStore()
+	app := newAuthzApp(store)
+	owner, viewer, editor, stranger := store.addUser(), store.addUser(), store.addUser(), store.addUser()
+
+	for _, resource := range []string{"media", "groups"} {
+		id := store.add(owner, resource == "groups")
+		store.share(id, viewer, "viewer")
+		store.share(id, editor, "editor")
+
+		tests := []struct {
+			name string
+			user db.User
+			// allowed holds the actions the user may perform, the others
+			// are answered as if the resource did not exist.
+			allowed map[string]bool
+		}{
+			{"owner", owner, map[string]bool{"read": true, "write": true, "own": true}},
+			{"viewer", viewer, map[string]bool{"read": true}},
+			{"editor", editor, map[string]bool{"read": true, "write": true}},
+			{"stranger", stranger, map[string]bool{}},
+		}
+
+		for _, tt := range tests {
+			for _, action := range []string{"read", "write", "own"} {
+				status, _ := requestAs(t, app, tt.user, fiber.MethodGet, "/check/"+resource+"/"+id.String()+"/"+action)
+
+				want := fiber.StatusNotFound
+				if tt.allowed[action] {
+					want = fiber.StatusNoContent
+				}
+				if status != want {
+					t.Errorf("%s %s %s: status = %d, want %d", tt.name, action, resource, status, want)
+				}
+			}
+		}
+	}
+}
+
+func TestForeignResourcesAreNotFound(t *testing.T) {
+	store := newAuthzStore()
+	app := newAuthzApp(store)
+	owner, stranger := store.addUser(), store.addUser()
+
+	media := store.add(owner, false).String()
+	group := store.add(owner, true).String()
+	missing := uuid.New().String()
+
+	tests := []struct {
+		method, path, message string
+	}{
+		{fiber.MethodGet, "/media/" + media, "media not found"},
+		{fiber.MethodDelete, "/media/" + media, "media not found"},
+		{fiber.MethodGet, "/groups/" + group, "group not found"},
+		{fiber.MethodDelete, "/groups/" + group, "group not found"},
+		// A group id does not name media, nor the other way around.
+		{fiber.MethodGet, "/media/" + group, "media not found"},
+		{fiber.MethodGet, "/groups/" + media, "group not found"},
+	}
+
+	for _, tt := range tests {
+		status, body := requestAs(t, app, stranger, tt.method, tt.path)
+		if status != fiber.StatusNotFound || body != tt.message {
+			t.Errorf("%s %s: %d %q, want 404 %q", tt.method, tt.path, status, body, tt.message)
+		}
+	}
+
+	// Foreign resources cannot be told apart from missing ones.
+	for _, path := range []string{"/media/", "/groups/"} {
+		status, body := requestAs(t, app, stranger, fiber.MethodGet, path+missing)
+		if status != fiber.StatusNotFound {
+			t.Errorf("GET %s: %d %q, want 404", path+missing, status, body)
+		}
+	}
+
+	status, _ := requestAs(t, app, owner, fiber.MethodGet, "/media/"+media)
+	if status != fiber.StatusOK {
+		t.Errorf("owner GET media: status = %d, want 200", status)
+	}
+}
+
+func TestAuthorizeErrors(t *testing.T) {
+	store := newAuthzStore()
+	app := newAuthzApp(store)
+	user := store.addUser()
+	media := store.add(user, false).String()
+
+	status, _ := requestAs(t, app, user, fiber.MethodGet, "/media/not-a-uuid")
+	if status != fiber.StatusBadRequest {
+		t.Errorf("invalid id: status = %d, want 400", status)
+	}
+
+	// Failures are not passed off as missing resources.
+	store.err = errors.New("connection refused")
+	status, body := requestAs(t, app, user, fiber.MethodGet, "/media/"+media)
+	if status != fiber.StatusInternalServerError {
+		t.Errorf("store failure: %d %q, want 500", status, body)
+	}
+}
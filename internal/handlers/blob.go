@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash/crc32"
+	"io"
+	"log"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/sangketkit01/media-library-api/internal/db/sqlc"
+	"github.com/sangketkit01/media-library-api/internal/sniff"
+	"github.com/sangketkit01/media-library-api/internal/storage"
+)
+
+// blobStorageKey returns the storage key of a user's blob with the given
+// SHA-256 hex digest.
+func blobStorageKey(userID pgtype.UUID, digest string) string {
+	return userID.String() + "/blobs/" + digest[:2] + "/" + digest
+}
+
+// createMediaFile hashes the content returned by open and stores a media file
+// referencing the matching blob. The content type and the CRC-32 used by ZIP
+// archives are computed in the same pass, the type is checked against the
+// upload policy of the user's tier, replacing the FileType of arg.
+//
+// The content is written under its digest key before the transaction, so that
+// no lock is held while it streams to storage. Writing the same key twice is
+// harmless as the key is derived from the content. Under the lock the content
+// is only looked up, and written again when a concurrent deletion removed it.
+// open may be called up to three times.
+func (h *Handler) createMediaFile(ctx context.Context, tier string, arg db.CreateMediaFileParams, open func() (io.ReadCloser, error)) (db.MediaFile, error) {
+	src, err := open()
+	if err != nil {
+		return db.MediaFile{}, err
+	}
+
+	hasher := sha256.New()
+	checksum := crc32.NewIEEE()
+	sniffer := &sniff.Sniffer{}
+	size, err := io.Copy(io.MultiWriter(hasher, checksum, sniffer), src)
+	src.Close()
+	if err != nil {
+		return db.MediaFile{}, err
+	}
+
+	arg.DetectedType = sniffer.Type()
+	arg.FileType, err = h.Policy.Check(tier, arg.DeclaredType, arg.DetectedType)
+	if err != nil {
+		return db.MediaFile{}, err
+	}
+
+	digest := hex.EncodeToString(hasher.Sum(nil))
+	storageKey := blobStorageKey(arg.UserID, digest)
+	arg.Size = size
+
+	putContent := func(key string) error {
+		src, err := open()
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+
+		return h.Storage.Put(ctx, key, src, size, arg.FileType)
+	}
+
+	// Identical bytes stored by the user are not written again.
+	written := false
+	if _, err := h.Storage.Stat(ctx, storageKey); errors.Is(err, storage.ErrNotFound) {
+		if err := putContent(storageKey); err != nil {
+			return db.MediaFile{}, err
+		}
+		written = true
+	} else if err != nil {
+		return db.MediaFile{}, err
+	}
+
+	result, err := h.Store.CreateMediaFileTx(ctx, db.CreateMediaFileTxParams{
+		CreateMediaFileParams: arg,
+		Digest:                digest,
+		StorageKey:            storageKey,
+		CRC32:                 checksum.Sum32(),
+		Quota:                 h.defaultQuota(),
+		AfterAcquireBlob: func(blob db.Blob) error {
+			_, err := h.Storage.Stat(ctx, blob.StorageKey)
+			if errors.Is(err, storage.ErrNotFound) {
+				return putContent(blob.StorageKey)
+			}
+			return err
+		},
+	})
+	if err != nil {
+		// Content written for a rejected upload is removed again, unless a
+		// concurrent upload of the same bytes references it by now.
+		if written {
+			orphan := db.Blob{UserID: arg.UserID, Digest: digest, StorageKey: storageKey}
+			delErr := h.Store.DeleteBlobContentTx(ctx, orphan, func() error {
+				err := h.Storage.Delete(ctx, storageKey)
+				if err != nil && !errors.Is(err, storage.ErrNotFound) {
+					return err
+				}
+				return nil
+			})
+			if delErr != nil {
+				log.Printf("failed to clean up blob %s: %v\n", storageKey, delErr)
+			}
+		}
+		return db.MediaFile{}, err
+	}
+
+	h.Pipeline.Enqueue(result.MediaFile.ID)
+
+	return result.MediaFile, nil
+}
+
+// deleteMediaFile deletes a media file together with its derivatives and
+// removes its blob content from storage once no other media file references it.
+// Content is only deleted after the rows are, an object left behind by a
+// failure takes space but a row without content would be lost.
+func (h *Handler) deleteMediaFile(ctx context.Context, id pgtype.UUID) error {
+	// Derivative rows are removed by the cascade, their content is not.
+	derivatives, err := h.Store.ListMediaDerivatives(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	result, err := h.Store.DeleteMediaFileTx(ctx, db.DeleteMediaFileTxParams{
+		ID: id,
+	})
+	if err != nil {
+		return err
+	}
+
+	if result.BlobDeleted {
+		err := h.Store.DeleteBlobContentTx(ctx, result.Blob, func() error {
+			err := h.Storage.Delete(ctx, result.Blob.StorageKey)
+			if err != nil && !errors.Is(err, storage.ErrNotFound) {
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, derivative := range derivatives {
+		if err := h.Storage.Delete(ctx, derivative.StorageKey); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+	}
+
+	return nil
+}
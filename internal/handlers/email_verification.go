@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/sangketkit01/media-library-api/internal/db/sqlc"
+	"github.com/sangketkit01/media-library-api/internal/mailer"
+	"github.com/sangketkit01/media-library-api/internal/util"
+)
+
+const verifyEmailPurpose = "verify-email"
+
+const resendVerificationMessage = "If the account exists and is not verified yet, a verification email has been sent."
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// startEmailVerification issues a new verification token for the address of
+// a user, invalidating earlier ones, and mails it to them.
+func (h *Handler) startEmailVerification(ctx context.Context, user db.User) error {
+	if err := h.Store.ExpireEmailVerificationsByUser(ctx, user.ID); err != nil {
+		return err
+	}
+
+	verification, err := h.Store.CreateEmailVerification(ctx, db.CreateEmailVerificationParams{
+		UserID: user.ID,
+		Email:  user.Email,
+		ExpiresAt: pgtype.Timestamptz{
+			Time:  time.Now().Add(h.Config.EmailVerificationTTL),
+			Valid: true,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	token := util.SignToken(h.Config.Secretkey, verifyEmailPurpose, verification.ID.Bytes[:])
+
+	text := fmt.Sprintf("Use this code to verify your email address:\n\n%s\n", token)
+	if h.Config.EmailVerificationURL != "" {
+		link := h.Config.EmailVerificationURL + "?token=" + url.QueryEscape(token)
+		text = fmt.Sprintf("Open this link to verify your email address:\n\n%s\n", link)
+	}
+	text += fmt.Sprintf("\nIt expires in %v. If you did not sign up, ignore this email.\n", h.Config.EmailVerificationTTL)
+
+	h.sendMail(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Text:    text,
+	})
+
+	return nil
+}
+
+// sendMail delivers a message in the background, so that requests neither
+// wait for the mail server nor tell by their timing whether mail was sent.
+func (h *Handler) sendMail(msg mailer.Message) {
+	go func() {
+		if err := h.Mailer.Send(context.Background(), msg); err != nil {
+			log.Printf("Failed to send %q to %s: %v\n", msg.Subject, msg.To, err)
+		}
+	}()
+}
+
+// VerifyEmail spends a verification token and marks the address it was sent
+// to as verified.
+func (h *Handler) VerifyEmail(c *fiber.Ctx) error {
+	var req VerifyEmailRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "bad request")
+	}
+
+	validator := validator.New()
+	if err := validator.Struct(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "verification token is not provided.")
+	}
+
+	data, err := util.VerifySignedToken(h.Config.Secretkey, verifyEmailPurpose, req.Token)
+	if err != nil || len(data) != 16 {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid verification token")
+	}
+
+	var verificationID pgtype.UUID
+	copy(verificationID.Bytes[:], data)
+	verificationID.Valid = true
+
+	user, err := h.Store.VerifyEmailTx(c.Context(), verificationID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fiber.NewError(fiber.StatusBadRequest, "verification token was already used or has expired")
+		}
+
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot verify email")
+	}
+
+	return c.JSON(fiber.Map{
+		"message":           "Verified email successfully.",
+		"email":             user.Email,
+		"email_verified_at": user.EmailVerifiedAt.Time,
+	})
+}
+
+// ResendVerification mails a new verification token. It answers the same
+// whether or not the address belongs to an unverified account, and sends at
+// most one email per cooldown.
+func (h *Handler) ResendVerification(c *fiber.Ctx) error {
+	var req ResendVerificationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "bad request")
+	}
+
+	validator := validator.New()
+	if err := validator.Struct(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid email")
+	}
+
+	accepted := func() error {
+		return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"message": resendVerificationMessage})
+	}
+
+	user, err := h.Store.GetUserByEmail(c.Context(), req.Email)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return accepted()
+		}
+
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot resend verification")
+	}
+
+	if user.EmailVerifiedAt.Valid {
+		return accepted()
+	}
+
+	latest, err := h.Store.GetLatestEmailVerification(c.Context(), user.ID)
+	if err != nil && err != pgx.ErrNoRows {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot resend verification")
+	}
+
+	if err == nil && time.Since(latest.CreatedAt.Time) < h.Config.EmailVerificationCooldown {
+		return accepted()
+	}
+
+	if err := h.startEmailVerification(c.Context(), user); err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot resend verification")
+	}
+
+	return accepted()
+}
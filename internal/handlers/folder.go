@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/sangketkit01/media-library-api/internal/authz"
+	db "github.com/sangketkit01/media-library-api/internal/db/sqlc"
+	"github.com/sangketkit01/media-library-api/internal/util"
+)
+
+type MoveGroupRequest struct {
+	// ParentID is the new parent group, null moves the group to the top level.
+	ParentID *uuid.UUID `json:"parent_id"`
+}
+
+type CopyGroupRequest struct {
+	ParentID *uuid.UUID `json:"parent_id"`
+	Name     string     `json:"name"`
+}
+
+type Breadcrumb struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+}
+
+type GroupChildrenResponse struct {
+	Group       GroupResponse   `json:"group"`
+	Breadcrumbs []Breadcrumb    `json:"breadcrumbs"`
+	Children    []GroupResponse `json:"children"`
+}
+
+// groupTreeError turns an error of a folder tree transaction into a response.
+func groupTreeError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case err == pgx.ErrNoRows:
+		return fiber.NewError(fiber.StatusNotFound, "group not found")
+	case errors.Is(err, db.ErrGroupCycle):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	case isUniqueViolation(err):
+		return fiber.NewError(fiber.StatusConflict, "Group name is already exists.")
+	}
+
+	util.RouteCustomError(err, c.Path())
+	return fiber.NewError(fiber.StatusInternalServerError, message)
+}
+
+func (h *Handler) MoveGroup(c *fiber.Ctx) error {
+	var req MoveGroupRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "bad request")
+	}
+
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	group, err := h.authorizeGroup(c, user, "id", authz.Own)
+	if err != nil {
+		return err
+	}
+
+	if req.ParentID != nil {
+		if _, err := h.Authz.Group(c.Context(), user, optionalUUID(req.ParentID), authz.Own); err != nil {
+			return authzError(c, err, "parent group")
+		}
+	}
+
+	group, err = h.Store.MoveMediaGroupTx(c.Context(), db.MoveMediaGroupTxParams{
+		UserID:   user.ID,
+		GroupID:  group.ID,
+		ParentID: optionalUUID(req.ParentID),
+	})
+	if err != nil {
+		return groupTreeError(c, err, "failed to move group.")
+	}
+
+	return h.sendGroup(c, group)
+}
+
+func (h *Handler) CopyGroup(c *fiber.Ctx) error {
+	var req CopyGroupRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "bad request")
+	}
+
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	group, err := h.authorizeGroup(c, user, "id", authz.Own)
+	if err != nil {
+		return err
+	}
+
+	if req.ParentID != nil {
+		if _, err := h.Authz.Group(c.Context(), user, optionalUUID(req.ParentID), authz.Own); err != nil {
+			return authzError(c, err, "parent group")
+		}
+	}
+
+	copied, err := h.Store.CopyMediaGroupTx(c.Context(), db.CopyMediaGroupTxParams{
+		UserID:   user.ID,
+		GroupID:  group.ID,
+		ParentID: optionalUUID(req.ParentID),
+		Name:     strings.TrimSpace(req.Name),
+	})
+	if err != nil {
+		return groupTreeError(c, err, "failed to copy group.")
+	}
+
+	c.Status(fiber.StatusCreated)
+	return h.sendGroup(c, copied)
+}
+
+// GetGroupChildren lists the groups directly inside a group together with the
+// breadcrumbs leading to it from the top level.
+func (h *Handler) GetGroupChildren(c *fiber.Ctx) error {
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	group, err := h.authorizeGroup(c, user, "id", authz.Read)
+	if err != nil {
+		return err
+	}
+
+	stats, err := h.Store.GetGroupStats(c.Context(), group.ID)
+	if err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to retreive group.")
+	}
+
+	ancestors, err := h.Store.ListGroupAncestors(c.Context(), db.ListGroupAncestorsParams{
+		UserID: group.UserID,
+		Path:   group.Path,
+	})
+	if err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to retreive group.")
+	}
+
+	children, err := h.Store.ListChildGroups(c.Context(), group.ID)
+	if err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to retreive group.")
+	}
+
+	response := GroupChildrenResponse{
+		Group: GroupResponse{
+			ID:         group.ID.Bytes,
+			UserID:     group.UserID.Bytes,
+			ParentID:   uuidPtr(group.ParentID),
+			Path:       group.Path,
+			Name:       group.Name,
+			CreatedAt:  group.CreatedAt.Time,
+			MediaCount: stats.MediaCount,
+			TotalSize:  stats.TotalSize,
+		},
+		Breadcrumbs: make([]Breadcrumb, 0, len(ancestors)),
+		Children:    make([]GroupResponse, 0, len(children)),
+	}
+
+	// Breadcrumbs of a shared group start at the group that was shared, the
+	// groups of the owner above it stay hidden.
+	if group.UserID != user.ID {
+		for len(ancestors) > 1 {
+			_, err := h.Authz.Group(c.Context(), user, ancestors[0].ID, authz.Read)
+			if err == nil {
+				break
+			}
+			if !errors.Is(err, authz.ErrNotFound) {
+				return authzError(c, err, "group")
+			}
+
+			ancestors = ancestors[1:]
+		}
+	}
+
+	for _, ancestor := range ancestors {
+		response.Breadcrumbs = append(response.Breadcrumbs, Breadcrumb{
+			ID:   ancestor.ID.Bytes,
+			Name: ancestor.Name,
+		})
+	}
+
+	for _, child := range children {
+		response.Children = append(response.Children, GroupResponse{
+			ID:         child.ID.Bytes,
+			UserID:     child.UserID.Bytes,
+			ParentID:   uuidPtr(child.ParentID),
+			Path:       child.Path,
+			Name:       child.Name,
+			CreatedAt:  child.CreatedAt.Time,
+			MediaCount: child.MediaCount,
+			TotalSize:  child.TotalSize,
+		})
+	}
+
+	return c.JSON(response)
+}
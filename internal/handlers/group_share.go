@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sangketkit01/media-library-api/internal/authz"
+	db "github.com/sangketkit01/media-library-api/internal/db/sqlc"
+	"github.com/sangketkit01/media-library-api/internal/mailer"
+	"github.com/sangketkit01/media-library-api/internal/util"
+)
+
+type InviteGroupShareRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	// Role is viewer, who may read the group, its descendants and their media,
+	// or editor, who may also rename the groups, change their members and edit
+	// the details of their media.
+	Role string `json:"role" validate:"required,oneof=viewer editor"`
+}
+
+// GroupShareResponse only tells who the invitee is once they accept, so that
+// it does not reveal whether the invited email has an account.
+type GroupShareResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	GroupID    uuid.UUID  `json:"group_id"`
+	UserID     *uuid.UUID `json:"user_id"`
+	Email      string     `json:"email"`
+	Role       string     `json:"role"`
+	AcceptedAt *time.Time `json:"accepted_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type SharedWithMeResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	GroupID    uuid.UUID  `json:"group_id"`
+	GroupName  string     `json:"group_name"`
+	OwnerID    uuid.UUID  `json:"owner_id"`
+	OwnerEmail string     `json:"owner_email"`
+	Role       string     `json:"role"`
+	AcceptedAt *time.Time `json:"accepted_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func newGroupShareResponse(share db.GroupShare) GroupShareResponse {
+	var userID *uuid.UUID
+	if share.AcceptedAt.Valid {
+		userID = uuidPtr(share.UserID)
+	}
+
+	return GroupShareResponse{
+		ID:         share.ID.Bytes,
+		GroupID:    share.GroupID.Bytes,
+		UserID:     userID,
+		Email:      share.Email,
+		Role:       share.Role,
+		AcceptedAt: timePtr(share.AcceptedAt),
+		CreatedAt:  share.CreatedAt.Time,
+	}
+}
+
+// InviteToGroup shares a group with the owner of an email, who has to accept
+// the invitation first. Inviting an email again changes its role. It answers
+// the same whether or not the email has an account: the invitation waits until
+// an account verifies the email.
+func (h *Handler) InviteToGroup(c *fiber.Ctx) error {
+	var req InviteGroupShareRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "bad request")
+	}
+
+	validator := validator.New()
+	req.Email = strings.TrimSpace(req.Email)
+	if err := validator.Struct(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "bad request")
+	}
+
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	group, err := h.authorizeGroup(c, user, "id", authz.Own)
+	if err != nil {
+		return err
+	}
+
+	if req.Email == user.Email {
+		return fiber.NewError(fiber.StatusBadRequest, "cannot share a group with yourself")
+	}
+
+	// Only an account that proved it owns the email gets the invitation,
+	// VerifyEmailTx binds it otherwise.
+	var inviteeID pgtype.UUID
+	invitee, err := h.Store.GetUserByEmail(c.Context(), req.Email)
+	if err != nil && err != pgx.ErrNoRows {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to share group.")
+	}
+	if err == nil && invitee.EmailVerifiedAt.Valid {
+		inviteeID = invitee.ID
+	}
+
+	share, err := h.Store.CreateGroupShare(c.Context(), db.CreateGroupShareParams{
+		GroupID: group.ID,
+		OwnerID: user.ID,
+		UserID:  inviteeID,
+		Email:   req.Email,
+		Role:    req.Role,
+	})
+	if err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to share group.")
+	}
+
+	h.sendMail(mailer.Message{
+		To:      req.Email,
+		Subject: "A group was shared with you",
+		Text: fmt.Sprintf("%s invited you to the group %q as %s.\n\nSign in, or sign up and verify this email address, to accept the invitation.\n",
+			user.Email, group.Name, req.Role),
+	})
+
+	return c.Status(fiber.StatusCreated).JSON(newGroupShareResponse(share))
+}
+
+func (h *Handler) ListGroupShares(c *fiber.Ctx) error {
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	group, err := h.authorizeGroup(c, user, "id", authz.Own)
+	if err != nil {
+		return err
+	}
+
+	shares, err := h.Store.ListGroupSharesByGroup(c.Context(), group.ID)
+	if err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to retreive group shares.")
+	}
+
+	response := make([]GroupShareResponse, 0, len(shares))
+	for _, share := range shares {
+		response = append(response, newGroupShareResponse(share))
+	}
+
+	return c.JSON(response)
+}
+
+func (h *Handler) RevokeGroupShare(c *fiber.Ctx) error {
+	shareID, err := uuidParam(c, "share_id", "share")
+	if err != nil {
+		return err
+	}
+
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	group, err := h.authorizeGroup(c, user, "id", authz.Own)
+	if err != nil {
+		return err
+	}
+
+	_, err = h.Store.RevokeGroupShare(c.Context(), db.RevokeGroupShareParams{
+		ID:      shareID,
+		GroupID: group.ID,
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fiber.NewError(fiber.StatusNotFound, "share not found")
+		}
+
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to revoke share.")
+	}
+
+	return c.JSON(fiber.Map{"message": "Revoked share successfully."})
+}
+
+// ListSharedWithMe lists the groups shared with the current user, pending
+// invitations included.
+func (h *Handler) ListSharedWithMe(c *fiber.Ctx) error {
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	shares, err := h.Store.ListGroupSharesByUser(c.Context(), user.ID)
+	if err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to retreive shared groups.")
+	}
+
+	response := make([]SharedWithMeResponse, 0, len(shares))
+	for _, share := range shares {
+		response = append(response, SharedWithMeResponse{
+			ID:         share.ID.Bytes,
+			GroupID:    share.GroupID.Bytes,
+			GroupName:  share.GroupName,
+			OwnerID:    share.OwnerID.Bytes,
+			OwnerEmail: share.OwnerEmail,
+			Role:       share.Role,
+			AcceptedAt: timePtr(share.AcceptedAt),
+			CreatedAt:  share.CreatedAt.Time,
+		})
+	}
+
+	return c.JSON(response)
+}
+
+func (h *Handler) AcceptGroupShare(c *fiber.Ctx) error {
+	shareID, err := uuidParam(c, "id", "share")
+	if err != nil {
+		return err
+	}
+
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	share, err := h.Store.AcceptGroupShare(c.Context(), db.AcceptGroupShareParams{
+		ID:     shareID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fiber.NewError(fiber.StatusNotFound, "share not found")
+		}
+
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to accept share.")
+	}
+
+	return c.JSON(newGroupShareResponse(share))
+}
+
+// LeaveGroupShare declines an invitation or gives up access to a shared group.
+func (h *Handler) LeaveGroupShare(c *fiber.Ctx) error {
+	shareID, err := uuidParam(c, "id", "share")
+	if err != nil {
+		return err
+	}
+
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	_, err = h.Store.LeaveGroupShare(c.Context(), db.LeaveGroupShareParams{
+		ID:     shareID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fiber.NewError(fiber.StatusNotFound, "share not found")
+		}
+
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to leave share.")
+	}
+
+	return c.JSON(fiber.Map{"message": "Left shared group successfully."})
+}
@@ -4,17 +4,33 @@ import (
 	"context"
 	"time"
 
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sangketkit01/media-library-api/internal/authz"
 	"github.com/sangketkit01/media-library-api/internal/config"
 	db "github.com/sangketkit01/media-library-api/internal/db/sqlc"
+	"github.com/sangketkit01/media-library-api/internal/mailer"
+	"github.com/sangketkit01/media-library-api/internal/pipeline"
+	"github.com/sangketkit01/media-library-api/internal/revocation"
+	"github.com/sangketkit01/media-library-api/internal/sniff"
+	"github.com/sangketkit01/media-library-api/internal/storage"
 	"github.com/sangketkit01/media-library-api/internal/token"
+	"github.com/sangketkit01/media-library-api/internal/util"
 )
 
 type Handler struct {
 	Store      db.Store
 	Config     *config.Config
 	tokenMaker token.Maker
-	Pool *pgxpool.Pool
+	Pool       *pgxpool.Pool
+	Storage    storage.Backend
+	Pipeline   *pipeline.Pipeline
+	Policy     *sniff.Policy
+	Authz      *authz.Policy
+	Sessions   *revocation.Cache
+	Mailer     mailer.Sender
 }
 
 func NewHandler(config *config.Config, tokenMaker token.Maker) (*Handler, error) {
@@ -31,7 +47,7 @@ func NewHandler(config *config.Config, tokenMaker token.Maker) (*Handler, error)
 	cfg.MaxConnLifetime = 30 * time.Minute
 
 	pool, err := pgxpool.NewWithConfig(ctx, cfg)
-	if err != nil{
+	if err != nil {
 		return nil, err
 	}
 
@@ -39,12 +55,65 @@ func NewHandler(config *config.Config, tokenMaker token.Maker) (*Handler, error)
 		return nil, err
 	}
 
+	policy, err := sniff.ParsePolicy(config.UploadAllowedTypes, config.UploadDeniedTypes, config.UploadTypeMismatch)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+
 	store := db.NewStore(pool)
 
+	backend, err := storage.NewBackend(config)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	sender, err := mailer.NewSender(config)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	mediaPipeline := pipeline.NewPipeline(store, config.PipelineWorkers,
+		pipeline.NewMetadataStage(store, backend),
+		pipeline.NewThumbnailStage(store, backend, config.ThumbnailSizes, config.ThumbnailFormats),
+	)
+
 	return &Handler{
 		Config:     config,
 		Store:      store,
 		tokenMaker: tokenMaker,
-		Pool: pool,
+		Pool:       pool,
+		Storage:    backend,
+		Pipeline:   mediaPipeline,
+		Policy:     policy,
+		Authz:      authz.NewPolicy(store),
+		Sessions:   revocation.NewCache(store, config.SessionCacheSize, config.SessionCacheTTL),
+		Mailer:     sender,
 	}, nil
 }
+
+// getCurrentUser loads the user the request was authenticated as.
+func (h *Handler) getCurrentUser(c *fiber.Ctx) (db.User, error) {
+	p := c.Locals("payload")
+	payload, ok := p.(*token.Payload)
+	if !ok {
+		return db.User{}, fiber.NewError(fiber.StatusBadRequest, "invalid payload")
+	}
+
+	user, err := h.Store.GetUserByID(c.Context(), pgtype.UUID{
+		Bytes: payload.ID,
+		Valid: true,
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return db.User{}, fiber.NewError(fiber.StatusNotFound, "user not found")
+		}
+
+		util.RouteCustomError(err, c.Path())
+		return db.User{}, fiber.NewError(fiber.StatusInternalServerError, "failed to retreive user data.")
+	}
+
+	return user, nil
+}
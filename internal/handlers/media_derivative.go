@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sangketkit01/media-library-api/internal/authz"
+	db "github.com/sangketkit01/media-library-api/internal/db/sqlc"
+	"github.com/sangketkit01/media-library-api/internal/imaging"
+	"github.com/sangketkit01/media-library-api/internal/pipeline"
+	"github.com/sangketkit01/media-library-api/internal/storage"
+	"github.com/sangketkit01/media-library-api/internal/util"
+)
+
+func (h *Handler) GetMediaThumbnail(c *fiber.Ctx) error {
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	media, err := h.authorizeMedia(c, user, "id", authz.Read)
+	if err != nil {
+		return err
+	}
+
+	size, err := strconv.Atoi(c.Query("size", "256"))
+	if err != nil || size < 1 {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid thumbnail size")
+	}
+
+	format := c.Query("format")
+	if format == "" {
+		format = imaging.FormatJPEG
+		if strings.Contains(c.Get(fiber.HeaderAccept), imaging.ContentType(imaging.FormatWebP)) {
+			format = imaging.FormatWebP
+		}
+	}
+
+	derivatives, err := h.Store.ListMediaDerivatives(c.Context(), media.ID)
+	if err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to retreive thumbnails.")
+	}
+
+	thumbnail, ok := pickThumbnail(derivatives, size, format)
+	if !ok {
+		return fiber.NewError(fiber.StatusNotFound, "thumbnail not available")
+	}
+
+	reader, info, err := h.Storage.Get(c.Context(), thumbnail.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "thumbnail not available")
+		}
+
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to read thumbnail")
+	}
+
+	setContentSecurityHeaders(c)
+	c.Set(fiber.HeaderContentType, imaging.ContentType(thumbnail.Format))
+	c.Set(fiber.HeaderCacheControl, "private, max-age=86400")
+	c.Set(fiber.HeaderVary, fiber.HeaderAccept)
+
+	return c.SendStream(reader, int(info.Size))
+}
+
+// pickThumbnail returns the smallest thumbnail in the given format that is at
+// least size pixels, or the largest one available.
+func pickThumbnail(derivatives []db.MediaDerivative, size int, format string) (db.MediaDerivative, bool) {
+	var best db.MediaDerivative
+	found := false
+
+	for _, derivative := range derivatives {
+		if derivative.Kind != pipeline.DerivativeThumbnail || derivative.Format != format {
+			continue
+		}
+
+		switch {
+		case !found:
+			best, found = derivative, true
+		case best.Size < int32(size):
+			if derivative.Size > best.Size {
+				best = derivative
+			}
+		case derivative.Size >= int32(size) && derivative.Size < best.Size:
+			best = derivative
+		}
+	}
+
+	return best, found
+}
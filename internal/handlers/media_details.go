@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/sangketkit01/media-library-api/internal/authz"
+	db "github.com/sangketkit01/media-library-api/internal/db/sqlc"
+	"github.com/sangketkit01/media-library-api/internal/util"
+)
+
+const (
+	maxDisplayNameLength = 255
+	maxTitleLength       = 255
+	maxDescriptionLength = 5000
+	maxCustomFields      = 50
+	maxCustomFieldKey    = 64
+	maxCustomFieldValue  = 1024
+)
+
+// UpdateMediaRequest changes the fields that are set. A custom field set to
+// null is removed, the other custom fields are kept.
+type UpdateMediaRequest struct {
+	DisplayName  *string            `json:"display_name"`
+	Title        *string            `json:"title"`
+	Description  *string            `json:"description"`
+	Favourite    *bool              `json:"favourite"`
+	CustomFields map[string]*string `json:"custom_fields"`
+}
+
+// mediaETag is the entity tag of a media file's details, which changes with
+// every update of them.
+func mediaETag(media db.MediaFile) string {
+	return strconv.Quote(strconv.Itoa(int(media.Version)))
+}
+
+// parseIfMatch returns the version of an If-Match header holding a single
+// entity tag as issued by mediaETag.
+func parseIfMatch(header string) (int32, bool) {
+	tag := strings.TrimPrefix(strings.TrimSpace(header), "W/")
+
+	value, err := strconv.Unquote(tag)
+	if err != nil {
+		return 0, false
+	}
+
+	version, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return 0, false
+	}
+
+	return int32(version), true
+}
+
+// validDisplayName rejects names that cannot be used as a file name when the
+// media is downloaded or archived.
+func validDisplayName(name string) bool {
+	if name == "" || name == "." || name == ".." || utf8.RuneCountInString(name) > maxDisplayNameLength {
+		return false
+	}
+
+	return !strings.ContainsFunc(name, func(r rune) bool {
+		return r == '/' || r == '\\' || unicode.IsControl(r)
+	})
+}
+
+// applyCustomFields merges changes into the custom fields of a media file.
+func applyCustomFields(current json.RawMessage, changes map[string]*string) (json.RawMessage, error) {
+	fields := map[string]string{}
+	if len(current) > 0 {
+		if err := json.Unmarshal(current, &fields); err != nil {
+			return nil, err
+		}
+	}
+
+	for key, value := range changes {
+		if key == "" || utf8.RuneCountInString(key) > maxCustomFieldKey {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "custom field names must be 1 to 64 characters long")
+		}
+
+		if value == nil {
+			delete(fields, key)
+			continue
+		}
+
+		if utf8.RuneCountInString(*value) > maxCustomFieldValue {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "custom field values must be at most 1024 characters long")
+		}
+		fields[key] = *value
+	}
+
+	if len(fields) > maxCustomFields {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "media can have at most 50 custom fields")
+	}
+
+	return json.Marshal(fields)
+}
+
+// UpdateMedia edits the details of a media file. The If-Match header must hold
+// the ETag the client last read, so concurrent edits are not lost.
+func (h *Handler) UpdateMedia(c *fiber.Ctx) error {
+	var req UpdateMediaRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "bad request")
+	}
+
+	ifMatch := c.Get(fiber.HeaderIfMatch)
+	if ifMatch == "" {
+		return fiber.NewError(fiber.StatusPreconditionRequired, "If-Match header is required")
+	}
+
+	version, ok := parseIfMatch(ifMatch)
+	if !ok {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid If-Match header")
+	}
+
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	media, err := h.authorizeMedia(c, user, "id", authz.Write)
+	if err != nil {
+		return err
+	}
+
+	if media.Version != version {
+		c.Set(fiber.HeaderETag, mediaETag(media))
+		return fiber.NewError(fiber.StatusPreconditionFailed, "media was modified, reload it and try again")
+	}
+
+	arg := db.UpdateMediaFileDetailsParams{
+		ID:           media.ID,
+		Version:      version,
+		DisplayName:  media.DisplayName,
+		Title:        media.Title,
+		Description:  media.Description,
+		Favourite:    media.Favourite,
+		CustomFields: media.CustomFields,
+	}
+
+	if req.DisplayName != nil {
+		arg.DisplayName = strings.TrimSpace(*req.DisplayName)
+		if !validDisplayName(arg.DisplayName) {
+			return fiber.NewError(fiber.StatusBadRequest, "display_name must be 1 to 255 characters long and must not contain slashes")
+		}
+	}
+
+	if req.Title != nil {
+		arg.Title = strings.TrimSpace(*req.Title)
+		if utf8.RuneCountInString(arg.Title) > maxTitleLength {
+			return fiber.NewError(fiber.StatusBadRequest, "title must be at most 255 characters long")
+		}
+	}
+
+	if req.Description != nil {
+		arg.Description = strings.TrimSpace(*req.Description)
+		if utf8.RuneCountInString(arg.Description) > maxDescriptionLength {
+			return fiber.NewError(fiber.StatusBadRequest, "description must be at most 5000 characters long")
+		}
+	}
+
+	if req.Favourite != nil {
+		arg.Favourite = *req.Favourite
+	}
+
+	if req.CustomFields != nil {
+		arg.CustomFields, err = applyCustomFields(media.CustomFields, req.CustomFields)
+		if err != nil {
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				return err
+			}
+
+			util.RouteCustomError(err, c.Path())
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to update media.")
+		}
+	}
+
+	media, err = h.Store.UpdateMediaFileDetails(c.Context(), arg)
+	if err != nil {
+		// The media file passed the version check above, so it changed since.
+		if err == pgx.ErrNoRows {
+			return fiber.NewError(fiber.StatusPreconditionFailed, "media was modified, reload it and try again")
+		}
+
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to update media.")
+	}
+
+	c.Set(fiber.HeaderETag, mediaETag(media))
+	return c.JSON(media)
+}
@@ -1,21 +1,22 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"io"
 	"mime"
 	"mime/multipart"
-	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sangketkit01/media-library-api/internal/authz"
 	db "github.com/sangketkit01/media-library-api/internal/db/sqlc"
+	"github.com/sangketkit01/media-library-api/internal/sniff"
 	"github.com/sangketkit01/media-library-api/internal/token"
 	"github.com/sangketkit01/media-library-api/internal/util"
 )
@@ -44,11 +45,6 @@ func (h *Handler) UploadFile(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "failed to retrieve user")
 	}
 
-	userFolder := filepath.Join("../../uploads", user.ID.String())
-	if err := os.MkdirAll(userFolder, os.ModePerm); err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, "failed to create user folder")
-	}
-
 	form, err := c.MultipartForm()
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "invalid multipart form")
@@ -62,6 +58,7 @@ func (h *Handler) UploadFile(c *fiber.Ctx) error {
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 	var saveErrors []string
+	var quotaExceeded bool
 
 	for _, file := range files {
 		if file.Size > 100<<20 { 
@@ -73,32 +70,41 @@ func (h *Handler) UploadFile(c *fiber.Ctx) error {
 		go func(file *multipart.FileHeader) {
 			defer wg.Done()
 
-			uniqueName := fmt.Sprintf("%d_%s", time.Now().UnixNano(), file.Filename)
-			dst := filepath.Join(userFolder, uniqueName)
+			originalName := filepath.Base(file.Filename)
+			uniqueName := fmt.Sprintf("%d_%s", time.Now().UnixNano(), originalName)
 
-			if err := c.SaveFile(file, dst); err != nil {
-				mu.Lock()
-				saveErrors = append(saveErrors, fmt.Sprintf("failed to save %s: %v", file.Filename, err))
-				mu.Unlock()
-				return
+			declaredType := sniff.Normalize(file.Header.Get("Content-Type"))
+			if declaredType == "" || declaredType == sniff.OctetStream {
+				declaredType = mime.TypeByExtension(filepath.Ext(file.Filename))
 			}
 
-			ext := filepath.Ext(file.Filename)
-			mimeTypeByExtension := mime.TypeByExtension(ext)
-
 			arg := db.CreateMediaFileParams{
 				UserID: pgtype.UUID{
 					Bytes: user.ID.Bytes,
 					Valid: true,
 				},
-				Filename: uniqueName,
-				FileType: mimeTypeByExtension,
-				Size:     file.Size,
+				Filename:         uniqueName,
+				DeclaredType:     declaredType,
+				Size:             file.Size,
+				OriginalFilename: originalName,
+			}
+
+			open := func() (io.ReadCloser, error) {
+				return file.Open()
 			}
 
-			if _, err := h.Store.CreateMediaFile(c.Context(), arg); err != nil {
+			if _, err := h.createMediaFile(c.Context(), user.Tier, arg, open); err != nil {
+				var exceeded *db.QuotaExceededError
+
 				mu.Lock()
-				saveErrors = append(saveErrors, fmt.Sprintf("DB save error for %s: %v", file.Filename, err))
+				if errors.As(err, &exceeded) {
+					quotaExceeded = true
+					saveErrors = append(saveErrors, fmt.Sprintf("file %s exceeds your storage quota", file.Filename))
+				} else if errors.Is(err, sniff.ErrRejected) {
+					saveErrors = append(saveErrors, fmt.Sprintf("rejected %s: %v", file.Filename, err))
+				} else {
+					saveErrors = append(saveErrors, fmt.Sprintf("failed to save %s: %v", file.Filename, err))
+				}
 				mu.Unlock()
 			}
 		}(file)
@@ -106,6 +112,20 @@ func (h *Handler) UploadFile(c *fiber.Ctx) error {
 
 	wg.Wait()
 
+	if quotaExceeded {
+		quota, err := h.getQuota(c.Context(), user.ID)
+		if err != nil {
+			util.RouteCustomError(err, c.Path())
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to retrieve usage")
+		}
+
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+			"message": "Storage quota exceeded",
+			"errors":  saveErrors,
+			"quota":   quota,
+		})
+	}
+
 	if len(saveErrors) > 0 {
 		return c.Status(fiber.StatusPartialContent).JSON(fiber.Map{
 			"message": "Some files failed to upload",
@@ -117,50 +137,27 @@ func (h *Handler) UploadFile(c *fiber.Ctx) error {
 }
 
 func (h *Handler) AssignMediaToGroup(c *fiber.Ctx) error {
-	mediaID, err := uuid.Parse(c.Params("id"))
+	mediaID, err := uuidParam(c, "id", "media")
 	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "invalid media id")
+		return err
 	}
 
-	groupID, err := uuid.Parse(c.Params("group_id"))
+	groupID, err := uuidParam(c, "group_id", "group")
 	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "invalid group id")
+		return err
 	}
 
-	p := c.Locals("payload")
-	payload, ok := p.(*token.Payload)
-	if !ok {
-		return fiber.NewError(fiber.StatusBadRequest, "invalid payload")
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
 	}
 
-	_, err = h.Store.GetUserByID(c.Context(), pgtype.UUID{
-		Bytes: payload.ID,
-		Valid: true,
-	})
-
+	_, err = h.Authz.AssignMediaToGroup(c.Context(), user, mediaID, groupID)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return fiber.NewError(fiber.StatusNotFound, "user not found")
+		if errors.Is(err, authz.ErrNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "media or group not found")
 		}
 
-		util.RouteCustomError(err, c.Path())
-		return fiber.NewError(fiber.StatusInternalServerError, "failed to retreive user data.")
-	}
-
-	arg := db.AssignMediaToGroupParams{
-		ID: pgtype.UUID{
-			Bytes: mediaID,
-			Valid: true,
-		},
-
-		GroupID: pgtype.UUID{
-			Bytes: groupID,
-			Valid: true,
-		},
-	}
-
-	err = h.Store.AssignMediaToGroup(c.Context(), arg)
-	if err != nil {
 		util.RouteCustomError(err, c.Path())
 
 		return fiber.NewError(fiber.StatusInternalServerError, "failed to assign media to a group")
@@ -170,115 +167,81 @@ func (h *Handler) AssignMediaToGroup(c *fiber.Ctx) error {
 }
 
 func (h *Handler) GetCurrentUserMedia(c *fiber.Ctx) error {
-	p := c.Locals("payload")
-	payload, ok := p.(*token.Payload)
-	if !ok {
-		return fiber.NewError(fiber.StatusBadRequest, "invalid payload")
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
 	}
 
-	user, err := h.Store.GetUserByID(c.Context(), pgtype.UUID{
-		Bytes: payload.ID,
-		Valid: true,
-	})
-
+	arg, err := h.parseMediaListing(c, user)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return fiber.NewError(fiber.StatusNotFound, "user not found")
-		}
+		return err
+	}
 
+	page, err := h.Store.ListMediaPage(c.Context(), arg)
+	if err != nil {
 		util.RouteCustomError(err, c.Path())
-		return fiber.NewError(fiber.StatusInternalServerError, "failed to retreive user data.")
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to retreive media data.")
 	}
 
-	groupQuery := c.Query("group_id")
-	if groupQuery != "" {
-		groupID, err := uuid.Parse(groupQuery)
-		if err != nil {
-			util.RouteCustomError(err, c.Path())
-			return fiber.NewError(fiber.StatusInternalServerError, "invalid group id.")
-		}
+	totals, err := h.Store.CountMediaMatching(c.Context(), arg.MediaFilter)
+	if err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to retreive media data.")
+	}
 
-		medias, err := h.Store.ListMediaByGroup(c.Context(), db.ListMediaByGroupParams{
-			UserID: pgtype.UUID{
-				Bytes: user.ID.Bytes,
-				Valid: true,
-			},
+	response := MediaPageResponse{
+		Items:      page.Media,
+		TotalCount: totals.TotalCount,
+		TotalSize:  totals.TotalSize,
+	}
 
-			GroupID: pgtype.UUID{
-				Bytes: groupID,
-				Valid: true,
-			},
-		})
+	if page.Next != nil {
+		cursor := encodeMediaCursor(arg.Sort, arg.Desc, *page.Next)
+		response.NextCursor = &cursor
+	}
 
-		if err != nil {
-			util.RouteCustomError(err, c.Path())
-			return fiber.NewError(fiber.StatusInternalServerError, "failed to retreive media data.")
-		}
+	return c.JSON(response)
+}
 
-		return c.JSON(medias)
+func (h *Handler) GetMedia(c *fiber.Ctx) error {
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
 	}
 
-	medias, err := h.Store.ListMediaByUser(c.Context(), pgtype.UUID{
-		Bytes: user.ID.Bytes,
-		Valid: true,
-	})
-
+	media, err := h.authorizeMedia(c, user, "id", authz.Read)
 	if err != nil {
-		util.RouteCustomError(err, c.Path())
-		return fiber.NewError(fiber.StatusInternalServerError, "failed to retreive media data.")
+		return err
 	}
 
-	return c.JSON(medias)
+	c.Set(fiber.HeaderETag, mediaETag(media))
+	return c.JSON(media)
 }
 
 func (h *Handler) DownloadMedia(c *fiber.Ctx) error {
-	p := c.Locals("payload")
-	payload, ok := p.(*token.Payload)
-	if !ok {
-		return fiber.NewError(fiber.StatusBadRequest, "invalid payload")
-	}
+	return h.sendMedia(c, dispositionAttachment)
+}
 
-	user, err := h.Store.GetUserByID(c.Context(), pgtype.UUID{
-		Bytes: payload.ID,
-		Valid: true,
-	})
+func (h *Handler) StreamMedia(c *fiber.Ctx) error {
+	return h.sendMedia(c, dispositionInline)
+}
 
+func (h *Handler) sendMedia(c *fiber.Ctx, disposition string) error {
+	user, err := h.getCurrentUser(c)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return fiber.NewError(fiber.StatusNotFound, "user not found")
-		}
-
-		util.RouteCustomError(err, c.Path())
-		return fiber.NewError(fiber.StatusInternalServerError, "failed to retreive user data.")
+		return err
 	}
 
-	mediaID, err := uuid.Parse(c.Params("id"))
+	media, err := h.authorizeMedia(c, user, "id", authz.Read)
 	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "invalid media id")
+		return err
 	}
 
-	media, err := h.Store.GetMediaFileByID(c.Context(), pgtype.UUID{
-		Bytes: mediaID,
-		Valid: true,
-	})
-
+	blob, err := h.Store.GetBlobByID(c.Context(), media.BlobID)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return fiber.NewError(fiber.StatusNotFound, "media not found")
-		}
-
 		util.RouteCustomError(err, c.Path())
 		return fiber.NewError(fiber.StatusInternalServerError, "failed to retreive media.")
 	}
 
-	if media.UserID != user.ID {
-		return fiber.NewError(fiber.StatusForbidden, "You are not allowed to download other's file")
-	}
-
-	filepath := filepath.Join("../../uploads", media.UserID.String(), media.Filename)
-	log.Println("file path =", filepath)
-	if err := c.Download(filepath); err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, "failed to download media file")
-	}
-	return nil
+	return h.serveMedia(c, media, blob, disposition)
 }
@@ -1,27 +1,62 @@
 package handlers
 
 import (
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sangketkit01/media-library-api/internal/authz"
 	db "github.com/sangketkit01/media-library-api/internal/db/sqlc"
 	"github.com/sangketkit01/media-library-api/internal/token"
 	"github.com/sangketkit01/media-library-api/internal/util"
 )
 
 type CreateGroupRequest struct {
-	Name string `json:"name" validate:"required"`
+	Name     string     `json:"name" validate:"required"`
+	ParentID *uuid.UUID `json:"parent_id"`
 }
 
 type CreateGroupResponse struct {
-	ID        uuid.UUID `json:"id"`
-	UserID    uuid.UUID `json:"user_id"`
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"create_at"`
+	ID        uuid.UUID  `json:"id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	ParentID  *uuid.UUID `json:"parent_id"`
+	Name      string     `json:"name"`
+	CreatedAt time.Time  `json:"create_at"`
+}
+
+type RenameGroupRequest struct {
+	Name string `json:"name" validate:"required"`
+}
+
+type GroupMediaRequest struct {
+	MediaIDs []uuid.UUID `json:"media_ids" validate:"required,min=1,max=100"`
+}
+
+type ReorderGroupMediaRequest struct {
+	MediaIDs []uuid.UUID `json:"media_ids" validate:"required,min=1,max=1000"`
+}
+
+type GroupMembershipResponse struct {
+	MediaID  uuid.UUID `json:"media_id"`
+	Position int32     `json:"position"`
+	AddedAt  time.Time `json:"added_at"`
+}
+
+type GroupResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	ParentID   *uuid.UUID `json:"parent_id"`
+	Path       string     `json:"path"`
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	MediaCount int64      `json:"media_count"`
+	TotalSize  int64      `json:"total_size"`
 }
 
 func (h *Handler) CreateGroup(c *fiber.Ctx) error {
@@ -31,6 +66,7 @@ func (h *Handler) CreateGroup(c *fiber.Ctx) error {
 	}
 
 	validator := validator.New()
+	req.Name = strings.TrimSpace(req.Name)
 	if err := validator.Struct(req); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, "bad request")
 	}
@@ -64,9 +100,21 @@ func (h *Handler) CreateGroup(c *fiber.Ctx) error {
 		Name: req.Name,
 	}
 
+	if req.ParentID != nil {
+		parent, err := h.Authz.Group(c.Context(), user, optionalUUID(req.ParentID), authz.Own)
+		if err != nil {
+			return authzError(c, err, "parent group")
+		}
+
+		arg.ParentID = parent.ID
+	}
+
 	group, err := h.Store.CreateMediaGroup(c.Context(), arg)
 
 	if err != nil {
+		if isUniqueViolation(err) {
+			return fiber.NewError(fiber.StatusConflict, "Group name is already exists.")
+		}
 
 		util.RouteCustomError(err, c.Path())
 
@@ -76,9 +124,238 @@ func (h *Handler) CreateGroup(c *fiber.Ctx) error {
 	response := CreateGroupResponse{
 		ID:        group.ID.Bytes,
 		UserID:    group.UserID.Bytes,
+		ParentID:  uuidPtr(group.ParentID),
 		Name:      group.Name,
 		CreatedAt: group.CreatedAt.Time,
 	}
 
 	return c.JSON(response)
 }
+
+func (h *Handler) ListGroups(c *fiber.Ctx) error {
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	groups, err := h.Store.ListGroupsByUser(c.Context(), user.ID)
+	if err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to retreive groups.")
+	}
+
+	response := make([]GroupResponse, 0, len(groups))
+	for _, group := range groups {
+		response = append(response, GroupResponse{
+			ID:         group.ID.Bytes,
+			UserID:     group.UserID.Bytes,
+			ParentID:   uuidPtr(group.ParentID),
+			Path:       group.Path,
+			Name:       group.Name,
+			CreatedAt:  group.CreatedAt.Time,
+			MediaCount: group.MediaCount,
+			TotalSize:  group.TotalSize,
+		})
+	}
+
+	return c.JSON(response)
+}
+
+func (h *Handler) GetGroup(c *fiber.Ctx) error {
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	group, err := h.authorizeGroup(c, user, "id", authz.Read)
+	if err != nil {
+		return err
+	}
+
+	return h.sendGroup(c, group)
+}
+
+func (h *Handler) RenameGroup(c *fiber.Ctx) error {
+	var req RenameGroupRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "bad request")
+	}
+
+	validator := validator.New()
+	req.Name = strings.TrimSpace(req.Name)
+	if err := validator.Struct(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "bad request")
+	}
+
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	group, err := h.authorizeGroup(c, user, "id", authz.Write)
+	if err != nil {
+		return err
+	}
+
+	group, err = h.Store.RenameMediaGroup(c.Context(), db.RenameMediaGroupParams{
+		ID:   group.ID,
+		Name: req.Name,
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fiber.NewError(fiber.StatusNotFound, "group not found")
+		}
+
+		if isUniqueViolation(err) {
+			return fiber.NewError(fiber.StatusConflict, "Group name is already exists.")
+		}
+
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to rename group.")
+	}
+
+	return h.sendGroup(c, group)
+}
+
+func (h *Handler) RemoveMediaFromGroup(c *fiber.Ctx) error {
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	group, err := h.authorizeGroup(c, user, "id", authz.Write)
+	if err != nil {
+		return err
+	}
+
+	media, err := h.authorizeMedia(c, user, "media_id", authz.Write)
+	if err != nil {
+		return err
+	}
+
+	_, err = h.Store.RemoveMediaFromGroup(c.Context(), db.RemoveMediaFromGroupParams{
+		GroupID: group.ID,
+		MediaID: media.ID,
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fiber.NewError(fiber.StatusNotFound, "media not found in group")
+		}
+
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to remove media from group.")
+	}
+
+	return c.JSON(fiber.Map{"message": "Removed media from group successfully."})
+}
+
+func (h *Handler) AddMediaToGroup(c *fiber.Ctx) error {
+	var req GroupMediaRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "bad request")
+	}
+
+	validator := validator.New()
+	if err := validator.Struct(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "bad request")
+	}
+
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	group, err := h.authorizeGroup(c, user, "id", authz.Write)
+	if err != nil {
+		return err
+	}
+
+	memberships, err := h.Authz.AddMediaToGroup(c.Context(), group, uuidList(req.MediaIDs))
+	if err != nil {
+		if errors.Is(err, authz.ErrNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "media not found")
+		}
+
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to add media to group.")
+	}
+
+	return c.JSON(newGroupMembershipResponse(memberships))
+}
+
+// ReorderGroupMedia moves the given media files to the front of a group in
+// the order they are listed.
+func (h *Handler) ReorderGroupMedia(c *fiber.Ctx) error {
+	var req ReorderGroupMediaRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "bad request")
+	}
+
+	validator := validator.New()
+	if err := validator.Struct(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "bad request")
+	}
+
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	group, err := h.authorizeGroup(c, user, "id", authz.Write)
+	if err != nil {
+		return err
+	}
+
+	memberships, err := h.Store.ReorderGroupMediaTx(c.Context(), db.ReorderGroupMediaTxParams{
+		UserID:   group.UserID,
+		GroupID:  group.ID,
+		MediaIDs: uuidList(req.MediaIDs),
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrNotGroupMember) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to reorder group.")
+	}
+
+	return c.JSON(newGroupMembershipResponse(memberships))
+}
+
+func newGroupMembershipResponse(memberships []db.GroupMembership) []GroupMembershipResponse {
+	response := make([]GroupMembershipResponse, 0, len(memberships))
+	for _, membership := range memberships {
+		response = append(response, GroupMembershipResponse{
+			MediaID:  membership.MediaID.Bytes,
+			Position: membership.Position,
+			AddedAt:  membership.AddedAt.Time,
+		})
+	}
+
+	return response
+}
+
+func (h *Handler) sendGroup(c *fiber.Ctx, group db.MediaGroup) error {
+	stats, err := h.Store.GetGroupStats(c.Context(), group.ID)
+	if err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to retreive group.")
+	}
+
+	return c.JSON(GroupResponse{
+		ID:         group.ID.Bytes,
+		UserID:     group.UserID.Bytes,
+		ParentID:   uuidPtr(group.ParentID),
+		Path:       group.Path,
+		Name:       group.Name,
+		CreatedAt:  group.CreatedAt.Time,
+		MediaCount: stats.MediaCount,
+		TotalSize:  stats.TotalSize,
+	})
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == util.UniqueViolationErrCode
+}
@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sangketkit01/media-library-api/internal/authz"
+	db "github.com/sangketkit01/media-library-api/internal/db/sqlc"
+)
+
+const (
+	defaultMediaLimit = 50
+	maxMediaLimit     = 200
+	sortAsc           = "asc"
+	sortDesc          = "desc"
+)
+
+// defaultMediaDesc is the direction of a sort order when none is requested,
+// newest and largest first, names and positions in reading order.
+var defaultMediaDesc = map[db.MediaSort]bool{
+	db.MediaSortUploadedAt: true,
+	db.MediaSortTakenAt:    true,
+	db.MediaSortSize:       true,
+	db.MediaSortFilename:   false,
+	db.MediaSortPosition:   false,
+}
+
+type MediaPageResponse struct {
+	Items      []db.MediaFile `json:"items"`
+	NextCursor *string        `json:"next_cursor"`
+	TotalCount int64          `json:"total_count"`
+	TotalSize  int64          `json:"total_size"`
+}
+
+// mediaCursor is the opaque cursor handed to clients. It carries the sort
+// order it was issued for, so that it cannot be replayed against another.
+type mediaCursor struct {
+	Sort     db.MediaSort `json:"s"`
+	Desc     bool         `json:"d"`
+	Time     *time.Time   `json:"t,omitempty"`
+	Size     int64        `json:"z,omitempty"`
+	Name     string       `json:"n,omitempty"`
+	Position int32        `json:"p,omitempty"`
+	ID       uuid.UUID    `json:"i"`
+}
+
+func encodeMediaCursor(sort db.MediaSort, desc bool, cursor db.MediaCursor) string {
+	value := mediaCursor{
+		Sort:     sort,
+		Desc:     desc,
+		Size:     cursor.Size,
+		Name:     cursor.Name,
+		Position: cursor.Position,
+		ID:       cursor.ID.Bytes,
+	}
+	if cursor.Time.Valid {
+		value.Time = &cursor.Time.Time
+	}
+
+	data, _ := json.Marshal(value)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeMediaCursor(s string, sort db.MediaSort, desc bool) (*db.MediaCursor, error) {
+	invalid := fiber.NewError(fiber.StatusBadRequest, "invalid cursor")
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, invalid
+	}
+
+	var value mediaCursor
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, invalid
+	}
+
+	if value.Sort != sort || value.Desc != desc {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "cursor does not match the requested sort")
+	}
+
+	cursor := &db.MediaCursor{
+		Size:     value.Size,
+		Name:     value.Name,
+		Position: value.Position,
+		ID: pgtype.UUID{
+			Bytes: value.ID,
+			Valid: true,
+		},
+	}
+
+	switch sort {
+	case db.MediaSortUploadedAt, db.MediaSortTakenAt:
+		if value.Time == nil {
+			return nil, invalid
+		}
+		cursor.Time = pgtype.Timestamptz{Time: *value.Time, Valid: true}
+	}
+
+	return cursor, nil
+}
+
+func parseSizeQuery(c *fiber.Ctx, key string, fallback int64) (int64, error) {
+	value := c.Query(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || size < 0 {
+		return 0, fiber.NewError(fiber.StatusBadRequest, "invalid "+key)
+	}
+
+	return size, nil
+}
+
+func parseTimeQuery(c *fiber.Ctx, key string, fallback pgtype.Timestamptz) (pgtype.Timestamptz, error) {
+	value := c.Query(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return pgtype.Timestamptz{}, fiber.NewError(fiber.StatusBadRequest, "invalid "+key+", expected an RFC 3339 time")
+	}
+
+	return pgtype.Timestamptz{Time: t, Valid: true}, nil
+}
+
+// parseMediaListing turns the query parameters of a media listing into the
+// parameters of a page:
+//
+//   - limit: page size, 1 to 200
+//   - cursor: next_cursor of the previous page
+//   - sort: uploaded_at, taken_at, size, filename or position, which requires
+//     a group_id without descendants and is then the default
+//   - order: asc or desc
+//   - favourite: only favourite media when true
+//   - type: MIME type prefix such as image/
+//   - min_size, max_size: size range in bytes
+//   - from, to: upload time range in RFC 3339, to is exclusive
+//   - group_id, descendants: members of a group or of its subtree, which may
+//     be shared with the user
+//   - tags, match: see parseTagFilter
+func (h *Handler) parseMediaListing(c *fiber.Ctx, user db.User) (db.ListMediaPageParams, error) {
+	arg := db.ListMediaPageParams{
+		MediaFilter: db.NewMediaFilter(user.ID),
+		Sort:        db.MediaSortUploadedAt,
+	}
+
+	limit := c.QueryInt("limit", defaultMediaLimit)
+	if limit < 1 || limit > maxMediaLimit {
+		return arg, fiber.NewError(fiber.StatusBadRequest, "limit must be between 1 and 200")
+	}
+	arg.Limit = int32(limit)
+
+	var err error
+	arg.TypePrefix = escapeLike(strings.ToLower(strings.TrimSpace(c.Query("type"))))
+
+	if arg.MinSize, err = parseSizeQuery(c, "min_size", arg.MinSize); err != nil {
+		return arg, err
+	}
+	if arg.MaxSize, err = parseSizeQuery(c, "max_size", arg.MaxSize); err != nil {
+		return arg, err
+	}
+	if arg.UploadedFrom, err = parseTimeQuery(c, "from", arg.UploadedFrom); err != nil {
+		return arg, err
+	}
+	if arg.UploadedTo, err = parseTimeQuery(c, "to", arg.UploadedTo); err != nil {
+		return arg, err
+	}
+
+	arg.FavouritesOnly = c.QueryBool("favourite")
+
+	if arg.Tags, arg.MinMatches, err = parseTagFilter(c); err != nil {
+		return arg, err
+	}
+
+	descendants := c.QueryBool("descendants")
+	if groupQuery := c.Query("group_id"); groupQuery != "" {
+		groupID, err := uuid.Parse(groupQuery)
+		if err != nil {
+			return arg, fiber.NewError(fiber.StatusBadRequest, "invalid group id.")
+		}
+
+		group, err := h.Authz.Group(c.Context(), user, pgtype.UUID{
+			Bytes: groupID,
+			Valid: true,
+		}, authz.Read)
+		if err != nil {
+			return arg, authzError(c, err, "group")
+		}
+
+		// Groups shared with the user list the media of their owner.
+		arg.UserID = group.UserID
+
+		if descendants {
+			arg.GroupPath = pgtype.Text{String: group.Path, Valid: true}
+		} else {
+			arg.GroupID = group.ID
+			arg.PositionGroupID = group.ID
+			arg.Sort = db.MediaSortPosition
+		}
+	}
+
+	if sort := c.Query("sort"); sort != "" {
+		arg.Sort = db.MediaSort(sort)
+	}
+
+	desc, ok := defaultMediaDesc[arg.Sort]
+	if !ok {
+		return arg, fiber.NewError(fiber.StatusBadRequest, "sort must be uploaded_at, taken_at, size, filename or position")
+	}
+	if arg.Sort == db.MediaSortPosition && !arg.PositionGroupID.Valid {
+		return arg, fiber.NewError(fiber.StatusBadRequest, "sort by position requires a group_id without descendants")
+	}
+
+	switch c.Query("order") {
+	case "":
+		arg.Desc = desc
+	case sortAsc:
+		arg.Desc = false
+	case sortDesc:
+		arg.Desc = true
+	default:
+		return arg, fiber.NewError(fiber.StatusBadRequest, "order must be asc or desc")
+	}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		if arg.After, err = decodeMediaCursor(cursor, arg.Sort, arg.Desc); err != nil {
+			return arg, err
+		}
+	}
+
+	return arg, nil
+}
@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	db "github.com/sangketkit01/media-library-api/internal/db/sqlc"
+	"github.com/sangketkit01/media-library-api/internal/sniff"
+	"github.com/sangketkit01/media-library-api/internal/storage"
+	"github.com/sangketkit01/media-library-api/internal/util"
+)
+
+const (
+	dispositionInline     = "inline"
+	dispositionAttachment = "attachment"
+
+	// maxByteRanges caps the number of ranges served in a single multipart
+	// response, requests asking for more get the whole file instead.
+	maxByteRanges = 16
+)
+
+var errUnsatisfiableRange = errors.New("unsatisfiable range")
+
+type byteRange struct {
+	start  int64
+	length int64
+}
+
+func (r byteRange) contentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", r.start, r.start+r.length-1, size)
+}
+
+// serveMedia writes the content of a media file honouring conditional
+// (If-None-Match, If-Modified-Since) and range (Range, If-Range) requests.
+func (h *Handler) serveMedia(c *fiber.Ctx, media db.MediaFile, blob db.Blob, disposition string) error {
+	return h.serveMediaWith(c, media, blob, disposition, nil)
+}
+
+// serveMediaWith is serveMedia calling beforeBody, when given, once it is
+// known which ranges a GET request is answered with and before any content is
+// sent. No ranges means the whole file. An error from beforeBody is returned
+// instead of the content.
+func (h *Handler) serveMediaWith(c *fiber.Ctx, media db.MediaFile, blob db.Blob, disposition string, beforeBody func(ranges []byteRange) error) error {
+	disposition = c.Query("disposition", disposition)
+	if disposition != dispositionInline && disposition != dispositionAttachment {
+		return fiber.NewError(fiber.StatusBadRequest, "disposition must be inline or attachment")
+	}
+
+	// Blobs are immutable and addressed by their digest, which makes it a
+	// strong validator.
+	etag := strconv.Quote(blob.Digest)
+	lastModified := media.UploadedAt.Time.UTC().Truncate(time.Second)
+	size := blob.Size
+
+	contentType := media.FileType
+	if contentType == "" {
+		contentType = fiber.MIMEOctetStream
+	}
+
+	// Markup stored before it was denied, or allowed by configuration, would
+	// run scripts on our origin if opened inline.
+	if sniff.Active(contentType) {
+		disposition = dispositionAttachment
+	}
+
+	setContentSecurityHeaders(c)
+	c.Set(fiber.HeaderETag, etag)
+	c.Set(fiber.HeaderLastModified, lastModified.Format(http.TimeFormat))
+	c.Set(fiber.HeaderAcceptRanges, "bytes")
+	c.Set(fiber.HeaderCacheControl, "private, no-cache")
+	c.Set(fiber.HeaderContentDisposition, mime.FormatMediaType(disposition, map[string]string{
+		"filename": media.DisplayName,
+	}))
+
+	if isNotModified(c, etag, lastModified) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	var ranges []byteRange
+	rangeHeader := c.Get(fiber.HeaderRange)
+	if rangeHeader != "" && c.Method() == fiber.MethodGet && ifRangeMatches(c.Get(fiber.HeaderIfRange), etag, lastModified) {
+		parsed, err := parseByteRanges(rangeHeader, size)
+		if err != nil {
+			if errors.Is(err, errUnsatisfiableRange) {
+				c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes */%d", size))
+				return fiber.NewError(fiber.StatusRequestedRangeNotSatisfiable, "requested range not satisfiable")
+			}
+		} else if len(parsed) <= maxByteRanges {
+			ranges = parsed
+		}
+	}
+
+	if beforeBody != nil && c.Method() == fiber.MethodGet {
+		if err := beforeBody(ranges); err != nil {
+			return err
+		}
+	}
+
+	switch len(ranges) {
+	case 0:
+		c.Set(fiber.HeaderContentType, contentType)
+		return h.sendMediaRange(c, blob, byteRange{start: 0, length: size})
+
+	case 1:
+		c.Status(fiber.StatusPartialContent)
+		c.Set(fiber.HeaderContentType, contentType)
+		c.Set(fiber.HeaderContentRange, ranges[0].contentRange(size))
+		return h.sendMediaRange(c, blob, ranges[0])
+
+	default:
+		return h.sendMediaMultiRange(c, blob, contentType, ranges)
+	}
+}
+
+// setContentSecurityHeaders keeps browsers from guessing another type than the
+// one served and from running anything in user content opened directly.
+func setContentSecurityHeaders(c *fiber.Ctx) {
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	c.Set(fiber.HeaderContentSecurityPolicy, "sandbox")
+}
+
+func (h *Handler) sendMediaRange(c *fiber.Ctx, blob db.Blob, r byteRange) error {
+	if c.Method() == fiber.MethodHead {
+		c.Response().Header.SetContentLength(int(r.length))
+		return nil
+	}
+
+	reader, _, err := h.Storage.GetRange(c.Context(), blob.StorageKey, r.start, r.length)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "media file not found")
+		}
+
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to read media file")
+	}
+
+	return c.SendStream(reader, int(r.length))
+}
+
+// sendMediaMultiRange streams a multipart/byteranges response, reading each
+// range from storage as the client consumes the body.
+func (h *Handler) sendMediaMultiRange(c *fiber.Ctx, blob db.Blob, contentType string, ranges []byteRange) error {
+	ctx := c.Context()
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+
+	go func() {
+		for _, r := range ranges {
+			part, err := writer.CreatePart(textproto.MIMEHeader{
+				fiber.HeaderContentType:  {contentType},
+				fiber.HeaderContentRange: {r.contentRange(blob.Size)},
+			})
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+
+			reader, _, err := h.Storage.GetRange(ctx, blob.StorageKey, r.start, r.length)
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+
+			_, err = io.Copy(part, reader)
+			reader.Close()
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+
+		pw.CloseWithError(writer.Close())
+	}()
+
+	c.Status(fiber.StatusPartialContent)
+	c.Set(fiber.HeaderContentType, "multipart/byteranges; boundary="+writer.Boundary())
+	return c.SendStream(pr)
+}
+
+// isNotModified evaluates If-None-Match, falling back to If-Modified-Since
+// when no entity tags were sent.
+func isNotModified(c *fiber.Ctx, etag string, lastModified time.Time) bool {
+	if c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead {
+		return false
+	}
+
+	if ifNoneMatch := c.Get(fiber.HeaderIfNoneMatch); ifNoneMatch != "" {
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ifModifiedSince := c.Get(fiber.HeaderIfModifiedSince); ifModifiedSince != "" {
+		since, err := http.ParseTime(ifModifiedSince)
+		return err == nil && !lastModified.After(since)
+	}
+
+	return false
+}
+
+// ifRangeMatches reports whether a Range request should be honoured. If-Range
+// carries either a strong entity tag or an HTTP date.
+func ifRangeMatches(ifRange, etag string, lastModified time.Time) bool {
+	if ifRange == "" {
+		return true
+	}
+
+	if strings.HasPrefix(ifRange, `"`) || strings.HasPrefix(ifRange, "W/") {
+		return ifRange == etag
+	}
+
+	date, err := http.ParseTime(ifRange)
+	return err == nil && date.Equal(lastModified)
+}
+
+// parseByteRanges parses a "bytes=" Range header against an object of the given
+// size. It returns errUnsatisfiableRange when no range overlaps the object.
+func parseByteRanges(header string, size int64) ([]byteRange, error) {
+	spec, found := strings.CutPrefix(header, "bytes=")
+	if !found {
+		return nil, errors.New("unsupported range unit")
+	}
+
+	var ranges []byteRange
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		first, last, found := strings.Cut(part, "-")
+		if !found {
+			return nil, errors.New("invalid range")
+		}
+
+		first = strings.TrimSpace(first)
+		last = strings.TrimSpace(last)
+
+		var r byteRange
+		if first == "" {
+			// Suffix range: the last N bytes.
+			suffix, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || suffix < 0 {
+				return nil, errors.New("invalid range")
+			}
+			if suffix == 0 || size == 0 {
+				continue
+			}
+			if suffix > size {
+				suffix = size
+			}
+			r = byteRange{start: size - suffix, length: suffix}
+		} else {
+			start, err := strconv.ParseInt(first, 10, 64)
+			if err != nil || start < 0 {
+				return nil, errors.New("invalid range")
+			}
+
+			end := size - 1
+			if last != "" {
+				end, err = strconv.ParseInt(last, 10, 64)
+				if err != nil || end < start {
+					return nil, errors.New("invalid range")
+				}
+				if end > size-1 {
+					end = size - 1
+				}
+			}
+
+			if start >= size {
+				continue
+			}
+			r = byteRange{start: start, length: end - start + 1}
+		}
+
+		ranges = append(ranges, r)
+	}
+
+	if len(ranges) == 0 {
+		return nil, errUnsatisfiableRange
+	}
+
+	return ranges, nil
+}
@@ -0,0 +1,353 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/sangketkit01/media-library-api/internal/db/sqlc"
+	"github.com/sangketkit01/media-library-api/internal/storage"
+)
+
+// testContent is the content of the media file served by the tests, 100
+// bytes long so that ranges are easy to follow.
+var testContent = strings.Repeat("0123456789", 10)
+
+var testUploadedAt = time.Date(2024, time.March, 9, 14, 30, 20, 0, time.UTC)
+
+// newTestMedia stores testContent in a local backend and returns a handler
+// serving from it with the matching media file and blob.
+func newTestMedia(t *testing.T) (*Handler, db.MediaFile, db.Blob) {
+	t.Helper()
+
+	backend, err := storage.NewLocalBackend(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blob := db.Blob{
+		Digest:     "4f2b0c6d",
+		Size:       int64(len(testContent)),
+		StorageKey: "user/blobs/4f/4f2b0c6d",
+	}
+	if err := backend.Put(context.Background(), blob.StorageKey, strings.NewReader(testContent), blob.Size, "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+
+	media := db.MediaFile{
+		FileType:    "text/plain",
+		DisplayName: "digits.txt",
+		UploadedAt:  pgtype.Timestamptz{Time: testUploadedAt, Valid: true},
+	}
+	return &Handler{Storage: backend}, media, blob
+}
+
+func newTestMediaApp(t *testing.T) *fiber.App {
+	t.Helper()
+
+	h, media, blob := newTestMedia(t)
+	app := fiber.New()
+	app.Get("/media", func(c *fiber.Ctx) error {
+		return h.serveMedia(c, media, blob, dispositionInline)
+	})
+	return app
+}
+
+func doRequest(t *testing.T, app *fiber.App, req *http.Request) (*http.Response, string) {
+	t.Helper()
+
+	res, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res, string(body)
+}
+
+func TestParseByteRanges(t *testing.T) {
+	const size = 100
+
+	tests := []struct {
+		header string
+		want   []byteRange
+		err    error
+	}{
+		{"bytes=0-9", []byteRange{{0, 10}}, nil},
+		{"bytes=90-", []byteRange{{90, 10}}, nil},
+		{"bytes=99-99", []byteRange{{99, 1}}, nil},
+		// Suffix ranges count from the end, longer ones cover the whole file.
+		{"bytes=-10", []byteRange{{90, 10}}, nil},
+		{"bytes=-100", []byteRange{{0, 100}}, nil},
+		{"bytes=-500", []byteRange{{0, 100}}, nil},
+		// Ends past the object are clamped.
+		{"bytes=50-500", []byteRange{{50, 50}}, nil},
+		// Overlapping and unordered ranges are served as asked.
+		{"bytes=0-49,25-74", []byteRange{{0, 50}, {25, 50}}, nil},
+		{"bytes=90-99, 0-9", []byteRange{{90, 10}, {0, 10}}, nil},
+		{"bytes= 0-1 , , 5-6", []byteRange{{0, 2}, {5, 2}}, nil},
+		// Ranges outside the object are dropped, the rest is kept.
+		{"bytes=100-200,0-0", []byteRange{{0, 1}}, nil},
+		{"bytes=100-", nil, errUnsatisfiableRange},
+		{"bytes=200-300", nil, errUnsatisfiableRange},
+		{"bytes=-0", nil, errUnsatisfiableRange},
+		{"bytes=", nil, errUnsatisfiableRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			got, err := parseByteRanges(tt.header, size)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("ranges = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseByteRangesRejectsInvalidHeaders(t *testing.T) {
+	for _, header := range []string{
+		"items=0-9",
+		"bytes=9-0",
+		"bytes=abc",
+		"bytes=5",
+		"bytes=--5",
+		"bytes=-1-2",
+		"bytes=0-9,x-y",
+	} {
+		ranges, err := parseByteRanges(header, 100)
+		if err == nil || errors.Is(err, errUnsatisfiableRange) {
+			t.Errorf("%q: ranges = %v, err = %v, want an invalid range", header, ranges, err)
+		}
+	}
+}
+
+func TestParseByteRangesOfEmptyObject(t *testing.T) {
+	for _, header := range []string{"bytes=0-", "bytes=-10"} {
+		if _, err := parseByteRanges(header, 0); !errors.Is(err, errUnsatisfiableRange) {
+			t.Errorf("%q: err = %v, want errUnsatisfiableRange", header, err)
+		}
+	}
+}
+
+func TestIfRangeMatches(t *testing.T) {
+	const etag = `"4f2b0c6d"`
+	lastModified := testUploadedAt
+
+	tests := []struct {
+		name    string
+		ifRange string
+		want    bool
+	}{
+		{"absent", "", true},
+		{"strong match", etag, true},
+		{"other tag", `"other"`, false},
+		// Weak tags never match, If-Range needs a strong comparison.
+		{"weak match", "W/" + etag, false},
+		{"same date", lastModified.Format(http.TimeFormat), true},
+		{"older date", lastModified.Add(-time.Second).Format(http.TimeFormat), false},
+		{"newer date", lastModified.Add(time.Second).Format(http.TimeFormat), false},
+		{"garbage", "yesterday", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ifRangeMatches(tt.ifRange, etag, lastModified); got != tt.want {
+				t.Errorf("ifRangeMatches(%q) = %v, want %v", tt.ifRange, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServeMediaRanges(t *testing.T) {
+	app := newTestMediaApp(t)
+
+	tests := []struct {
+		name         string
+		header       map[string]string
+		status       int
+		contentRange string
+		body         string
+	}{
+		{"whole file", nil, fiber.StatusOK, "", testContent},
+		{"single range", map[string]string{"Range": "bytes=10-14"},
+			fiber.StatusPartialContent, "bytes 10-14/100", "01234"},
+		{"suffix range", map[string]string{"Range": "bytes=-3"},
+			fiber.StatusPartialContent, "bytes 97-99/100", "789"},
+		{"invalid range ignored", map[string]string{"Range": "bytes=9-0"},
+			fiber.StatusOK, "", testContent},
+		{"unsatisfiable", map[string]string{"Range": "bytes=100-"},
+			fiber.StatusRequestedRangeNotSatisfiable, "bytes */100", ""},
+		{"matching If-Range", map[string]string{"Range": "bytes=0-1", "If-Range": `"4f2b0c6d"`},
+			fiber.StatusPartialContent, "bytes 0-1/100", "01"},
+		{"stale If-Range", map[string]string{"Range": "bytes=0-1", "If-Range": `"other"`},
+			fiber.StatusOK, "", testContent},
+		{"weak If-Range", map[string]string{"Range": "bytes=0-1", "If-Range": `W/"4f2b0c6d"`},
+			fiber.StatusOK, "", testContent},
+		{"stale If-Range skips 416", map[string]string{"Range": "bytes=100-", "If-Range": `"other"`},
+			fiber.StatusOK, "", testContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(fiber.MethodGet, "/media", nil)
+			for key, value := range tt.header {
+				req.Header.Set(key, value)
+			}
+
+			res, body := doRequest(t, app, req)
+			if res.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", res.StatusCode, tt.status)
+			}
+			if got := res.Header.Get("Content-Range"); got != tt.contentRange {
+				t.Errorf("Content-Range = %q, want %q", got, tt.contentRange)
+			}
+			if tt.status != fiber.StatusRequestedRangeNotSatisfiable && body != tt.body {
+				t.Errorf("body = %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
+
+func TestServeMediaMultipleRanges(t *testing.T) {
+	app := newTestMediaApp(t)
+
+	req := httptest.NewRequest(fiber.MethodGet, "/media", nil)
+	req.Header.Set("Range", "bytes=0-1,-2,50-52")
+	res, body := doRequest(t, app, req)
+	if res.StatusCode != fiber.StatusPartialContent {
+		t.Fatalf("status = %d, want 206", res.StatusCode)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/byteranges" {
+		t.Fatalf("Content-Type = %q, %v", res.Header.Get("Content-Type"), err)
+	}
+
+	want := []struct{ contentRange, body string }{
+		{"bytes 0-1/100", "01"},
+		{"bytes 98-99/100", "89"},
+		{"bytes 50-52/100", "012"},
+	}
+
+	reader := multipart.NewReader(strings.NewReader(body), params["boundary"])
+	for i := 0; ; i++ {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			if i != len(want) {
+				t.Errorf("%d parts, want %d", i, len(want))
+			}
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i >= len(want) {
+			t.Fatalf("more than %d parts", len(want))
+		}
+
+		content, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := part.Header.Get("Content-Range"); got != want[i].contentRange {
+			t.Errorf("part %d: Content-Range = %q, want %q", i, got, want[i].contentRange)
+		}
+		if got := part.Header.Get("Content-Type"); got != "text/plain" {
+			t.Errorf("part %d: Content-Type = %q", i, got)
+		}
+		if string(content) != want[i].body {
+			t.Errorf("part %d: body = %q, want %q", i, content, want[i].body)
+		}
+	}
+}
+
+func TestServeMediaCapsRanges(t *testing.T) {
+	app := newTestMediaApp(t)
+
+	ranges := func(n int) string {
+		specs := make([]string, n)
+		for i := range specs {
+			specs[i] = strconv.Itoa(i*5) + "-" + strconv.Itoa(i*5+1)
+		}
+		return "bytes=" + strings.Join(specs, ",")
+	}
+
+	req := httptest.NewRequest(fiber.MethodGet, "/media", nil)
+	req.Header.Set("Range", ranges(maxByteRanges))
+	res, _ := doRequest(t, app, req)
+	if res.StatusCode != fiber.StatusPartialContent {
+		t.Errorf("%d ranges: status = %d, want 206", maxByteRanges, res.StatusCode)
+	}
+
+	// Past the cap the whole file is sent.
+	req = httptest.NewRequest(fiber.MethodGet, "/media", nil)
+	req.Header.Set("Range", ranges(maxByteRanges+1))
+	res, body := doRequest(t, app, req)
+	if res.StatusCode != fiber.StatusOK || body != testContent {
+		t.Errorf("%d ranges: status = %d, want the whole file", maxByteRanges+1, res.StatusCode)
+	}
+}
+
+func TestServeMediaNotModified(t *testing.T) {
+	app := newTestMediaApp(t)
+
+	tests := []struct {
+		name   string
+		header map[string]string
+		status int
+	}{
+		{"matching tag", map[string]string{"If-None-Match": `"4f2b0c6d"`}, fiber.StatusNotModified},
+		// If-None-Match uses the weak comparison.
+		{"weak tag", map[string]string{"If-None-Match": `W/"4f2b0c6d"`}, fiber.StatusNotModified},
+		{"tag in a list", map[string]string{"If-None-Match": `"a", "4f2b0c6d"`}, fiber.StatusNotModified},
+		{"any tag", map[string]string{"If-None-Match": "*"}, fiber.StatusNotModified},
+		{"other tag", map[string]string{"If-None-Match": `"other"`}, fiber.StatusOK},
+		{"not modified since", map[string]string{"If-Modified-Since": testUploadedAt.Format(http.TimeFormat)},
+			fiber.StatusNotModified},
+		{"modified since", map[string]string{"If-Modified-Since": testUploadedAt.Add(-time.Hour).Format(http.TimeFormat)},
+			fiber.StatusOK},
+		// Entity tags take precedence over dates.
+		{"other tag, old date", map[string]string{
+			"If-None-Match":     `"other"`,
+			"If-Modified-Since": testUploadedAt.Format(http.TimeFormat),
+		}, fiber.StatusOK},
+		// Not modified wins over ranges.
+		{"matching tag with range", map[string]string{"If-None-Match": `"4f2b0c6d"`, "Range": "bytes=100-"},
+			fiber.StatusNotModified},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(fiber.MethodGet, "/media", nil)
+			for key, value := range tt.header {
+				req.Header.Set(key, value)
+			}
+
+			res, _ := doRequest(t, app, req)
+			if res.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", res.StatusCode, tt.status)
+			}
+			if etag := res.Header.Get("ETag"); etag != `"4f2b0c6d"` {
+				t.Errorf("ETag = %q", etag)
+			}
+		})
+	}
+}
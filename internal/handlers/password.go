@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/sangketkit01/media-library-api/internal/db/sqlc"
+	"github.com/sangketkit01/media-library-api/internal/mailer"
+	"github.com/sangketkit01/media-library-api/internal/util"
+)
+
+const (
+	auditPasswordReset   = "password_reset"
+	auditPasswordChanged = "password_changed"
+)
+
+const forgotPasswordMessage = "If an account exists for this email, a password reset link has been sent."
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// Passwords are hashed with bcrypt, which ignores anything past 72 bytes.
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=8,max=72"`
+}
+
+// ForgotPassword mails a password reset token. It answers the same whether or
+// not the email belongs to an account, and sends at most
+// PasswordResetLimit emails per account and window.
+func (h *Handler) ForgotPassword(c *fiber.Ctx) error {
+	var req ForgotPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "bad request")
+	}
+
+	validator := validator.New()
+	if err := validator.Struct(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid email")
+	}
+
+	accepted := func() error {
+		return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"message": forgotPasswordMessage})
+	}
+
+	user, err := h.Store.GetUserByEmail(c.Context(), req.Email)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return accepted()
+		}
+
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot reset password")
+	}
+
+	sent, err := h.Store.CountPasswordResetsSince(c.Context(), db.CountPasswordResetsSinceParams{
+		UserID: user.ID,
+		CreatedAt: pgtype.Timestamptz{
+			Time:  time.Now().Add(-h.Config.PasswordResetWindow),
+			Valid: true,
+		},
+	})
+	if err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot reset password")
+	}
+
+	if sent >= int64(h.Config.PasswordResetLimit) {
+		return accepted()
+	}
+
+	token, err := util.RandomToken(32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot reset password")
+	}
+
+	_, err = h.Store.CreatePasswordReset(c.Context(), db.CreatePasswordResetParams{
+		UserID:    user.ID,
+		TokenHash: util.HashToken(token),
+		ClientIp:  pgtype.Text{String: c.IP(), Valid: true},
+		ExpiresAt: pgtype.Timestamptz{
+			Time:  time.Now().Add(h.Config.PasswordResetTTL),
+			Valid: true,
+		},
+	})
+	if err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot reset password")
+	}
+
+	text := fmt.Sprintf("Use this code to reset your password:\n\n%s\n", token)
+	if h.Config.PasswordResetURL != "" {
+		link := h.Config.PasswordResetURL + "?token=" + url.QueryEscape(token)
+		text = fmt.Sprintf("Open this link to reset your password:\n\n%s\n", link)
+	}
+	text += fmt.Sprintf("\nIt expires in %v and signs you out of every device. If you did not ask for it, ignore this email.\n", h.Config.PasswordResetTTL)
+
+	h.sendMail(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Text:    text,
+	})
+
+	return accepted()
+}
+
+// ResetPassword sets a new password with a token mailed by ForgotPassword and
+// signs the user out of every session.
+func (h *Handler) ResetPassword(c *fiber.Ctx) error {
+	var req ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "bad request")
+	}
+
+	validator := validator.New()
+	if err := validator.Struct(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "a token and a password of 8 to 72 characters are required")
+	}
+
+	hashedPassword, err := util.HashPassword(req.Password)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot reset password")
+	}
+
+	result, err := h.Store.ResetPasswordTx(c.Context(), db.ResetPasswordTxParams{
+		TokenHash: util.HashToken(req.Token),
+		Password:  hashedPassword,
+		Audit: db.CreateAuditEventParams{
+			Event:     auditPasswordReset,
+			ClientIp:  pgtype.Text{String: c.IP(), Valid: true},
+			UserAgent: pgtype.Text{String: c.Get(fiber.HeaderUserAgent), Valid: true},
+		},
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fiber.NewError(fiber.StatusBadRequest, "reset token is invalid, was already used or has expired")
+		}
+
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot reset password")
+	}
+
+	h.revokeSessions(result.BlockedSessions)
+
+	return c.JSON(fiber.Map{
+		"message": "Reset password successfully.",
+		"revoked": len(result.BlockedSessions),
+	})
+}
+
+// ChangePassword sets a new password for the current user, provided they know
+// the current one, and signs them out of every other session.
+func (h *Handler) ChangePassword(c *fiber.Ctx) error {
+	var req ChangePasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "bad request")
+	}
+
+	validator := validator.New()
+	if err := validator.Struct(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "a new password of 8 to 72 characters is required")
+	}
+
+	currentID, err := currentSessionID(c)
+	if err != nil {
+		return err
+	}
+
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	if err := util.CheckPassword(user.Password, req.CurrentPassword); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid password credential")
+	}
+
+	hashedPassword, err := util.HashPassword(req.NewPassword)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot change password")
+	}
+
+	result, err := h.Store.ChangePasswordTx(c.Context(), db.ChangePasswordTxParams{
+		UserID:        user.ID,
+		Password:      hashedPassword,
+		KeepSessionID: currentID,
+		Audit: db.CreateAuditEventParams{
+			UserID:    user.ID,
+			SessionID: currentID,
+			Event:     auditPasswordChanged,
+			ClientIp:  pgtype.Text{String: c.IP(), Valid: true},
+			UserAgent: pgtype.Text{String: c.Get(fiber.HeaderUserAgent), Valid: true},
+		},
+	})
+	if err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot change password")
+	}
+
+	h.revokeSessions(result.BlockedSessions)
+
+	return c.JSON(fiber.Map{
+		"message": "Changed password successfully.",
+		"revoked": len(result.BlockedSessions),
+	})
+}
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"html"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	db "github.com/sangketkit01/media-library-api/internal/db/sqlc"
+	"github.com/sangketkit01/media-library-api/internal/util"
+)
+
+const (
+	searchModeFullText = "fulltext"
+	searchModeTrigram  = "trigram"
+
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	maxSearchQuery     = 200
+
+	// Highlights are delimited with private use characters and escaped before
+	// the markers are turned into tags, so matched text cannot inject markup.
+	highlightStart = "\uE000"
+	highlightStop  = "\uE001"
+)
+
+var headlineOptions = "StartSel=" + highlightStart + ", StopSel=" + highlightStop + ", MaxFragments=2, MaxWords=20, MinWords=5"
+
+var highlightReplacer = strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>")
+
+type SearchResult struct {
+	Media     db.MediaFile `json:"media"`
+	Rank      float32      `json:"rank"`
+	Highlight string       `json:"highlight"`
+}
+
+type SearchResponse struct {
+	Items      []SearchResult `json:"items"`
+	Mode       string         `json:"mode"`
+	NextCursor *string        `json:"next_cursor"`
+	Total      int64          `json:"total"`
+}
+
+// searchCursor is the opaque cursor of search results. Ranked results are
+// paged by offset, and the mode keeps later pages on the matching strategy
+// the first page fell back to.
+type searchCursor struct {
+	Mode   string `json:"m"`
+	Offset int32  `json:"o"`
+}
+
+func encodeSearchCursor(cursor searchCursor) *string {
+	data, _ := json.Marshal(cursor)
+	encoded := base64.RawURLEncoding.EncodeToString(data)
+	return &encoded
+}
+
+func decodeSearchCursor(s string) (searchCursor, error) {
+	var cursor searchCursor
+	invalid := fiber.NewError(fiber.StatusBadRequest, "invalid cursor")
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor, invalid
+	}
+
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Offset < 0 {
+		return cursor, invalid
+	}
+
+	if cursor.Mode != searchModeFullText && cursor.Mode != searchModeTrigram {
+		return cursor, invalid
+	}
+
+	return cursor, nil
+}
+
+// highlightMarkers escapes a ts_headline result and turns its markers into
+// mark tags.
+func highlightMarkers(s string) string {
+	return highlightReplacer.Replace(html.EscapeString(s))
+}
+
+// highlightSubstring marks the first case insensitive occurrence of query in
+// text, returning the escaped text.
+func highlightSubstring(text, query string) string {
+	lower := strings.ToLower(text)
+	// Lower casing may change the length of some characters, offsets into
+	// lower only apply to text when it does not.
+	i := strings.Index(lower, strings.ToLower(query))
+	if i < 0 || len(lower) != len(text) {
+		return html.EscapeString(text)
+	}
+
+	end := i + len(query)
+	return html.EscapeString(text[:i]) + "<mark>" + html.EscapeString(text[i:end]) + "</mark>" + html.EscapeString(text[end:])
+}
+
+// Search ranks the media of the current user against a full text query. When
+// nothing matches whole words, partial matches on filenames and titles are
+// returned instead.
+func (h *Handler) Search(c *fiber.Ctx) error {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" || utf8.RuneCountInString(query) > maxSearchQuery {
+		return fiber.NewError(fiber.StatusBadRequest, "q must be 1 to 200 characters long")
+	}
+
+	limit := c.QueryInt("limit", defaultSearchLimit)
+	if limit < 1 || limit > maxSearchLimit {
+		return fiber.NewError(fiber.StatusBadRequest, "limit must be between 1 and 100")
+	}
+
+	cursor := searchCursor{Mode: searchModeFullText}
+	if value := c.Query("cursor"); value != "" {
+		var err error
+		if cursor, err = decodeSearchCursor(value); err != nil {
+			return err
+		}
+	}
+
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	response := SearchResponse{
+		Items: []SearchResult{},
+		Mode:  cursor.Mode,
+	}
+
+	if cursor.Mode == searchModeFullText {
+		rows, err := h.Store.SearchMedia(c.Context(), db.SearchMediaParams{
+			Query:           query,
+			HeadlineOptions: headlineOptions,
+			UserID:          user.ID,
+			RowOffset:       cursor.Offset,
+			RowLimit:        int32(limit),
+		})
+		if err != nil {
+			util.RouteCustomError(err, c.Path())
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to search media.")
+		}
+
+		for _, row := range rows {
+			response.Total = row.Total
+			response.Items = append(response.Items, SearchResult{
+				Media:     row.MediaFile,
+				Rank:      row.Rank,
+				Highlight: highlightMarkers(row.Highlight),
+			})
+		}
+
+		if len(rows) == 0 && cursor.Offset == 0 {
+			response.Mode = searchModeTrigram
+		}
+	}
+
+	if response.Mode == searchModeTrigram {
+		rows, err := h.Store.SearchMediaByFilename(c.Context(), db.SearchMediaByFilenameParams{
+			Query:     query,
+			UserID:    user.ID,
+			Pattern:   escapeLike(query),
+			RowOffset: cursor.Offset,
+			RowLimit:  int32(limit),
+		})
+		if err != nil {
+			util.RouteCustomError(err, c.Path())
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to search media.")
+		}
+
+		for _, row := range rows {
+			text := row.MediaFile.DisplayName
+			if strings.Contains(strings.ToLower(row.MediaFile.Title), strings.ToLower(query)) {
+				text = row.MediaFile.Title
+			}
+
+			response.Total = row.Total
+			response.Items = append(response.Items, SearchResult{
+				Media:     row.MediaFile,
+				Rank:      row.Rank,
+				Highlight: highlightSubstring(text, query),
+			})
+		}
+	}
+
+	next := cursor.Offset + int32(len(response.Items))
+	if int64(next) < response.Total {
+		response.NextCursor = encodeSearchCursor(searchCursor{
+			Mode:   response.Mode,
+			Offset: next,
+		})
+	}
+
+	return c.JSON(response)
+}
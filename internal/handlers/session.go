@@ -1,17 +1,48 @@
 package handlers
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/sangketkit01/media-library-api/internal/db/sqlc"
+	"github.com/sangketkit01/media-library-api/internal/token"
+	"github.com/sangketkit01/media-library-api/internal/util"
 )
 
+const auditRefreshTokenReused = "refresh_token_reused"
+
 type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" validate:"required"`
 }
 
+type RefreshTokenResponse struct {
+	AccessToken           string    `json:"access_token"`
+	RefreshToken          string    `json:"refresh_token"`
+	TokenExpiredAt        time.Time `json:"token_expired_at"`
+	RefreshTokenExpiredAt time.Time `json:"refresh_token_expired"`
+}
+
+type SessionResponse struct {
+	ID uuid.UUID `json:"id"`
+	// Current is set on the session the request was made with.
+	Current    bool           `json:"current"`
+	Device     util.UserAgent `json:"device"`
+	UserAgent  string         `json:"user_agent"`
+	ClientIP   string         `json:"client_ip"`
+	CreatedAt  time.Time      `json:"created_at"`
+	LastUsedAt time.Time      `json:"last_used_at"`
+	ExpiresAt  time.Time      `json:"expires_at"`
+}
+
+// RefreshToken exchanges a refresh token for a new access token and the next
+// refresh token of its session. A refresh token of an earlier generation was
+// already exchanged and may have been stolen, presenting it again blocks the
+// session.
 func (h *Handler) RefreshToken(c *fiber.Ctx) error {
 	var req RefreshTokenRequest
 	if err := c.BodyParser(&req); err != nil {
@@ -24,26 +55,210 @@ func (h *Handler) RefreshToken(c *fiber.Ctx) error {
 	}
 
 	payload, err := h.tokenMaker.VerifyToken(req.RefreshToken)
-	if err != nil {
+	if err != nil || payload.Type == token.TypeAccess {
 		return fiber.NewError(fiber.StatusUnauthorized, "invalid refresh token.")
 	}
 
-	arg := pgtype.UUID{
+	sessionID := pgtype.UUID{
 		Bytes: payload.SessionID,
 		Valid: true,
 	}
 
-	session, err := h.Store.GetSession(c.Context(), arg)
-	if err != nil || session.IsBlocked.Bool || session.UserID.Bytes != payload.ID ||
-		session.RefreshToken != req.RefreshToken || time.Now().After(session.ExpiresAt.Time) {
+	session, err := h.Store.GetSession(c.Context(), sessionID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fiber.NewError(fiber.StatusUnauthorized, "invalid session")
+		}
+
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot refresh session")
+	}
 
-		return fiber.NewError(fiber.StatusInternalServerError, "invalid session")
+	if session.IsBlocked.Bool || session.UserID.Bytes != payload.ID || time.Now().After(session.ExpiresAt.Time) {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid session")
 	}
 
-	accessToken, _, err := h.tokenMaker.CreateToken(payload.ID, payload.SessionID, h.Config.AccessTokenDuration)
+	// The refresh tokens of a session expire with it, rotating them does not
+	// extend the session.
+	accessToken, accessPayload, err := h.tokenMaker.CreateToken(payload.ID, payload.SessionID, h.Config.AccessTokenDuration)
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "cannot create new access token")
 	}
 
-	return c.JSON(fiber.Map{"access_token": accessToken})
+	refreshToken, refreshPayload, err := h.tokenMaker.CreateRefreshToken(payload.ID, payload.SessionID, session.Generation+1, time.Until(session.ExpiresAt.Time))
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot create new refresh token")
+	}
+
+	_, err = h.Store.RotateSessionRefreshToken(c.Context(), db.RotateSessionRefreshTokenParams{
+		ID:              sessionID,
+		RefreshToken:    req.RefreshToken,
+		NewRefreshToken: refreshToken,
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			// Only refresh tokens that carry an older generation are known to
+			// have been rotated. Anything else, such as a token issued before
+			// generations were recorded, is refused without blocking.
+			if payload.Type == token.TypeRefresh && payload.Generation < session.Generation {
+				return h.blockReusedSession(c, session, payload.Generation)
+			}
+
+			return fiber.NewError(fiber.StatusUnauthorized, "invalid refresh token.")
+		}
+
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot refresh session")
+	}
+
+	return c.JSON(RefreshTokenResponse{
+		AccessToken:           accessToken,
+		RefreshToken:          refreshToken,
+		TokenExpiredAt:        accessPayload.ExpiredAt,
+		RefreshTokenExpiredAt: refreshPayload.ExpiredAt,
+	})
+}
+
+// blockReusedSession blocks a session whose refresh token was presented after
+// it had been rotated. The token is signed for the session, so it was issued
+// by us, and only the latest generation may be exchanged.
+func (h *Handler) blockReusedSession(c *fiber.Ctx, session db.Session, reusedGeneration int32) error {
+	details, _ := json.Marshal(fiber.Map{
+		"generation":        session.Generation,
+		"reused_generation": reusedGeneration,
+	})
+
+	err := h.Store.BlockSessionTx(c.Context(), db.BlockSessionTxParams{
+		SessionID: session.ID,
+		Audit: db.CreateAuditEventParams{
+			UserID:    session.UserID,
+			SessionID: session.ID,
+			Event:     auditRefreshTokenReused,
+			ClientIp:  pgtype.Text{String: c.IP(), Valid: true},
+			UserAgent: pgtype.Text{String: c.Get(fiber.HeaderUserAgent), Valid: true},
+			Details:   details,
+		},
+	})
+	if err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot refresh session")
+	}
+
+	h.Sessions.Revoke(session.ID.Bytes)
+
+	return fiber.NewError(fiber.StatusUnauthorized, "refresh token was already used, the session has been revoked")
+}
+
+// currentSessionID returns the session the request was authenticated with.
+func currentSessionID(c *fiber.Ctx) (pgtype.UUID, error) {
+	payload, ok := c.Locals("payload").(*token.Payload)
+	if !ok {
+		return pgtype.UUID{}, fiber.NewError(fiber.StatusUnauthorized, "invalid payload")
+	}
+
+	return pgtype.UUID{
+		Bytes: payload.SessionID,
+		Valid: true,
+	}, nil
+}
+
+// ListSessions lists the devices the current user is signed in on.
+func (h *Handler) ListSessions(c *fiber.Ctx) error {
+	currentID, err := currentSessionID(c)
+	if err != nil {
+		return err
+	}
+
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := h.Store.ListSessionsByUser(c.Context(), user.ID)
+	if err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to retreive sessions.")
+	}
+
+	response := make([]SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, SessionResponse{
+			ID:         session.ID.Bytes,
+			Current:    session.ID == currentID,
+			Device:     util.ParseUserAgent(session.UserAgent.String),
+			UserAgent:  session.UserAgent.String,
+			ClientIP:   session.ClientIp.String,
+			CreatedAt:  session.CreatedAt.Time,
+			LastUsedAt: session.LastUsedAt.Time,
+			ExpiresAt:  session.ExpiresAt.Time,
+		})
+	}
+
+	return c.JSON(response)
+}
+
+// RevokeSession signs the current user out of one of their sessions, which
+// may be the current one.
+func (h *Handler) RevokeSession(c *fiber.Ctx) error {
+	sessionID, err := uuidParam(c, "id", "session")
+	if err != nil {
+		return err
+	}
+
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	revoked, err := h.Store.BlockSessionForUser(c.Context(), db.BlockSessionForUserParams{
+		ID:     sessionID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot block session")
+	}
+
+	if revoked == 0 {
+		return fiber.NewError(fiber.StatusNotFound, "session not found")
+	}
+
+	h.Sessions.Revoke(sessionID.Bytes)
+
+	return c.JSON(fiber.Map{"message": "Revoked session successfully."})
+}
+
+// RevokeOtherSessions signs the current user out everywhere but the current
+// session.
+func (h *Handler) RevokeOtherSessions(c *fiber.Ctx) error {
+	currentID, err := currentSessionID(c)
+	if err != nil {
+		return err
+	}
+
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	revoked, err := h.Store.BlockOtherSessionsByUser(c.Context(), db.BlockOtherSessionsByUserParams{
+		UserID: user.ID,
+		ID:     currentID,
+	})
+	if err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot block sessions")
+	}
+
+	h.revokeSessions(revoked)
+
+	return c.JSON(fiber.Map{"revoked": len(revoked)})
+}
+
+// revokeSessions stops sessions that were just blocked from being used on
+// this replica, without waiting for the session cache to expire.
+func (h *Handler) revokeSessions(ids []pgtype.UUID) {
+	for _, id := range ids {
+		h.Sessions.Revoke(id.Bytes)
+	}
 }
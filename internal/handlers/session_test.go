@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sangketkit01/media-library-api/internal/config"
+	db "github.com/sangketkit01/media-library-api/internal/db/sqlc"
+	"github.com/sangketkit01/media-library-api/internal/revocation"
+	"github.com/sangketkit01/media-library-api/internal/token"
+)
+
+// sessionStore holds a single session, rotating and blocking it the way the
+// queries do. Calling any other method of db.Store panics.
+type sessionStore struct {
+	db.Store
+
+	session db.Session
+	// racer, when set, rotates the session right before a rotation is
+	// applied, as a concurrent request presenting the same token would.
+	racer  func(session *db.Session)
+	audits []db.CreateAuditEventParams
+}
+
+func (s *sessionStore) GetSession(ctx context.Context, id pgtype.UUID) (db.Session, error) {
+	if id != s.session.ID {
+		return db.Session{}, pgx.ErrNoRows
+	}
+	return s.session, nil
+}
+
+func (s *sessionStore) RotateSessionRefreshToken(ctx context.Context, arg db.RotateSessionRefreshTokenParams) (db.Session, error) {
+	if s.racer != nil {
+		s.racer(&s.session)
+		s.racer = nil
+	}
+
+	if arg.ID != s.session.ID || arg.RefreshToken != s.session.RefreshToken ||
+		s.session.IsBlocked.Bool || !s.session.ExpiresAt.Time.After(time.Now()) {
+		return db.Session{}, pgx.ErrNoRows
+	}
+
+	s.session.RefreshToken = arg.NewRefreshToken
+	s.session.Generation++
+	return s.session, nil
+}
+
+func (s *sessionStore) BlockSessionTx(ctx context.Context, arg db.BlockSessionTxParams) error {
+	if arg.SessionID == s.session.ID {
+		s.session.IsBlocked = pgtype.Bool{Bool: true, Valid: true}
+	}
+	s.audits = append(s.audits, arg.Audit)
+	return nil
+}
+
+type sessionTest struct {
+	app   *fiber.App
+	h     *Handler
+	store *sessionStore
+	maker token.Maker
+}
+
+// newSessionTest starts a session at generation 1 and returns its refresh
+// token.
+func newSessionTest(t *testing.T) (*sessionTest, string) {
+	t.Helper()
+
+	maker, err := token.NewPasetoMaker(strings.Repeat("k", 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userID, sessionID := uuid.New(), uuid.New()
+	refreshToken, _, err := maker.CreateRefreshToken(userID, sessionID, 1, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := &sessionStore{
+		session: db.Session{
+			ID:           pgtype.UUID{Bytes: sessionID, Valid: true},
+			UserID:       pgtype.UUID{Bytes: userID, Valid: true},
+			RefreshToken: refreshToken,
+			IsBlocked:    pgtype.Bool{Bool: false, Valid: true},
+			ExpiresAt:    pgtype.Timestamptz{Time: time.Now().Add(time.Hour), Valid: true},
+			Generation:   1,
+		},
+	}
+
+	h := &Handler{
+		Store:      store,
+		Config:     &config.Config{AccessTokenDuration: time.Minute},
+		tokenMaker: maker,
+		Sessions:   revocation.NewCache(store, 10, time.Hour),
+	}
+
+	app := fiber.New()
+	app.Post("/tokens/refresh", h.RefreshToken)
+	return &sessionTest{app: app, h: h, store: store, maker: maker}, refreshToken
+}
+
+// refresh exchanges a refresh token, returning the next one on success.
+func (st *sessionTest) refresh(t *testing.T, refreshToken string) (int, string) {
+	t.Helper()
+
+	body, _ := json.Marshal(RefreshTokenRequest{RefreshToken: refreshToken})
+	req := httptest.NewRequest(fiber.MethodPost, "/tokens/refresh", strings.NewReader(string(body)))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+	res, resBody := doRequest(t, st.app, req)
+	if res.StatusCode != fiber.StatusOK {
+		return res.StatusCode, ""
+	}
+
+	var response RefreshTokenResponse
+	if err := json.Unmarshal([]byte(resBody), &response); err != nil {
+		t.Fatal(err)
+	}
+	return res.StatusCode, response.RefreshToken
+}
+
+func (st *sessionTest) revoked(t *testing.T) bool {
+	t.Helper()
+
+	revoked, err := st.h.Sessions.IsRevoked(context.Background(), st.store.session.ID.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return revoked
+}
+
+func TestRefreshTokenRotates(t *testing.T) {
+	st, first := newSessionTest(t)
+
+	status, second := st.refresh(t, first)
+	if status != fiber.StatusOK {
+		t.Fatalf("status = %d, want 200", status)
+	}
+
+	payload, err := st.maker.VerifyToken(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payload.Type != token.TypeRefresh || payload.Generation != 2 {
+		t.Errorf("next token is a %q token of generation %d, want a refresh token of generation 2", payload.Type, payload.Generation)
+	}
+	if st.store.session.Generation != 2 || st.store.session.RefreshToken != second {
+		t.Errorf("session at generation %d, want 2 with the next token", st.store.session.Generation)
+	}
+
+	if status, _ := st.refresh(t, second); status != fiber.StatusOK {
+		t.Errorf("next token: status = %d, want 200", status)
+	}
+}
+
+func TestRefreshTokenCurrentGenerationRetryDoesNotBlock(t *testing.T) {
+	st, first := newSessionTest(t)
+
+	// Two requests present the same token at once, the other one rotates
+	// the session after this one read it. Such retries are refused, but the
+	// token was never replayed after its rotation was known.
+	st.store.racer = func(session *db.Session) {
+		session.RefreshToken = "rotated by the other request"
+		session.Generation++
+	}
+
+	if status, _ := st.refresh(t, first); status != fiber.StatusUnauthorized {
+		t.Errorf("status = %d, want 401", status)
+	}
+	if st.store.session.IsBlocked.Bool || len(st.store.audits) != 0 {
+		t.Error("a retry of the current generation blocked the session")
+	}
+	if st.revoked(t) {
+		t.Error("a retry of the current generation revoked the session")
+	}
+}
+
+func TestRefreshTokenOlderGenerationReplayBlocks(t *testing.T) {
+	st, first := newSessionTest(t)
+
+	status, second := st.refresh(t, first)
+	if status != fiber.StatusOK {
+		t.Fatalf("status = %d, want 200", status)
+	}
+
+	// The first token was rotated, presenting it again means it leaked.
+	if status, _ := st.refresh(t, first); status != fiber.StatusUnauthorized {
+		t.Errorf("replay: status = %d, want 401", status)
+	}
+	if !st.store.session.IsBlocked.Bool {
+		t.Fatal("replaying an older generation did not block the session")
+	}
+	if !st.revoked(t) {
+		t.Error("blocked session not revoked in the cache")
+	}
+
+	if len(st.store.audits) != 1 {
+		t.Fatalf("%d audit events, want 1", len(st.store.audits))
+	}
+	audit := st.store.audits[0]
+	if audit.Event != auditRefreshTokenReused || audit.SessionID != st.store.session.ID {
+		t.Errorf("audit event %q for session %v", audit.Event, audit.SessionID)
+	}
+	var details map[string]int32
+	if err := json.Unmarshal(audit.Details, &details); err != nil {
+		t.Fatal(err)
+	}
+	if details["generation"] != 2 || details["reused_generation"] != 1 {
+		t.Errorf("audit details = %v", details)
+	}
+
+	// The legitimate holder is signed out as well.
+	if status, _ := st.refresh(t, second); status != fiber.StatusUnauthorized {
+		t.Errorf("latest token after the replay: status = %d, want 401", status)
+	}
+}
+
+func TestRefreshTokenRejectsAccessTokens(t *testing.T) {
+	st, _ := newSessionTest(t)
+
+	accessToken, _, err := st.maker.CreateToken(st.store.session.UserID.Bytes, st.store.session.ID.Bytes, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status, _ := st.refresh(t, accessToken); status != fiber.StatusUnauthorized {
+		t.Errorf("status = %d, want 401", status)
+	}
+	if st.store.session.IsBlocked.Bool || st.store.session.Generation != 1 {
+		t.Error("an access token changed the session")
+	}
+}
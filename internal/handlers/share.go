@@ -0,0 +1,467 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sangketkit01/media-library-api/internal/authz"
+	db "github.com/sangketkit01/media-library-api/internal/db/sqlc"
+	"github.com/sangketkit01/media-library-api/internal/util"
+)
+
+const (
+	shareTokenSize = 32
+
+	// Passwords of protected share links are sent in a header rather than the
+	// URL, which ends up in logs and browser history.
+	sharePasswordHeader = "X-Share-Password"
+)
+
+type CreateShareLinkRequest struct {
+	MediaID      *uuid.UUID `json:"media_id"`
+	GroupID      *uuid.UUID `json:"group_id"`
+	Password     string     `json:"password" validate:"omitempty,min=4,max=72"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	MaxDownloads *int32     `json:"max_downloads" validate:"omitempty,min=1"`
+}
+
+type ShareLinkResponse struct {
+	ID            uuid.UUID  `json:"id"`
+	MediaID       *uuid.UUID `json:"media_id"`
+	GroupID       *uuid.UUID `json:"group_id"`
+	HasPassword   bool       `json:"has_password"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+	MaxDownloads  *int32     `json:"max_downloads"`
+	DownloadCount int32      `json:"download_count"`
+	RevokedAt     *time.Time `json:"revoked_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	// Token and URL are only returned when the link is created.
+	Token string `json:"token,omitempty"`
+	URL   string `json:"url,omitempty"`
+}
+
+type SharedMediaResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	GroupID    *uuid.UUID `json:"group_id,omitempty"`
+	Name       string     `json:"name"`
+	FileType   string     `json:"file_type"`
+	Size       int64      `json:"size"`
+	UploadedAt time.Time  `json:"uploaded_at"`
+	URL        string     `json:"url"`
+}
+
+type SharedFolderResponse struct {
+	ID       uuid.UUID  `json:"id"`
+	ParentID *uuid.UUID `json:"parent_id"`
+	Name     string     `json:"name"`
+}
+
+type SharedGroupResponse struct {
+	ID        uuid.UUID              `json:"id"`
+	Name      string                 `json:"name"`
+	Groups    []SharedFolderResponse `json:"groups"`
+	Media     []SharedMediaResponse  `json:"media"`
+	ExpiresAt *time.Time             `json:"expires_at"`
+}
+
+func timePtr(t pgtype.Timestamptz) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+
+	return &t.Time
+}
+
+func newShareLinkResponse(link db.ShareLink) ShareLinkResponse {
+	response := ShareLinkResponse{
+		ID:            link.ID.Bytes,
+		MediaID:       uuidPtr(link.MediaID),
+		GroupID:       uuidPtr(link.GroupID),
+		HasPassword:   link.PasswordHash.Valid,
+		ExpiresAt:     timePtr(link.ExpiresAt),
+		DownloadCount: link.DownloadCount,
+		RevokedAt:     timePtr(link.RevokedAt),
+		CreatedAt:     link.CreatedAt.Time,
+	}
+
+	if link.MaxDownloads.Valid {
+		response.MaxDownloads = &link.MaxDownloads.Int32
+	}
+
+	return response
+}
+
+func shareURL(c *fiber.Ctx, token string) string {
+	return c.BaseURL() + "/s/" + token
+}
+
+func (h *Handler) CreateShareLink(c *fiber.Ctx) error {
+	var req CreateShareLinkRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "bad request")
+	}
+
+	validator := validator.New()
+	if err := validator.Struct(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "bad request")
+	}
+
+	if (req.MediaID == nil) == (req.GroupID == nil) {
+		return fiber.NewError(fiber.StatusBadRequest, "either media_id or group_id is required")
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return fiber.NewError(fiber.StatusBadRequest, "expires_at must be in the future")
+	}
+
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	arg := db.CreateShareLinkParams{
+		UserID: user.ID,
+	}
+
+	if req.MediaID != nil {
+		media, err := h.Authz.Media(c.Context(), user, optionalUUID(req.MediaID), authz.Own)
+		if err != nil {
+			return authzError(c, err, "media")
+		}
+		arg.MediaID = media.ID
+	} else {
+		group, err := h.Authz.Group(c.Context(), user, optionalUUID(req.GroupID), authz.Own)
+		if err != nil {
+			return authzError(c, err, "group")
+		}
+		arg.GroupID = group.ID
+	}
+
+	if req.Password != "" {
+		hashed, err := util.HashPassword(req.Password)
+		if err != nil {
+			util.RouteCustomError(err, c.Path())
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to create share link.")
+		}
+		arg.PasswordHash = pgtype.Text{String: hashed, Valid: true}
+	}
+
+	if req.ExpiresAt != nil {
+		arg.ExpiresAt = pgtype.Timestamptz{Time: *req.ExpiresAt, Valid: true}
+	}
+
+	if req.MaxDownloads != nil {
+		arg.MaxDownloads = pgtype.Int4{Int32: *req.MaxDownloads, Valid: true}
+	}
+
+	token, err := util.RandomToken(shareTokenSize)
+	if err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to create share link.")
+	}
+	arg.TokenHash = util.HashToken(token)
+
+	link, err := h.Store.CreateShareLink(c.Context(), arg)
+	if err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to create share link.")
+	}
+
+	response := newShareLinkResponse(link)
+	response.Token = token
+	response.URL = shareURL(c, token)
+
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+func (h *Handler) ListShareLinks(c *fiber.Ctx) error {
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	links, err := h.Store.ListShareLinksByUser(c.Context(), user.ID)
+	if err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to retreive share links.")
+	}
+
+	response := make([]ShareLinkResponse, 0, len(links))
+	for _, link := range links {
+		response = append(response, newShareLinkResponse(link))
+	}
+
+	return c.JSON(response)
+}
+
+func (h *Handler) RevokeShareLink(c *fiber.Ctx) error {
+	id, err := uuidParam(c, "id", "share link")
+	if err != nil {
+		return err
+	}
+
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	link, err := h.Store.RevokeShareLink(c.Context(), db.RevokeShareLinkParams{
+		ID:     id,
+		UserID: user.ID,
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fiber.NewError(fiber.StatusNotFound, "share link not found")
+		}
+
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to revoke share link.")
+	}
+
+	return c.JSON(newShareLinkResponse(link))
+}
+
+// resolveShareLink returns the share link of the token in the path once it is
+// known to be usable and its password, if any, has been given.
+func (h *Handler) resolveShareLink(c *fiber.Ctx) (db.ShareLink, error) {
+	link, err := h.Store.GetShareLinkByTokenHash(c.Context(), util.HashToken(c.Params("token")))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return link, fiber.NewError(fiber.StatusNotFound, "share link not found")
+		}
+
+		util.RouteCustomError(err, c.Path())
+		return link, fiber.NewError(fiber.StatusInternalServerError, "failed to retreive share link.")
+	}
+
+	switch {
+	case link.RevokedAt.Valid:
+		return link, fiber.NewError(fiber.StatusGone, "share link has been revoked")
+	case link.ExpiresAt.Valid && !link.ExpiresAt.Time.After(time.Now()):
+		return link, fiber.NewError(fiber.StatusGone, "share link has expired")
+	case link.MaxDownloads.Valid && link.DownloadCount >= link.MaxDownloads.Int32:
+		return link, fiber.NewError(fiber.StatusGone, "share link has reached its download limit")
+	}
+
+	if link.PasswordHash.Valid {
+		password := c.Get(sharePasswordHeader)
+		if password == "" {
+			return link, fiber.NewError(fiber.StatusUnauthorized, "share link requires a password")
+		}
+
+		if link.LockedUntil.Valid && link.LockedUntil.Time.After(time.Now()) {
+			return link, fiber.NewError(fiber.StatusTooManyRequests, "too many wrong passwords, try again later")
+		}
+
+		if err := util.CheckPassword(link.PasswordHash.String, password); err != nil {
+			return link, h.recordSharePasswordFailure(c, link)
+		}
+
+		if link.FailedAttempts > 0 {
+			if err := h.Store.ResetShareLinkPasswordFailures(c.Context(), link.ID); err != nil {
+				util.RouteCustomError(err, c.Path())
+			}
+		}
+	}
+
+	return link, nil
+}
+
+// recordSharePasswordFailure counts a wrong password against a share link,
+// locking it for a while once too many were given in a row.
+func (h *Handler) recordSharePasswordFailure(c *fiber.Ctx, link db.ShareLink) error {
+	_, err := h.Store.RecordShareLinkPasswordFailure(c.Context(), db.RecordShareLinkPasswordFailureParams{
+		ID:          link.ID,
+		MaxAttempts: int32(h.Config.SharePasswordMaxAttempts),
+		LockUntil: pgtype.Timestamptz{
+			Time:  time.Now().Add(h.Config.SharePasswordLockout),
+			Valid: true,
+		},
+	})
+	if err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to retreive share link.")
+	}
+
+	return fiber.NewError(fiber.StatusUnauthorized, "invalid share link password")
+}
+
+// countsAsDownload reports whether a response with the given ranges starts a
+// download: the whole file, or any range from its first byte. Range requests
+// continuing a download, such as seeking in a video, are not counted again.
+func countsAsDownload(ranges []byteRange) bool {
+	if len(ranges) == 0 {
+		return true
+	}
+
+	for _, r := range ranges {
+		if r.start == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// serveSharedMedia streams a media file through a share link, counting the
+// download against the link's limit.
+func (h *Handler) serveSharedMedia(c *fiber.Ctx, link db.ShareLink, media db.MediaFile) error {
+	blob, err := h.Store.GetBlobByID(c.Context(), media.BlobID)
+	if err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to retreive media.")
+	}
+
+	// Whether the request is a new download is only known once its ranges
+	// are parsed, ranges that cannot be served fall back to the whole file.
+	return h.serveMediaWith(c, media, blob, dispositionAttachment, func(ranges []byteRange) error {
+		if !countsAsDownload(ranges) {
+			return nil
+		}
+
+		_, err := h.Store.CountShareLinkDownload(c.Context(), link.ID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return fiber.NewError(fiber.StatusGone, "share link has reached its download limit")
+			}
+
+			util.RouteCustomError(err, c.Path())
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to retreive media.")
+		}
+
+		return nil
+	})
+}
+
+// GetShare serves a share link. Media links stream the file, group links list
+// the group with its descendants and their media.
+func (h *Handler) GetShare(c *fiber.Ctx) error {
+	link, err := h.resolveShareLink(c)
+	if err != nil {
+		return err
+	}
+
+	if link.MediaID.Valid {
+		media, err := h.Store.GetMediaFileByID(c.Context(), link.MediaID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return fiber.NewError(fiber.StatusNotFound, "media not found")
+			}
+
+			util.RouteCustomError(err, c.Path())
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to retreive media.")
+		}
+
+		return h.serveSharedMedia(c, link, media)
+	}
+
+	group, err := h.Store.GetGroupByID(c.Context(), link.GroupID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fiber.NewError(fiber.StatusNotFound, "group not found")
+		}
+
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to retreive group.")
+	}
+
+	groups, err := h.Store.ListGroupSubtree(c.Context(), db.ListGroupSubtreeParams{
+		UserID: group.UserID,
+		Path:   group.Path,
+	})
+	if err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to retreive group.")
+	}
+
+	response := SharedGroupResponse{
+		ID:        group.ID.Bytes,
+		Name:      group.Name,
+		Groups:    make([]SharedFolderResponse, 0, len(groups)),
+		Media:     []SharedMediaResponse{},
+		ExpiresAt: timePtr(link.ExpiresAt),
+	}
+
+	groupIDs := make([]pgtype.UUID, 0, len(groups))
+	for _, subgroup := range groups {
+		groupIDs = append(groupIDs, subgroup.ID)
+		if subgroup.ID == group.ID {
+			continue
+		}
+
+		response.Groups = append(response.Groups, SharedFolderResponse{
+			ID:       subgroup.ID.Bytes,
+			ParentID: uuidPtr(subgroup.ParentID),
+			Name:     subgroup.Name,
+		})
+	}
+
+	rows, err := h.Store.ListMediaByGroupMemberships(c.Context(), groupIDs)
+	if err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to retreive group.")
+	}
+
+	base := shareURL(c, c.Params("token")) + "/media/"
+	for _, row := range rows {
+		id := uuid.UUID(row.MediaFile.ID.Bytes)
+		response.Media = append(response.Media, SharedMediaResponse{
+			ID:         id,
+			GroupID:    uuidPtr(row.GroupID),
+			Name:       row.MediaFile.DisplayName,
+			FileType:   row.MediaFile.FileType,
+			Size:       row.MediaFile.Size,
+			UploadedAt: row.MediaFile.UploadedAt.Time,
+			URL:        base + id.String(),
+		})
+	}
+
+	return c.JSON(response)
+}
+
+// GetSharedGroupMedia streams a media file of a shared group or of one of its
+// descendants.
+func (h *Handler) GetSharedGroupMedia(c *fiber.Ctx) error {
+	mediaID, err := uuidParam(c, "media_id", "media")
+	if err != nil {
+		return err
+	}
+
+	link, err := h.resolveShareLink(c)
+	if err != nil {
+		return err
+	}
+
+	if !link.GroupID.Valid {
+		return fiber.NewError(fiber.StatusNotFound, "media not found")
+	}
+
+	group, err := h.Store.GetGroupByID(c.Context(), link.GroupID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fiber.NewError(fiber.StatusNotFound, "group not found")
+		}
+
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to retreive group.")
+	}
+
+	media, err := h.Store.GetMediaFileInGroupTree(c.Context(), db.GetMediaFileInGroupTreeParams{
+		ID:     mediaID,
+		UserID: group.UserID,
+		Path:   group.Path,
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fiber.NewError(fiber.StatusNotFound, "media not found")
+		}
+
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to retreive media.")
+	}
+
+	return h.serveSharedMedia(c, link, media)
+}
@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sangketkit01/media-library-api/internal/config"
+	db "github.com/sangketkit01/media-library-api/internal/db/sqlc"
+	"github.com/sangketkit01/media-library-api/internal/util"
+)
+
+const testSharePassword = "open sesame"
+
+// shareStore holds share links of a single media file, applying the updates
+// the way the queries do. Calling any other method of db.Store panics.
+type shareStore struct {
+	db.Store
+
+	media db.MediaFile
+	blob  db.Blob
+	links map[string]*db.ShareLink
+}
+
+func (s *shareStore) addLink(token string, link db.ShareLink) *db.ShareLink {
+	link.ID = pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	link.MediaID = s.media.ID
+	link.TokenHash = util.HashToken(token)
+	s.links[string(link.TokenHash)] = &link
+	return &link
+}
+
+func (s *shareStore) byID(id pgtype.UUID) (*db.ShareLink, error) {
+	for _, link := range s.links {
+		if link.ID == id {
+			return link, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (s *shareStore) GetShareLinkByTokenHash(ctx context.Context, tokenHash []byte) (db.ShareLink, error) {
+	link, ok := s.links[string(tokenHash)]
+	if !ok {
+		return db.ShareLink{}, pgx.ErrNoRows
+	}
+	return *link, nil
+}
+
+func (s *shareStore) CountShareLinkDownload(ctx context.Context, id pgtype.UUID) (db.ShareLink, error) {
+	link, err := s.byID(id)
+	if err != nil {
+		return db.ShareLink{}, err
+	}
+	if link.MaxDownloads.Valid && link.DownloadCount >= link.MaxDownloads.Int32 {
+		return db.ShareLink{}, pgx.ErrNoRows
+	}
+	link.DownloadCount++
+	return *link, nil
+}
+
+func (s *shareStore) RecordShareLinkPasswordFailure(ctx context.Context, arg db.RecordShareLinkPasswordFailureParams) (db.ShareLink, error) {
+	link, err := s.byID(arg.ID)
+	if err != nil {
+		return db.ShareLink{}, err
+	}
+	if link.FailedAttempts+1 >= arg.MaxAttempts {
+		link.FailedAttempts = 0
+		link.LockedUntil = arg.LockUntil
+	} else {
+		link.FailedAttempts++
+	}
+	return *link, nil
+}
+
+func (s *shareStore) ResetShareLinkPasswordFailures(ctx context.Context, id pgtype.UUID) error {
+	link, err := s.byID(id)
+	if err != nil {
+		return err
+	}
+	link.FailedAttempts = 0
+	return nil
+}
+
+func (s *shareStore) GetMediaFileByID(ctx context.Context, id pgtype.UUID) (db.MediaFile, error) {
+	if id != s.media.ID {
+		return db.MediaFile{}, pgx.ErrNoRows
+	}
+	return s.media, nil
+}
+
+func (s *shareStore) GetBlobByID(ctx context.Context, id pgtype.UUID) (db.Blob, error) {
+	if id != s.blob.ID {
+		return db.Blob{}, pgx.ErrNoRows
+	}
+	return s.blob, nil
+}
+
+// newShareApp serves share links of the test media, locking links for a
+// minute after three wrong passwords.
+func newShareApp(t *testing.T) (*fiber.App, *shareStore) {
+	t.Helper()
+
+	h, media, blob := newTestMedia(t)
+	blob.ID = pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	media.ID = pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	media.BlobID = blob.ID
+
+	store := &shareStore{
+		media: media,
+		blob:  blob,
+		links: make(map[string]*db.ShareLink),
+	}
+	h.Store = store
+	h.Config = &config.Config{
+		SharePasswordMaxAttempts: 3,
+		SharePasswordLockout:     time.Minute,
+	}
+
+	app := fiber.New()
+	app.Get("/s/:token", h.GetShare)
+	return app, store
+}
+
+func getShare(t *testing.T, app *fiber.App, method, token string, header map[string]string) int {
+	t.Helper()
+
+	req := httptest.NewRequest(method, "/s/"+token, nil)
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	res, _ := doRequest(t, app, req)
+	return res.StatusCode
+}
+
+func TestCountsAsDownload(t *testing.T) {
+	tests := []struct {
+		name   string
+		ranges []byteRange
+		want   bool
+	}{
+		{"whole file", nil, true},
+		{"from the start", []byteRange{{0, 10}}, true},
+		{"from the middle", []byteRange{{50, 10}}, false},
+		{"suffix", []byteRange{{90, 10}}, false},
+		{"several without the start", []byteRange{{10, 5}, {50, 5}}, false},
+		{"several with the start", []byteRange{{50, 5}, {0, 1}}, true},
+	}
+
+	for _, tt := range tests {
+		if got := countsAsDownload(tt.ranges); got != tt.want {
+			t.Errorf("%s: countsAsDownload(%v) = %v, want %v", tt.name, tt.ranges, got, tt.want)
+		}
+	}
+}
+
+func TestShareLinkStates(t *testing.T) {
+	app, store := newShareApp(t)
+
+	store.addLink("live", db.ShareLink{
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(time.Hour), Valid: true},
+	})
+	store.addLink("revoked", db.ShareLink{
+		RevokedAt: pgtype.Timestamptz{Time: time.Now().Add(-time.Minute), Valid: true},
+	})
+	store.addLink("expired", db.ShareLink{
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(-time.Second), Valid: true},
+	})
+	store.addLink("used-up", db.ShareLink{
+		MaxDownloads:  pgtype.Int4{Int32: 2, Valid: true},
+		DownloadCount: 2,
+	})
+
+	tests := []struct {
+		token  string
+		status int
+	}{
+		{"live", fiber.StatusOK},
+		{"revoked", fiber.StatusGone},
+		{"expired", fiber.StatusGone},
+		{"used-up", fiber.StatusGone},
+		{"unknown", fiber.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		if status := getShare(t, app, fiber.MethodGet, tt.token, nil); status != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.token, status, tt.status)
+		}
+	}
+}
+
+func TestShareLinkDownloadLimit(t *testing.T) {
+	app, store := newShareApp(t)
+	link := store.addLink("limited", db.ShareLink{
+		MaxDownloads: pgtype.Int4{Int32: 2, Valid: true},
+	})
+
+	steps := []struct {
+		name   string
+		method string
+		header map[string]string
+		status int
+		count  int32
+	}{
+		{"download", fiber.MethodGet, nil, fiber.StatusOK, 1},
+		// Seeking and resuming continue the download.
+		{"range in the middle", fiber.MethodGet, map[string]string{"Range": "bytes=50-59"}, fiber.StatusPartialContent, 1},
+		{"suffix range", fiber.MethodGet, map[string]string{"Range": "bytes=-10"}, fiber.StatusPartialContent, 1},
+		{"head", fiber.MethodHead, nil, fiber.StatusOK, 1},
+		{"not modified", fiber.MethodGet, map[string]string{"If-None-Match": `"4f2b0c6d"`}, fiber.StatusNotModified, 1},
+		// A range from the first byte starts another download.
+		{"range from the start", fiber.MethodGet, map[string]string{"Range": "bytes=0-9"}, fiber.StatusPartialContent, 2},
+		{"past the limit", fiber.MethodGet, nil, fiber.StatusGone, 2},
+		{"range past the limit", fiber.MethodGet, map[string]string{"Range": "bytes=50-59"}, fiber.StatusGone, 2},
+	}
+
+	for _, step := range steps {
+		if status := getShare(t, app, step.method, "limited", step.header); status != step.status {
+			t.Errorf("%s: status = %d, want %d", step.name, status, step.status)
+		}
+		if link.DownloadCount != step.count {
+			t.Errorf("%s: %d downloads counted, want %d", step.name, link.DownloadCount, step.count)
+		}
+	}
+}
+
+func TestShareLinkPasswordLockout(t *testing.T) {
+	app, store := newShareApp(t)
+
+	hashed, err := util.HashPassword(testSharePassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	link := store.addLink("protected", db.ShareLink{
+		PasswordHash: pgtype.Text{String: hashed, Valid: true},
+	})
+
+	right := map[string]string{sharePasswordHeader: testSharePassword}
+	wrong := map[string]string{sharePasswordHeader: "guess"}
+
+	get := func(header map[string]string) int {
+		return getShare(t, app, fiber.MethodGet, "protected", header)
+	}
+
+	if status := get(nil); status != fiber.StatusUnauthorized {
+		t.Errorf("no password: status = %d, want 401", status)
+	}
+	if link.FailedAttempts != 0 {
+		t.Errorf("a missing password counted as a failure")
+	}
+
+	// Failures only lock the link when they follow each other.
+	for i := 0; i < 2; i++ {
+		if status := get(wrong); status != fiber.StatusUnauthorized {
+			t.Errorf("wrong password: status = %d, want 401", status)
+		}
+	}
+	if status := get(right); status != fiber.StatusOK {
+		t.Errorf("right password: status = %d, want 200", status)
+	}
+	if link.FailedAttempts != 0 {
+		t.Errorf("%d failures left after the right password, want 0", link.FailedAttempts)
+	}
+
+	for i := 0; i < 3; i++ {
+		if status := get(wrong); status != fiber.StatusUnauthorized {
+			t.Errorf("wrong password %d: status = %d, want 401", i+1, status)
+		}
+	}
+	if !link.LockedUntil.Valid || !link.LockedUntil.Time.After(time.Now()) {
+		t.Fatalf("link not locked after 3 wrong passwords")
+	}
+
+	// While locked, not even the right password is checked.
+	if status := get(right); status != fiber.StatusTooManyRequests {
+		t.Errorf("locked: status = %d, want 429", status)
+	}
+	if status := get(wrong); status != fiber.StatusTooManyRequests {
+		t.Errorf("locked, wrong password: status = %d, want 429", status)
+	}
+
+	link.LockedUntil.Time = time.Now().Add(-time.Second)
+	if status := get(right); status != fiber.StatusOK {
+		t.Errorf("lock over: status = %d, want 200", status)
+	}
+	if link.DownloadCount != 2 {
+		t.Errorf("%d downloads counted, want 2", link.DownloadCount)
+	}
+}
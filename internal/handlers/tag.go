@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sangketkit01/media-library-api/internal/authz"
+	db "github.com/sangketkit01/media-library-api/internal/db/sqlc"
+	"github.com/sangketkit01/media-library-api/internal/util"
+)
+
+const (
+	maxTagLength      = 64
+	defaultTagsLimit  = 10
+	maxTagsLimit      = 100
+	tagMatchAll       = "all"
+	tagMatchAny       = "any"
+	tagQuerySeparator = ","
+)
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+var errInvalidTag = errors.New("tags must be 1 to 64 characters long and must not contain commas")
+
+type TagMediaRequest struct {
+	Tags []string `json:"tags" validate:"required,min=1,max=20"`
+}
+
+type BulkTagMediaRequest struct {
+	MediaIDs []uuid.UUID `json:"media_ids" validate:"required,min=1,max=100"`
+	Tags     []string    `json:"tags" validate:"required,min=1,max=20"`
+}
+
+type TagResponse struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+}
+
+type TagUsageResponse struct {
+	ID         uuid.UUID `json:"id"`
+	Name       string    `json:"name"`
+	MediaCount int64     `json:"media_count"`
+}
+
+// normalizeTag trims and lower cases a tag so that "Vacation " and "vacation"
+// are the same tag.
+func normalizeTag(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || utf8.RuneCountInString(name) > maxTagLength || strings.Contains(name, tagQuerySeparator) {
+		return "", errInvalidTag
+	}
+
+	return name, nil
+}
+
+// normalizeTags normalizes tags and removes duplicates, keeping their order.
+func normalizeTags(names []string) ([]string, error) {
+	tags := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+
+	for _, name := range names {
+		tag, err := normalizeTag(name)
+		if err != nil {
+			return nil, err
+		}
+		if seen[tag] {
+			continue
+		}
+
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+
+	return tags, nil
+}
+
+// parseTagFilter parses the tags and match query parameters of a media
+// listing into the tags and the number of them a media file must have.
+func parseTagFilter(c *fiber.Ctx) ([]string, int32, error) {
+	query := c.Query("tags")
+	if query == "" {
+		return nil, 0, nil
+	}
+
+	tags, err := normalizeTags(strings.Split(query, tagQuerySeparator))
+	if err != nil {
+		return nil, 0, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	switch c.Query("match", tagMatchAll) {
+	case tagMatchAll:
+		return tags, int32(len(tags)), nil
+	case tagMatchAny:
+		return tags, 1, nil
+	default:
+		return nil, 0, fiber.NewError(fiber.StatusBadRequest, "match must be all or any")
+	}
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+func uniqueUUIDs(ids []uuid.UUID) []uuid.UUID {
+	unique := make([]uuid.UUID, 0, len(ids))
+	seen := make(map[uuid.UUID]bool, len(ids))
+
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	return unique
+}
+
+func (h *Handler) GetMediaTags(c *fiber.Ctx) error {
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	media, err := h.authorizeMedia(c, user, "id", authz.Read)
+	if err != nil {
+		return err
+	}
+
+	return h.sendMediaTags(c, media.ID)
+}
+
+func (h *Handler) AddMediaTags(c *fiber.Ctx) error {
+	var req TagMediaRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "bad request")
+	}
+
+	validator := validator.New()
+	if err := validator.Struct(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "bad request")
+	}
+
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	media, err := h.authorizeMedia(c, user, "id", authz.Own)
+	if err != nil {
+		return err
+	}
+
+	if _, err := h.Authz.TagMedia(c.Context(), user, []pgtype.UUID{media.ID}, tags); err != nil {
+		return tagError(c, err, "failed to add tags.")
+	}
+
+	return h.sendMediaTags(c, media.ID)
+}
+
+func (h *Handler) RemoveMediaTag(c *fiber.Ctx) error {
+	name, err := url.PathUnescape(c.Params("tag"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid tag")
+	}
+
+	tag, err := normalizeTag(name)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	media, err := h.authorizeMedia(c, user, "id", authz.Own)
+	if err != nil {
+		return err
+	}
+
+	removed, err := h.Authz.UntagMedia(c.Context(), user, []pgtype.UUID{media.ID}, []string{tag})
+	if err != nil {
+		return tagError(c, err, "failed to remove tag.")
+	}
+
+	if removed == 0 {
+		return fiber.NewError(fiber.StatusNotFound, "tag not found on media")
+	}
+
+	return h.sendMediaTags(c, media.ID)
+}
+
+func (h *Handler) BulkTagMedia(c *fiber.Ctx) error {
+	return h.bulkTagMedia(c, false)
+}
+
+func (h *Handler) BulkUntagMedia(c *fiber.Ctx) error {
+	return h.bulkTagMedia(c, true)
+}
+
+func (h *Handler) bulkTagMedia(c *fiber.Ctx, remove bool) error {
+	var req BulkTagMediaRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "bad request")
+	}
+
+	validator := validator.New()
+	if err := validator.Struct(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "bad request")
+	}
+
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	mediaIDs := uuidList(uniqueUUIDs(req.MediaIDs))
+
+	if remove {
+		removed, err := h.Authz.UntagMedia(c.Context(), user, mediaIDs, tags)
+		if err != nil {
+			return tagError(c, err, "failed to remove tags.")
+		}
+
+		return c.JSON(fiber.Map{"removed": removed})
+	}
+
+	if _, err := h.Authz.TagMedia(c.Context(), user, mediaIDs, tags); err != nil {
+		return tagError(c, err, "failed to add tags.")
+	}
+
+	return c.JSON(fiber.Map{
+		"media_count": len(mediaIDs),
+		"tags":        tags,
+	})
+}
+
+// ListTags autocompletes the tags of the current user, most used first.
+func (h *Handler) ListTags(c *fiber.Ctx) error {
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	limit := c.QueryInt("limit", defaultTagsLimit)
+	if limit < 1 || limit > maxTagsLimit {
+		return fiber.NewError(fiber.StatusBadRequest, "limit must be between 1 and 100")
+	}
+
+	prefix := strings.ToLower(strings.TrimSpace(c.Query("prefix")))
+
+	tags, err := h.Store.ListTagsByPrefix(c.Context(), db.ListTagsByPrefixParams{
+		UserID:   user.ID,
+		Prefix:   escapeLike(prefix),
+		RowLimit: int32(limit),
+	})
+	if err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to retreive tags.")
+	}
+
+	response := make([]TagUsageResponse, 0, len(tags))
+	for _, tag := range tags {
+		response = append(response, TagUsageResponse{
+			ID:         tag.ID.Bytes,
+			Name:       tag.Name,
+			MediaCount: tag.MediaCount,
+		})
+	}
+
+	return c.JSON(response)
+}
+
+func (h *Handler) sendMediaTags(c *fiber.Ctx, mediaID pgtype.UUID) error {
+	tags, err := h.Store.ListTagsByMedia(c.Context(), mediaID)
+	if err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to retreive tags.")
+	}
+
+	response := make([]TagResponse, 0, len(tags))
+	for _, tag := range tags {
+		response = append(response, TagResponse{
+			ID:   tag.ID.Bytes,
+			Name: tag.Name,
+		})
+	}
+
+	return c.JSON(response)
+}
+
+func tagError(c *fiber.Ctx, err error, message string) error {
+	if errors.Is(err, authz.ErrNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, "media not found")
+	}
+
+	util.RouteCustomError(err, c.Path())
+	return fiber.NewError(fiber.StatusInternalServerError, message)
+}
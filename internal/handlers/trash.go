@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sangketkit01/media-library-api/internal/authz"
+	db "github.com/sangketkit01/media-library-api/internal/db/sqlc"
+	"github.com/sangketkit01/media-library-api/internal/util"
+)
+
+const purgeBatchSize = 100
+
+type TrashedMedia struct {
+	db.MediaFile
+	PurgeAt time.Time `json:"purge_at"`
+}
+
+type TrashedGroup struct {
+	db.MediaGroup
+	PurgeAt time.Time `json:"purge_at"`
+}
+
+type TrashResponse struct {
+	Media  []TrashedMedia `json:"media"`
+	Groups []TrashedGroup `json:"groups"`
+}
+
+func (h *Handler) DeleteMedia(c *fiber.Ctx) error {
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	media, err := h.authorizeMedia(c, user, "id", authz.Own)
+	if err != nil {
+		return err
+	}
+
+	_, err = h.Store.TrashMediaFile(c.Context(), media.ID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fiber.NewError(fiber.StatusNotFound, "media not found")
+		}
+
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to move media to trash.")
+	}
+
+	return c.JSON(fiber.Map{"message": "Moved media to trash."})
+}
+
+func (h *Handler) DeleteGroup(c *fiber.Ctx) error {
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	group, err := h.authorizeGroup(c, user, "id", authz.Own)
+	if err != nil {
+		return err
+	}
+
+	_, err = h.Store.TrashMediaGroupTx(c.Context(), group.ID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fiber.NewError(fiber.StatusNotFound, "group not found")
+		}
+
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to move group to trash.")
+	}
+
+	return c.JSON(fiber.Map{"message": "Moved group and its media to trash."})
+}
+
+func (h *Handler) GetTrash(c *fiber.Ctx) error {
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	medias, err := h.Store.ListTrashedMediaByUser(c.Context(), user.ID)
+	if err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to retreive trash.")
+	}
+
+	groups, err := h.Store.ListTrashedGroupsByUser(c.Context(), user.ID)
+	if err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to retreive trash.")
+	}
+
+	response := TrashResponse{
+		Media:  make([]TrashedMedia, 0, len(medias)),
+		Groups: make([]TrashedGroup, 0, len(groups)),
+	}
+
+	for _, media := range medias {
+		response.Media = append(response.Media, TrashedMedia{
+			MediaFile: media,
+			PurgeAt:   media.DeletedAt.Time.Add(h.Config.TrashRetention),
+		})
+	}
+
+	for _, group := range groups {
+		response.Groups = append(response.Groups, TrashedGroup{
+			MediaGroup: group,
+			PurgeAt:    group.DeletedAt.Time.Add(h.Config.TrashRetention),
+		})
+	}
+
+	return c.JSON(response)
+}
+
+// RestoreFromTrash restores the trashed media file or group with the given id.
+func (h *Handler) RestoreFromTrash(c *fiber.Ctx) error {
+	user, err := h.getCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	itemID, err := uuidParam(c, "id", "item")
+	if err != nil {
+		return err
+	}
+
+	media, err := h.Authz.TrashedMedia(c.Context(), user, itemID)
+	if err == nil {
+		restored, err := h.Store.RestoreMediaFile(c.Context(), media.ID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return fiber.NewError(fiber.StatusNotFound, "item not found in trash")
+			}
+
+			util.RouteCustomError(err, c.Path())
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to restore media.")
+		}
+
+		return c.JSON(fiber.Map{"media": restored})
+	}
+	if !errors.Is(err, authz.ErrNotFound) {
+		return authzError(c, err, "trash")
+	}
+
+	group, err := h.Authz.TrashedGroup(c.Context(), user, itemID)
+	if err == nil {
+		restored, err := h.Store.RestoreMediaGroupTx(c.Context(), group.ID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return fiber.NewError(fiber.StatusNotFound, "item not found in trash")
+			}
+
+			if isUniqueViolation(err) {
+				return fiber.NewError(fiber.StatusConflict, "Group name is already exists.")
+			}
+
+			util.RouteCustomError(err, c.Path())
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to restore group.")
+		}
+
+		return c.JSON(fiber.Map{"group": restored})
+	}
+	if !errors.Is(err, authz.ErrNotFound) {
+		return authzError(c, err, "trash")
+	}
+
+	return fiber.NewError(fiber.StatusNotFound, "item not found in trash")
+}
+
+// PurgeTrash permanently deletes media files and groups that have been in the
+// trash for longer than the configured retention. Items that cannot be deleted
+// are logged and skipped until the next run, their errors are returned joined.
+func (h *Handler) PurgeTrash(ctx context.Context) error {
+	cutoff := pgtype.Timestamptz{
+		Time:  time.Now().Add(-h.Config.TrashRetention),
+		Valid: true,
+	}
+
+	var errs []error
+	failedMedia := []pgtype.UUID{}
+	for {
+		medias, err := h.Store.ListExpiredTrashedMedia(ctx, db.ListExpiredTrashedMediaParams{
+			DeletedAt: cutoff,
+			SkipIds:   failedMedia,
+			MaxCount:  purgeBatchSize,
+		})
+		if err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+
+		for _, media := range medias {
+			if err := h.deleteMediaFile(ctx, media.ID); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+
+				log.Printf("Failed to purge media %s: %v\n", media.ID.String(), err)
+				errs = append(errs, fmt.Errorf("media %s: %w", media.ID.String(), err))
+				failedMedia = append(failedMedia, media.ID)
+			}
+		}
+
+		if len(medias) < purgeBatchSize {
+			break
+		}
+	}
+
+	failedGroups := []pgtype.UUID{}
+	for {
+		groups, err := h.Store.ListExpiredTrashedGroups(ctx, db.ListExpiredTrashedGroupsParams{
+			DeletedAt: cutoff,
+			SkipIds:   failedGroups,
+			MaxCount:  purgeBatchSize,
+		})
+		if err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+
+		for _, group := range groups {
+			if err := h.Store.DeleteMediaGroup(ctx, group.ID); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+
+				log.Printf("Failed to purge group %s: %v\n", group.ID.String(), err)
+				errs = append(errs, fmt.Errorf("group %s: %w", group.ID.String(), err))
+				failedGroups = append(failedGroups, group.ID)
+			}
+		}
+
+		if len(groups) < purgeBatchSize {
+			break
+		}
+	}
+
+	return errors.Join(errs...)
+}
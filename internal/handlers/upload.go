@@ -0,0 +1,459 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/sangketkit01/media-library-api/internal/db/sqlc"
+	"github.com/sangketkit01/media-library-api/internal/sniff"
+	"github.com/sangketkit01/media-library-api/internal/storage"
+	"github.com/sangketkit01/media-library-api/internal/token"
+	"github.com/sangketkit01/media-library-api/internal/util"
+)
+
+// Resumable uploads implement the tus 1.0.0 core protocol together with the
+// creation and termination extensions, see https://tus.io/protocols/resumable-upload.
+const (
+	tusVersion     = "1.0.0"
+	tusExtensions  = "creation,termination"
+	tusContentType = "application/offset+octet-stream"
+)
+
+func (h *Handler) TusOptions(c *fiber.Ctx) error {
+	c.Set("Tus-Resumable", tusVersion)
+	c.Set("Tus-Version", tusVersion)
+	c.Set("Tus-Extension", tusExtensions)
+	c.Set("Tus-Max-Size", strconv.FormatInt(h.Config.TusMaxSize, 10))
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *Handler) CreateUpload(c *fiber.Ctx) error {
+	if err := checkTusResumable(c); err != nil {
+		return err
+	}
+
+	p := c.Locals("payload")
+	payload, ok := p.(*token.Payload)
+	if !ok {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid payload")
+	}
+
+	user, err := h.Store.GetUserByID(c.Context(), pgtype.UUID{
+		Bytes: payload.ID,
+		Valid: true,
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fiber.NewError(fiber.StatusNotFound, "user not found")
+		}
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to retrieve user")
+	}
+
+	length, err := strconv.ParseInt(c.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid Upload-Length header")
+	}
+
+	if length > h.Config.TusMaxSize {
+		return fiber.NewError(fiber.StatusRequestEntityTooLarge, fmt.Sprintf("upload exceeds maximum size of %d bytes", h.Config.TusMaxSize))
+	}
+
+	quota, err := h.getQuota(c.Context(), user.ID)
+	if err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to retrieve usage")
+	}
+
+	if (quota.RemainingBytes != nil && length > *quota.RemainingBytes) || (quota.RemainingFiles != nil && *quota.RemainingFiles == 0) {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+			"message": "Storage quota exceeded",
+			"quota":   quota,
+		})
+	}
+
+	rawMetadata := c.Get("Upload-Metadata")
+	metadata, err := parseTusMetadata(rawMetadata)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid Upload-Metadata header")
+	}
+
+	filename := filepath.Base(metadata["filename"])
+	if filename == "." || filename == "/" {
+		filename = "upload"
+	}
+
+	fileType := metadata["filetype"]
+	if fileType == "" {
+		fileType = mime.TypeByExtension(filepath.Ext(filename))
+	}
+
+	upload, err := h.Store.CreateUpload(c.Context(), db.CreateUploadParams{
+		UserID:       user.ID,
+		Filename:     filename,
+		FileType:     fileType,
+		Metadata:     rawMetadata,
+		UploadLength: length,
+	})
+	if err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to create upload")
+	}
+
+	if length == 0 {
+		if _, err := h.finalizeUpload(c.Context(), upload); err != nil {
+			return finalizeUploadError(c, err)
+		}
+	}
+
+	c.Location(fmt.Sprintf("%s/media/uploads/%s", c.BaseURL(), upload.ID.String()))
+	return c.SendStatus(fiber.StatusCreated)
+}
+
+func (h *Handler) GetUploadOffset(c *fiber.Ctx) error {
+	if err := checkTusResumable(c); err != nil {
+		return err
+	}
+
+	upload, err := h.getOwnedUpload(c)
+	if err != nil {
+		return err
+	}
+
+	upload, err = h.finalizeUpload(c.Context(), upload)
+	if err != nil {
+		return finalizeUploadError(c, err)
+	}
+
+	c.Set("Upload-Offset", strconv.FormatInt(upload.UploadOffset, 10))
+	c.Set("Upload-Length", strconv.FormatInt(upload.UploadLength, 10))
+	if upload.Metadata != "" {
+		c.Set("Upload-Metadata", upload.Metadata)
+	}
+	c.Set(fiber.HeaderCacheControl, "no-store")
+
+	c.Status(fiber.StatusOK)
+	return nil
+}
+
+func (h *Handler) AppendUpload(c *fiber.Ctx) error {
+	if err := checkTusResumable(c); err != nil {
+		return err
+	}
+
+	if c.Get(fiber.HeaderContentType) != tusContentType {
+		return fiber.NewError(fiber.StatusUnsupportedMediaType, "content type must be "+tusContentType)
+	}
+
+	offset, err := strconv.ParseInt(c.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid Upload-Offset header")
+	}
+
+	upload, err := h.getOwnedUpload(c)
+	if err != nil {
+		return err
+	}
+
+	if offset != upload.UploadOffset {
+		return fiber.NewError(fiber.StatusConflict, "Upload-Offset does not match current offset")
+	}
+
+	// Chunks are streamed to storage as they arrive, so they may be as large
+	// as the whole upload, but their size must be known up front.
+	size := int64(c.Request().Header.ContentLength())
+	if size < 0 {
+		return fiber.NewError(fiber.StatusLengthRequired, "Content-Length is required")
+	}
+
+	if size > upload.UploadLength-upload.UploadOffset {
+		return fiber.NewError(fiber.StatusBadRequest, "chunk exceeds Upload-Length")
+	}
+
+	if size > 0 {
+		var body io.Reader = c.Context().RequestBodyStream()
+		if body == nil {
+			body = bytes.NewReader(c.Body())
+		}
+
+		key := uploadPartStorageKey(upload, uuid.New())
+		if err := h.Storage.Put(c.Context(), key, &exactReader{r: body, remaining: size}, size, tusContentType); err != nil {
+			if delErr := h.Storage.Delete(c.Context(), key); delErr != nil && !errors.Is(delErr, storage.ErrNotFound) {
+				util.RouteCustomError(delErr, c.Path())
+			}
+
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				return fiber.NewError(fiber.StatusBadRequest, "chunk is shorter than Content-Length")
+			}
+
+			util.RouteCustomError(err, c.Path())
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to store chunk")
+		}
+
+		upload, err = h.Store.AppendUploadPartTx(c.Context(), db.AppendUploadPartTxParams{
+			UploadID:     upload.ID,
+			UploadOffset: offset,
+			Size:         size,
+			StorageKey:   key,
+		})
+		if err != nil {
+			if delErr := h.Storage.Delete(c.Context(), key); delErr != nil {
+				util.RouteCustomError(delErr, c.Path())
+			}
+
+			if err == pgx.ErrNoRows {
+				return fiber.NewError(fiber.StatusConflict, "Upload-Offset does not match current offset")
+			}
+
+			util.RouteCustomError(err, c.Path())
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to record chunk")
+		}
+	}
+
+	upload, err = h.finalizeUpload(c.Context(), upload)
+	if err != nil {
+		return finalizeUploadError(c, err)
+	}
+
+	c.Set("Upload-Offset", strconv.FormatInt(upload.UploadOffset, 10))
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// exactReader reads remaining bytes from r, failing with io.ErrUnexpectedEOF
+// when r ends early, as when a client disconnects in the middle of a chunk.
+type exactReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (e *exactReader) Read(p []byte) (int, error) {
+	if e.remaining <= 0 {
+		return 0, io.EOF
+	}
+
+	if int64(len(p)) > e.remaining {
+		p = p[:e.remaining]
+	}
+
+	n, err := e.r.Read(p)
+	e.remaining -= int64(n)
+	if err == io.EOF && e.remaining > 0 {
+		err = io.ErrUnexpectedEOF
+	} else if err == io.EOF {
+		err = nil
+	}
+
+	return n, err
+}
+
+func (h *Handler) TerminateUpload(c *fiber.Ctx) error {
+	if err := checkTusResumable(c); err != nil {
+		return err
+	}
+
+	upload, err := h.getOwnedUpload(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.deleteUploadParts(c.Context(), upload); err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to delete upload")
+	}
+
+	if err := h.Store.DeleteUpload(c.Context(), upload.ID); err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to delete upload")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func checkTusResumable(c *fiber.Ctx) error {
+	c.Set("Tus-Resumable", tusVersion)
+
+	if c.Get("Tus-Resumable") != tusVersion {
+		c.Set("Tus-Version", tusVersion)
+		return fiber.NewError(fiber.StatusPreconditionFailed, "unsupported tus version")
+	}
+
+	return nil
+}
+
+func (h *Handler) getOwnedUpload(c *fiber.Ctx) (db.Upload, error) {
+	p := c.Locals("payload")
+	payload, ok := p.(*token.Payload)
+	if !ok {
+		return db.Upload{}, fiber.NewError(fiber.StatusBadRequest, "invalid payload")
+	}
+
+	uploadID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return db.Upload{}, fiber.NewError(fiber.StatusNotFound, "upload not found")
+	}
+
+	upload, err := h.Store.GetUploadByID(c.Context(), pgtype.UUID{
+		Bytes: uploadID,
+		Valid: true,
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return db.Upload{}, fiber.NewError(fiber.StatusNotFound, "upload not found")
+		}
+
+		util.RouteCustomError(err, c.Path())
+		return db.Upload{}, fiber.NewError(fiber.StatusInternalServerError, "failed to retrieve upload")
+	}
+
+	if upload.UserID.Bytes != payload.ID {
+		return db.Upload{}, fiber.NewError(fiber.StatusNotFound, "upload not found")
+	}
+
+	return upload, nil
+}
+
+// finalizeUpload turns a fully received upload into a media file. It is a
+// no-op for uploads that are incomplete or were already finalized, so it can
+// be retried safely after a failure.
+func (h *Handler) finalizeUpload(ctx context.Context, upload db.Upload) (db.Upload, error) {
+	if upload.CompletedAt.Valid || upload.UploadOffset != upload.UploadLength {
+		return upload, nil
+	}
+
+	parts, err := h.Store.ListUploadParts(ctx, upload.ID)
+	if err != nil {
+		return upload, err
+	}
+
+	keys := make([]string, 0, len(parts))
+	for _, part := range parts {
+		keys = append(keys, part.StorageKey)
+	}
+
+	user, err := h.Store.GetUserByID(ctx, upload.UserID)
+	if err != nil {
+		return upload, err
+	}
+
+	arg := db.CreateMediaFileParams{
+		UserID:           upload.UserID,
+		Filename:         fmt.Sprintf("%d_%s", time.Now().UnixNano(), upload.Filename),
+		DeclaredType:     upload.FileType,
+		Size:             upload.UploadLength,
+		OriginalFilename: upload.Filename,
+	}
+
+	media, err := h.createMediaFile(ctx, user.Tier, arg, func() (io.ReadCloser, error) {
+		return storage.NewConcatReader(ctx, h.Storage, keys), nil
+	})
+	if err != nil {
+		// Rejected content will never be accepted, drop the upload altogether.
+		if errors.Is(err, sniff.ErrRejected) {
+			if delErr := h.deleteUploadParts(ctx, upload); delErr != nil {
+				return upload, delErr
+			}
+			if delErr := h.Store.DeleteUpload(ctx, upload.ID); delErr != nil {
+				return upload, delErr
+			}
+		}
+		return upload, err
+	}
+
+	completed, err := h.Store.CompleteUpload(ctx, db.CompleteUploadParams{
+		ID:          upload.ID,
+		MediaFileID: media.ID,
+	})
+	if err != nil {
+		// Another request finalized the upload first, drop our copy.
+		if err == pgx.ErrNoRows {
+			if err := h.deleteMediaFile(ctx, media.ID); err != nil {
+				return upload, err
+			}
+			return h.Store.GetUploadByID(ctx, upload.ID)
+		}
+		return upload, err
+	}
+
+	if err := h.deleteUploadParts(ctx, completed); err != nil {
+		log.Printf("failed to clean up parts of upload %s: %v\n", upload.ID.String(), err)
+	}
+
+	return completed, nil
+}
+
+func finalizeUploadError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, sniff.ErrRejected) {
+		return fiber.NewError(fiber.StatusUnsupportedMediaType, err.Error())
+	}
+
+	// The upload is kept so it can be finalized once space has been freed.
+	var exceeded *db.QuotaExceededError
+	if errors.As(err, &exceeded) {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+			"message": "Storage quota exceeded",
+			"quota":   newQuotaResponse(exceeded.Limits, exceeded.Usage),
+		})
+	}
+
+	util.RouteCustomError(err, c.Path())
+	return fiber.NewError(fiber.StatusInternalServerError, "failed to finalize upload")
+}
+
+func (h *Handler) deleteUploadParts(ctx context.Context, upload db.Upload) error {
+	parts, err := h.Store.ListUploadParts(ctx, upload.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, part := range parts {
+		if err := h.Storage.Delete(ctx, part.StorageKey); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+	}
+
+	return h.Store.DeleteUploadParts(ctx, upload.ID)
+}
+
+func uploadPartStorageKey(upload db.Upload, partID uuid.UUID) string {
+	return upload.UserID.String() + "/uploads/" + upload.ID.String() + "/" + partID.String()
+}
+
+// parseTusMetadata decodes an Upload-Metadata header made of comma separated
+// "key base64value" pairs. Values may be omitted.
+func parseTusMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, errors.New("empty metadata key")
+		}
+
+		value, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, err
+		}
+
+		metadata[key] = string(value)
+	}
+
+	return metadata, nil
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/sangketkit01/media-library-api/internal/db/sqlc"
+	"github.com/sangketkit01/media-library-api/internal/token"
+	"github.com/sangketkit01/media-library-api/internal/util"
+)
+
+type QuotaResponse struct {
+	UsedBytes      int64  `json:"used_bytes"`
+	LimitBytes     *int64 `json:"limit_bytes"`
+	RemainingBytes *int64 `json:"remaining_bytes"`
+	UsedFiles      int64  `json:"used_files"`
+	LimitFiles     *int64 `json:"limit_files"`
+	RemainingFiles *int64 `json:"remaining_files"`
+}
+
+// UsageCategory counts the bytes of identical files once in Bytes, as they
+// are stored and count towards the quota, and once per copy in LogicalBytes.
+type UsageCategory struct {
+	Category     string `json:"category"`
+	Files        int64  `json:"files"`
+	Bytes        int64  `json:"bytes"`
+	LogicalBytes int64  `json:"logical_bytes"`
+}
+
+type UsageResponse struct {
+	QuotaResponse
+	Categories []UsageCategory `json:"categories"`
+}
+
+// usageCategories lists the categories reported by GetUserUsage in order.
+var usageCategories = []string{"image", "video", "audio", "document", "archive", "other"}
+
+func mimeCategory(mimeType string) string {
+	mediaType, _, _ := strings.Cut(mimeType, "/")
+
+	switch {
+	case mediaType == "image" || mediaType == "video" || mediaType == "audio":
+		return mediaType
+	case mediaType == "text",
+		mimeType == "application/pdf",
+		mimeType == "application/msword",
+		strings.HasPrefix(mimeType, "application/vnd.openxmlformats-officedocument."),
+		strings.HasPrefix(mimeType, "application/vnd.oasis.opendocument."),
+		strings.HasPrefix(mimeType, "application/vnd.ms-"):
+		return "document"
+	case mimeType == "application/zip",
+		mimeType == "application/gzip",
+		mimeType == "application/x-tar",
+		mimeType == "application/x-7z-compressed",
+		mimeType == "application/vnd.rar":
+		return "archive"
+	default:
+		return "other"
+	}
+}
+
+func (h *Handler) defaultQuota() *db.QuotaLimits {
+	return &db.QuotaLimits{
+		MaxBytes: h.Config.QuotaDefaultBytes,
+		MaxFiles: h.Config.QuotaDefaultFiles,
+	}
+}
+
+// getQuota returns the limits that apply to a user and what they currently use.
+func (h *Handler) getQuota(ctx context.Context, userID pgtype.UUID) (QuotaResponse, error) {
+	quota, err := h.Store.GetUserQuota(ctx, userID)
+	if err != nil && err != pgx.ErrNoRows {
+		return QuotaResponse{}, err
+	}
+	limits := db.ResolveQuota(quota, *h.defaultQuota())
+
+	usedBytes, err := h.Store.CountMediaSizeByUser(ctx, userID)
+	if err != nil {
+		return QuotaResponse{}, err
+	}
+
+	usedFiles, err := h.Store.CountMediaFilesByUser(ctx, userID)
+	if err != nil {
+		return QuotaResponse{}, err
+	}
+
+	return newQuotaResponse(limits, db.QuotaUsage{Bytes: usedBytes, Files: usedFiles}), nil
+}
+
+func newQuotaResponse(limits db.QuotaLimits, usage db.QuotaUsage) QuotaResponse {
+	response := QuotaResponse{
+		UsedBytes: usage.Bytes,
+		UsedFiles: usage.Files,
+	}
+
+	if limits.MaxBytes > 0 {
+		remaining := max(0, limits.MaxBytes-usage.Bytes)
+		response.LimitBytes = &limits.MaxBytes
+		response.RemainingBytes = &remaining
+	}
+
+	if limits.MaxFiles > 0 {
+		remaining := max(0, limits.MaxFiles-usage.Files)
+		response.LimitFiles = &limits.MaxFiles
+		response.RemainingFiles = &remaining
+	}
+
+	return response
+}
+
+func (h *Handler) GetUserUsage(c *fiber.Ctx) error {
+	p := c.Locals("payload")
+	payload, ok := p.(*token.Payload)
+	if !ok {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid payload")
+	}
+
+	user, err := h.Store.GetUserByID(c.Context(), pgtype.UUID{
+		Bytes: payload.ID,
+		Valid: true,
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fiber.NewError(fiber.StatusNotFound, "user not found")
+		}
+
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to retrieve user")
+	}
+
+	quota, err := h.getQuota(c.Context(), user.ID)
+	if err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to retrieve usage")
+	}
+
+	rows, err := h.Store.ListMediaUsageByType(c.Context(), user.ID)
+	if err != nil {
+		util.RouteCustomError(err, c.Path())
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to retrieve usage")
+	}
+
+	totals := make(map[string]*UsageCategory, len(usageCategories))
+	categories := make([]UsageCategory, len(usageCategories))
+	for i, category := range usageCategories {
+		categories[i].Category = category
+		totals[category] = &categories[i]
+	}
+
+	for _, row := range rows {
+		category := totals[mimeCategory(row.FileType)]
+		category.Files += row.Files
+		category.Bytes += row.Bytes
+		category.LogicalBytes += row.LogicalBytes
+	}
+
+	return c.JSON(UsageResponse{
+		QuotaResponse: quota,
+		Categories:    categories,
+	})
+}
@@ -7,8 +7,9 @@ import (
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
-	"github.com/jackc/pgx"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sangketkit01/media-library-api/internal/config"
 	db "github.com/sangketkit01/media-library-api/internal/db/sqlc"
 	"github.com/sangketkit01/media-library-api/internal/token"
 	"github.com/sangketkit01/media-library-api/internal/util"
@@ -20,9 +21,10 @@ type CreateUserRequest struct {
 }
 
 type CreateUserResponse struct {
-	ID        uuid.UUID `json:"id"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
+	ID            uuid.UUID `json:"id"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	CreatedAt     time.Time `json:"created_at"`
 }
 
 func (h *Handler) CreateUser(c *fiber.Ctx) error {
@@ -48,17 +50,23 @@ func (h *Handler) CreateUser(c *fiber.Ctx) error {
 
 	user, err := h.Store.CreateUser(c.Context(), arg)
 	if err != nil {
-		if pgError, ok := err.(*pgx.PgError); ok && pgError.Code == util.UniqueViolationErrCode {
+		if isUniqueViolation(err) {
 			return fiber.NewError(fiber.StatusConflict, "Email is already exists.")
 		}
 
 		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
 	}
 
+	// The account is created either way, the user can ask for another email.
+	if err := h.startEmailVerification(c.Context(), user); err != nil {
+		util.RouteCustomError(err, c.Path())
+	}
+
 	response := CreateUserResponse{
-		ID:        user.ID.Bytes,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt.Time,
+		ID:            user.ID.Bytes,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt.Valid,
+		CreatedAt:     user.CreatedAt.Time,
 	}
 
 	return c.JSON(response)
@@ -78,6 +86,7 @@ type LoginUserResponse struct {
 	RefreshTokenExpiredAt time.Time `json:"refresh_token_expired"`
 	ID                    uuid.UUID `json:"id"`
 	Email                 string    `json:"email"`
+	EmailVerified         bool      `json:"email_verified"`
 	CreatedAt             time.Time `json:"created_at"`
 }
 func (h *Handler) LoginUser(c *fiber.Ctx) error {
@@ -103,93 +112,55 @@ func (h *Handler) LoginUser(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "invalid password credential")
 	}
 
-	session, err := h.Store.GetReusableSessionByUserID(c.Context(), db.GetReusableSessionByUserIDParams{
-		UserID: user.ID,
-		ExpiresAt: pgtype.Timestamptz{
-			Time: time.Now(),
-			Valid: true,
-		},
-	})
-
-	var sessionID uuid.UUID
-	var accessToken, refreshToken string
-	var accessPayload, refreshPayload *token.Payload
-
-	if err == nil && !session.IsBlocked.Bool && session.ExpiresAt.Time.After(time.Now()) {
-		sessionID = session.ID.Bytes
-
-		accessToken, accessPayload, err = h.tokenMaker.CreateToken(user.ID.Bytes, sessionID, h.Config.AccessTokenDuration)
-		if err != nil {
-			return fiber.NewError(fiber.StatusInternalServerError, err.Error())
-		}
-
-		refreshToken, refreshPayload, err = h.tokenMaker.CreateToken(user.ID.Bytes, sessionID, h.Config.RefreshTokenDuration)
-		if err != nil {
-			return fiber.NewError(fiber.StatusInternalServerError, err.Error())
-		}
-
-		err = h.Store.UpdateSessionTokenAndExpiry(c.Context(), db.UpdateSessionTokenAndExpiryParams{
-			RefreshToken: refreshToken,
-
-			ID: pgtype.UUID{
-				Bytes: sessionID,
-				Valid: true,
-			},
+	if !user.EmailVerifiedAt.Valid && h.Config.UnverifiedEmailPolicy == config.UnverifiedDeny {
+		return fiber.NewError(fiber.StatusForbidden, "email is not verified")
+	}
 
-			ExpiresAt: pgtype.Timestamptz{
-				Time: refreshPayload.ExpiredAt,
-				Valid: true,
-			},
-		})
+	// Every login starts a new session, which is its own refresh token family.
+	sessionID, _ := uuid.NewUUID()
 
-		if err != nil {
-			return fiber.NewError(fiber.StatusInternalServerError, err.Error())
-		}
-	} else {
-		sessionID, _ = uuid.NewUUID()
-
-		accessToken, accessPayload, err = h.tokenMaker.CreateToken(user.ID.Bytes, sessionID, h.Config.AccessTokenDuration)
-		if err != nil {
-			return fiber.NewError(fiber.StatusInternalServerError, err.Error())
-		}
+	accessToken, accessPayload, err := h.tokenMaker.CreateToken(user.ID.Bytes, sessionID, h.Config.AccessTokenDuration)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
 
-		refreshToken, refreshPayload, err = h.tokenMaker.CreateToken(user.ID.Bytes, sessionID, h.Config.RefreshTokenDuration)
-		if err != nil {
-			return fiber.NewError(fiber.StatusInternalServerError, err.Error())
-		}
+	// New sessions start at generation 1.
+	refreshToken, refreshPayload, err := h.tokenMaker.CreateRefreshToken(user.ID.Bytes, sessionID, 1, h.Config.RefreshTokenDuration)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
 
-		arg := db.CreateSessionParams{
-			ID: pgtype.UUID{
-				Bytes: sessionID,
-				Valid: true,
-			},
-			UserID: pgtype.UUID{
-				Bytes: user.ID.Bytes,
-				Valid: true,
-			},
-			RefreshToken: refreshToken,
-			UserAgent: pgtype.Text{
-				String: c.Get("User-Agent"),
-				Valid:  true,
-			},
-			ClientIp: pgtype.Text{
-				String: c.IP(),
-				Valid:  true,
-			},
-			IsBlocked: pgtype.Bool{
-				Bool:  false,
-				Valid: true,
-			},
-			ExpiresAt: pgtype.Timestamptz{
-				Time:  refreshPayload.ExpiredAt,
-				Valid: true,
-			},
-		}
+	arg := db.CreateSessionParams{
+		ID: pgtype.UUID{
+			Bytes: sessionID,
+			Valid: true,
+		},
+		UserID: pgtype.UUID{
+			Bytes: user.ID.Bytes,
+			Valid: true,
+		},
+		RefreshToken: refreshToken,
+		UserAgent: pgtype.Text{
+			String: c.Get("User-Agent"),
+			Valid:  true,
+		},
+		ClientIp: pgtype.Text{
+			String: c.IP(),
+			Valid:  true,
+		},
+		IsBlocked: pgtype.Bool{
+			Bool:  false,
+			Valid: true,
+		},
+		ExpiresAt: pgtype.Timestamptz{
+			Time:  refreshPayload.ExpiredAt,
+			Valid: true,
+		},
+	}
 
-		_, err = h.Store.CreateSession(c.Context(), arg)
-		if err != nil {
-			return fiber.NewError(fiber.StatusInternalServerError, err.Error())
-		}
+	_, err = h.Store.CreateSession(c.Context(), arg)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
 	}
 
 	response := LoginUserResponse{
@@ -201,6 +172,7 @@ func (h *Handler) LoginUser(c *fiber.Ctx) error {
 		RefreshTokenExpiredAt: refreshPayload.ExpiredAt,
 		ID:                    user.ID.Bytes,
 		Email:                 user.Email,
+		EmailVerified:         user.EmailVerifiedAt.Valid,
 		CreatedAt:             user.CreatedAt.Time,
 	}
 
@@ -249,6 +221,8 @@ func (h *Handler) LogoutUser(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "cannot block session")
 	}
 
+	h.Sessions.Revoke(payload.SessionID)
+
 	return c.JSON(fiber.Map{
 		"message": "logged out successfully",
 	})
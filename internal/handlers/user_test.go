@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	db "github.com/sangketkit01/media-library-api/internal/db/sqlc"
+	"github.com/sangketkit01/media-library-api/internal/util"
+)
+
+// userStore fails user lookups and inserts with the errors of pgx/v5.
+// Calling any other method of db.Store panics.
+type userStore struct {
+	db.Store
+}
+
+func (s *userStore) CreateUser(ctx context.Context, arg db.CreateUserParams) (db.User, error) {
+	return db.User{}, &pgconn.PgError{Code: util.UniqueViolationErrCode, ConstraintName: "users_email_key"}
+}
+
+func (s *userStore) GetUserByEmail(ctx context.Context, email string) (db.User, error) {
+	return db.User{}, pgx.ErrNoRows
+}
+
+func TestUserErrorsFromTheStore(t *testing.T) {
+	h := &Handler{Store: &userStore{}}
+	app := fiber.New()
+	app.Post("/users", h.CreateUser)
+	app.Post("/users/login", h.LoginUser)
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{"duplicate signup", "/users", fiber.StatusConflict},
+		{"unknown email", "/users/login", fiber.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(fiber.MethodPost, tt.path,
+			strings.NewReader(`{"email": "ann@example.com", "password": "correct horse"}`))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+		res, body := doRequest(t, app, req)
+		if res.StatusCode != tt.status {
+			t.Errorf("%s: %d %q, want %d", tt.name, res.StatusCode, body, tt.status)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package imaging
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"io"
+	"strings"
+
+	// Register the decoders used by image.Decode.
+	_ "image/gif"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+const (
+	FormatJPEG = "jpeg"
+	FormatWebP = "webp"
+
+	// MaxPixels bounds the size of images we are willing to decode.
+	MaxPixels = 64 << 20
+
+	jpegQuality = 82
+)
+
+var ErrTooLarge = errors.New("image dimensions are too large")
+
+var supportedTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// IsSupported reports whether images of the given MIME type can be decoded.
+func IsSupported(mimeType string) bool {
+	mediaType, _, _ := strings.Cut(mimeType, ";")
+	return supportedTypes[strings.TrimSpace(strings.ToLower(mediaType))]
+}
+
+// Decode decodes a JPEG, PNG, GIF or WebP image, refusing images larger than
+// MaxPixels before allocating their pixel buffer.
+func Decode(r io.Reader) (image.Image, string, error) {
+	br := bufio.NewReader(r)
+
+	// Peek enough bytes to read the header without consuming the stream.
+	header, _ := br.Peek(64 << 10)
+	config, _, err := image.DecodeConfig(bytes.NewReader(header))
+	if err == nil && config.Width*config.Height > MaxPixels {
+		return nil, "", ErrTooLarge
+	}
+
+	return image.Decode(br)
+}
+
+// Fit scales img down so that it fits in a size x size box, keeping its aspect
+// ratio. Images already smaller than the box are copied unchanged.
+func Fit(img image.Image, size int) *image.NRGBA {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	if width > size || height > size {
+		if width >= height {
+			height = max(1, height*size/width)
+			width = size
+		} else {
+			width = max(1, width*size/height)
+			height = size
+		}
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	if width == bounds.Dx() && height == bounds.Dy() {
+		draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Src)
+		return dst
+	}
+
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, xdraw.Src, nil)
+	return dst
+}
+
+// Encode writes img in the given format. JPEG output is flattened onto a white
+// background since the format has no alpha channel.
+func Encode(w io.Writer, img image.Image, format string) error {
+	switch format {
+	case FormatJPEG:
+		bounds := img.Bounds()
+		flat := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+		draw.Draw(flat, flat.Bounds(), img, bounds.Min, draw.Over)
+		return jpeg.Encode(w, flat, &jpeg.Options{Quality: jpegQuality})
+
+	case FormatWebP:
+		return EncodeWebP(w, img)
+
+	default:
+		return fmt.Errorf("unsupported image format %q", format)
+	}
+}
+
+// ContentType returns the MIME type of an encoding format.
+func ContentType(format string) string {
+	return "image/" + format
+}
+
+// Orient applies an EXIF orientation (1 to 8) to img so that it is displayed
+// upright. Unknown orientations return img unchanged.
+func Orient(img *image.NRGBA, orientation int) *image.NRGBA {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = width-1-x, y
+			case 3:
+				dx, dy = width-1-x, height-1-y
+			case 4:
+				dx, dy = x, height-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = height-1-y, x
+			case 7:
+				dx, dy = height-1-y, width-1-x
+			case 8:
+				dx, dy = y, width-1-x
+			}
+
+			src := img.PixOffset(bounds.Min.X+x, bounds.Min.Y+y)
+			copy(dst.Pix[dst.PixOffset(dx, dy):], img.Pix[src:src+4])
+		}
+	}
+
+	return dst
+}
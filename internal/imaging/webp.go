@@ -0,0 +1,628 @@
+package imaging
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+	"math/bits"
+)
+
+// The encoder below produces lossless WebP (VP8L) images, see
+// https://developers.google.com/speed/webp/docs/webp_lossless_bitstream_specification.
+// It applies the subtract green and predictor transforms and entropy codes the
+// residuals with canonical prefix codes. Backward references are limited to
+// runs copied from the left or the row above and no color cache is used. That
+// is plenty for thumbnails and keeps the code small.
+
+const (
+	vp8lSignature = 0x2f
+
+	transformPredictor    = 0
+	transformSubtractGrn  = 2
+	predictorSizeBits     = 2 // 16x16 blocks
+	numLiteralCodes       = 256
+	numLengthCodes        = 24
+	numDistanceCodes      = 40
+	maxCodeLength         = 15
+	maxCodeLengthCodeBits = 7
+	numCodeLengthCodes    = 19
+	vp8lMaxDimension      = 1 << 14
+
+	minCopyLength = 3
+	maxCopyLength = 4096
+	// Distance codes of the pixel above and the pixel to the left in the
+	// VP8L distance map.
+	distanceCodeAbove = 1
+	distanceCodeLeft  = 2
+)
+
+var codeLengthCodeOrder = [numCodeLengthCodes]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// Predictor modes tried for every block. Modes that read the top-right pixel
+// are left out.
+var predictorModes = []uint32{1, 2, 11, 12, 13}
+
+// EncodeWebP writes img as a lossless WebP image.
+func EncodeWebP(w io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width < 1 || height < 1 || width > vp8lMaxDimension || height > vp8lMaxDimension {
+		return errors.New("webp: invalid image dimensions")
+	}
+
+	nrgba, ok := img.(*image.NRGBA)
+	if !ok || nrgba.Bounds().Min != (image.Point{}) {
+		nrgba = image.NewNRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(nrgba, nrgba.Bounds(), img, bounds.Min, draw.Src)
+	}
+
+	argb := make([]uint32, width*height)
+	hasAlpha := false
+	for y := 0; y < height; y++ {
+		row := nrgba.Pix[y*nrgba.Stride : y*nrgba.Stride+width*4]
+		for x := 0; x < width; x++ {
+			r, g, b, a := uint32(row[x*4]), uint32(row[x*4+1]), uint32(row[x*4+2]), uint32(row[x*4+3])
+			if a != 0xff {
+				hasAlpha = true
+			}
+			argb[y*width+x] = a<<24 | r<<16 | g<<8 | b
+		}
+	}
+
+	bw := &bitWriter{}
+	bw.writeBits(vp8lSignature, 8)
+	bw.writeBits(uint32(width-1), 14)
+	bw.writeBits(uint32(height-1), 14)
+	if hasAlpha {
+		bw.writeBits(1, 1)
+	} else {
+		bw.writeBits(0, 1)
+	}
+	bw.writeBits(0, 3) // version
+
+	// Transforms are undone by the decoder in reverse order.
+	subtractGreen(argb)
+	bw.writeBits(1, 1)
+	bw.writeBits(transformSubtractGrn, 2)
+
+	modes, residuals := predict(argb, width, height)
+	bw.writeBits(1, 1)
+	bw.writeBits(transformPredictor, 2)
+	bw.writeBits(predictorSizeBits, 3)
+	writeEntropyImage(bw, modes, (width+(1<<(predictorSizeBits+2))-1)>>(predictorSizeBits+2), false)
+
+	bw.writeBits(0, 1) // no more transforms
+	writeEntropyImage(bw, residuals, width, true)
+
+	data := bw.bytes()
+	chunkSize := len(data)
+	padding := chunkSize & 1
+
+	header := make([]byte, 20)
+	copy(header[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:8], uint32(4+8+chunkSize+padding))
+	copy(header[8:12], "WEBP")
+	copy(header[12:16], "VP8L")
+	binary.LittleEndian.PutUint32(header[16:20], uint32(chunkSize))
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if padding == 1 {
+		_, err := w.Write([]byte{0})
+		return err
+	}
+	return nil
+}
+
+func subtractGreen(argb []uint32) {
+	for i, p := range argb {
+		green := (p >> 8) & 0xff
+		red := ((p >> 16) - green) & 0xff
+		blue := (p - green) & 0xff
+		argb[i] = p&0xff00ff00 | red<<16 | blue
+	}
+}
+
+// predict picks a predictor mode for every block and returns the predictor
+// sub-image together with the residual image.
+func predict(argb []uint32, width, height int) ([]uint32, []uint32) {
+	blockSize := 1 << (predictorSizeBits + 2)
+	tilesX := (width + blockSize - 1) / blockSize
+	tilesY := (height + blockSize - 1) / blockSize
+
+	modes := make([]uint32, tilesX*tilesY)
+	residuals := make([]uint32, len(argb))
+
+	for ty := 0; ty < tilesY; ty++ {
+		for tx := 0; tx < tilesX; tx++ {
+			x0, y0 := tx*blockSize, ty*blockSize
+			x1, y1 := min(x0+blockSize, width), min(y0+blockSize, height)
+
+			bestMode, bestCost := predictorModes[0], -1
+			for _, mode := range predictorModes {
+				cost := 0
+				for y := y0; y < y1; y++ {
+					for x := x0; x < x1; x++ {
+						cost += residualCost(subPixels(argb[y*width+x], predictPixel(argb, width, x, y, mode)))
+					}
+				}
+				if bestCost < 0 || cost < bestCost {
+					bestMode, bestCost = mode, cost
+				}
+			}
+
+			modes[ty*tilesX+tx] = 0xff000000 | bestMode<<8
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					residuals[y*width+x] = subPixels(argb[y*width+x], predictPixel(argb, width, x, y, bestMode))
+				}
+			}
+		}
+	}
+
+	return modes, residuals
+}
+
+func predictPixel(argb []uint32, width, x, y int, mode uint32) uint32 {
+	switch {
+	case x == 0 && y == 0:
+		return 0xff000000
+	case y == 0:
+		return argb[x-1]
+	case x == 0:
+		return argb[(y-1)*width]
+	}
+
+	left := argb[y*width+x-1]
+	top := argb[(y-1)*width+x]
+	topLeft := argb[(y-1)*width+x-1]
+
+	switch mode {
+	case 1:
+		return left
+	case 2:
+		return top
+	case 11:
+		return selectPredictor(left, top, topLeft)
+	case 12:
+		return clampAddSubtractFull(left, top, topLeft)
+	case 13:
+		return clampAddSubtractHalf(average2(left, top), topLeft)
+	default:
+		return 0xff000000
+	}
+}
+
+func channel(p uint32, shift uint) int {
+	return int((p >> shift) & 0xff)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func clamp255(v int) uint32 {
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return uint32(v)
+}
+
+func average2(a, b uint32) uint32 {
+	return (((a ^ b) & 0xfefefefe) >> 1) + (a & b)
+}
+
+func selectPredictor(left, top, topLeft uint32) uint32 {
+	distLeft, distTop := 0, 0
+	for shift := uint(0); shift < 32; shift += 8 {
+		estimate := channel(left, shift) + channel(top, shift) - channel(topLeft, shift)
+		distLeft += abs(estimate - channel(left, shift))
+		distTop += abs(estimate - channel(top, shift))
+	}
+
+	if distLeft < distTop {
+		return left
+	}
+	return top
+}
+
+func clampAddSubtractFull(a, b, c uint32) uint32 {
+	var p uint32
+	for shift := uint(0); shift < 32; shift += 8 {
+		p |= clamp255(channel(a, shift)+channel(b, shift)-channel(c, shift)) << shift
+	}
+	return p
+}
+
+func clampAddSubtractHalf(a, b uint32) uint32 {
+	var p uint32
+	for shift := uint(0); shift < 32; shift += 8 {
+		ca := channel(a, shift)
+		p |= clamp255(ca+(ca-channel(b, shift))/2) << shift
+	}
+	return p
+}
+
+func subPixels(a, b uint32) uint32 {
+	var p uint32
+	for shift := uint(0); shift < 32; shift += 8 {
+		p |= ((a>>shift - b>>shift) & 0xff) << shift
+	}
+	return p
+}
+
+// residualCost approximates how expensive a residual is to entropy code, small
+// values in either direction are cheap.
+func residualCost(p uint32) int {
+	cost := 0
+	for shift := uint(0); shift < 32; shift += 8 {
+		cost += abs(int(int8(p >> shift)))
+	}
+	return cost
+}
+
+// token is either a literal pixel or, when length is non zero, a copy of
+// length pixels from the given distance code.
+type token struct {
+	pixel        uint32
+	length       int
+	distanceCode int
+}
+
+// tokenize greedily replaces runs of pixels equal to their left or upper
+// neighbour with backward references.
+func tokenize(argb []uint32, width int) []token {
+	tokens := make([]token, 0, len(argb))
+
+	for i := 0; i < len(argb); {
+		left, above := 0, 0
+		if i >= 1 {
+			for left < maxCopyLength && i+left < len(argb) && argb[i+left] == argb[i+left-1] {
+				left++
+			}
+		}
+		if i >= width {
+			for above < maxCopyLength && i+above < len(argb) && argb[i+above] == argb[i+above-width] {
+				above++
+			}
+		}
+
+		switch {
+		case left >= minCopyLength && left >= above:
+			tokens = append(tokens, token{length: left, distanceCode: distanceCodeLeft})
+			i += left
+		case above >= minCopyLength:
+			tokens = append(tokens, token{length: above, distanceCode: distanceCodeAbove})
+			i += above
+		default:
+			tokens = append(tokens, token{pixel: argb[i]})
+			i++
+		}
+	}
+
+	return tokens
+}
+
+// prefixEncode splits a length or distance code value into the prefix symbol
+// and extra bits used by VP8L.
+func prefixEncode(value int) (symbol int, extraBits uint, extra uint32) {
+	d := value - 1
+	if d < 4 {
+		return d, 0, 0
+	}
+
+	highest := bits.Len(uint(d)) - 1
+	second := (d >> (highest - 1)) & 1
+	extraBits = uint(highest - 1)
+	extra = uint32(d & (1<<extraBits - 1))
+
+	return 2*highest + second, extraBits, extra
+}
+
+// writeEntropyImage writes an image using a single prefix code group. The main
+// image additionally signals that no meta prefix codes are used.
+func writeEntropyImage(bw *bitWriter, argb []uint32, width int, mainImage bool) {
+	bw.writeBits(0, 1) // no color cache
+	if mainImage {
+		bw.writeBits(0, 1) // no meta prefix codes
+	}
+
+	tokens := tokenize(argb, width)
+
+	green := make([]int, numLiteralCodes+numLengthCodes)
+	red := make([]int, numLiteralCodes)
+	blue := make([]int, numLiteralCodes)
+	alpha := make([]int, numLiteralCodes)
+	distance := make([]int, numDistanceCodes)
+
+	for _, t := range tokens {
+		if t.length > 0 {
+			lengthSymbol, _, _ := prefixEncode(t.length)
+			distanceSymbol, _, _ := prefixEncode(t.distanceCode)
+			green[numLiteralCodes+lengthSymbol]++
+			distance[distanceSymbol]++
+			continue
+		}
+
+		p := t.pixel
+		green[(p>>8)&0xff]++
+		red[(p>>16)&0xff]++
+		blue[p&0xff]++
+		alpha[p>>24]++
+	}
+
+	greenCode := writePrefixCode(bw, green)
+	redCode := writePrefixCode(bw, red)
+	blueCode := writePrefixCode(bw, blue)
+	alphaCode := writePrefixCode(bw, alpha)
+	distanceCode := writePrefixCode(bw, distance)
+
+	for _, t := range tokens {
+		if t.length > 0 {
+			lengthSymbol, lengthBits, lengthExtra := prefixEncode(t.length)
+			greenCode.write(bw, numLiteralCodes+lengthSymbol)
+			bw.writeBits(lengthExtra, lengthBits)
+
+			distanceSymbol, distanceBits, distanceExtra := prefixEncode(t.distanceCode)
+			distanceCode.write(bw, distanceSymbol)
+			bw.writeBits(distanceExtra, distanceBits)
+			continue
+		}
+
+		p := t.pixel
+		greenCode.write(bw, int((p>>8)&0xff))
+		redCode.write(bw, int((p>>16)&0xff))
+		blueCode.write(bw, int(p&0xff))
+		alphaCode.write(bw, int(p>>24))
+	}
+}
+
+type prefixCode struct {
+	lengths []uint8
+	codes   []uint32
+	// trivial codes have a single symbol which takes no bits to write.
+	trivial bool
+}
+
+func (c *prefixCode) write(bw *bitWriter, symbol int) {
+	if c.trivial {
+		return
+	}
+	bw.writeBits(c.codes[symbol], uint(c.lengths[symbol]))
+}
+
+// writePrefixCode builds a prefix code from symbol counts and writes its
+// definition to the stream.
+func writePrefixCode(bw *bitWriter, counts []int) *prefixCode {
+	var used []int
+	for symbol, count := range counts {
+		if count > 0 {
+			used = append(used, symbol)
+		}
+	}
+
+	if len(used) == 0 {
+		used = []int{0}
+	}
+
+	if len(used) <= 2 && used[len(used)-1] < numLiteralCodes {
+		return writeSimplePrefixCode(bw, len(counts), used)
+	}
+
+	lengths := buildCodeLengths(counts, maxCodeLength)
+
+	codeLengthCounts := make([]int, numCodeLengthCodes)
+	for _, length := range lengths {
+		codeLengthCounts[length]++
+	}
+	codeLengthCode := newPrefixCode(buildCodeLengths(codeLengthCounts, maxCodeLengthCodeBits))
+
+	numCodes := 4
+	for i, symbol := range codeLengthCodeOrder {
+		if codeLengthCode.lengths[symbol] > 0 {
+			numCodes = max(numCodes, i+1)
+		}
+	}
+
+	bw.writeBits(0, 1) // normal code
+	bw.writeBits(uint32(numCodes-4), 4)
+	for _, symbol := range codeLengthCodeOrder[:numCodes] {
+		bw.writeBits(uint32(codeLengthCode.lengths[symbol]), 3)
+	}
+	bw.writeBits(0, 1) // code lengths cover the whole alphabet
+
+	for _, length := range lengths {
+		codeLengthCode.write(bw, int(length))
+	}
+
+	return newPrefixCode(lengths)
+}
+
+func writeSimplePrefixCode(bw *bitWriter, alphabetSize int, symbols []int) *prefixCode {
+	bw.writeBits(1, 1) // simple code
+	bw.writeBits(uint32(len(symbols)-1), 1)
+
+	if symbols[0] < 2 {
+		bw.writeBits(0, 1)
+		bw.writeBits(uint32(symbols[0]), 1)
+	} else {
+		bw.writeBits(1, 1)
+		bw.writeBits(uint32(symbols[0]), 8)
+	}
+
+	if len(symbols) == 2 {
+		bw.writeBits(uint32(symbols[1]), 8)
+	}
+
+	lengths := make([]uint8, alphabetSize)
+	for _, symbol := range symbols {
+		lengths[symbol] = 1
+	}
+	return newPrefixCode(lengths)
+}
+
+// newPrefixCode assigns canonical codes to code lengths. Codes are stored bit
+// reversed since the stream is written least significant bit first.
+func newPrefixCode(lengths []uint8) *prefixCode {
+	code := &prefixCode{
+		lengths: lengths,
+		codes:   make([]uint32, len(lengths)),
+	}
+
+	var lengthCounts [maxCodeLength + 1]int
+	used := 0
+	for _, length := range lengths {
+		if length > 0 {
+			lengthCounts[length]++
+			used++
+		}
+	}
+
+	if used <= 1 {
+		code.trivial = true
+		return code
+	}
+
+	var nextCode [maxCodeLength + 1]uint32
+	next := uint32(0)
+	for length := 1; length <= maxCodeLength; length++ {
+		next = (next + uint32(lengthCounts[length-1])) << 1
+		nextCode[length] = next
+	}
+
+	for symbol, length := range lengths {
+		if length == 0 {
+			continue
+		}
+		code.codes[symbol] = reverseBits(nextCode[length], uint(length))
+		nextCode[length]++
+	}
+
+	return code
+}
+
+func reverseBits(code uint32, length uint) uint32 {
+	var reversed uint32
+	for i := uint(0); i < length; i++ {
+		reversed = reversed<<1 | (code>>i)&1
+	}
+	return reversed
+}
+
+type huffmanNode struct {
+	count  int
+	symbol int
+	left   *huffmanNode
+	right  *huffmanNode
+}
+
+type huffmanHeap []*huffmanNode
+
+func (h huffmanHeap) Len() int { return len(h) }
+func (h huffmanHeap) Less(i, j int) bool {
+	if h[i].count == h[j].count {
+		return h[i].symbol < h[j].symbol
+	}
+	return h[i].count < h[j].count
+}
+func (h huffmanHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *huffmanHeap) Push(x any)   { *h = append(*h, x.(*huffmanNode)) }
+func (h *huffmanHeap) Pop() any {
+	old := *h
+	node := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return node
+}
+
+// buildCodeLengths computes Huffman code lengths no longer than maxLength. When
+// the optimal tree is too deep the counts are flattened and the tree rebuilt.
+func buildCodeLengths(counts []int, maxLength int) []uint8 {
+	lengths := make([]uint8, len(counts))
+
+	for minCount := 1; ; minCount *= 2 {
+		h := &huffmanHeap{}
+		for symbol, count := range counts {
+			if count > 0 {
+				*h = append(*h, &huffmanNode{count: max(count, minCount), symbol: symbol})
+			}
+		}
+
+		switch h.Len() {
+		case 0:
+			return lengths
+		case 1:
+			lengths[(*h)[0].symbol] = 1
+			return lengths
+		}
+
+		heap.Init(h)
+		for h.Len() > 1 {
+			a := heap.Pop(h).(*huffmanNode)
+			b := heap.Pop(h).(*huffmanNode)
+			heap.Push(h, &huffmanNode{
+				count:  a.count + b.count,
+				symbol: min(a.symbol, b.symbol),
+				left:   a,
+				right:  b,
+			})
+		}
+
+		for i := range lengths {
+			lengths[i] = 0
+		}
+		if assignDepths((*h)[0], 0, lengths, maxLength) {
+			return lengths
+		}
+	}
+}
+
+func assignDepths(node *huffmanNode, depth int, lengths []uint8, maxLength int) bool {
+	if node.left == nil {
+		if depth > maxLength {
+			return false
+		}
+		lengths[node.symbol] = uint8(depth)
+		return true
+	}
+
+	return assignDepths(node.left, depth+1, lengths, maxLength) &&
+		assignDepths(node.right, depth+1, lengths, maxLength)
+}
+
+type bitWriter struct {
+	buf   []byte
+	acc   uint64
+	nbits uint
+}
+
+func (w *bitWriter) writeBits(bits uint32, n uint) {
+	w.acc |= uint64(bits) << w.nbits
+	w.nbits += n
+	for w.nbits >= 8 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc >>= 8
+		w.nbits -= 8
+	}
+}
+
+func (w *bitWriter) bytes() []byte {
+	if w.nbits > 0 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc = 0
+		w.nbits = 0
+	}
+	return w.buf
+}
@@ -0,0 +1,161 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+func testImage(width, height int, pixel func(x, y int) color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, pixel(x, y))
+		}
+	}
+	return img
+}
+
+// roundTrip encodes img and decodes it again with the reference decoder.
+func roundTrip(t *testing.T, img image.Image) image.Image {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := EncodeWebP(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.Len()%2 != 0 {
+		t.Errorf("file size %d is odd, RIFF chunks are padded to even sizes", buf.Len())
+	}
+
+	decoded, err := webp.Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return decoded
+}
+
+func assertSamePixels(t *testing.T, want *image.NRGBA, got image.Image) {
+	t.Helper()
+
+	if got.Bounds().Size() != want.Bounds().Size() {
+		t.Fatalf("size = %v, want %v", got.Bounds().Size(), want.Bounds().Size())
+	}
+
+	origin := got.Bounds().Min
+	for y := 0; y < want.Bounds().Dy(); y++ {
+		for x := 0; x < want.Bounds().Dx(); x++ {
+			w := want.NRGBAAt(x, y)
+			g := color.NRGBAModel.Convert(got.At(origin.X+x, origin.Y+y)).(color.NRGBA)
+			// Fully transparent pixels may come back with any color.
+			if w.A == 0 && g.A == 0 {
+				continue
+			}
+			if g != w {
+				t.Fatalf("pixel (%d, %d) = %v, want %v", x, y, g, w)
+			}
+		}
+	}
+}
+
+func TestEncodeWebPIsLossless(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+
+	tests := []struct {
+		name          string
+		width, height int
+		pixel         func(x, y int) color.NRGBA
+	}{
+		{"single pixel", 1, 1, func(x, y int) color.NRGBA {
+			return color.NRGBA{200, 100, 50, 255}
+		}},
+		{"solid color", 64, 48, func(x, y int) color.NRGBA {
+			return color.NRGBA{12, 34, 56, 255}
+		}},
+		{"gradient", 97, 61, func(x, y int) color.NRGBA {
+			return color.NRGBA{uint8(x * 2), uint8(y * 4), uint8(x + y), 255}
+		}},
+		{"repeated rows", 40, 40, func(x, y int) color.NRGBA {
+			return color.NRGBA{uint8(x * 37), uint8(x * 11), uint8(x * 5), 255}
+		}},
+		{"stripes", 300, 7, func(x, y int) color.NRGBA {
+			if (x/3)%2 == 0 {
+				return color.NRGBA{255, 255, 255, 255}
+			}
+			return color.NRGBA{0, 0, 0, 255}
+		}},
+		{"alpha", 33, 17, func(x, y int) color.NRGBA {
+			return color.NRGBA{uint8(x * 7), 90, uint8(y * 13), uint8(x * y)}
+		}},
+		{"noise", 128, 96, func(x, y int) color.NRGBA {
+			return color.NRGBA{uint8(random.Intn(256)), uint8(random.Intn(256)), uint8(random.Intn(256)), uint8(random.Intn(256))}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := testImage(tt.width, tt.height, tt.pixel)
+			assertSamePixels(t, img, roundTrip(t, img))
+		})
+	}
+}
+
+func TestEncodeWebPConvertsOtherImages(t *testing.T) {
+	src := testImage(50, 30, func(x, y int) color.NRGBA {
+		return color.NRGBA{uint8(x * 5), uint8(y * 8), 128, 255}
+	})
+
+	// A sub-image does not start at the origin.
+	sub := src.SubImage(image.Rect(10, 5, 40, 25)).(*image.NRGBA)
+	want := image.NewNRGBA(image.Rect(0, 0, 30, 20))
+	draw.Draw(want, want.Bounds(), sub, sub.Bounds().Min, draw.Src)
+	assertSamePixels(t, want, roundTrip(t, sub))
+
+	// Other color models are converted first.
+	rgba := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	draw.Draw(rgba, rgba.Bounds(), src, image.Point{}, draw.Src)
+	want = image.NewNRGBA(rgba.Bounds())
+	draw.Draw(want, want.Bounds(), rgba, image.Point{}, draw.Src)
+	assertSamePixels(t, want, roundTrip(t, rgba))
+}
+
+func TestEncodeWebPRejectsInvalidDimensions(t *testing.T) {
+	for _, size := range []image.Rectangle{
+		image.Rect(0, 0, 0, 10),
+		image.Rect(0, 0, vp8lMaxDimension+1, 1),
+	} {
+		if err := EncodeWebP(&bytes.Buffer{}, image.NewNRGBA(size)); err == nil {
+			t.Errorf("encoded a %v image", size.Size())
+		}
+	}
+}
+
+func TestBuildCodeLengthsLimitsLength(t *testing.T) {
+	// Fibonacci counts make the optimal tree as deep as there are symbols.
+	counts := make([]int, 30)
+	a, b := 1, 1
+	for i := range counts {
+		counts[i] = a
+		a, b = b, a+b
+	}
+
+	lengths := buildCodeLengths(counts, maxCodeLength)
+
+	// A complete prefix code satisfies Kraft's equality.
+	kraft := 0
+	for symbol, length := range lengths {
+		if length == 0 || length > maxCodeLength {
+			t.Fatalf("symbol %d has length %d", symbol, length)
+		}
+		kraft += 1 << (maxCodeLength - length)
+	}
+	if kraft != 1<<maxCodeLength {
+		t.Errorf("Kraft sum = %d/%d, want 1", kraft, 1<<maxCodeLength)
+	}
+}
@@ -0,0 +1,58 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sangketkit01/media-library-api/internal/util"
+)
+
+// FileSender saves emails as .eml files in a directory instead of sending
+// them, for development and tests.
+type FileSender struct {
+	dir  string
+	from *mail.Address
+}
+
+func NewFileSender(dir string, from *mail.Address) (*FileSender, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &FileSender{
+		dir:  dir,
+		from: from,
+	}, nil
+}
+
+func (s *FileSender) Send(ctx context.Context, msg Message) error {
+	data, err := compose(s.from, msg)
+	if err != nil {
+		return err
+	}
+
+	suffix, err := util.RandomToken(6)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), suffix)
+	return os.WriteFile(filepath.Join(s.dir, name), data, 0o644)
+}
+
+// LogSender writes emails to the log instead of sending them.
+type LogSender struct{}
+
+func NewLogSender() *LogSender {
+	return &LogSender{}
+}
+
+func (s *LogSender) Send(ctx context.Context, msg Message) error {
+	log.Printf("Mail to %s: %s\n%s\n", msg.To, msg.Subject, msg.Text)
+	return nil
+}
@@ -0,0 +1,92 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/sangketkit01/media-library-api/internal/config"
+	"github.com/sangketkit01/media-library-api/internal/util"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+}
+
+// Sender delivers emails. Implementations must be safe for concurrent use.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+func NewSender(config *config.Config) (Sender, error) {
+	from, err := mail.ParseAddress(config.MailerFrom)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mailer from address %q: %w", config.MailerFrom, err)
+	}
+
+	switch strings.ToLower(config.MailerBackend) {
+	case "", "log":
+		return NewLogSender(), nil
+	case "file":
+		return NewFileSender(config.MailerFilePath, from)
+	case "smtp":
+		return NewSMTPSender(SMTPOptions{
+			Host:     config.SMTPHost,
+			Port:     config.SMTPPort,
+			Username: config.SMTPUsername,
+			Password: config.SMTPPassword,
+			From:     from,
+		})
+	default:
+		return nil, fmt.Errorf("unknown mailer backend %q", config.MailerBackend)
+	}
+}
+
+// compose renders a message in the Internet Message Format, as sent over
+// SMTP or saved as an .eml file.
+func compose(from *mail.Address, msg Message) ([]byte, error) {
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient %q: %w", msg.To, err)
+	}
+
+	id, err := util.RandomToken(18)
+	if err != nil {
+		return nil, err
+	}
+
+	domain := "localhost"
+	if _, d, ok := strings.Cut(from.Address, "@"); ok {
+		domain = d
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", to.String())
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", id, domain)
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n")
+	buf.WriteString("\r\n")
+
+	w := quotedprintable.NewWriter(&buf)
+	text := strings.ReplaceAll(msg.Text, "\r\n", "\n")
+	if _, err := w.Write([]byte(strings.ReplaceAll(text, "\n", "\r\n"))); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
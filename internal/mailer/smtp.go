@@ -0,0 +1,110 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+const smtpTimeout = 30 * time.Second
+
+type SMTPOptions struct {
+	Host string
+	Port int
+	// Username and Password are only used when Username is set. Servers are
+	// expected to offer STARTTLS for authentication, except on localhost.
+	Username string
+	Password string
+	From     *mail.Address
+}
+
+// SMTPSender delivers emails through an SMTP relay. STARTTLS is used when the
+// server offers it, so plain local stand-ins work too.
+type SMTPSender struct {
+	options SMTPOptions
+	addr    string
+}
+
+func NewSMTPSender(options SMTPOptions) (*SMTPSender, error) {
+	if options.Host == "" {
+		return nil, errors.New("smtp host is not configured")
+	}
+	if options.Port == 0 {
+		options.Port = 587
+	}
+
+	return &SMTPSender{
+		options: options,
+		addr:    net.JoinHostPort(options.Host, strconv.Itoa(options.Port)),
+	}, nil
+}
+
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	data, err := compose(s.options.From, msg)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, smtpTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.options.Host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.options.Host}); err != nil {
+			return err
+		}
+	}
+
+	if s.options.Username != "" {
+		auth := smtp.PlainAuth("", s.options.Username, s.options.Password, s.options.Host)
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return err
+	}
+
+	if err := client.Mail(s.options.From.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
@@ -0,0 +1,240 @@
+package mailer
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+type receivedMail struct {
+	auth string
+	from string
+	to   []string
+	data string
+}
+
+// fakeSMTP is a minimal SMTP stand-in. It offers AUTH PLAIN but not
+// STARTTLS, and rejects recipients in reject.
+type fakeSMTP struct {
+	listener net.Listener
+	reject   string
+	received chan receivedMail
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	server := &fakeSMTP{
+		listener: listener,
+		received: make(chan receivedMail, 1),
+	}
+	go server.serve()
+	return server
+}
+
+func (s *fakeSMTP) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTP) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeSMTP) handle(conn net.Conn) {
+	defer conn.Close()
+
+	text := textproto.NewConn(conn)
+	var msg receivedMail
+
+	text.PrintfLine("220 localhost ESMTP ready")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			text.PrintfLine("250-localhost")
+			text.PrintfLine("250 AUTH PLAIN")
+		case "AUTH":
+			mechanism, initial, _ := strings.Cut(arg, " ")
+			if mechanism != "PLAIN" {
+				text.PrintfLine("504 unsupported mechanism")
+				continue
+			}
+			decoded, err := base64.StdEncoding.DecodeString(initial)
+			if err != nil {
+				text.PrintfLine("501 invalid credentials")
+				continue
+			}
+			msg.auth = string(decoded)
+			text.PrintfLine("235 authenticated")
+		case "MAIL":
+			msg.from = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+			text.PrintfLine("250 ok")
+		case "RCPT":
+			to := strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>")
+			if to == s.reject {
+				text.PrintfLine("550 no such user")
+				continue
+			}
+			msg.to = append(msg.to, to)
+			text.PrintfLine("250 ok")
+		case "DATA":
+			text.PrintfLine("354 end data with <CR><LF>.<CR><LF>")
+			data, err := io.ReadAll(text.DotReader())
+			if err != nil {
+				return
+			}
+			msg.data = string(data)
+			text.PrintfLine("250 queued")
+			s.received <- msg
+		case "QUIT":
+			text.PrintfLine("221 bye")
+			return
+		default:
+			text.PrintfLine("502 unknown command")
+		}
+	}
+}
+
+func newTestSMTPSender(t *testing.T, server *fakeSMTP, username, password string) *SMTPSender {
+	sender, err := NewSMTPSender(SMTPOptions{
+		Host:     "localhost",
+		Port:     server.port(),
+		Username: username,
+		Password: password,
+		From:     &mail.Address{Name: "Media Library", Address: "no-reply@example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sender
+}
+
+func TestSMTPSenderDeliversMessage(t *testing.T) {
+	server := newFakeSMTP(t)
+	sender := newTestSMTPSender(t, server, "", "")
+
+	err := sender.Send(context.Background(), Message{
+		To:      "Ann <ann@example.com>",
+		Subject: "Vérifiez votre adresse",
+		Text:    "Line one\nLine two with a = sign\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := <-server.received
+	if got.auth != "" {
+		t.Errorf("authenticated without credentials: %q", got.auth)
+	}
+	if got.from != "no-reply@example.com" {
+		t.Errorf("MAIL FROM = %q", got.from)
+	}
+	if len(got.to) != 1 || got.to[0] != "ann@example.com" {
+		t.Errorf("RCPT TO = %q", got.to)
+	}
+
+	parsed, err := mail.ReadMessage(strings.NewReader(got.data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != "Vérifiez votre adresse" {
+		t.Errorf("Subject = %q, %v", subject, err)
+	}
+	if to := parsed.Header.Get("To"); !strings.Contains(to, "<ann@example.com>") {
+		t.Errorf("To = %q", to)
+	}
+	if id := parsed.Header.Get("Message-Id"); !strings.HasSuffix(id, "@example.com>") {
+		t.Errorf("Message-ID = %q", id)
+	}
+
+	body, err := io.ReadAll(quotedprintable.NewReader(parsed.Body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The dot reader of the stand-in turns CRLF line endings into LF.
+	if want := "Line one\nLine two with a = sign\n"; string(body) != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+}
+
+func TestSMTPSenderAuthenticates(t *testing.T) {
+	server := newFakeSMTP(t)
+	sender := newTestSMTPSender(t, server, "mailer", "s3cret")
+
+	err := sender.Send(context.Background(), Message{To: "ann@example.com", Subject: "Hi", Text: "Hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := <-server.received
+	if want := "\x00mailer\x00s3cret"; got.auth != want {
+		t.Errorf("AUTH PLAIN = %q, want %q", got.auth, want)
+	}
+}
+
+func TestSMTPSenderReportsRejectedRecipient(t *testing.T) {
+	server := newFakeSMTP(t)
+	server.reject = "nobody@example.com"
+	sender := newTestSMTPSender(t, server, "", "")
+
+	err := sender.Send(context.Background(), Message{To: "nobody@example.com", Subject: "Hi", Text: "Hello"})
+	if err == nil || !strings.Contains(err.Error(), "550") {
+		t.Errorf("err = %v, want the 550 rejection", err)
+	}
+}
+
+func TestSMTPSenderRejectsInvalidRecipient(t *testing.T) {
+	server := newFakeSMTP(t)
+	sender := newTestSMTPSender(t, server, "", "")
+
+	if err := sender.Send(context.Background(), Message{To: "not an address", Subject: "Hi"}); err == nil {
+		t.Error("sent a message to an invalid address")
+	}
+}
+
+func TestSMTPSenderFailsWhenServerIsDown(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	sender, err := NewSMTPSender(SMTPOptions{
+		Host: "127.0.0.1",
+		Port: port,
+		From: &mail.Address{Address: "no-reply@example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sender.Send(context.Background(), Message{To: "ann@example.com", Subject: "Hi"})
+	if err == nil {
+		t.Errorf("sent a message to the closed port %d", port)
+	}
+}
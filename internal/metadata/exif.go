@@ -0,0 +1,416 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// EXIF tags we care about, see the EXIF 2.32 specification.
+const (
+	tagMake               = 0x010f
+	tagModel              = 0x0110
+	tagOrientation        = 0x0112
+	tagDateTime           = 0x0132
+	tagExifIFD            = 0x8769
+	tagGPSIFD             = 0x8825
+	tagDateTimeOriginal   = 0x9003
+	tagDateTimeDigitized  = 0x9004
+	tagOffsetTimeOriginal = 0x9011
+	tagLensModel          = 0xa434
+
+	tagGPSLatitudeRef  = 0x0001
+	tagGPSLatitude     = 0x0002
+	tagGPSLongitudeRef = 0x0003
+	tagGPSLongitude    = 0x0004
+	tagGPSAltitudeRef  = 0x0005
+	tagGPSAltitude     = 0x0006
+
+	exifTypeByte      = 1
+	exifTypeASCII     = 2
+	exifTypeShort     = 3
+	exifTypeLong      = 4
+	exifTypeRational  = 5
+	exifTypeUndefined = 7
+	exifTypeSLong     = 9
+	exifTypeSRational = 10
+
+	exifDateLayout = "2006:01:02 15:04:05"
+
+	// maxSegments bounds how many JPEG segments or PNG/WebP chunks are
+	// inspected while looking for EXIF data.
+	maxSegments = 1024
+)
+
+var (
+	exifHeader     = []byte("Exif\x00\x00")
+	errInvalidEXIF = errors.New("invalid exif data")
+)
+
+// findEXIF returns the TIFF structured EXIF block of a JPEG, PNG or WebP
+// image, or nil when the image has none.
+func findEXIF(r io.ReaderAt, size int64, mediaType string) ([]byte, error) {
+	switch mediaType {
+	case "image/jpeg":
+		return findJPEGEXIF(r, size)
+	case "image/png":
+		return findPNGEXIF(r, size)
+	case "image/webp":
+		return findWebPEXIF(r, size)
+	default:
+		return nil, nil
+	}
+}
+
+func readAt(r io.ReaderAt, size, offset, length int64) ([]byte, error) {
+	if offset < 0 || length < 0 || offset+length > size {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	buf := make([]byte, length)
+	if _, err := r.ReadAt(buf, offset); err != nil && !(err == io.EOF && length > 0) {
+		return nil, err
+	}
+	return buf, nil
+}
+
+func findJPEGEXIF(r io.ReaderAt, size int64) ([]byte, error) {
+	soi, err := readAt(r, size, 0, 2)
+	if err != nil || soi[0] != 0xff || soi[1] != 0xd8 {
+		return nil, errors.New("invalid jpeg")
+	}
+
+	offset := int64(2)
+	for i := 0; i < maxSegments; i++ {
+		header, err := readAt(r, size, offset, 4)
+		if err != nil {
+			return nil, nil
+		}
+
+		if header[0] != 0xff {
+			return nil, nil
+		}
+
+		marker := header[1]
+		switch {
+		case marker == 0xff:
+			// Fill byte before the actual marker.
+			offset++
+			continue
+		case marker == 0xd8 || marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7):
+			offset += 2
+			continue
+		case marker == 0xda || marker == 0xd9:
+			// Start of scan or end of image, metadata comes before.
+			return nil, nil
+		}
+
+		length := int64(binary.BigEndian.Uint16(header[2:4]))
+		if length < 2 {
+			return nil, nil
+		}
+
+		if marker == 0xe1 && length >= 2+int64(len(exifHeader)) {
+			data, err := readAt(r, size, offset+4, length-2)
+			if err != nil {
+				return nil, nil
+			}
+			if bytes.HasPrefix(data, exifHeader) {
+				return data[len(exifHeader):], nil
+			}
+		}
+
+		offset += 2 + length
+	}
+
+	return nil, nil
+}
+
+func findPNGEXIF(r io.ReaderAt, size int64) ([]byte, error) {
+	offset := int64(8)
+	for i := 0; i < maxSegments; i++ {
+		header, err := readAt(r, size, offset, 8)
+		if err != nil {
+			return nil, nil
+		}
+
+		length := int64(binary.BigEndian.Uint32(header[0:4]))
+		chunkType := string(header[4:8])
+
+		switch chunkType {
+		case "eXIf":
+			return readAt(r, size, offset+8, length)
+		case "IEND":
+			return nil, nil
+		}
+
+		offset += 8 + length + 4
+	}
+
+	return nil, nil
+}
+
+func findWebPEXIF(r io.ReaderAt, size int64) ([]byte, error) {
+	header, err := readAt(r, size, 0, 12)
+	if err != nil || string(header[0:4]) != "RIFF" || string(header[8:12]) != "WEBP" {
+		return nil, errors.New("invalid webp")
+	}
+
+	offset := int64(12)
+	for i := 0; i < maxSegments; i++ {
+		chunk, err := readAt(r, size, offset, 8)
+		if err != nil {
+			return nil, nil
+		}
+
+		length := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+		if string(chunk[0:4]) == "EXIF" {
+			data, err := readAt(r, size, offset+8, length)
+			if err != nil {
+				return nil, nil
+			}
+			return bytes.TrimPrefix(data, exifHeader), nil
+		}
+
+		offset += 8 + length + length&1
+	}
+
+	return nil, nil
+}
+
+type tiff struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+type ifdEntry struct {
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+func exifTypeSize(typ uint16) uint32 {
+	switch typ {
+	case exifTypeByte, exifTypeASCII, exifTypeUndefined:
+		return 1
+	case exifTypeShort:
+		return 2
+	case exifTypeLong, exifTypeSLong:
+		return 4
+	case exifTypeRational, exifTypeSRational:
+		return 8
+	default:
+		return 0
+	}
+}
+
+func (t *tiff) readIFD(offset uint32) (map[uint16]ifdEntry, error) {
+	if uint64(offset)+2 > uint64(len(t.data)) {
+		return nil, errInvalidEXIF
+	}
+
+	count := uint32(t.order.Uint16(t.data[offset:]))
+	if uint64(offset)+2+uint64(count)*12 > uint64(len(t.data)) {
+		return nil, errInvalidEXIF
+	}
+
+	entries := make(map[uint16]ifdEntry, count)
+	for i := uint32(0); i < count; i++ {
+		raw := t.data[offset+2+i*12 : offset+2+(i+1)*12]
+
+		entry := ifdEntry{
+			typ:   t.order.Uint16(raw[2:4]),
+			count: t.order.Uint32(raw[4:8]),
+		}
+
+		typeSize := exifTypeSize(entry.typ)
+		if typeSize == 0 {
+			continue
+		}
+
+		length := uint64(typeSize) * uint64(entry.count)
+		if length <= 4 {
+			entry.value = raw[8 : 8+length]
+		} else {
+			valueOffset := uint64(t.order.Uint32(raw[8:12]))
+			if valueOffset+length > uint64(len(t.data)) {
+				continue
+			}
+			entry.value = t.data[valueOffset : valueOffset+length]
+		}
+
+		entries[t.order.Uint16(raw[0:2])] = entry
+	}
+
+	return entries, nil
+}
+
+func (t *tiff) ascii(entry ifdEntry) string {
+	if entry.typ != exifTypeASCII {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimRight(string(entry.value), "\x00"))
+}
+
+func (t *tiff) uint(entry ifdEntry) (uint32, bool) {
+	switch {
+	case entry.typ == exifTypeByte && len(entry.value) >= 1:
+		return uint32(entry.value[0]), true
+	case entry.typ == exifTypeShort && len(entry.value) >= 2:
+		return uint32(t.order.Uint16(entry.value)), true
+	case entry.typ == exifTypeLong && len(entry.value) >= 4:
+		return t.order.Uint32(entry.value), true
+	default:
+		return 0, false
+	}
+}
+
+func (t *tiff) rationals(entry ifdEntry) []float64 {
+	if entry.typ != exifTypeRational && entry.typ != exifTypeSRational {
+		return nil
+	}
+
+	values := make([]float64, 0, entry.count)
+	for i := 0; i+8 <= len(entry.value); i += 8 {
+		var numerator, denominator float64
+		if entry.typ == exifTypeSRational {
+			numerator = float64(int32(t.order.Uint32(entry.value[i:])))
+			denominator = float64(int32(t.order.Uint32(entry.value[i+4:])))
+		} else {
+			numerator = float64(t.order.Uint32(entry.value[i:]))
+			denominator = float64(t.order.Uint32(entry.value[i+4:]))
+		}
+
+		if denominator == 0 {
+			return nil
+		}
+		values = append(values, numerator/denominator)
+	}
+
+	return values
+}
+
+// parseEXIF fills md from a TIFF structured EXIF block.
+func parseEXIF(data []byte, md *Metadata) error {
+	if len(data) < 8 {
+		return errInvalidEXIF
+	}
+
+	t := &tiff{data: data}
+	switch string(data[0:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return errInvalidEXIF
+	}
+
+	if t.order.Uint16(data[2:4]) != 42 {
+		return errInvalidEXIF
+	}
+
+	ifd0, err := t.readIFD(t.order.Uint32(data[4:8]))
+	if err != nil {
+		return err
+	}
+
+	md.CameraMake = t.ascii(ifd0[tagMake])
+	md.CameraModel = t.ascii(ifd0[tagModel])
+	if orientation, ok := t.uint(ifd0[tagOrientation]); ok {
+		md.Orientation = int(orientation)
+	}
+
+	dateTime := t.ascii(ifd0[tagDateTime])
+	offsetTime := ""
+
+	if offset, ok := t.uint(ifd0[tagExifIFD]); ok {
+		if exifIFD, err := t.readIFD(offset); err == nil {
+			md.LensModel = t.ascii(exifIFD[tagLensModel])
+			offsetTime = t.ascii(exifIFD[tagOffsetTimeOriginal])
+
+			if original := t.ascii(exifIFD[tagDateTimeOriginal]); original != "" {
+				dateTime = original
+			} else if digitized := t.ascii(exifIFD[tagDateTimeDigitized]); digitized != "" {
+				dateTime = digitized
+			}
+		}
+	}
+
+	if takenAt, ok := parseEXIFTime(dateTime, offsetTime); ok {
+		md.TakenAt = &takenAt
+	}
+
+	if offset, ok := t.uint(ifd0[tagGPSIFD]); ok {
+		if gpsIFD, err := t.readIFD(offset); err == nil {
+			md.GPS = t.gps(gpsIFD)
+		}
+	}
+
+	return nil
+}
+
+// parseEXIFTime parses an EXIF date. Dates without an offset are assumed to
+// be UTC since the camera time zone is unknown.
+func parseEXIFTime(value, offset string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	if offset != "" {
+		if t, err := time.Parse(exifDateLayout+"-07:00", value+offset); err == nil {
+			return t, true
+		}
+	}
+
+	t, err := time.Parse(exifDateLayout, value)
+	if err != nil || t.Year() < 1900 {
+		return time.Time{}, false
+	}
+
+	return t, true
+}
+
+func (t *tiff) gps(ifd map[uint16]ifdEntry) *GPS {
+	latitude, ok := dmsToDegrees(t.rationals(ifd[tagGPSLatitude]))
+	if !ok {
+		return nil
+	}
+
+	longitude, ok := dmsToDegrees(t.rationals(ifd[tagGPSLongitude]))
+	if !ok {
+		return nil
+	}
+
+	if t.ascii(ifd[tagGPSLatitudeRef]) == "S" {
+		latitude = -latitude
+	}
+	if t.ascii(ifd[tagGPSLongitudeRef]) == "W" {
+		longitude = -longitude
+	}
+
+	gps := &GPS{
+		Latitude:  latitude,
+		Longitude: longitude,
+	}
+
+	if altitude := t.rationals(ifd[tagGPSAltitude]); len(altitude) == 1 {
+		value := altitude[0]
+		if ref, ok := t.uint(ifd[tagGPSAltitudeRef]); ok && ref == 1 {
+			value = -value
+		}
+		gps.Altitude = &value
+	}
+
+	return gps
+}
+
+func dmsToDegrees(dms []float64) (float64, bool) {
+	if len(dms) != 3 {
+		return 0, false
+	}
+	return dms[0] + dms[1]/60 + dms[2]/3600, true
+}
@@ -0,0 +1,107 @@
+package metadata
+
+import (
+	"errors"
+	"image"
+	"io"
+	"strings"
+	"time"
+
+	// Register the decoders used by image.DecodeConfig.
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+)
+
+// Metadata holds what could be extracted from a media file. Fields that are
+// not present in the file are left empty and omitted from JSON.
+type Metadata struct {
+	Width       int        `json:"width,omitempty"`
+	Height      int        `json:"height,omitempty"`
+	TakenAt     *time.Time `json:"taken_at,omitempty"`
+	CameraMake  string     `json:"camera_make,omitempty"`
+	CameraModel string     `json:"camera_model,omitempty"`
+	LensModel   string     `json:"lens_model,omitempty"`
+	Orientation int        `json:"orientation,omitempty"`
+	GPS         *GPS       `json:"gps,omitempty"`
+
+	Duration   float64 `json:"duration,omitempty"`
+	VideoCodec string  `json:"video_codec,omitempty"`
+	AudioCodec string  `json:"audio_codec,omitempty"`
+}
+
+type GPS struct {
+	Latitude  float64  `json:"latitude"`
+	Longitude float64  `json:"longitude"`
+	Altitude  *float64 `json:"altitude,omitempty"`
+}
+
+var ErrUnsupported = errors.New("unsupported media type")
+
+// Extract reads the metadata of a media file of the given MIME type. r must
+// give access to the whole file, which is size bytes long.
+func Extract(r io.ReaderAt, size int64, mimeType string) (*Metadata, error) {
+	mediaType, _, _ := strings.Cut(strings.ToLower(mimeType), ";")
+	mediaType = strings.TrimSpace(mediaType)
+
+	switch {
+	case mediaType == "video/mp4" || mediaType == "video/quicktime" || mediaType == "audio/mp4" ||
+		mediaType == "video/x-m4v" || mediaType == "audio/x-m4a":
+		return extractMP4(r, size)
+
+	case strings.HasPrefix(mediaType, "image/"):
+		return extractImage(r, size, mediaType)
+
+	default:
+		return nil, ErrUnsupported
+	}
+}
+
+func extractImage(r io.ReaderAt, size int64, mediaType string) (*Metadata, error) {
+	config, _, err := image.DecodeConfig(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return nil, err
+	}
+
+	md := &Metadata{
+		Width:  config.Width,
+		Height: config.Height,
+	}
+
+	exif, err := findEXIF(r, size, mediaType)
+	if err != nil || exif == nil {
+		// Images without EXIF still have useful dimensions.
+		return md, nil
+	}
+
+	if err := parseEXIF(exif, md); err != nil {
+		return md, nil
+	}
+
+	// Orientations 5 to 8 rotate the image by 90 degrees, report the
+	// dimensions as displayed.
+	if md.Orientation >= 5 && md.Orientation <= 8 {
+		md.Width, md.Height = md.Height, md.Width
+	}
+
+	return md, nil
+}
+
+// Orientation returns the EXIF orientation of an image, or 1 when unknown.
+func Orientation(r io.ReaderAt, size int64, mimeType string) int {
+	mediaType, _, _ := strings.Cut(strings.ToLower(mimeType), ";")
+
+	exif, err := findEXIF(r, size, strings.TrimSpace(mediaType))
+	if err != nil || exif == nil {
+		return 1
+	}
+
+	var md Metadata
+	if err := parseEXIF(exif, &md); err != nil || md.Orientation < 1 || md.Orientation > 8 {
+		return 1
+	}
+
+	return md.Orientation
+}
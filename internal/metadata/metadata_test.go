@@ -0,0 +1,373 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"testing"
+	"time"
+)
+
+// exifField is an IFD entry. Fields with a non-zero ifd point to that IFD
+// of the block instead of holding value.
+type exifField struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	value []byte
+	ifd   int
+}
+
+func asciiField(tag uint16, value string) exifField {
+	return exifField{tag: tag, typ: exifTypeASCII, count: uint32(len(value) + 1), value: []byte(value + "\x00")}
+}
+
+func shortField(order binary.AppendByteOrder, tag, value uint16) exifField {
+	return exifField{tag: tag, typ: exifTypeShort, count: 1, value: order.AppendUint16(nil, value)}
+}
+
+func rationalField(order binary.AppendByteOrder, tag uint16, values ...uint32) exifField {
+	var data []byte
+	for _, value := range values {
+		data = order.AppendUint32(data, value)
+		data = order.AppendUint32(data, 1)
+	}
+	return exifField{tag: tag, typ: exifTypeRational, count: uint32(len(values)), value: data}
+}
+
+func pointerField(tag uint16, ifd int) exifField {
+	return exifField{tag: tag, typ: exifTypeLong, count: 1, ifd: ifd}
+}
+
+// tiffBlock lays out ifds one after the other, each followed by the values
+// that do not fit in its entries. The first one is IFD0.
+func tiffBlock(order binary.AppendByteOrder, ifds ...[]exifField) []byte {
+	offsets := make([]uint32, len(ifds))
+	next := uint32(8)
+	for i, fields := range ifds {
+		offsets[i] = next
+		next += 2 + uint32(len(fields))*12 + 4
+		for _, field := range fields {
+			if len(field.value) > 4 {
+				next += uint32(len(field.value))
+			}
+		}
+	}
+
+	data := []byte("II")
+	if order == binary.BigEndian {
+		data = []byte("MM")
+	}
+	data = order.AppendUint16(data, 42)
+	data = order.AppendUint32(data, offsets[0])
+
+	for i, fields := range ifds {
+		valueOffset := offsets[i] + 2 + uint32(len(fields))*12 + 4
+		var values []byte
+
+		data = order.AppendUint16(data, uint16(len(fields)))
+		for _, field := range fields {
+			data = order.AppendUint16(data, field.tag)
+			data = order.AppendUint16(data, field.typ)
+			data = order.AppendUint32(data, field.count)
+
+			switch {
+			case field.ifd > 0:
+				data = order.AppendUint32(data, offsets[field.ifd])
+			case len(field.value) > 4:
+				data = order.AppendUint32(data, valueOffset+uint32(len(values)))
+				values = append(values, field.value...)
+			default:
+				data = append(data, append(field.value, make([]byte, 4-len(field.value))...)...)
+			}
+		}
+		data = order.AppendUint32(data, 0)
+		data = append(data, values...)
+	}
+
+	return data
+}
+
+// cameraEXIF describes a photo taken in San Francisco with the camera held
+// upright, Orientation 6.
+func cameraEXIF(order binary.AppendByteOrder) []byte {
+	return tiffBlock(order,
+		[]exifField{
+			asciiField(tagMake, "Canon"),
+			asciiField(tagModel, "EOS R6"),
+			shortField(order, tagOrientation, 6),
+			asciiField(tagDateTime, "2023:07:14 12:00:00"),
+			pointerField(tagExifIFD, 1),
+			pointerField(tagGPSIFD, 2),
+		},
+		[]exifField{
+			asciiField(tagDateTimeOriginal, "2023:07:14 09:30:15"),
+			asciiField(tagOffsetTimeOriginal, "-07:00"),
+			asciiField(tagLensModel, "RF24-105mm F4 L IS USM"),
+		},
+		[]exifField{
+			asciiField(tagGPSLatitudeRef, "N"),
+			rationalField(order, tagGPSLatitude, 37, 46, 30),
+			asciiField(tagGPSLongitudeRef, "W"),
+			rationalField(order, tagGPSLongitude, 122, 25, 12),
+			{tag: tagGPSAltitudeRef, typ: exifTypeByte, count: 1, value: []byte{1}},
+			rationalField(order, tagGPSAltitude, 12),
+		},
+	)
+}
+
+func encodeJPEG(t *testing.T, width, height int, exif []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height)), nil); err != nil {
+		t.Fatal(err)
+	}
+	if exif == nil {
+		return buf.Bytes()
+	}
+
+	segment := append([]byte("Exif\x00\x00"), exif...)
+	app1 := []byte{0xff, 0xe1}
+	app1 = binary.BigEndian.AppendUint16(app1, uint16(len(segment)+2))
+	app1 = append(app1, segment...)
+
+	data := buf.Bytes()
+	return append(append(append([]byte{}, data[:2]...), app1...), data[2:]...)
+}
+
+func encodePNG(t *testing.T, width, height int, exif []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(exif)))
+	chunk = append(chunk, "eXIf"...)
+	chunk = append(chunk, exif...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+
+	// The eXIf chunk goes right after IHDR: signature, then 8+13+4 bytes.
+	data := buf.Bytes()
+	const afterIHDR = 8 + 8 + 13 + 4
+	return append(append(append([]byte{}, data[:afterIHDR]...), chunk...), data[afterIHDR:]...)
+}
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestExtractJPEGWithEXIF(t *testing.T) {
+	data := encodeJPEG(t, 40, 20, cameraEXIF(binary.LittleEndian))
+
+	md, err := Extract(bytes.NewReader(data), int64(len(data)), "image/jpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Orientation 6 displays the image rotated by 90 degrees.
+	if md.Width != 20 || md.Height != 40 {
+		t.Errorf("size = %dx%d, want 20x40", md.Width, md.Height)
+	}
+	if md.CameraMake != "Canon" || md.CameraModel != "EOS R6" || md.LensModel != "RF24-105mm F4 L IS USM" {
+		t.Errorf("camera = %q %q %q", md.CameraMake, md.CameraModel, md.LensModel)
+	}
+	if md.Orientation != 6 {
+		t.Errorf("orientation = %d, want 6", md.Orientation)
+	}
+
+	want := time.Date(2023, time.July, 14, 16, 30, 15, 0, time.UTC)
+	if md.TakenAt == nil || !md.TakenAt.Equal(want) {
+		t.Errorf("taken at = %v, want DateTimeOriginal with its offset, %v", md.TakenAt, want)
+	}
+
+	if md.GPS == nil {
+		t.Fatal("no gps")
+	}
+	if !approx(md.GPS.Latitude, 37.775) || !approx(md.GPS.Longitude, -(122+25.0/60+12.0/3600)) {
+		t.Errorf("gps = %v, %v", md.GPS.Latitude, md.GPS.Longitude)
+	}
+	if md.GPS.Altitude == nil || *md.GPS.Altitude != -12 {
+		t.Errorf("altitude = %v, want 12 m below sea level", md.GPS.Altitude)
+	}
+
+	if orientation := Orientation(bytes.NewReader(data), int64(len(data)), "image/jpeg"); orientation != 6 {
+		t.Errorf("Orientation = %d, want 6", orientation)
+	}
+}
+
+func TestExtractPNGWithBigEndianEXIF(t *testing.T) {
+	order := binary.BigEndian
+	exif := tiffBlock(order, []exifField{
+		asciiField(tagModel, "Pixel 8"),
+		shortField(order, tagOrientation, 3),
+		asciiField(tagDateTime, "2024:02:29 23:59:59"),
+	})
+	data := encodePNG(t, 30, 10, exif)
+
+	md, err := Extract(bytes.NewReader(data), int64(len(data)), "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Orientation 3 turns the image upside down without swapping its sides.
+	if md.Width != 30 || md.Height != 10 || md.Orientation != 3 {
+		t.Errorf("size = %dx%d, orientation %d", md.Width, md.Height, md.Orientation)
+	}
+	if md.CameraModel != "Pixel 8" || md.GPS != nil {
+		t.Errorf("model = %q, gps = %v", md.CameraModel, md.GPS)
+	}
+
+	// Without an offset the date is taken as UTC.
+	want := time.Date(2024, time.February, 29, 23, 59, 59, 0, time.UTC)
+	if md.TakenAt == nil || !md.TakenAt.Equal(want) {
+		t.Errorf("taken at = %v, want %v", md.TakenAt, want)
+	}
+}
+
+func TestExtractImageWithoutUsableEXIF(t *testing.T) {
+	tests := []struct {
+		name string
+		exif []byte
+	}{
+		{"no exif", nil},
+		{"garbage", []byte("not a tiff header at all")},
+		{"ifd past the end", []byte("II*\x00\xff\xff\x00\x00")},
+		{"entries past the end", []byte("II*\x00\x08\x00\x00\x00\xff\x00")},
+	}
+
+	for _, tt := range tests {
+		data := encodeJPEG(t, 12, 8, tt.exif)
+
+		md, err := Extract(bytes.NewReader(data), int64(len(data)), "image/jpeg")
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if md.Width != 12 || md.Height != 8 || md.TakenAt != nil || md.Orientation != 0 {
+			t.Errorf("%s: got %+v, want only the dimensions", tt.name, md)
+		}
+
+		if orientation := Orientation(bytes.NewReader(data), int64(len(data)), "image/jpeg"); orientation != 1 {
+			t.Errorf("%s: Orientation = %d, want 1", tt.name, orientation)
+		}
+	}
+}
+
+func mp4Box(typ string, payload ...[]byte) []byte {
+	data := bytes.Join(payload, nil)
+	header := binary.BigEndian.AppendUint32(nil, uint32(8+len(data)))
+	return append(append(header, typ...), data...)
+}
+
+func fullBoxPayload(size int, fields map[int]uint32) []byte {
+	data := make([]byte, size)
+	for offset, value := range fields {
+		binary.BigEndian.PutUint32(data[offset:], value)
+	}
+	return data
+}
+
+func mp4Track(handler, codec string, tkhd []byte) []byte {
+	hdlr := append(make([]byte, 8), handler...)
+	hdlr = append(hdlr, make([]byte, 13)...)
+
+	stsd := fullBoxPayload(8, map[int]uint32{4: 1})
+	stsd = append(stsd, mp4Box(codec, make([]byte, 78))...)
+
+	children := [][]byte{}
+	if tkhd != nil {
+		children = append(children, mp4Box("tkhd", tkhd))
+	}
+	children = append(children, mp4Box("mdia",
+		mp4Box("hdlr", hdlr),
+		mp4Box("minf", mp4Box("stbl", mp4Box("stsd", stsd))),
+	))
+	return mp4Box("trak", children...)
+}
+
+func TestExtractMP4(t *testing.T) {
+	created := time.Date(2022, time.May, 1, 8, 0, 0, 0, time.UTC)
+
+	// A 1920x1080 track rotated by 90 degrees, as recorded by phones held
+	// upright.
+	tkhd := fullBoxPayload(84, map[int]uint32{
+		40: 0, 44: 0x00010000,
+		52: 0xffff0000, 56: 0,
+		72: 0x40000000,
+		76: 1920 << 16, 80: 1080 << 16,
+	})
+
+	mvhd := fullBoxPayload(100, map[int]uint32{
+		4:  uint32(created.Sub(mp4Epoch) / time.Second),
+		12: 1000,
+		16: 12500,
+	})
+
+	location := "+37.7858-122.4064+012.000/"
+	xyz := binary.BigEndian.AppendUint16(nil, uint16(len(location)))
+	xyz = append(xyz, 0x15, 0xc7)
+	xyz = append(xyz, location...)
+
+	// mdat uses a 64 bit size, as large recordings do.
+	mdat := binary.BigEndian.AppendUint32(nil, 1)
+	mdat = append(mdat, "mdat"...)
+	mdat = binary.BigEndian.AppendUint64(mdat, 16+4)
+	mdat = append(mdat, 0, 0, 0, 0)
+
+	data := bytes.Join([][]byte{
+		mp4Box("ftyp", []byte("isom\x00\x00\x02\x00isomiso2avc1mp41")),
+		mdat,
+		mp4Box("moov",
+			mp4Box("mvhd", mvhd),
+			mp4Track("vide", "avc1", tkhd),
+			mp4Track("soun", "mp4a", nil),
+			mp4Box("udta", mp4Box("\xa9xyz", xyz)),
+		),
+	}, nil)
+
+	md, err := Extract(bytes.NewReader(data), int64(len(data)), "video/mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if md.Duration != 12.5 {
+		t.Errorf("duration = %v, want 12.5", md.Duration)
+	}
+	if md.TakenAt == nil || !md.TakenAt.Equal(created) {
+		t.Errorf("taken at = %v, want %v", md.TakenAt, created)
+	}
+	if md.VideoCodec != "h264" || md.AudioCodec != "aac" {
+		t.Errorf("codecs = %q, %q", md.VideoCodec, md.AudioCodec)
+	}
+	if md.Width != 1080 || md.Height != 1920 {
+		t.Errorf("size = %dx%d, want 1080x1920", md.Width, md.Height)
+	}
+	if md.GPS == nil || md.GPS.Latitude != 37.7858 || md.GPS.Longitude != -122.4064 ||
+		md.GPS.Altitude == nil || *md.GPS.Altitude != 12 {
+		t.Errorf("gps = %+v", md.GPS)
+	}
+}
+
+func TestExtractMP4RejectsInvalidFiles(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"no moov", mp4Box("ftyp", []byte("isom"))},
+		{"box larger than the file", append(binary.BigEndian.AppendUint32(nil, 1000), "moov"...)},
+		{"box smaller than its header", append(binary.BigEndian.AppendUint32(nil, 4), "moov"...)},
+	}
+
+	for _, tt := range tests {
+		if md, err := Extract(bytes.NewReader(tt.data), int64(len(tt.data)), "video/quicktime"); err == nil {
+			t.Errorf("%s: got %+v, want an error", tt.name, md)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// ISO base media file format (MP4) and QuickTime (MOV) files are trees of
+// boxes, each starting with a 32 bit size and a four character type. Only the
+// boxes needed for duration, codecs, dimensions, creation time and location
+// are read, everything else is skipped without being loaded.
+
+const maxBoxes = 4096
+
+var (
+	errInvalidMP4 = errors.New("invalid mp4 file")
+
+	// mp4Epoch is the reference of MP4 timestamps.
+	mp4Epoch = time.Date(1904, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	// iso6709 matches locations such as "+37.7858-122.4064+012.000/".
+	iso6709 = regexp.MustCompile(`^([+-]\d+(?:\.\d+)?)([+-]\d+(?:\.\d+)?)([+-]\d+(?:\.\d+)?)?`)
+
+	codecNames = map[string]string{
+		"avc1": "h264",
+		"avc3": "h264",
+		"hvc1": "hevc",
+		"hev1": "hevc",
+		"av01": "av1",
+		"vp09": "vp9",
+		"mp4v": "mpeg4",
+		"mp4a": "aac",
+		"ac-3": "ac3",
+		"ec-3": "eac3",
+		"Opus": "opus",
+		"fLaC": "flac",
+		"alac": "alac",
+		"apch": "prores",
+		"apcn": "prores",
+		"apcs": "prores",
+		"apco": "prores",
+		"ap4h": "prores",
+	}
+)
+
+type box struct {
+	typ    string
+	offset int64 // start of the payload
+	size   int64 // size of the payload
+}
+
+type boxReader struct {
+	r     io.ReaderAt
+	size  int64
+	boxes int
+}
+
+// children lists the boxes contained in the byte range [offset, offset+size).
+func (b *boxReader) children(offset, size int64) ([]box, error) {
+	var boxes []box
+	end := offset + size
+
+	for offset+8 <= end {
+		b.boxes++
+		if b.boxes > maxBoxes {
+			return nil, errInvalidMP4
+		}
+
+		header, err := readAt(b.r, b.size, offset, 8)
+		if err != nil {
+			return nil, err
+		}
+
+		boxSize := int64(binary.BigEndian.Uint32(header[0:4]))
+		headerSize := int64(8)
+
+		switch boxSize {
+		case 0:
+			boxSize = end - offset
+		case 1:
+			large, err := readAt(b.r, b.size, offset+8, 8)
+			if err != nil {
+				return nil, err
+			}
+			boxSize = int64(binary.BigEndian.Uint64(large))
+			headerSize = 16
+		}
+
+		if boxSize < headerSize || offset+boxSize > end {
+			return nil, errInvalidMP4
+		}
+
+		boxes = append(boxes, box{
+			typ:    string(header[4:8]),
+			offset: offset + headerSize,
+			size:   boxSize - headerSize,
+		})
+		offset += boxSize
+	}
+
+	return boxes, nil
+}
+
+func (b *boxReader) find(parent box, path ...string) (box, bool) {
+	current := parent
+	for _, typ := range path {
+		children, err := b.children(current.offset, current.size)
+		if err != nil {
+			return box{}, false
+		}
+
+		found := false
+		for _, child := range children {
+			if child.typ == typ {
+				current, found = child, true
+				break
+			}
+		}
+		if !found {
+			return box{}, false
+		}
+	}
+	return current, true
+}
+
+func (b *boxReader) payload(bx box, limit int64) ([]byte, error) {
+	return readAt(b.r, b.size, bx.offset, min(bx.size, limit))
+}
+
+func extractMP4(r io.ReaderAt, size int64) (*Metadata, error) {
+	b := &boxReader{r: r, size: size}
+
+	root := box{offset: 0, size: size}
+	moov, ok := b.find(root, "moov")
+	if !ok {
+		return nil, errInvalidMP4
+	}
+
+	md := &Metadata{}
+
+	if mvhd, ok := b.find(moov, "mvhd"); ok {
+		data, err := b.payload(mvhd, 32)
+		if err == nil {
+			parseMovieHeader(data, md)
+		}
+	}
+
+	traks, err := b.children(moov.offset, moov.size)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, trak := range traks {
+		if trak.typ != "trak" {
+			continue
+		}
+		b.parseTrack(trak, md)
+	}
+
+	if xyz, ok := b.find(moov, "udta", "\xa9xyz"); ok {
+		if data, err := b.payload(xyz, 256); err == nil && len(data) > 4 {
+			md.GPS = parseISO6709(string(data[4:]))
+		}
+	}
+
+	return md, nil
+}
+
+func parseMovieHeader(data []byte, md *Metadata) {
+	if len(data) < 4 {
+		return
+	}
+
+	var created uint64
+	var timescale uint32
+	var duration uint64
+
+	if data[0] == 1 {
+		if len(data) < 32 {
+			return
+		}
+		created = binary.BigEndian.Uint64(data[4:12])
+		timescale = binary.BigEndian.Uint32(data[20:24])
+		duration = binary.BigEndian.Uint64(data[24:32])
+	} else {
+		if len(data) < 20 {
+			return
+		}
+		created = uint64(binary.BigEndian.Uint32(data[4:8]))
+		timescale = binary.BigEndian.Uint32(data[12:16])
+		duration = uint64(binary.BigEndian.Uint32(data[16:20]))
+	}
+
+	if timescale > 0 {
+		md.Duration = float64(duration) / float64(timescale)
+	}
+
+	if created > 0 {
+		takenAt := mp4Epoch.Add(time.Duration(created) * time.Second)
+		md.TakenAt = &takenAt
+	}
+}
+
+func (b *boxReader) parseTrack(trak box, md *Metadata) {
+	hdlr, ok := b.find(trak, "mdia", "hdlr")
+	if !ok {
+		return
+	}
+
+	data, err := b.payload(hdlr, 12)
+	if err != nil || len(data) < 12 {
+		return
+	}
+	handler := string(data[8:12])
+
+	codec := ""
+	if stsd, ok := b.find(trak, "mdia", "minf", "stbl", "stsd"); ok {
+		if data, err := b.payload(stsd, 16); err == nil && len(data) >= 16 {
+			codec = string(data[12:16])
+			if name, ok := codecNames[codec]; ok {
+				codec = name
+			}
+		}
+	}
+
+	switch handler {
+	case "vide":
+		if md.VideoCodec == "" {
+			md.VideoCodec = codec
+		}
+
+		if md.Width == 0 {
+			if tkhd, ok := b.find(trak, "tkhd"); ok {
+				if data, err := b.payload(tkhd, 96); err == nil {
+					md.Width, md.Height = trackDimensions(data)
+				}
+			}
+		}
+
+	case "soun":
+		if md.AudioCodec == "" {
+			md.AudioCodec = codec
+		}
+	}
+}
+
+// trackDimensions returns the display size of a track header, swapping width
+// and height when the transformation matrix rotates the video by 90 degrees.
+func trackDimensions(data []byte) (int, int) {
+	matrixOffset, sizeOffset := 40, 76
+	if len(data) > 0 && data[0] == 1 {
+		matrixOffset, sizeOffset = 52, 88
+	}
+
+	if len(data) < sizeOffset+8 {
+		return 0, 0
+	}
+
+	width := int(binary.BigEndian.Uint32(data[sizeOffset:]) >> 16)
+	height := int(binary.BigEndian.Uint32(data[sizeOffset+4:]) >> 16)
+
+	a := int32(binary.BigEndian.Uint32(data[matrixOffset:]))
+	b := int32(binary.BigEndian.Uint32(data[matrixOffset+4:]))
+	if a == 0 && b != 0 {
+		width, height = height, width
+	}
+
+	return width, height
+}
+
+func parseISO6709(value string) *GPS {
+	match := iso6709.FindStringSubmatch(value)
+	if match == nil {
+		return nil
+	}
+
+	latitude, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return nil
+	}
+
+	longitude, err := strconv.ParseFloat(match[2], 64)
+	if err != nil {
+		return nil
+	}
+
+	gps := &GPS{
+		Latitude:  latitude,
+		Longitude: longitude,
+	}
+
+	if match[3] != "" {
+		if altitude, err := strconv.ParseFloat(match[3], 64); err == nil {
+			gps.Altitude = &altitude
+		}
+	}
+
+	return gps
+}
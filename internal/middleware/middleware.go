@@ -1,13 +1,23 @@
 package middleware
 
-import "github.com/sangketkit01/media-library-api/internal/token"
+import (
+	db "github.com/sangketkit01/media-library-api/internal/db/sqlc"
+	"github.com/sangketkit01/media-library-api/internal/revocation"
+	"github.com/sangketkit01/media-library-api/internal/token"
+)
 
 type Middleware struct {
-	tokenMaker token.Maker
+	tokenMaker       token.Maker
+	sessions         *revocation.Cache
+	store            db.Querier
+	unverifiedPolicy string
 }
 
-func NewMiddleware(tokenMaker token.Maker) *Middleware{
+func NewMiddleware(tokenMaker token.Maker, sessions *revocation.Cache, store db.Querier, unverifiedPolicy string) *Middleware{
 	return &Middleware{
-		tokenMaker: tokenMaker,
+		tokenMaker:       tokenMaker,
+		sessions:         sessions,
+		store:            store,
+		unverifiedPolicy: unverifiedPolicy,
 	}
 }
\ No newline at end of file
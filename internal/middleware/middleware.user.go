@@ -7,6 +7,10 @@ import (
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sangketkit01/media-library-api/internal/config"
 	"github.com/sangketkit01/media-library-api/internal/token"
 )
 
@@ -37,7 +41,22 @@ func (m *Middleware)AuthMiddleware() fiber.Handler{
 		
 			return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
 		}
-		
+
+		if payload.Type == token.TypeRefresh {
+			return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+		}
+
+		// Access tokens outlive logout and revocation, the session they were
+		// issued for has to be checked too.
+		revoked, err := m.sessions.IsRevoked(c.Context(), payload.SessionID)
+		if err != nil {
+			log.Printf("cannot check session %s: %v\n", payload.SessionID, err)
+			return fiber.NewError(fiber.StatusInternalServerError, "cannot verify session")
+		}
+
+		if revoked {
+			return fiber.NewError(fiber.StatusUnauthorized, "session revoked")
+		}
 
 		c.Locals(payloadHeader, payload)
 
@@ -45,6 +64,80 @@ func (m *Middleware)AuthMiddleware() fiber.Handler{
 	}
 }
 
+// VerifiedEmailMiddleware keeps users who have not verified their email from
+// changing anything when the unverified email policy is read only. It must
+// come after AuthMiddleware.
+func (m *Middleware) VerifiedEmailMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if m.unverifiedPolicy != config.UnverifiedReadOnly {
+			return c.Next()
+		}
+
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+			return c.Next()
+		}
+
+		payload, ok := c.Locals(payloadHeader).(*token.Payload)
+		if !ok {
+			return fiber.NewError(fiber.StatusUnauthorized, "invalid payload")
+		}
+
+		user, err := m.store.GetUserByID(c.Context(), pgtype.UUID{
+			Bytes: payload.ID,
+			Valid: true,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return fiber.NewError(fiber.StatusUnauthorized, "user not found")
+			}
+
+			log.Printf("cannot check email verification of %s: %v\n", payload.ID, err)
+			return fiber.NewError(fiber.StatusInternalServerError, "cannot verify user")
+		}
+
+		if !user.EmailVerifiedAt.Valid {
+			return fiber.NewError(fiber.StatusForbidden, "verify your email to make changes")
+		}
+
+		return c.Next()
+	}
+}
+
+// BodyLimitMiddleware refuses request bodies larger than limit bytes, unless
+// skip reports that the route streams its body itself. Request bodies are
+// streamed, so the server does not enforce a limit on its own.
+func (m *Middleware) BodyLimitMiddleware(limit int, skip func(c *fiber.Ctx) bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if skip != nil && skip(c) {
+			return c.Next()
+		}
+
+		length := c.Request().Header.ContentLength()
+		if length == -1 {
+			// Chunked bodies have no length to check before reading them.
+			return fiber.NewError(fiber.StatusLengthRequired, "Content-Length is required")
+		}
+
+		if length > limit {
+			return fiber.NewError(fiber.StatusRequestEntityTooLarge, "request body too large")
+		}
+
+		return c.Next()
+	}
+}
+
+// RateLimitMiddleware allows each client IP max requests per window.
+func (m *Middleware) RateLimitMiddleware(max int, window time.Duration) fiber.Handler {
+	return limiter.New(limiter.Config{
+		Max:        max,
+		Expiration: window,
+		LimitReached: func(c *fiber.Ctx) error {
+			return fiber.NewError(fiber.StatusTooManyRequests, "too many requests, try again later")
+		},
+	})
+}
+
 func (m *Middleware) LoggerMiddleware() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		start := time.Now()
@@ -0,0 +1,66 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/sangketkit01/media-library-api/internal/db/sqlc"
+	"github.com/sangketkit01/media-library-api/internal/metadata"
+	"github.com/sangketkit01/media-library-api/internal/storage"
+)
+
+// MetadataStage extracts EXIF and container metadata and stores it on the
+// media file, with the capture time and dimensions in their own columns.
+type MetadataStage struct {
+	store   db.Store
+	storage storage.Backend
+}
+
+func NewMetadataStage(store db.Store, backend storage.Backend) *MetadataStage {
+	return &MetadataStage{
+		store:   store,
+		storage: backend,
+	}
+}
+
+func (s *MetadataStage) Name() string {
+	return "metadata"
+}
+
+func (s *MetadataStage) Process(ctx context.Context, job Job) error {
+	reader := storage.NewReaderAt(ctx, s.storage, job.Blob.StorageKey, job.Blob.Size)
+
+	md, err := metadata.Extract(reader, job.Blob.Size, job.Media.FileType)
+	if errors.Is(err, metadata.ErrUnsupported) {
+		return nil
+	}
+	if err != nil {
+		// A corrupt or truncated file should not block the other stages.
+		log.Printf("failed to extract metadata of media %s: %v", job.Media.ID.String(), err)
+		return nil
+	}
+
+	data, err := json.Marshal(md)
+	if err != nil {
+		return err
+	}
+
+	arg := db.UpdateMediaFileMetadataParams{
+		ID:       job.Media.ID,
+		Metadata: data,
+	}
+
+	if md.TakenAt != nil {
+		arg.TakenAt = pgtype.Timestamptz{Time: *md.TakenAt, Valid: true}
+	}
+
+	if md.Width > 0 && md.Height > 0 {
+		arg.Width = pgtype.Int4{Int32: int32(md.Width), Valid: true}
+		arg.Height = pgtype.Int4{Int32: int32(md.Height), Valid: true}
+	}
+
+	return s.store.UpdateMediaFileMetadata(ctx, arg)
+}
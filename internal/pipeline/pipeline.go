@@ -0,0 +1,222 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/sangketkit01/media-library-api/internal/db/sqlc"
+)
+
+const (
+	queueSize      = 256
+	rescanInterval = time.Minute
+
+	// Failed media files are retried after retryDelay, doubling with every
+	// failure up to maxRetryDelay, and given up on after maxAttempts.
+	maxAttempts   = 8
+	retryDelay    = time.Minute
+	maxRetryDelay = 6 * time.Hour
+)
+
+type Job struct {
+	Media db.MediaFile
+	Blob  db.Blob
+}
+
+// Stage is one processing step run for every new media file, e.g. generating
+// thumbnails. Stages must be safe to run again for the same media file.
+type Stage interface {
+	Name() string
+	Process(ctx context.Context, job Job) error
+}
+
+// Pipeline runs its stages in the background for media files that have not
+// been processed yet. Media files are queued right after upload and pending
+// ones are picked up again periodically, so nothing is lost on restart.
+type Pipeline struct {
+	store   db.Store
+	stages  []Stage
+	workers int
+	queue   chan pgtype.UUID
+
+	mu      sync.Mutex
+	pending map[pgtype.UUID]bool
+}
+
+func NewPipeline(store db.Store, workers int, stages ...Stage) *Pipeline {
+	if workers < 1 {
+		workers = 1
+	}
+
+	return &Pipeline{
+		store:   store,
+		stages:  stages,
+		workers: workers,
+		queue:   make(chan pgtype.UUID, queueSize),
+		pending: make(map[pgtype.UUID]bool),
+	}
+}
+
+// Enqueue schedules a media file for processing without blocking. When the
+// queue is full the media file is left for the next rescan.
+func (p *Pipeline) Enqueue(mediaID pgtype.UUID) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.pending[mediaID] {
+		return
+	}
+
+	select {
+	case p.queue <- mediaID:
+		p.pending[mediaID] = true
+	default:
+	}
+}
+
+// Run processes queued media files until ctx is cancelled and waits for the
+// workers to finish their current job before returning.
+func (p *Pipeline) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < p.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.work(ctx)
+		}()
+	}
+
+	ticker := time.NewTicker(rescanInterval)
+	defer ticker.Stop()
+
+	p.enqueueUnprocessed(ctx)
+
+	for {
+		select {
+		case <-ticker.C:
+			p.enqueueUnprocessed(ctx)
+
+		case <-ctx.Done():
+			log.Println("Media pipeline stopping...")
+			wg.Wait()
+			return
+		}
+	}
+}
+
+func (p *Pipeline) work(ctx context.Context) {
+	for {
+		select {
+		case mediaID := <-p.queue:
+			p.process(ctx, mediaID)
+
+			p.mu.Lock()
+			delete(p.pending, mediaID)
+			p.mu.Unlock()
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (p *Pipeline) enqueueUnprocessed(ctx context.Context) {
+	medias, err := p.store.ListUnprocessedMediaFiles(ctx, db.ListUnprocessedMediaFilesParams{
+		MaxAttempts: maxAttempts,
+		MaxCount:    queueSize,
+	})
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			log.Println("[pipeline] failed to list unprocessed media:", err)
+		}
+		return
+	}
+
+	for _, media := range medias {
+		p.Enqueue(media.ID)
+	}
+}
+
+func (p *Pipeline) process(ctx context.Context, mediaID pgtype.UUID) {
+	media, err := p.store.GetMediaFileByID(ctx, mediaID)
+	if err != nil {
+		if err != pgx.ErrNoRows {
+			log.Printf("[pipeline] failed to load media %s: %v\n", mediaID.String(), err)
+		}
+		return
+	}
+
+	if media.ProcessedAt.Valid || media.ProcessAttempts >= maxAttempts {
+		return
+	}
+
+	// Failed media files wait for their retry, the rescan picks them up.
+	if media.ProcessRetryAt.Valid && media.ProcessRetryAt.Time.After(time.Now()) {
+		return
+	}
+
+	blob, err := p.store.GetBlobByID(ctx, media.BlobID)
+	if err != nil {
+		log.Printf("[pipeline] failed to load blob of media %s: %v\n", mediaID.String(), err)
+		return
+	}
+
+	job := Job{
+		Media: media,
+		Blob:  blob,
+	}
+
+	var errs []error
+	for _, stage := range p.stages {
+		if err := stage.Process(ctx, job); err != nil {
+			log.Printf("[pipeline] %s failed for media %s: %v\n", stage.Name(), mediaID.String(), err)
+			errs = append(errs, fmt.Errorf("%s: %w", stage.Name(), err))
+		}
+	}
+
+	// Leave interrupted jobs for the next run.
+	if ctx.Err() != nil {
+		return
+	}
+
+	if len(errs) > 0 {
+		p.recordFailure(ctx, media, errors.Join(errs...))
+		return
+	}
+
+	if err := p.store.MarkMediaFileProcessed(ctx, mediaID); err != nil {
+		log.Printf("[pipeline] failed to mark media %s as processed: %v\n", mediaID.String(), err)
+	}
+}
+
+// recordFailure schedules the next attempt at processing a media file. Stages
+// are safe to run again, so all of them are retried.
+func (p *Pipeline) recordFailure(ctx context.Context, media db.MediaFile, cause error) {
+	delay := retryDelay << media.ProcessAttempts
+	if delay <= 0 || delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+
+	attempts, err := p.store.RecordMediaFileProcessingFailure(ctx, db.RecordMediaFileProcessingFailureParams{
+		ID:           media.ID,
+		ProcessError: pgtype.Text{String: cause.Error(), Valid: true},
+		RetryAt: pgtype.Timestamptz{
+			Time:  time.Now().Add(delay),
+			Valid: true,
+		},
+	})
+	if err != nil {
+		log.Printf("[pipeline] failed to record failure of media %s: %v\n", media.ID.String(), err)
+		return
+	}
+
+	if attempts >= maxAttempts {
+		log.Printf("[pipeline] giving up on media %s after %d attempts\n", media.ID.String(), attempts)
+	}
+}
@@ -0,0 +1,97 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	db "github.com/sangketkit01/media-library-api/internal/db/sqlc"
+	"github.com/sangketkit01/media-library-api/internal/imaging"
+	"github.com/sangketkit01/media-library-api/internal/metadata"
+	"github.com/sangketkit01/media-library-api/internal/storage"
+)
+
+const DerivativeThumbnail = "thumbnail"
+
+// ThumbnailStage renders every image into each configured size and format and
+// records the results as media derivatives.
+type ThumbnailStage struct {
+	store   db.Store
+	storage storage.Backend
+	sizes   []int
+	formats []string
+}
+
+func NewThumbnailStage(store db.Store, backend storage.Backend, sizes []int, formats []string) *ThumbnailStage {
+	return &ThumbnailStage{
+		store:   store,
+		storage: backend,
+		sizes:   sizes,
+		formats: formats,
+	}
+}
+
+func (s *ThumbnailStage) Name() string {
+	return DerivativeThumbnail
+}
+
+func (s *ThumbnailStage) Process(ctx context.Context, job Job) error {
+	if !imaging.IsSupported(job.Media.FileType) {
+		return nil
+	}
+
+	reader, _, err := s.storage.Get(ctx, job.Blob.StorageKey)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	img, _, err := imaging.Decode(reader)
+	if err != nil {
+		return fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	orientation := metadata.Orientation(
+		storage.NewReaderAt(ctx, s.storage, job.Blob.StorageKey, job.Blob.Size),
+		job.Blob.Size,
+		job.Media.FileType,
+	)
+
+	for _, size := range s.sizes {
+		thumbnail := imaging.Orient(imaging.Fit(img, size), orientation)
+
+		for _, format := range s.formats {
+			var buf bytes.Buffer
+			if err := imaging.Encode(&buf, thumbnail, format); err != nil {
+				return err
+			}
+
+			key := DerivativeStorageKey(job.Media, DerivativeThumbnail, size, format)
+			length := int64(buf.Len())
+			if err := s.storage.Put(ctx, key, &buf, length, imaging.ContentType(format)); err != nil {
+				return err
+			}
+
+			_, err := s.store.UpsertMediaDerivative(ctx, db.UpsertMediaDerivativeParams{
+				MediaID:    job.Media.ID,
+				Kind:       DerivativeThumbnail,
+				Size:       int32(size),
+				Format:     format,
+				StorageKey: key,
+				Width:      int32(thumbnail.Bounds().Dx()),
+				Height:     int32(thumbnail.Bounds().Dy()),
+				Bytes:      length,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// DerivativeStorageKey returns the storage key of a derivative of media.
+func DerivativeStorageKey(media db.MediaFile, kind string, size int, format string) string {
+	return fmt.Sprintf("%s/derivatives/%s/%s_%d.%s", media.UserID.String(), media.ID.String(), kind, size, format)
+}
@@ -0,0 +1,224 @@
+package revocation
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	db "github.com/sangketkit01/media-library-api/internal/db/sqlc"
+)
+
+// Channel is the Postgres channel sessions are announced on when they are
+// blocked or deleted, the payload is the session id.
+const Channel = "sessions_revoked"
+
+const (
+	defaultSize      = 10000
+	defaultTTL       = 10 * time.Second
+	reconnectDelay   = time.Second
+	maxReconnectWait = 30 * time.Second
+)
+
+type entry struct {
+	id        uuid.UUID
+	revoked   bool
+	expiresAt time.Time
+}
+
+// Cache tells whether sessions are still usable without querying the
+// database on every request. Live sessions are only trusted for a short TTL,
+// so a session revoked by another replica stops working within it. Revoked
+// sessions never come back and stay cached until evicted.
+type Cache struct {
+	store db.Querier
+	size  int
+	ttl   time.Duration
+
+	mu      sync.Mutex
+	entries map[uuid.UUID]*list.Element
+	lru     *list.List
+}
+
+func NewCache(store db.Querier, size int, ttl time.Duration) *Cache {
+	if size < 1 {
+		size = defaultSize
+	}
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+
+	return &Cache{
+		store:   store,
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[uuid.UUID]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// IsRevoked reports whether a session is blocked, expired or gone.
+func (c *Cache) IsRevoked(ctx context.Context, sessionID uuid.UUID) (bool, error) {
+	if revoked, ok := c.get(sessionID); ok {
+		return revoked, nil
+	}
+
+	session, err := c.store.GetSession(ctx, pgtype.UUID{Bytes: sessionID, Valid: true})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.Revoke(sessionID)
+			return true, nil
+		}
+
+		return false, err
+	}
+
+	if session.IsBlocked.Bool || time.Now().After(session.ExpiresAt.Time) {
+		c.Revoke(sessionID)
+		return true, nil
+	}
+
+	expiresAt := time.Now().Add(c.ttl)
+	if session.ExpiresAt.Time.Before(expiresAt) {
+		expiresAt = session.ExpiresAt.Time
+	}
+	c.put(sessionID, false, expiresAt)
+
+	return false, nil
+}
+
+// Revoke marks a session as revoked on this replica right away.
+func (c *Cache) Revoke(sessionID uuid.UUID) {
+	c.put(sessionID, true, time.Time{})
+}
+
+// Forget drops what is known about live sessions, they are looked up again on
+// their next use. Revoked sessions are kept.
+func (c *Cache) Forget() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id, elem := range c.entries {
+		if !elem.Value.(*entry).revoked {
+			c.lru.Remove(elem)
+			delete(c.entries, id)
+		}
+	}
+}
+
+func (c *Cache) get(sessionID uuid.UUID) (revoked, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[sessionID]
+	if !ok {
+		return false, false
+	}
+
+	e := elem.Value.(*entry)
+	if !e.revoked && time.Now().After(e.expiresAt) {
+		c.lru.Remove(elem)
+		delete(c.entries, sessionID)
+		return false, false
+	}
+
+	c.lru.MoveToFront(elem)
+	return e.revoked, true
+}
+
+func (c *Cache) put(sessionID uuid.UUID, revoked bool, expiresAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[sessionID]; ok {
+		e := elem.Value.(*entry)
+		// A revoked session must not be overwritten by a lookup that raced
+		// with its revocation.
+		if e.revoked && !revoked {
+			return
+		}
+
+		e.revoked = revoked
+		e.expiresAt = expiresAt
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	c.entries[sessionID] = c.lru.PushFront(&entry{
+		id:        sessionID,
+		revoked:   revoked,
+		expiresAt: expiresAt,
+	})
+
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry).id)
+	}
+}
+
+// Listen revokes sessions announced on Channel until ctx is done, so that
+// revocations on other replicas take effect without waiting for the TTL.
+// Notifications sent while disconnected are lost, so live sessions are
+// looked up again after every reconnect.
+func (c *Cache) Listen(ctx context.Context, pool *pgxpool.Pool) {
+	wait := reconnectDelay
+
+	for {
+		err := c.listen(ctx, pool, func() { wait = reconnectDelay })
+		if ctx.Err() != nil {
+			log.Println("Session revocation listener stopping...")
+			return
+		}
+
+		log.Printf("Session revocation listener error, retrying in %v: %v\n", wait, err)
+		c.Forget()
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			log.Println("Session revocation listener stopping...")
+			return
+		}
+
+		wait = min(wait*2, maxReconnectWait)
+	}
+}
+
+func (c *Cache) listen(ctx context.Context, pool *pgxpool.Pool, connected func()) error {
+	pooled, err := pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// The connection is left in LISTEN mode, it must not go back to the pool.
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+Channel); err != nil {
+		return err
+	}
+
+	c.Forget()
+	connected()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		sessionID, err := uuid.Parse(notification.Payload)
+		if err != nil {
+			log.Printf("Invalid session revocation payload %q: %v\n", notification.Payload, err)
+			continue
+		}
+
+		c.Revoke(sessionID)
+	}
+}
@@ -0,0 +1,246 @@
+package revocation
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/sangketkit01/media-library-api/internal/db/sqlc"
+)
+
+// fakeStore answers GetSession from memory and counts the lookups. Calling
+// any other method of db.Querier panics.
+type fakeStore struct {
+	db.Querier
+
+	mu       sync.Mutex
+	sessions map[uuid.UUID]db.Session
+	err      error
+	lookups  map[uuid.UUID]int
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		sessions: make(map[uuid.UUID]db.Session),
+		lookups:  make(map[uuid.UUID]int),
+	}
+}
+
+func (s *fakeStore) GetSession(ctx context.Context, id pgtype.UUID) (db.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lookups[id.Bytes]++
+	if s.err != nil {
+		return db.Session{}, s.err
+	}
+
+	session, ok := s.sessions[id.Bytes]
+	if !ok {
+		return db.Session{}, pgx.ErrNoRows
+	}
+	return session, nil
+}
+
+func (s *fakeStore) add(blocked bool, expiresIn time.Duration) uuid.UUID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := uuid.New()
+	s.sessions[id] = db.Session{
+		ID:        pgtype.UUID{Bytes: id, Valid: true},
+		IsBlocked: pgtype.Bool{Bool: blocked, Valid: true},
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(expiresIn), Valid: true},
+	}
+	return id
+}
+
+func (s *fakeStore) block(id uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session := s.sessions[id]
+	session.IsBlocked = pgtype.Bool{Bool: true, Valid: true}
+	s.sessions[id] = session
+}
+
+func (s *fakeStore) lookupsOf(id uuid.UUID) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lookups[id]
+}
+
+func isRevoked(t *testing.T, cache *Cache, id uuid.UUID) bool {
+	t.Helper()
+
+	revoked, err := cache.IsRevoked(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return revoked
+}
+
+func TestCacheTrustsLiveSessionsForTTL(t *testing.T) {
+	store := newFakeStore()
+	cache := NewCache(store, 10, 50*time.Millisecond)
+	id := store.add(false, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		if isRevoked(t, cache, id) {
+			t.Fatal("live session reported as revoked")
+		}
+	}
+	if n := store.lookupsOf(id); n != 1 {
+		t.Errorf("%d lookups, want 1", n)
+	}
+
+	// Blocked by another replica without a notification, noticed once the
+	// TTL is over.
+	store.block(id)
+	if isRevoked(t, cache, id) {
+		t.Error("cached live session reported as revoked before the TTL")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if !isRevoked(t, cache, id) {
+		t.Error("blocked session still live after the TTL")
+	}
+	if n := store.lookupsOf(id); n != 2 {
+		t.Errorf("%d lookups, want 2", n)
+	}
+}
+
+func TestCacheDoesNotTrustSessionsPastTheirExpiry(t *testing.T) {
+	store := newFakeStore()
+	cache := NewCache(store, 10, time.Hour)
+	id := store.add(false, 30*time.Millisecond)
+
+	if isRevoked(t, cache, id) {
+		t.Fatal("live session reported as revoked")
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	if !isRevoked(t, cache, id) {
+		t.Error("expired session still live")
+	}
+}
+
+func TestCacheRevokedSessions(t *testing.T) {
+	store := newFakeStore()
+	cache := NewCache(store, 10, time.Hour)
+
+	blocked := store.add(true, time.Hour)
+	expired := store.add(false, -time.Minute)
+	missing := uuid.New()
+
+	for _, id := range []uuid.UUID{blocked, expired, missing} {
+		for i := 0; i < 2; i++ {
+			if !isRevoked(t, cache, id) {
+				t.Errorf("session %s reported as live", id)
+			}
+		}
+		// Revoked sessions never come back, they are not looked up again.
+		if n := store.lookupsOf(id); n != 1 {
+			t.Errorf("session %s: %d lookups, want 1", id, n)
+		}
+	}
+}
+
+func TestCacheDoesNotCacheErrors(t *testing.T) {
+	store := newFakeStore()
+	cache := NewCache(store, 10, time.Hour)
+	id := store.add(false, time.Hour)
+
+	store.err = errors.New("connection refused")
+	if _, err := cache.IsRevoked(context.Background(), id); err == nil {
+		t.Fatal("lookup error was not returned")
+	}
+
+	store.err = nil
+	if isRevoked(t, cache, id) {
+		t.Error("live session reported as revoked after a failed lookup")
+	}
+	if n := store.lookupsOf(id); n != 2 {
+		t.Errorf("%d lookups, want 2", n)
+	}
+}
+
+func TestCacheRevokeWinsOverLookups(t *testing.T) {
+	store := newFakeStore()
+	cache := NewCache(store, 10, time.Hour)
+	id := store.add(false, time.Hour)
+
+	if isRevoked(t, cache, id) {
+		t.Fatal("live session reported as revoked")
+	}
+
+	cache.Revoke(id)
+	if !isRevoked(t, cache, id) {
+		t.Error("revoked session reported as live")
+	}
+
+	// A lookup that started before the revocation finishes after it.
+	cache.put(id, false, time.Now().Add(time.Hour))
+	if !isRevoked(t, cache, id) {
+		t.Error("a racing lookup brought a revoked session back")
+	}
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	store := newFakeStore()
+	cache := NewCache(store, 2, time.Hour)
+	a := store.add(false, time.Hour)
+	b := store.add(false, time.Hour)
+	c := store.add(false, time.Hour)
+
+	isRevoked(t, cache, a)
+	isRevoked(t, cache, b)
+	// Using a again makes b the least recently used.
+	isRevoked(t, cache, a)
+	isRevoked(t, cache, c)
+
+	if cache.lru.Len() != 2 || len(cache.entries) != 2 {
+		t.Fatalf("cache holds %d entries and %d list elements, want 2", len(cache.entries), cache.lru.Len())
+	}
+
+	isRevoked(t, cache, a)
+	isRevoked(t, cache, c)
+	isRevoked(t, cache, b)
+
+	for id, want := range map[uuid.UUID]int{a: 1, c: 1, b: 2} {
+		if n := store.lookupsOf(id); n != want {
+			t.Errorf("session %s: %d lookups, want %d", id, n, want)
+		}
+	}
+}
+
+func TestCacheForgetKeepsRevokedSessions(t *testing.T) {
+	store := newFakeStore()
+	cache := NewCache(store, 10, time.Hour)
+	live := store.add(false, time.Hour)
+	revoked := store.add(false, time.Hour)
+
+	isRevoked(t, cache, live)
+	isRevoked(t, cache, revoked)
+	cache.Revoke(revoked)
+
+	cache.Forget()
+
+	if isRevoked(t, cache, live) {
+		t.Error("live session reported as revoked")
+	}
+	if !isRevoked(t, cache, revoked) {
+		t.Error("revoked session forgotten")
+	}
+	if n := store.lookupsOf(live); n != 2 {
+		t.Errorf("live session: %d lookups, want 2", n)
+	}
+	if n := store.lookupsOf(revoked); n != 1 {
+		t.Errorf("revoked session: %d lookups, want 1", n)
+	}
+}
@@ -1,6 +1,8 @@
 package routes
 
 import (
+	"strings"
+
 	"github.com/bytedance/sonic"
 	"github.com/gofiber/fiber/v2"
 	"github.com/sangketkit01/media-library-api/internal/handlers"
@@ -14,13 +16,21 @@ type Route struct{
 }
 
 func NewRoute(middleware *middleware.Middleware, handler *handlers.Handler) *Route {
+	// Bodies are streamed so tus chunks can be as large as the upload, every
+	// other route is held to RequestBodyLimit by BodyLimitMiddleware.
 	router := fiber.New(fiber.Config{
-		JSONEncoder: sonic.Marshal,
-		JSONDecoder: sonic.Unmarshal,
+		JSONEncoder:                  sonic.Marshal,
+		JSONDecoder:                  sonic.Unmarshal,
+		BodyLimit:                    handler.Config.RequestBodyLimit,
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
 	})
 
 
 	router.Use(middleware.LoggerMiddleware())
+	router.Use(middleware.BodyLimitMiddleware(handler.Config.RequestBodyLimit, func(c *fiber.Ctx) bool {
+		return c.Method() == fiber.MethodPatch && strings.HasPrefix(c.Path(), "/media/uploads/")
+	}))
 	router.Get("/", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{"message": "Hello world"})
 	})
@@ -28,18 +38,79 @@ func NewRoute(middleware *middleware.Middleware, handler *handlers.Handler) *Rou
 	router.Post("/create-user", handler.CreateUser)
 	router.Post("/login-user", handler.LoginUser)
 	router.Post("/refresh-token", handler.RefreshToken)
+	router.Post("/verify-email", handler.VerifyEmail)
+	router.Post("/resend-verification", handler.ResendVerification)
+	router.Post("/password/forgot",
+		middleware.RateLimitMiddleware(handler.Config.PasswordResetIPLimit, handler.Config.PasswordResetWindow),
+		handler.ForgotPassword)
+	router.Post("/password/reset", handler.ResetPassword)
+	router.Options("/media/uploads", handler.TusOptions)
+	shareLimit := middleware.RateLimitMiddleware(handler.Config.ShareRateLimit, handler.Config.ShareRateWindow)
+	router.Get("/s/:token", shareLimit, handler.GetShare)
+	router.Get("/s/:token/media/:media_id", shareLimit, handler.GetSharedGroupMedia)
 	
 
 	authRouter := router.Use(middleware.AuthMiddleware())
 	authRouter.Get("/user", handler.GetCurrentUser)
+	authRouter.Get("/user/usage", handler.GetUserUsage)
+	authRouter.Post("/user/password", handler.ChangePassword)
 	authRouter.Get("/logout", handler.LogoutUser)
+	authRouter.Get("/sessions", handler.ListSessions)
+	authRouter.Post("/sessions/revoke-others", handler.RevokeOtherSessions)
+	authRouter.Delete("/sessions/:id", handler.RevokeSession)
+
+	// Routes below are read only until the email is verified, if so configured.
+	authRouter.Use(middleware.VerifiedEmailMiddleware())
 
 	authRouter.Post("/media/upload", handler.UploadFile)
+	authRouter.Post("/media/uploads", handler.CreateUpload)
+	authRouter.Head("/media/uploads/:id", handler.GetUploadOffset)
+	authRouter.Patch("/media/uploads/:id", handler.AppendUpload)
+	authRouter.Delete("/media/uploads/:id", handler.TerminateUpload)
 	authRouter.Post("/groups", handler.CreateGroup)
+	authRouter.Get("/groups", handler.ListGroups)
+	authRouter.Get("/groups/:id", handler.GetGroup)
+	authRouter.Patch("/groups/:id", handler.RenameGroup)
+	authRouter.Delete("/groups/:id", handler.DeleteGroup)
+	authRouter.Post("/groups/:id/media", handler.AddMediaToGroup)
+	authRouter.Put("/groups/:id/media/order", handler.ReorderGroupMedia)
+	authRouter.Delete("/groups/:id/media/:media_id", handler.RemoveMediaFromGroup)
+	authRouter.Get("/groups/:id/children", handler.GetGroupChildren)
+	authRouter.Post("/groups/:id/move", handler.MoveGroup)
+	authRouter.Post("/groups/:id/copy", handler.CopyGroup)
+	authRouter.Get("/groups/:id/archive", handler.ArchiveGroup)
 	authRouter.Patch("/media/:id/group/:group_id", handler.AssignMediaToGroup)
 
 	authRouter.Get("/media", handler.GetCurrentUserMedia)
+	authRouter.Post("/media/tags", handler.BulkTagMedia)
+	authRouter.Post("/media/tags/remove", handler.BulkUntagMedia)
+	authRouter.Post("/media/archive", handler.ArchiveMedia)
+	authRouter.Get("/media/:id", handler.GetMedia)
+	authRouter.Patch("/media/:id", handler.UpdateMedia)
 	authRouter.Get("/media/:id/download", handler.DownloadMedia)
+	authRouter.Get("/media/:id/stream", handler.StreamMedia)
+	authRouter.Get("/media/:id/thumbnail", handler.GetMediaThumbnail)
+	authRouter.Delete("/media/:id", handler.DeleteMedia)
+	authRouter.Get("/media/:id/tags", handler.GetMediaTags)
+	authRouter.Post("/media/:id/tags", handler.AddMediaTags)
+	authRouter.Delete("/media/:id/tags/:tag", handler.RemoveMediaTag)
+
+	authRouter.Get("/tags", handler.ListTags)
+	authRouter.Get("/search", handler.Search)
+
+	authRouter.Post("/groups/:id/shares", handler.InviteToGroup)
+	authRouter.Get("/groups/:id/shares", handler.ListGroupShares)
+	authRouter.Delete("/groups/:id/shares/:share_id", handler.RevokeGroupShare)
+	authRouter.Get("/shared-with-me", handler.ListSharedWithMe)
+	authRouter.Post("/shared-with-me/:id/accept", handler.AcceptGroupShare)
+	authRouter.Delete("/shared-with-me/:id", handler.LeaveGroupShare)
+
+	authRouter.Post("/shares", handler.CreateShareLink)
+	authRouter.Get("/shares", handler.ListShareLinks)
+	authRouter.Delete("/shares/:id", handler.RevokeShareLink)
+
+	authRouter.Get("/trash", handler.GetTrash)
+	authRouter.Post("/trash/:id/restore", handler.RestoreFromTrash)
 
 	return &Route{
 		Router: router,
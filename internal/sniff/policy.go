@@ -0,0 +1,263 @@
+package sniff
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultTier is the policy entry used for tiers without their own entry.
+const DefaultTier = "*"
+
+var ErrRejected = errors.New("content type rejected")
+
+// RejectedError explains why content was refused. It matches ErrRejected
+// with errors.Is.
+type RejectedError struct {
+	Declared string
+	Detected string
+	Reason   string
+}
+
+func (e *RejectedError) Error() string {
+	return e.Reason
+}
+
+func (e *RejectedError) Is(target error) bool {
+	return target == ErrRejected
+}
+
+// Policy decides, per user tier, which content types may be stored and
+// whether the declared type may disagree with the detected one.
+type Policy struct {
+	allow    map[string][]string
+	deny     map[string][]string
+	mismatch map[string]bool
+}
+
+// ParsePolicy builds a policy from its configuration. allow and deny are
+// semicolon separated "tier:pattern,pattern" entries where a pattern is a MIME
+// type, a "type/*" prefix or "*"; the "*" tier applies to tiers without an
+// entry. mismatch is a comma separated list of tiers allowed to upload content
+// whose declared type does not match the detected one.
+func ParsePolicy(allow, deny, mismatch string) (*Policy, error) {
+	allowRules, err := parseRules(allow)
+	if err != nil {
+		return nil, fmt.Errorf("invalid allow list: %w", err)
+	}
+
+	denyRules, err := parseRules(deny)
+	if err != nil {
+		return nil, fmt.Errorf("invalid deny list: %w", err)
+	}
+
+	tolerant := make(map[string]bool)
+	for _, tier := range strings.Split(mismatch, ",") {
+		if tier = strings.TrimSpace(tier); tier != "" {
+			tolerant[tier] = true
+		}
+	}
+
+	return &Policy{
+		allow:    allowRules,
+		deny:     denyRules,
+		mismatch: tolerant,
+	}, nil
+}
+
+func parseRules(value string) (map[string][]string, error) {
+	rules := make(map[string][]string)
+
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		tier, list, ok := strings.Cut(entry, ":")
+		tier = strings.TrimSpace(tier)
+		if !ok || tier == "" {
+			return nil, fmt.Errorf("entry %q must be tier:patterns", entry)
+		}
+
+		patterns := []string{}
+		for _, pattern := range strings.Split(list, ",") {
+			if pattern = strings.ToLower(strings.TrimSpace(pattern)); pattern != "" {
+				patterns = append(patterns, pattern)
+			}
+		}
+
+		rules[tier] = patterns
+	}
+
+	return rules, nil
+}
+
+func lookup(rules map[string][]string, tier string) []string {
+	if patterns, ok := rules[tier]; ok {
+		return patterns
+	}
+	return rules[DefaultTier]
+}
+
+func matchAny(patterns []string, mimeType string) bool {
+	for _, pattern := range patterns {
+		if pattern == "*" || pattern == mimeType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok && strings.HasPrefix(mimeType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Check decides whether content of the detected type, uploaded as the declared
+// type by a user of the given tier, may be stored. It returns the type the
+// content should be stored and served as.
+func (p *Policy) Check(tier, declared, detected string) (string, error) {
+	declared = Normalize(declared)
+	detected = Normalize(detected)
+	if detected == "" {
+		detected = OctetStream
+	}
+
+	var effective string
+	switch {
+	case declared == "" || declared == OctetStream:
+		effective = detected
+
+	case Compatible(declared, detected):
+		effective = detected
+		// Markup a browser would render must keep its type, whatever it
+		// was declared as, so the deny and allow lists see it for what it is.
+		if isContainer(detected) && !Active(detected) {
+			// The declared type is more specific than what the bytes tell.
+			effective = declared
+		}
+
+	case p.mismatch[tier]:
+		effective = detected
+		if detected == OctetStream {
+			effective = declared
+		}
+
+	default:
+		return "", &RejectedError{
+			Declared: declared,
+			Detected: detected,
+			Reason:   fmt.Sprintf("content looks like %s but was uploaded as %s", detected, declared),
+		}
+	}
+
+	deny := lookup(p.deny, tier)
+	for _, mimeType := range []string{effective, detected} {
+		if matchAny(deny, mimeType) {
+			return "", &RejectedError{
+				Declared: declared,
+				Detected: detected,
+				Reason:   fmt.Sprintf("content type %s is not allowed", mimeType),
+			}
+		}
+	}
+
+	if !matchAny(lookup(p.allow, tier), effective) {
+		return "", &RejectedError{
+			Declared: declared,
+			Detected: detected,
+			Reason:   fmt.Sprintf("content type %s is not allowed", effective),
+		}
+	}
+
+	return effective, nil
+}
+
+var aliases = map[string]string{
+	"image/jpg":                    "image/jpeg",
+	"image/pjpeg":                  "image/jpeg",
+	"image/x-png":                  "image/png",
+	"image/heif":                   "image/heic",
+	"audio/x-wav":                  "audio/wav",
+	"audio/wave":                   "audio/wav",
+	"audio/vnd.wave":               "audio/wav",
+	"audio/mp3":                    "audio/mpeg",
+	"audio/x-flac":                 "audio/flac",
+	"audio/x-m4a":                  "video/mp4",
+	"audio/mp4":                    "video/mp4",
+	"video/x-m4v":                  "video/mp4",
+	"audio/webm":                   "video/webm",
+	"video/x-matroska":             "video/webm",
+	"audio/ogg":                    "application/ogg",
+	"video/ogg":                    "application/ogg",
+	"application/x-pdf":            "application/pdf",
+	"application/x-zip-compressed": "application/zip",
+	"application/x-gzip":           "application/gzip",
+	"application/x-rar-compressed": "application/vnd.rar",
+	"image/vnd.microsoft.icon":     "image/x-icon",
+	"application/x-msdos-program":  "application/x-msdownload",
+	"application/vnd.microsoft.portable-executable": "application/x-msdownload",
+	"application/x-sh": "text/x-shellscript",
+}
+
+func canonical(mimeType string) string {
+	if alias, ok := aliases[mimeType]; ok {
+		return alias
+	}
+	return mimeType
+}
+
+// isContainer reports whether the detected type is a generic format shared by
+// many more specific declared types.
+func isContainer(detected string) bool {
+	switch detected {
+	case "text/plain", "text/html", "text/xml", "application/zip", "application/x-ole-storage":
+		return true
+	}
+	return false
+}
+
+func isText(mimeType string) bool {
+	switch {
+	case strings.HasPrefix(mimeType, "text/"),
+		strings.HasSuffix(mimeType, "+json"),
+		strings.HasSuffix(mimeType, "+xml"):
+		return true
+	}
+
+	switch mimeType {
+	case "application/json", "application/xml", "application/javascript", "application/x-yaml",
+		"application/yaml", "application/x-ndjson", "application/sql", "application/toml":
+		return true
+	}
+	return false
+}
+
+// Compatible reports whether content detected as detected may legitimately be
+// declared as declared.
+func Compatible(declared, detected string) bool {
+	declared, detected = canonical(Normalize(declared)), canonical(Normalize(detected))
+
+	if declared == detected {
+		return true
+	}
+
+	switch detected {
+	case "text/plain", "text/html", "text/xml":
+		return isText(declared)
+
+	case "application/zip":
+		return strings.HasPrefix(declared, "application/vnd.openxmlformats-officedocument.") ||
+			strings.HasPrefix(declared, "application/vnd.oasis.opendocument.") ||
+			strings.HasSuffix(declared, "+zip") ||
+			declared == "application/java-archive" ||
+			declared == "application/vnd.android.package-archive"
+
+	case "application/x-ole-storage":
+		return declared == "application/msword" ||
+			declared == "application/vnd.ms-excel" ||
+			declared == "application/vnd.ms-powerpoint" ||
+			declared == "application/vnd.ms-outlook"
+	}
+
+	return false
+}
@@ -0,0 +1,53 @@
+package sniff
+
+import (
+	"errors"
+	"testing"
+)
+
+const (
+	testAllow = "*:image/*,video/*,audio/*,application/pdf,text/plain,text/csv;pro:*"
+	testDeny  = "*:application/x-msdownload,text/html,application/xhtml+xml,image/svg+xml"
+)
+
+func TestCheckRejectsActiveMarkup(t *testing.T) {
+	policy, err := ParsePolicy(testAllow, testDeny, "pro")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		declared string
+		content  string
+	}{
+		{"svg with script", "image/svg+xml", `<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`},
+		{"html declared as svg", "image/svg+xml", "<html><script>alert(1)</script></html>"},
+		{"html declared as text", "text/plain", "<html><script>alert(1)</script></html>"},
+		{"html declared as png", "image/png", "<html><script>alert(1)</script></html>"},
+	}
+
+	for _, tt := range tests {
+		for _, tier := range []string{"free", "pro"} {
+			effective, err := policy.Check(tier, tt.declared, Detect([]byte(tt.content)))
+			if !errors.Is(err, ErrRejected) {
+				t.Errorf("%s (%s): got %q, %v, want rejected", tt.name, tier, effective, err)
+			}
+		}
+	}
+}
+
+func TestCheckKeepsDeclaredTypeOfPlainText(t *testing.T) {
+	policy, err := ParsePolicy(testAllow, testDeny, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	effective, err := policy.Check("free", "text/csv", Detect([]byte("a,b,c\n1,2,3\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if effective != "text/csv" {
+		t.Errorf("effective type = %q, want text/csv", effective)
+	}
+}
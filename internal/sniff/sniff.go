@@ -0,0 +1,201 @@
+// Package sniff detects the type of uploaded content from its leading bytes
+// and decides whether it may be stored.
+package sniff
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// HeaderSize is the number of leading bytes Detect looks at.
+const HeaderSize = 3072
+
+const OctetStream = "application/octet-stream"
+
+type signature struct {
+	offset   int
+	magic    []byte
+	mimeType string
+}
+
+// signatures are checked in order before falling back to
+// http.DetectContentType, which misses several media and executable formats.
+var signatures = []signature{
+	{0, []byte("\xFF\xD8\xFF"), "image/jpeg"},
+	{0, []byte("\x89PNG\r\n\x1A\n"), "image/png"},
+	{0, []byte("GIF87a"), "image/gif"},
+	{0, []byte("GIF89a"), "image/gif"},
+	{0, []byte("II*\x00"), "image/tiff"},
+	{0, []byte("MM\x00*"), "image/tiff"},
+	{0, []byte("8BPS"), "image/vnd.adobe.photoshop"},
+	{0, []byte("\x00\x00\x01\x00"), "image/x-icon"},
+	{0, []byte("fLaC"), "audio/flac"},
+	{0, []byte("ID3"), "audio/mpeg"},
+	{0, []byte("OggS"), "application/ogg"},
+	{0, []byte("%PDF-"), "application/pdf"},
+	{0, []byte("\xD0\xCF\x11\xE0\xA1\xB1\x1A\xE1"), "application/x-ole-storage"},
+	{0, []byte("7z\xBC\xAF\x27\x1C"), "application/x-7z-compressed"},
+	{0, []byte("Rar!\x1A\x07"), "application/vnd.rar"},
+	{0, []byte("\x1F\x8B"), "application/gzip"},
+	{257, []byte("ustar"), "application/x-tar"},
+	{0, []byte("MZ"), "application/x-msdownload"},
+	{0, []byte("\x7FELF"), "application/x-executable"},
+	{0, []byte("\xFE\xED\xFA\xCE"), "application/x-mach-binary"},
+	{0, []byte("\xFE\xED\xFA\xCF"), "application/x-mach-binary"},
+	{0, []byte("\xCE\xFA\xED\xFE"), "application/x-mach-binary"},
+	{0, []byte("\xCF\xFA\xED\xFE"), "application/x-mach-binary"},
+	{0, []byte("#!"), "text/x-shellscript"},
+}
+
+// ftypBrands maps the major brand of ISO base media files to a MIME type.
+var ftypBrands = map[string]string{
+	"heic": "image/heic",
+	"heix": "image/heic",
+	"hevc": "image/heic",
+	"hevx": "image/heic",
+	"heim": "image/heic",
+	"heis": "image/heic",
+	"mif1": "image/heif",
+	"msf1": "image/heif",
+	"avif": "image/avif",
+	"avis": "image/avif",
+	"qt  ": "video/quicktime",
+	"M4A ": "audio/mp4",
+	"M4B ": "audio/mp4",
+	"M4V ": "video/mp4",
+	"crx ": "image/x-canon-cr3",
+}
+
+// Detect returns the MIME type of content starting with header, without
+// parameters. Unknown binary content is reported as OctetStream.
+func Detect(header []byte) string {
+	if len(header) > HeaderSize {
+		header = header[:HeaderSize]
+	}
+
+	if len(header) >= 12 && bytes.Equal(header[4:8], []byte("ftyp")) {
+		brand := string(header[8:12])
+		if mimeType, ok := ftypBrands[brand]; ok {
+			return mimeType
+		}
+		if strings.HasPrefix(brand, "3gp") {
+			return "video/3gpp"
+		}
+		return "video/mp4"
+	}
+
+	if len(header) >= 12 && bytes.Equal(header[0:4], []byte("RIFF")) {
+		switch string(header[8:12]) {
+		case "WEBP":
+			return "image/webp"
+		case "WAVE":
+			return "audio/wav"
+		case "AVI ":
+			return "video/x-msvideo"
+		}
+	}
+
+	if bytes.HasPrefix(header, []byte("\x1A\x45\xDF\xA3")) {
+		if bytes.Contains(header, []byte("webm")) {
+			return "video/webm"
+		}
+		return "video/x-matroska"
+	}
+
+	if bytes.HasPrefix(header, []byte("PK\x03\x04")) {
+		// EPUB and OpenDocument files start with an uncompressed "mimetype"
+		// entry holding their type.
+		if len(header) > 38 && bytes.Equal(header[30:38], []byte("mimetype")) {
+			end := bytes.Index(header[38:], []byte("PK\x03\x04"))
+			if end > 0 {
+				if mimeType := Normalize(string(header[38 : 38+end])); strings.HasPrefix(mimeType, "application/") {
+					return mimeType
+				}
+			}
+		}
+		return "application/zip"
+	}
+
+	for _, sig := range signatures {
+		if len(header) >= sig.offset+len(sig.magic) && bytes.Equal(header[sig.offset:sig.offset+len(sig.magic)], sig.magic) {
+			return sig.mimeType
+		}
+	}
+
+	detected := Normalize(http.DetectContentType(header))
+	if detected == "text/xml" || detected == "text/plain" {
+		if isSVG(header) {
+			return "image/svg+xml"
+		}
+	}
+
+	return detected
+}
+
+func isSVG(header []byte) bool {
+	trimmed := bytes.TrimSpace(header)
+	if bytes.HasPrefix(trimmed, []byte("<?xml")) {
+		end := bytes.Index(trimmed, []byte("?>"))
+		if end < 0 {
+			return false
+		}
+		trimmed = bytes.TrimSpace(trimmed[end+2:])
+	}
+
+	for bytes.HasPrefix(trimmed, []byte("<!--")) || bytes.HasPrefix(trimmed, []byte("<!DOCTYPE")) {
+		end := bytes.IndexByte(trimmed, '>')
+		if end < 0 {
+			return false
+		}
+		trimmed = bytes.TrimSpace(trimmed[end+1:])
+	}
+
+	return bytes.HasPrefix(trimmed, []byte("<svg"))
+}
+
+// Active reports whether browsers may run scripts from content of the given
+// type when it is opened directly, such content must never be served inline.
+func Active(mimeType string) bool {
+	switch canonical(Normalize(mimeType)) {
+	case "text/html", "application/xhtml+xml", "image/svg+xml", "text/xml", "application/xml",
+		"text/javascript", "application/javascript", "application/x-javascript", "text/xsl",
+		"application/xslt+xml", "application/vnd.wap.xhtml+xml", "multipart/x-mixed-replace":
+		return true
+	}
+	return false
+}
+
+// Normalize lower-cases a MIME type and strips its parameters. Invalid values
+// normalize to an empty string.
+func Normalize(mimeType string) string {
+	if strings.TrimSpace(mimeType) == "" {
+		return ""
+	}
+
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return ""
+	}
+
+	return mediaType
+}
+
+// Sniffer is an io.Writer keeping the first HeaderSize bytes written to it,
+// so content can be detected while it is streamed somewhere else.
+type Sniffer struct {
+	header []byte
+}
+
+func (s *Sniffer) Write(p []byte) (int, error) {
+	if remaining := HeaderSize - len(s.header); remaining > 0 {
+		s.header = append(s.header, p[:min(remaining, len(p))]...)
+	}
+	return len(p), nil
+}
+
+// Type returns the detected type of the content written so far.
+func (s *Sniffer) Type() string {
+	return Detect(s.header)
+}
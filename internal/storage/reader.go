@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"context"
+	"io"
+)
+
+type concatReader struct {
+	ctx     context.Context
+	backend Backend
+	keys    []string
+	current io.ReadCloser
+}
+
+// NewConcatReader returns a reader over the objects stored under keys, read
+// one after another. Objects are opened lazily as the reader advances.
+func NewConcatReader(ctx context.Context, backend Backend, keys []string) io.ReadCloser {
+	return &concatReader{
+		ctx:     ctx,
+		backend: backend,
+		keys:    keys,
+	}
+}
+
+func (r *concatReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.keys) == 0 {
+				return 0, io.EOF
+			}
+
+			reader, _, err := r.backend.Get(r.ctx, r.keys[0])
+			if err != nil {
+				return 0, err
+			}
+
+			r.current = reader
+			r.keys = r.keys[1:]
+		}
+
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			r.current.Close()
+			r.current = nil
+
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+
+		return n, err
+	}
+}
+
+func (r *concatReader) Close() error {
+	if r.current == nil {
+		return nil
+	}
+
+	err := r.current.Close()
+	r.current = nil
+	return err
+}
+
+// readerAtBlockSize is the granularity of the range requests made by the
+// reader returned from NewReaderAt. Metadata parsers issue many small reads
+// close to each other, which are served from the cached blocks.
+const (
+	readerAtBlockSize = 64 << 10
+	readerAtMaxBlocks = 8
+)
+
+type readerAt struct {
+	ctx     context.Context
+	backend Backend
+	key     string
+	size    int64
+	blocks  map[int64][]byte
+	order   []int64
+}
+
+// NewReaderAt returns an io.ReaderAt over the object stored under key, which
+// is size bytes long. Data is fetched with range requests in fixed blocks and
+// the most recently used blocks are kept in memory.
+func NewReaderAt(ctx context.Context, backend Backend, key string, size int64) io.ReaderAt {
+	return &readerAt{
+		ctx:     ctx,
+		backend: backend,
+		key:     key,
+		size:    size,
+		blocks:  make(map[int64][]byte),
+	}
+}
+
+func (r *readerAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, ErrInvalidRange
+	}
+
+	n := 0
+	for n < len(p) {
+		if off >= r.size {
+			return n, io.EOF
+		}
+
+		index := off / readerAtBlockSize
+		block, err := r.block(index)
+		if err != nil {
+			return n, err
+		}
+
+		copied := copy(p[n:], block[off-index*readerAtBlockSize:])
+		n += copied
+		off += int64(copied)
+	}
+
+	return n, nil
+}
+
+func (r *readerAt) block(index int64) ([]byte, error) {
+	if block, ok := r.blocks[index]; ok {
+		return block, nil
+	}
+
+	offset := index * readerAtBlockSize
+	length := min(int64(readerAtBlockSize), r.size-offset)
+
+	reader, _, err := r.backend.GetRange(r.ctx, r.key, offset, length)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	block := make([]byte, length)
+	if _, err := io.ReadFull(reader, block); err != nil {
+		return nil, err
+	}
+
+	if len(r.order) >= readerAtMaxBlocks {
+		delete(r.blocks, r.order[0])
+		r.order = r.order[1:]
+	}
+	r.blocks[index] = block
+	r.order = append(r.order, index)
+
+	return block, nil
+}
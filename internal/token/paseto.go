@@ -11,6 +11,9 @@ import (
 
 type Maker interface {
 	CreateToken(id uuid.UUID, sessionID uuid.UUID,duration time.Duration) (string, *Payload, error)
+	// CreateRefreshToken creates a refresh token for the given generation of
+	// a session.
+	CreateRefreshToken(id uuid.UUID, sessionID uuid.UUID, generation int32, duration time.Duration) (string, *Payload, error)
 	VerifyToken(token string) (*Payload, error)
 }
 
@@ -40,6 +43,23 @@ func (maker *PasetoMaker) CreateToken(id uuid.UUID, sessionID uuid.UUID,duration
 	return token, payload, nil
 }
 
+func (maker *PasetoMaker) CreateRefreshToken(id uuid.UUID, sessionID uuid.UUID, generation int32, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(id, sessionID, duration)
+	if err != nil {
+		return "", nil, err
+	}
+
+	payload.Type = TypeRefresh
+	payload.Generation = generation
+
+	token, err := maker.paseto.Encrypt(maker.secretKey, payload, nil)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return token, payload, nil
+}
+
 func (maker *PasetoMaker) VerifyToken(token string) (*Payload, error){
 	payload := &Payload{}
 
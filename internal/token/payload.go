@@ -13,17 +13,28 @@ var (
 	ErrInvalidUserID = errors.New("invalid ID")
 )
 
+// Token types, so that a token cannot be used in place of the other kind.
+// Tokens issued before types were added have an empty type.
+const (
+	TypeAccess  = "access"
+	TypeRefresh = "refresh"
+)
+
 type Payload struct {
 	SessionID uuid.UUID `json:"session_id"`
 	ID        uuid.UUID `json:"id"`
-	IssuedAt  time.Time `json:"issued_at"`
-	ExpiredAt time.Time `json:"expired_at"`
+	Type      string    `json:"type,omitempty"`
+	// Generation is the session generation a refresh token was issued for.
+	Generation int32     `json:"generation,omitempty"`
+	IssuedAt   time.Time `json:"issued_at"`
+	ExpiredAt  time.Time `json:"expired_at"`
 }
 
 func NewPayload(id uuid.UUID, sessionID uuid.UUID, duration time.Duration) (*Payload, error) {
 	payload := &Payload{
 		SessionID: sessionID,
 		ID:        id,
+		Type:      TypeAccess,
 		IssuedAt:  time.Now(),
 		ExpiredAt: time.Now().Add(duration),
 	}
@@ -0,0 +1,66 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+var ErrInvalidSignedToken = errors.New("invalid signed token")
+
+// RandomToken returns a URL safe token of size random bytes.
+func RandomToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the SHA-256 of a token. Tokens are random, so unlike
+// passwords they do not need a slow hash to be stored safely.
+func HashToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+// SignToken returns a URL safe token carrying data and its HMAC-SHA256 under
+// secret. The purpose is signed too, so a token made for one use is not
+// accepted for another.
+func SignToken(secret, purpose string, data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data) + "." +
+		base64.RawURLEncoding.EncodeToString(tokenMAC(secret, purpose, data))
+}
+
+// VerifySignedToken returns the data of a token made by SignToken for the
+// same secret and purpose.
+func VerifySignedToken(secret, purpose, token string) ([]byte, error) {
+	encodedData, encodedMAC, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidSignedToken
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(encodedData)
+	if err != nil {
+		return nil, ErrInvalidSignedToken
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(encodedMAC)
+	if err != nil || !hmac.Equal(mac, tokenMAC(secret, purpose, data)) {
+		return nil, ErrInvalidSignedToken
+	}
+
+	return data, nil
+}
+
+func tokenMAC(secret, purpose string, data []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(purpose))
+	h.Write([]byte{0})
+	h.Write(data)
+	return h.Sum(nil)
+}
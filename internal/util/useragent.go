@@ -0,0 +1,99 @@
+package util
+
+import "strings"
+
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+	DeviceUnknown = "unknown"
+)
+
+// UserAgent is the client described by a User-Agent header, as far as it can
+// be told. Fields that cannot be told are empty.
+type UserAgent struct {
+	Browser string `json:"browser"`
+	OS      string `json:"os"`
+	Device  string `json:"device"`
+}
+
+type uaRule struct {
+	token string
+	name  string
+}
+
+// Browsers are checked in order, as most of them also claim to be the ones
+// they are built on, e.g. Edge sends both "Edg/" and "Chrome/".
+var browserRules = []uaRule{
+	{"Edg/", "Edge"},
+	{"EdgiOS/", "Edge"},
+	{"EdgA/", "Edge"},
+	{"OPR/", "Opera"},
+	{"SamsungBrowser/", "Samsung Internet"},
+	{"FxiOS/", "Firefox"},
+	{"Firefox/", "Firefox"},
+	{"CriOS/", "Chrome"},
+	{"Chrome/", "Chrome"},
+	{"Version/", "Safari"},
+	{"PostmanRuntime/", "Postman"},
+	{"curl/", "curl"},
+	{"okhttp/", "OkHttp"},
+	{"Go-http-client/", "Go"},
+}
+
+var osRules = []uaRule{
+	{"iPhone", "iOS"},
+	{"iPad", "iPadOS"},
+	{"iPod", "iOS"},
+	{"Android", "Android"},
+	{"CrOS", "ChromeOS"},
+	{"Windows", "Windows"},
+	{"Macintosh", "macOS"},
+	{"Mac OS X", "macOS"},
+	{"Linux", "Linux"},
+}
+
+// ParseUserAgent tells the browser, its major version, the operating system
+// and the kind of device from a User-Agent header.
+func ParseUserAgent(header string) UserAgent {
+	ua := UserAgent{Device: DeviceUnknown}
+
+	for _, rule := range browserRules {
+		i := strings.Index(header, rule.token)
+		if i < 0 {
+			continue
+		}
+
+		ua.Browser = rule.name
+		version := header[i+len(rule.token):]
+		if end := strings.IndexAny(version, ". ;)"); end >= 0 {
+			version = version[:end]
+		}
+		if version != "" {
+			ua.Browser += " " + version
+		}
+		break
+	}
+
+	for _, rule := range osRules {
+		if strings.Contains(header, rule.token) {
+			ua.OS = rule.name
+			break
+		}
+	}
+
+	lower := strings.ToLower(header)
+	switch {
+	case strings.Contains(lower, "bot") || strings.Contains(lower, "crawler") || strings.Contains(lower, "spider"):
+		ua.Device = DeviceBot
+	case ua.OS == "iPadOS" || (ua.OS == "Android" && !strings.Contains(header, "Mobile")):
+		ua.Device = DeviceTablet
+	case strings.Contains(header, "Mobi") || ua.OS == "iOS":
+		ua.Device = DeviceMobile
+	case ua.OS == "Windows" || ua.OS == "macOS" || ua.OS == "Linux" || ua.OS == "ChromeOS":
+		ua.Device = DeviceDesktop
+	}
+
+	return ua
+}